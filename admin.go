@@ -0,0 +1,60 @@
+package ramchi
+
+import (
+	"net/http"
+
+	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+)
+
+// AdminListener is the name of the listener serving operational endpoints when
+// config.AdminAddress is set. Routers may be bound to it with
+// router.WithRouterListener.
+const AdminListener = "admin"
+
+// opsListener returns the name of the listener serving operational endpoints: the
+// admin listener when one is configured, or the main listener.
+func (s *Server) opsListener() string {
+	if s.cfg.AdminAddress != "" {
+		return AdminListener
+	}
+	return ""
+}
+
+// pprofListener returns the name of the listener serving the pprof handlers.
+func (s *Server) pprofListener() string {
+	if s.cfg.PprofListener != "" {
+		return s.cfg.PprofListener
+	}
+	return s.opsListener()
+}
+
+// listenerAddresses returns the addresses of the listeners served besides the main
+// listener, by name, including the admin listener.
+func (s *Server) listenerAddresses() map[string]string {
+	addrs := make(map[string]string, len(s.cfg.Listeners)+1)
+	for name, addr := range s.cfg.Listeners {
+		addrs[name] = addr
+	}
+	if s.cfg.AdminAddress != "" {
+		addrs[AdminListener] = s.cfg.AdminAddress
+	}
+	return addrs
+}
+
+// adminRouter returns the router of the admin listener, serving a liveness check at
+// /healthz, the readiness check at /readyz, the certificate status at /healthz/tls,
+// and the configuration, redacted, at /config.
+func (s *Server) adminRouter() router.Router {
+	return router.NewRouter([]router.Route{
+		router.NewGetRoute("/healthz", true, false, func(w http.ResponseWriter, r *http.Request) {
+			helpers.JSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+		}, router.WithInternal()),
+		router.NewGetRoute("/readyz", true, false, s.Readiness, router.WithInternal()),
+		router.NewGetRoute("/healthz/tls", true, false, s.CertificateHealth, router.WithInternal()),
+		router.NewGetRoute("/config", true, false, func(w http.ResponseWriter, r *http.Request) {
+			helpers.JSON(w, r, http.StatusOK, helpers.Redact(c.FromContext(r.Context())))
+		}, router.WithInternal()),
+	}, true)
+}
@@ -0,0 +1,51 @@
+package ramchi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// autocert returns the TLS configuration obtaining certificates for the configured
+// domains from the ACME certificate authority.
+func (s *Server) autocert() (*tls.Config, error) {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(s.cfg.AutocertDomains...),
+		Email:      s.cfg.AutocertEmail,
+	}
+	if s.cfg.AutocertCacheDir != "" {
+		m.Cache = autocert.DirCache(s.cfg.AutocertCacheDir)
+	}
+	if s.cfg.AutocertDirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: s.cfg.AutocertDirectoryURL}
+	}
+
+	cfg, err := s.tlsConfig(m.GetCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("autocert: %w", err)
+	}
+	cfg.NextProtos = append(cfg.NextProtos, acme.ALPNProto)
+
+	if addr := s.cfg.AutocertHTTPAddress; addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("autocert: failed binding challenge listener: %w", err)
+		}
+		srv := &http.Server{Addr: addr, Handler: m.HTTPHandler(nil)}
+		s.configure(srv)
+		s.listeners = append(s.listeners, srv)
+
+		s.log.Debug().Str("Listener", "autocert").Str("Address", addr).Strs("Domains", s.cfg.AutocertDomains).Msg("Listener started")
+		go func() {
+			if err := srv.Serve(ln); err != http.ErrServerClosed {
+				s.log.Error().Str("Function", "autocert").Err(err).Msg("Challenge listener failed")
+			}
+		}()
+	}
+	return cfg, nil
+}
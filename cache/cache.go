@@ -0,0 +1,79 @@
+// Package cache provides the storage backends of the response and cache-aside helpers.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by fetch functions when the value does not exist, so
+// that its absence may be cached.
+var ErrNotFound = errors.New("not found")
+
+// Backend stores values with an expiry.
+type Backend interface {
+	// Get returns the value of the key, and false when it is absent or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of the key until the ttl elapses.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key.
+	Delete(ctx context.Context, key string) error
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+type memory struct {
+	mu         sync.Mutex
+	entries    map[string]entry
+	maxEntries int
+}
+
+// NewMemory initializes a Backend local to the process, holding up to maxEntries
+// values. When full, expired values are evicted first, then arbitrary ones.
+func NewMemory(maxEntries int) Backend {
+	return &memory{entries: make(map[string]entry), maxEntries: maxEntries}
+}
+
+func (m *memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !time.Now().Before(e.expires) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := m.entries[key]; !ok && m.maxEntries > 0 && len(m.entries) >= m.maxEntries {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		for k := range m.entries {
+			if len(m.entries) < m.maxEntries {
+				break
+			}
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = entry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
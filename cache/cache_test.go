@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+
+	if err := m.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := m.Get(ctx, "a"); err != nil || !ok || string(v) != "1" {
+		t.Fatalf("got %q, %v, %v, want the value set", v, ok, err)
+	}
+	if _, ok, _ := m.Get(ctx, "missing"); ok {
+		t.Fatal("found a key never set")
+	}
+
+	if err := m.Set(ctx, "expired", []byte("2"), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := m.Get(ctx, "expired"); ok {
+		t.Fatal("found an expired value")
+	}
+
+	// Full, so the expired value is evicted before the live one.
+	if err := m.Set(ctx, "b", []byte("3"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, ok, _ := m.Get(ctx, key); !ok {
+			t.Fatalf("evicted %s, want the expired value evicted instead", key)
+		}
+	}
+	if err := m.Set(ctx, "c", []byte("4"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	held := 0
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok, _ := m.Get(ctx, key); ok {
+			held++
+		}
+	}
+	if held != 2 {
+		t.Fatalf("holding %d values, want at most 2", held)
+	}
+
+	if err := m.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := m.Get(ctx, "c"); ok {
+		t.Fatal("found a deleted value")
+	}
+}
@@ -1,37 +1,203 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 const CONFIG = "./ramchi.config.json"
 
+// The environment profiles a server may run under.
+const (
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+// The exporters of metrics.
+const (
+	MetricsPrometheus = "prometheus"
+	MetricsStatsD     = "statsd"
+	MetricsDogStatsD  = "dogstatsd"
+	MetricsOTLP       = "otlp"
+)
+
+// The minimum TLS versions a server may accept.
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// The policies for TLS client certificates, from none requested to a verified
+// certificate required, as for mutual TLS.
+const (
+	TLSClientAuthNone          = "none"
+	TLSClientAuthRequest       = "request"
+	TLSClientAuthRequire       = "require"
+	TLSClientAuthVerify        = "verify"
+	TLSClientAuthRequireVerify = "require-verify"
+)
+
+// The outputs a server may log to.
+const (
+	LogOutputConsole  = "console"
+	LogOutputSyslog   = "syslog"
+	LogOutputJournald = "journald"
+	LogOutputOTLP     = "otlp"
+)
+
+// The modes of path normalization, retrying requests which match no route with
+// their normalized path.
+const (
+	NormalizeRedirect = "redirect"
+	NormalizeRewrite  = "rewrite"
+)
+
 var c *Config
 
+// loaded is the path of the file the configuration was loaded from, if any.
+var loaded string
+
+// Files are the configuration files Load looks for, in order. The first found is
+// loaded in the format of its extension: JSON, YAML or TOML.
+var Files = []string{CONFIG, "./ramchi.config.yaml", "./ramchi.config.yml", "./ramchi.config.toml"}
+
+// Load loads the first of Files found. If none is found, the defaults are written
+// to CONFIG, or kept in memory when the working directory is read-only.
 func Load() error {
-	_, err := os.Stat(CONFIG)
-	if os.IsNotExist(err) {
-		if err := Create(); err != nil {
+	path := ""
+	for _, f := range Files {
+		if _, err := os.Stat(f); err == nil {
+			path = f
+			break
+		}
+	}
+	if path == "" {
+		err := Create()
+		if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+			c = Defaults()
+			loaded = ""
+			return nil
+		}
+		if err != nil {
 			return fmt.Errorf("Load: failed creating load: %w", err)
 		}
+		path = CONFIG
 	}
 
-	file, err := os.ReadFile(CONFIG)
+	cfg, err := Read(path)
 	if err != nil {
-		return fmt.Errorf("Load: failed reading json: %w", err)
+		return fmt.Errorf("Load: %w", err)
 	}
+	c = cfg
+	loaded = path
+	useSections(cfg)
+	return nil
+}
 
-	err = json.Unmarshal(file, &c)
+// Read reads the configuration file at path, in the format of its extension,
+// resolving its secrets and validating it, without replacing the configuration.
+func Read(path string) (*Config, error) {
+	file, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("Load: failed marshalling json: %w", err)
+		return nil, fmt.Errorf("Read: failed reading config: %w", err)
 	}
-	return nil
+
+	cfg, err := parse(path, file)
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+
+	err = resolveSecrets(reflect.ValueOf(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("Read: failed resolving secrets: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+	return cfg, nil
+}
+
+// parse parses the configuration in the format of the extension of its path. The
+// fields of Config have the same names in every format.
+func parse(path string, file []byte) (*Config, error) {
+	cfg := &Config{}
+	var raw map[string]json.RawMessage
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err := json.Unmarshal(file, cfg); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling json: %w", err)
+		}
+		if err := json.Unmarshal(file, &raw); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling json: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(file, cfg); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling yaml: %w", err)
+		}
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(file, &tree); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling yaml: %w", err)
+		}
+		var err error
+		if raw, err = rawSections(tree); err != nil {
+			return nil, fmt.Errorf("parse: %w", err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(file, cfg); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling toml: %w", err)
+		}
+		var tree map[string]interface{}
+		if err := toml.Unmarshal(file, &tree); err != nil {
+			return nil, fmt.Errorf("parse: failed unmarshalling toml: %w", err)
+		}
+		var err error
+		if raw, err = rawSections(tree); err != nil {
+			return nil, fmt.Errorf("parse: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("parse: unknown config format %q", ext)
+	}
+
+	if err := parseSections(cfg, raw); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	return cfg, nil
+}
+
+// rawSections converts the registered sections of a YAML or TOML file to JSON, so
+// that sections are decoded by their json field names in every format.
+func rawSections(tree map[string]interface{}) (map[string]json.RawMessage, error) {
+	raw := make(map[string]json.RawMessage)
+	for _, name := range sectionNames() {
+		value, ok := tree[name]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("rawSections: failed converting section %s: %w", name, err)
+		}
+		raw[name] = b
+	}
+	return raw, nil
 }
 
 func Create() error {
-	file, err := json.MarshalIndent(&Config{Port: "7000", Address: "0.0.0.0", Experimental: false}, "", " ")
+	file, err := json.MarshalIndent(Defaults(), "", " ")
 	if err != nil {
 		return fmt.Errorf("Create: failed marshalling config: %w", err)
 	}
@@ -42,6 +208,91 @@ func Create() error {
 	return nil
 }
 
+// Defaults returns the configuration written by Create, so that a configuration
+// may be built in memory and passed to Use, or ramchi.WithConfig, without a file.
+func Defaults() *Config {
+	return &Config{
+		Port:                  "7000",
+		Address:               "0.0.0.0",
+		Experimental:          false,
+		Profile:               ProfileProd,
+		EnableCORS:            false,
+		CORSAllowedOrigins:    []string{"*"},
+		CORSAllowedMethods:    []string{"GET", "POST", "PUT", "DELETE", "HEAD"},
+		CORSAllowedHeaders:    []string{},
+		CORSExposedHeaders:    []string{},
+		MethodOverrideOrigins: []string{},
+		CookieProfile:         "strict",
+		TLSExpiryWarningDays:  30,
+		TLSSessionTickets:     true,
+		TLSMinVersion:         TLSVersion12,
+		TLSCipherSuites:       []string{},
+		TLSClientAuth:         TLSClientAuthNone,
+		AutocertDomains:       []string{},
+		AutocertCacheDir:      "./certs",
+		AutocertHTTPAddress:   ":80",
+		ServiceName:           "ramchi",
+		ProxyProtocolTrusted:  []string{},
+		ProxyProtocolTimeout:  5,
+		ConnectionBurst:       20,
+		Listeners:             map[string]string{},
+		RewriteRules:          []RewriteRule{},
+		DefaultHeaders:        map[string]string{},
+		ShutdownSignals:       []string{"SIGINT", "SIGTERM"},
+		WatchdogInterval:      10,
+		MetricsPath:           "/metrics",
+		MetricsExporter:       MetricsPrometheus,
+		PprofPrefix:           "/debug/pprof",
+		ProfilingEndpoint:     "http://127.0.0.1:4040",
+		ProfilingInterval:     10,
+		ProfilingTags:         map[string]string{},
+		StatsDAddress:         "127.0.0.1:8125",
+		MetricsTenantLimit:    100,
+		LogOutput:             LogOutputConsole,
+		OTLPEndpoint:          "http://127.0.0.1:4318",
+		OTLPHeaders:           map[string]string{},
+		OTLPResource:          map[string]string{},
+		LogLevel:              "debug",
+		IdleTimeout:           Duration(2 * time.Minute),
+		ShutdownTimeout:       Duration(30 * time.Second),
+	}
+}
+
+// Use replaces the configuration with cfg, so that it is not loaded from disk.
+func Use(cfg *Config) {
+	c = cfg
+	loaded = ""
+}
+
+// Path returns the path of the file the configuration was loaded from, or an
+// empty string if it was not loaded from disk.
+func Path() string {
+	return loaded
+}
+
+// Current returns the loaded configuration, or nil.
+func Current() *Config {
+	return c
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying cfg, the configuration of the server
+// serving a request.
+func WithContext(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the configuration carried by the context, or the loaded
+// configuration.
+func FromContext(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(contextKey{}).(*Config); ok {
+		return cfg
+	}
+	return c
+}
+
+// New loads the configuration, unless one is already in use.
 func New() error {
 	if c == nil {
 		err := Load()
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestUseWithoutDisk(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := Defaults()
+	cfg.Port = "8080"
+	Use(cfg)
+	defer Use(nil)
+	if err := New(); err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if Current() != cfg || Port() != "8080" || Path() != "" {
+		t.Fatalf("got %+v loaded from %q, want the config in use", Current(), Path())
+	}
+	if _, err := os.Stat(CONFIG); !os.IsNotExist(err) {
+		t.Fatalf("got %v statting %s, want it not written", err, CONFIG)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	for path, file := range map[string]string{
+		"ramchi.config.json": `{"readTimeout": "15s", "writeTimeout": 30, "idleTimeout": "2m", "shutdownTimeout": "1m30s"}`,
+		"ramchi.config.yaml": "readTimeout: 15s\nwriteTimeout: 30\nidleTimeout: 2m\nshutdownTimeout: 1m30s\n",
+		"ramchi.config.toml": "readTimeout = \"15s\"\nwriteTimeout = 30\nidleTimeout = \"2m\"\nshutdownTimeout = \"1m30s\"\n",
+	} {
+		cfg, err := parse(path, []byte(file))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if cfg.ReadTimeout.Duration() != 15*time.Second || cfg.WriteTimeout.Duration() != 30*time.Second ||
+			cfg.IdleTimeout.Duration() != 2*time.Minute || cfg.ShutdownTimeout.Duration() != 90*time.Second {
+			t.Fatalf("%s: got %s, %s, %s and %s", path, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout, cfg.ShutdownTimeout)
+		}
+	}
+
+	if _, err := parse("ramchi.config.json", []byte(`{"readTimeout": "15 seconds"}`)); err == nil {
+		t.Fatal("parsed an invalid duration")
+	}
+	b, err := json.Marshal(Duration(90 * time.Second))
+	if err != nil || string(b) != `"1m30s"` {
+		t.Fatalf("got %s, %v, want \"1m30s\"", b, err)
+	}
+}
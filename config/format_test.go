@@ -0,0 +1,92 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const formatJSON = `{
+	"port": "8080",
+	"enableCors": true,
+	"otlpHeaders": {"Authorization": "Bearer token"},
+	"corsAllowedMethods": ["GET", "POST"],
+	"connectionRate": 2.5,
+	"maxResponseBytes": 1048576,
+	"listeners": {"admin": "127.0.0.1:9090"},
+	"rewriteRules": [
+		{"from": "/old", "to": "/new", "status": 301},
+		{"from": "^/v1/(.*)$", "to": "/api/$1", "regex": true}
+	],
+	"serverHeader": "ramchi # edge",
+	"readTimeout": "15s"
+}`
+
+const formatYAML = `# The service configuration.
+port: 8080
+enableCors: true
+otlpHeaders:
+  Authorization: Bearer token
+corsAllowedMethods: [GET, POST]
+connectionRate: 2.5
+maxResponseBytes: 1_048_576
+listeners:
+  admin: 127.0.0.1:9090 # private
+rewriteRules:
+- from: /old
+  to: /new
+  status: 301
+- from: '^/v1/(.*)$'
+  to: /api/$1
+  regex: true
+serverHeader: "ramchi # edge"
+readTimeout: 15s
+`
+
+const formatTOML = `# The service configuration.
+port = "8080"
+enableCors = true
+corsAllowedMethods = [
+	"GET",
+	"POST", # writes
+]
+connectionRate = 2.5
+maxResponseBytes = 1_048_576
+serverHeader = "ramchi # edge"
+readTimeout = "15s"
+
+[otlpHeaders]
+Authorization = "Bearer token"
+
+[listeners]
+admin = "127.0.0.1:9090"
+
+[[rewriteRules]]
+from = "/old"
+to = "/new"
+status = 301
+
+[[rewriteRules]]
+from = '^/v1/(.*)$'
+to = "/api/$1"
+regex = true
+`
+
+func TestFormats(t *testing.T) {
+	want, err := parse("ramchi.config.json", []byte(formatJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, doc := range map[string]string{"ramchi.config.yaml": formatYAML, "ramchi.config.toml": formatTOML} {
+		got, err := parse(path, []byte(doc))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %+v, want %+v", path, got, want)
+		}
+	}
+
+	if _, err := parse("ramchi.config.yaml", []byte("port: 80\nenableCors: maybe\n")); err == nil {
+		t.Fatal("parsed an invalid boolean")
+	}
+}
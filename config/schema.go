@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema describing ramchi.config.json and the registered
+// sections, suitable for editor validation and autocompletion, or for validating
+// config files in CI.
+func Schema() ([]byte, error) {
+	s := schemaOf(reflect.TypeOf(Config{}), reflect.ValueOf(Defaults()).Elem())
+	properties := s["properties"].(map[string]interface{})
+	for _, name := range sectionNames() {
+		v := newSection(name)
+		properties[name] = schemaOf(v.Type(), v)
+	}
+	s["$schema"] = SchemaDraft
+	s["title"] = "ramchi configuration"
+
+	file, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		return nil, fmt.Errorf("Schema: failed marshalling schema: %w", err)
+	}
+	return file, nil
+}
+
+// schemaOf builds the schema for t. When def is valid, its value is
+// used as the default for each struct field.
+func schemaOf(t reflect.Type, def reflect.Value) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if def.IsValid() {
+			if def.IsNil() {
+				def = reflect.Value{}
+			} else {
+				def = def.Elem()
+			}
+		}
+	}
+
+	if t == reflect.TypeOf(Duration(0)) {
+		return map[string]interface{}{"type": []string{"string", "integer"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), reflect.Value{})}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), reflect.Value{})}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := jsonName(f)
+			if !ok {
+				continue
+			}
+			var fdef reflect.Value
+			if def.IsValid() {
+				fdef = def.Field(i)
+			}
+			p := schemaOf(f.Type, fdef)
+			if fdef.IsValid() && !fdef.IsZero() {
+				p["default"] = fdef.Interface()
+			}
+			properties[name] = p
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]interface{}{}
+}
+
+// jsonName returns the name a struct field is encoded under, and whether it is encoded at all.
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	file, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s struct {
+		Schema     string                            `json:"$schema"`
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal(file, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Schema != SchemaDraft {
+		t.Fatalf("unexpected $schema: %s", s.Schema)
+	}
+	if s.Properties["port"]["type"] != "string" || s.Properties["port"]["default"] != "7000" {
+		t.Fatalf("unexpected port schema: %v", s.Properties["port"])
+	}
+	if s.Properties["experimental"]["type"] != "boolean" {
+		t.Fatalf("unexpected experimental schema: %v", s.Properties["experimental"])
+	}
+}
+
+func TestSchemaSections(t *testing.T) {
+	RegisterSection("app", &appSection{Workers: 4})
+	defer func() {
+		sectionsMu.Lock()
+		delete(sections, "app")
+		sectionsMu.Unlock()
+	}()
+
+	file, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]interface{}
+	if err := json.Unmarshal(file, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	for doc, valid := range map[string]bool{
+		`{"port": "8080", "app": {"workers": 8, "queue": "jobs"}}`: true,
+		`{"port": "8080", "app": {"workers": "eight"}}`:            false,
+		`{"port": "8080", "app": {"retries": 3}}`:                  false,
+		`{"port": "8080", "other": {}}`:                            false,
+	} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateSchema(s, v); (err == nil) != valid {
+			t.Fatalf("%s: got %v, want valid %v", doc, err, valid)
+		}
+	}
+	app := s["properties"].(map[string]interface{})["app"].(map[string]interface{})
+	if workers := app["properties"].(map[string]interface{})["workers"].(map[string]interface{}); workers["default"] != float64(4) {
+		t.Fatalf("unexpected workers schema: %v", workers)
+	}
+}
+
+// validateSchema checks v against the types, properties and additionalProperties
+// of the schema, the subset of JSON Schema that Schema produces.
+func validateSchema(schema map[string]interface{}, v interface{}) error {
+	types, ok := schema["type"].([]interface{})
+	if !ok && schema["type"] != nil {
+		types = []interface{}{schema["type"]}
+	}
+	if len(types) > 0 {
+		matched := false
+		for _, typ := range types {
+			switch x := v.(type) {
+			case bool:
+				matched = matched || typ == "boolean"
+			case string:
+				matched = matched || typ == "string"
+			case float64:
+				matched = matched || typ == "number" || typ == "integer" && x == float64(int64(x))
+			case []interface{}:
+				matched = matched || typ == "array"
+			case map[string]interface{}:
+				matched = matched || typ == "object"
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%v is not of type %v", v, types)
+		}
+	}
+
+	switch x := v.(type) {
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for _, item := range x {
+				if err := validateSchema(items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, value := range x {
+			if p, ok := properties[key].(map[string]interface{}); ok {
+				if err := validateSchema(p, value); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s is not allowed", key)
+				}
+			case map[string]interface{}:
+				if err := validateSchema(extra, value); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
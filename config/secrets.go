@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Resolver returns the secret value a reference names.
+type Resolver func(ref string) (string, error)
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{
+		"env":  resolveEnv,
+		"file": resolveFile,
+	}
+)
+
+// secretPattern matches references of the form ${scheme:reference}.
+var secretPattern = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9]*):([^}]*)\}`)
+
+// RegisterResolver makes references of the form ${scheme:reference} in string
+// values of the config resolve through the resolver when it is loaded. The env
+// and file schemes are registered by default, reading environment variables and
+// trimmed file contents. Resolvers must be registered before the config is loaded.
+func RegisterResolver(scheme string, resolver Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = resolver
+}
+
+// Resolve replaces the secret references in the string.
+func Resolve(s string) (string, error) {
+	var failure error
+	resolved := secretPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := secretPattern.FindStringSubmatch(match)
+		resolversMu.RLock()
+		resolver, ok := resolvers[parts[1]]
+		resolversMu.RUnlock()
+		if !ok {
+			if failure == nil {
+				failure = fmt.Errorf("Resolve: no resolver registered for %q", parts[1])
+			}
+			return match
+		}
+		value, err := resolver(parts[2])
+		if err != nil && failure == nil {
+			failure = fmt.Errorf("Resolve: failed resolving %s: %w", match, err)
+		}
+		return value
+	})
+	return resolved, failure
+}
+
+// resolveSecrets replaces the secret references in every string of the value.
+func resolveSecrets(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return resolveSecrets(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := resolveSecrets(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveSecrets(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := resolveSecrets(value); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.String:
+		if v.CanSet() && strings.Contains(v.String(), "${") {
+			resolved, err := Resolve(v.String())
+			if err != nil {
+				return err
+			}
+			v.SetString(resolved)
+		}
+	}
+	return nil
+}
+
+func resolveEnv(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("resolveEnv: %s is not set", ref)
+	}
+	return value, nil
+}
+
+func resolveFile(ref string) (string, error) {
+	b, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("resolveFile: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
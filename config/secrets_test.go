@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("RAMCHI_TEST_DOMAIN", "example.com")
+	t.Setenv("RAMCHI_TEST_TOK", "token")
+	RegisterResolver("test", func(ref string) (string, error) {
+		if ref == "missing" {
+			return "", errors.New("no such secret")
+		}
+		return "secret-" + ref, nil
+	})
+
+	cfg := &Config{
+		CookieDomain:       "${env:RAMCHI_TEST_DOMAIN}",
+		CORSAllowedOrigins: []string{"https://${test:origin}"},
+		OTLPHeaders:        map[string]string{"Authorization": "Bearer ${env:RAMCHI_TEST_TOK}"},
+	}
+	if err := resolveSecrets(reflect.ValueOf(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CookieDomain != "example.com" || cfg.CORSAllowedOrigins[0] != "https://secret-origin" {
+		t.Fatalf("unexpected resolution: %q %q", cfg.CookieDomain, cfg.CORSAllowedOrigins)
+	}
+	if cfg.OTLPHeaders["Authorization"] != "Bearer token" {
+		t.Fatalf("unexpected header resolution: %q", cfg.OTLPHeaders)
+	}
+	if err := resolveSecrets(reflect.ValueOf(&Config{OTLPHeaders: map[string]string{"X": "${test:missing}"}})); err == nil {
+		t.Fatal("expected map value resolver error to be returned")
+	}
+
+	if _, err := Resolve("${test:missing}"); err == nil {
+		t.Fatal("expected resolver error to be returned")
+	}
+	if _, err := Resolve("${unknown:x}"); err == nil {
+		t.Fatal("expected unknown scheme to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	sectionsMu sync.RWMutex
+	sections   = map[string]interface{}{}
+)
+
+// RegisterSection registers a section of the application, a pointer to a struct
+// holding its defaults, decoded from the key of the name in the config file.
+// Sections implementing Validate() error are validated with the config.
+func RegisterSection(name string, v interface{}) {
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: section %q is not a pointer to a struct", name))
+	}
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	if _, ok := sections[name]; ok {
+		panic(fmt.Sprintf("config: section %q registered twice", name))
+	}
+	sections[name] = v
+}
+
+// Section returns the section of the name as read with the config, or as
+// registered when the config was not read from a file, such as with Use. It
+// returns nil if no section of the name is registered.
+func (cfg *Config) Section(name string) interface{} {
+	if v, ok := cfg.sections[name]; ok {
+		return v
+	}
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	return sections[name]
+}
+
+// sectionNames returns the names of the registered sections, sorted.
+func sectionNames() []string {
+	sectionsMu.RLock()
+	defer sectionsMu.RUnlock()
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newSection returns a copy of the registered section, so that it may be decoded
+// without changing the section in use until the config is loaded.
+func newSection(name string) reflect.Value {
+	sectionsMu.RLock()
+	registered := reflect.ValueOf(sections[name]).Elem()
+	sectionsMu.RUnlock()
+	v := reflect.New(registered.Type())
+	v.Elem().Set(registered)
+	return v
+}
+
+// parseSections decodes the registered sections of the config file, from the raw
+// JSON objects keyed by their names.
+func parseSections(cfg *Config, raw map[string]json.RawMessage) error {
+	for _, name := range sectionNames() {
+		v := newSection(name)
+		if msg, ok := raw[name]; ok {
+			if err := json.Unmarshal(msg, v.Interface()); err != nil {
+				return fmt.Errorf("parseSections: failed unmarshalling section %s: %w", name, err)
+			}
+		}
+		if err := resolveSecrets(v); err != nil {
+			return fmt.Errorf("parseSections: failed resolving secrets of section %s: %w", name, err)
+		}
+		if cfg.sections == nil {
+			cfg.sections = make(map[string]interface{})
+		}
+		cfg.sections[name] = v.Interface()
+	}
+	return nil
+}
+
+// useSections sets the registered sections to those read with the config.
+func useSections(cfg *Config) {
+	sectionsMu.Lock()
+	defer sectionsMu.Unlock()
+	for name, v := range cfg.sections {
+		if registered, ok := sections[name]; ok {
+			reflect.ValueOf(registered).Elem().Set(reflect.ValueOf(v).Elem())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type appSection struct {
+	Workers int    `json:"workers"`
+	Queue   string `json:"queue"`
+}
+
+func (a *appSection) Validate() error {
+	if a.Workers < 1 {
+		return errors.New("workers must be positive")
+	}
+	return nil
+}
+
+func TestSection(t *testing.T) {
+	app := &appSection{Workers: 4, Queue: "default"}
+	RegisterSection("app", app)
+	defer func() {
+		sectionsMu.Lock()
+		delete(sections, "app")
+		sectionsMu.Unlock()
+	}()
+
+	for path, file := range map[string]string{
+		"ramchi.config.json": `{"port": "8080", "app": {"queue": "jobs"}}`,
+		"ramchi.config.yaml": "port: 8080\napp:\n  queue: jobs\n",
+		"ramchi.config.toml": "port = \"8080\"\n[app]\nqueue = \"jobs\"\n",
+	} {
+		cfg, err := parse(path, []byte(file))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		got := cfg.Section("app").(*appSection)
+		if got.Workers != 4 || got.Queue != "jobs" {
+			t.Fatalf("%s: got %+v, want the defaults overridden", path, got)
+		}
+		if app.Queue != "default" {
+			t.Fatalf("%s: parsing changed the registered section to %+v", path, app)
+		}
+	}
+
+	cfg, err := parse("ramchi.config.json", []byte(`{"port": "8080", "app": {"workers": 0}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "app: workers must be positive") {
+		t.Fatalf("got %v, want the section invalid", err)
+	}
+
+	cfg, _ = parse("ramchi.config.json", []byte(`{"port": "8080", "app": {"workers": 8}}`))
+	useSections(cfg)
+	if app.Workers != 8 {
+		t.Fatalf("got %+v, want the section in use", app)
+	}
+	if (&Config{}).Section("app") != app || (&Config{}).Section("other") != nil {
+		t.Fatal("Section did not fall back to the registered sections")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a section twice did not panic")
+		}
+	}()
+	RegisterSection("app", &appSection{})
+}
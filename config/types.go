@@ -1,9 +1,117 @@
 package config
 
+import "time"
+
 type Config struct {
-	Port         string `json:"port"`
-	Address      string `json:"address"`
-	Experimental bool   `json:"experimental"`
+	Port                  string            `json:"port" yaml:"port" toml:"port"`
+	Address               string            `json:"address" yaml:"address" toml:"address"`
+	Experimental          bool              `json:"experimental" yaml:"experimental" toml:"experimental"`
+	Profile               string            `json:"profile" yaml:"profile" toml:"profile"`
+	EnableRequestLogging  bool              `json:"enableRequestLogging" yaml:"enableRequestLogging" toml:"enableRequestLogging"`
+	EnableCORS            bool              `json:"enableCors" yaml:"enableCors" toml:"enableCors"`
+	CORSAllowedOrigins    []string          `json:"corsAllowedOrigins" yaml:"corsAllowedOrigins" toml:"corsAllowedOrigins"`
+	CORSAllowedMethods    []string          `json:"corsAllowedMethods" yaml:"corsAllowedMethods" toml:"corsAllowedMethods"`
+	CORSAllowedHeaders    []string          `json:"corsAllowedHeaders" yaml:"corsAllowedHeaders" toml:"corsAllowedHeaders"`
+	CORSExposedHeaders    []string          `json:"corsExposedHeaders" yaml:"corsExposedHeaders" toml:"corsExposedHeaders"`
+	CORSAllowCredentials  bool              `json:"corsAllowCredentials" yaml:"corsAllowCredentials" toml:"corsAllowCredentials"`
+	CORSMaxAge            int               `json:"corsMaxAge" yaml:"corsMaxAge" toml:"corsMaxAge"`
+	PathNormalization     string            `json:"pathNormalization" yaml:"pathNormalization" toml:"pathNormalization"`
+	TrailingSlash         bool              `json:"trailingSlash" yaml:"trailingSlash" toml:"trailingSlash"`
+	CleanPath             bool              `json:"cleanPath" yaml:"cleanPath" toml:"cleanPath"`
+	CaseInsensitivePaths  bool              `json:"caseInsensitivePaths" yaml:"caseInsensitivePaths" toml:"caseInsensitivePaths"`
+	EnableMethodOverride  bool              `json:"enableMethodOverride" yaml:"enableMethodOverride" toml:"enableMethodOverride"`
+	MethodOverrideOrigins []string          `json:"methodOverrideOrigins" yaml:"methodOverrideOrigins" toml:"methodOverrideOrigins"`
+	ErrorRequestID        bool              `json:"errorRequestId" yaml:"errorRequestId" toml:"errorRequestId"`
+	ResponseEnvelope      bool              `json:"responseEnvelope" yaml:"responseEnvelope" toml:"responseEnvelope"`
+	CookieProfile         string            `json:"cookieProfile" yaml:"cookieProfile" toml:"cookieProfile"`
+	CookieDomain          string            `json:"cookieDomain" yaml:"cookieDomain" toml:"cookieDomain"`
+	EnableTLS             bool              `json:"enableTls" yaml:"enableTls" toml:"enableTls"`
+	TLSCertFile           string            `json:"tlsCertFile" yaml:"tlsCertFile" toml:"tlsCertFile"`
+	TLSKeyFile            string            `json:"tlsKeyFile" yaml:"tlsKeyFile" toml:"tlsKeyFile"`
+	TLSExpiryWarningDays  int               `json:"tlsExpiryWarningDays" yaml:"tlsExpiryWarningDays" toml:"tlsExpiryWarningDays"`
+	TLSSessionTickets     bool              `json:"tlsSessionTickets" yaml:"tlsSessionTickets" toml:"tlsSessionTickets"`
+	TLSTicketRotation     int               `json:"tlsTicketRotation" yaml:"tlsTicketRotation" toml:"tlsTicketRotation"`
+	TLSEarlyData          bool              `json:"tlsEarlyData" yaml:"tlsEarlyData" toml:"tlsEarlyData"`
+	TLSMinVersion         string            `json:"tlsMinVersion" yaml:"tlsMinVersion" toml:"tlsMinVersion"`
+	TLSCipherSuites       []string          `json:"tlsCipherSuites" yaml:"tlsCipherSuites" toml:"tlsCipherSuites"`
+	TLSClientAuth         string            `json:"tlsClientAuth" yaml:"tlsClientAuth" toml:"tlsClientAuth"`
+	TLSClientCAFile       string            `json:"tlsClientCaFile" yaml:"tlsClientCaFile" toml:"tlsClientCaFile"`
+	EnableAutocert        bool              `json:"enableAutocert" yaml:"enableAutocert" toml:"enableAutocert"`
+	AutocertDomains       []string          `json:"autocertDomains" yaml:"autocertDomains" toml:"autocertDomains"`
+	AutocertCacheDir      string            `json:"autocertCacheDir" yaml:"autocertCacheDir" toml:"autocertCacheDir"`
+	AutocertEmail         string            `json:"autocertEmail" yaml:"autocertEmail" toml:"autocertEmail"`
+	AutocertHTTPAddress   string            `json:"autocertHttpAddress" yaml:"autocertHttpAddress" toml:"autocertHttpAddress"`
+	AutocertDirectoryURL  string            `json:"autocertDirectoryUrl" yaml:"autocertDirectoryUrl" toml:"autocertDirectoryUrl"`
+	EnableOCSPStapling    bool              `json:"enableOcspStapling" yaml:"enableOcspStapling" toml:"enableOcspStapling"`
+	EnableSPIFFE          bool              `json:"enableSpiffe" yaml:"enableSpiffe" toml:"enableSpiffe"`
+	SPIFFEDir             string            `json:"spiffeDir" yaml:"spiffeDir" toml:"spiffeDir"`
+	SPIFFETrustDomain     string            `json:"spiffeTrustDomain" yaml:"spiffeTrustDomain" toml:"spiffeTrustDomain"`
+	EnableUpgrade         bool              `json:"enableUpgrade" yaml:"enableUpgrade" toml:"enableUpgrade"`
+	EnableH2C             bool              `json:"enableH2c" yaml:"enableH2c" toml:"enableH2c"`
+	EnableHTTP3           bool              `json:"enableHttp3" yaml:"enableHttp3" toml:"enableHttp3"`
+	ServiceName           string            `json:"serviceName" yaml:"serviceName" toml:"serviceName"`
+	ServiceVersion        string            `json:"serviceVersion" yaml:"serviceVersion" toml:"serviceVersion"`
+	EnableProxyProtocol   bool              `json:"enableProxyProtocol" yaml:"enableProxyProtocol" toml:"enableProxyProtocol"`
+	ProxyProtocolTrusted  []string          `json:"proxyProtocolTrusted" yaml:"proxyProtocolTrusted" toml:"proxyProtocolTrusted"`
+	ProxyProtocolTimeout  int               `json:"proxyProtocolTimeout" yaml:"proxyProtocolTimeout" toml:"proxyProtocolTimeout"`
+	ConnectionRate        float64           `json:"connectionRate" yaml:"connectionRate" toml:"connectionRate"`
+	ConnectionBurst       int               `json:"connectionBurst" yaml:"connectionBurst" toml:"connectionBurst"`
+	MaxConnectionsPerIP   int               `json:"maxConnectionsPerIp" yaml:"maxConnectionsPerIp" toml:"maxConnectionsPerIp"`
+	Listeners             map[string]string `json:"listeners" yaml:"listeners" toml:"listeners"`
+	AdminAddress          string            `json:"adminAddress" yaml:"adminAddress" toml:"adminAddress"`
+	MemoryLimitMB         int               `json:"memoryLimitMb" yaml:"memoryLimitMb" toml:"memoryLimitMb"`
+	MaxResponseBytes      int64             `json:"maxResponseBytes" yaml:"maxResponseBytes" toml:"maxResponseBytes"`
+	WatchdogHeapMB        int               `json:"watchdogHeapMb" yaml:"watchdogHeapMb" toml:"watchdogHeapMb"`
+	WatchdogGoroutines    int               `json:"watchdogGoroutines" yaml:"watchdogGoroutines" toml:"watchdogGoroutines"`
+	WatchdogInterval      int               `json:"watchdogInterval" yaml:"watchdogInterval" toml:"watchdogInterval"`
+	RewriteRules          []RewriteRule     `json:"rewriteRules" yaml:"rewriteRules" toml:"rewriteRules"`
+	DefaultHeaders        map[string]string `json:"defaultHeaders" yaml:"defaultHeaders" toml:"defaultHeaders"`
+	ServerHeader          string            `json:"serverHeader" yaml:"serverHeader" toml:"serverHeader"`
+	HideServerHeader      bool              `json:"hideServerHeader" yaml:"hideServerHeader" toml:"hideServerHeader"`
+	ShutdownSignals       []string          `json:"shutdownSignals" yaml:"shutdownSignals" toml:"shutdownSignals"`
+	DisableRecovery       bool              `json:"disableRecovery" yaml:"disableRecovery" toml:"disableRecovery"`
+	RecoveryBody          string            `json:"recoveryBody" yaml:"recoveryBody" toml:"recoveryBody"`
+	EnableMetrics         bool              `json:"enableMetrics" yaml:"enableMetrics" toml:"enableMetrics"`
+	MetricsPath           string            `json:"metricsPath" yaml:"metricsPath" toml:"metricsPath"`
+	MetricsExporter       string            `json:"metricsExporter" yaml:"metricsExporter" toml:"metricsExporter"`
+	EnablePprof           bool              `json:"enablePprof" yaml:"enablePprof" toml:"enablePprof"`
+	EnableProfiling       bool              `json:"enableProfiling" yaml:"enableProfiling" toml:"enableProfiling"`
+	ProfilingEndpoint     string            `json:"profilingEndpoint" yaml:"profilingEndpoint" toml:"profilingEndpoint"`
+	ProfilingInterval     int               `json:"profilingInterval" yaml:"profilingInterval" toml:"profilingInterval"`
+	ProfilingTags         map[string]string `json:"profilingTags" yaml:"profilingTags" toml:"profilingTags"`
+	PprofPrefix           string            `json:"pprofPrefix" yaml:"pprofPrefix" toml:"pprofPrefix"`
+	PprofListener         string            `json:"pprofListener" yaml:"pprofListener" toml:"pprofListener"`
+	PprofPublic           bool              `json:"pprofPublic" yaml:"pprofPublic" toml:"pprofPublic"`
+	StatsDAddress         string            `json:"statsdAddress" yaml:"statsdAddress" toml:"statsdAddress"`
+	StatsDPrefix          string            `json:"statsdPrefix" yaml:"statsdPrefix" toml:"statsdPrefix"`
+	MetricsTenantHeader   string            `json:"metricsTenantHeader" yaml:"metricsTenantHeader" toml:"metricsTenantHeader"`
+	MetricsTenantLimit    int               `json:"metricsTenantLimit" yaml:"metricsTenantLimit" toml:"metricsTenantLimit"`
+	MetricsTenantRaw      bool              `json:"metricsTenantRaw" yaml:"metricsTenantRaw" toml:"metricsTenantRaw"`
+	LogOutput             string            `json:"logOutput" yaml:"logOutput" toml:"logOutput"`
+	OTLPEndpoint          string            `json:"otlpEndpoint" yaml:"otlpEndpoint" toml:"otlpEndpoint"`
+	OTLPHeaders           map[string]string `json:"otlpHeaders" yaml:"otlpHeaders" toml:"otlpHeaders" redact:"drop"`
+	OTLPResource          map[string]string `json:"otlpResource" yaml:"otlpResource" toml:"otlpResource"`
+	SyslogNetwork         string            `json:"syslogNetwork" yaml:"syslogNetwork" toml:"syslogNetwork"`
+	SyslogAddress         string            `json:"syslogAddress" yaml:"syslogAddress" toml:"syslogAddress"`
+	LogLevel              string            `json:"logLevel" yaml:"logLevel" toml:"logLevel"`
+	WatchConfig           bool              `json:"watchConfig" yaml:"watchConfig" toml:"watchConfig"`
+	ReadTimeout           Duration          `json:"readTimeout" yaml:"readTimeout" toml:"readTimeout"`
+	WriteTimeout          Duration          `json:"writeTimeout" yaml:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout           Duration          `json:"idleTimeout" yaml:"idleTimeout" toml:"idleTimeout"`
+	ShutdownTimeout       Duration          `json:"shutdownTimeout" yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+
+	// sections are the registered sections of the application, read with the config.
+	sections map[string]interface{}
+}
+
+// RewriteRule rewrites or redirects request paths before routing. From is a path
+// prefix matching whole segments, or a regular expression when Regex is set. A
+// Status of 3xx redirects.
+type RewriteRule struct {
+	From   string `json:"from" yaml:"from" toml:"from"`
+	To     string `json:"to" yaml:"to" toml:"to"`
+	Regex  bool   `json:"regex" yaml:"regex" toml:"regex"`
+	Status int    `json:"status" yaml:"status" toml:"status"`
 }
 
 func Port() string {
@@ -17,3 +125,422 @@ func Address() string {
 func Experimental() bool {
 	return c.Experimental
 }
+
+func Profile() string {
+	return c.Profile
+}
+
+func EnableRequestLogging() bool {
+	return c.EnableRequestLogging
+}
+
+func EnableCORS() bool {
+	return c.EnableCORS
+}
+
+func CORSAllowedOrigins() []string {
+	return c.CORSAllowedOrigins
+}
+
+func CORSAllowedMethods() []string {
+	return c.CORSAllowedMethods
+}
+
+func CORSAllowedHeaders() []string {
+	return c.CORSAllowedHeaders
+}
+
+func CORSExposedHeaders() []string {
+	return c.CORSExposedHeaders
+}
+
+func CORSAllowCredentials() bool {
+	return c.CORSAllowCredentials
+}
+
+func CORSMaxAge() int {
+	return c.CORSMaxAge
+}
+
+func PathNormalization() string {
+	return c.PathNormalization
+}
+
+func TrailingSlash() bool {
+	return c.TrailingSlash
+}
+
+func CleanPath() bool {
+	return c.CleanPath
+}
+
+func CaseInsensitivePaths() bool {
+	return c.CaseInsensitivePaths
+}
+
+func EnableMethodOverride() bool {
+	return c.EnableMethodOverride
+}
+
+func MethodOverrideOrigins() []string {
+	return c.MethodOverrideOrigins
+}
+
+func ErrorRequestID() bool {
+	return c.ErrorRequestID
+}
+
+func ResponseEnvelope() bool {
+	return c.ResponseEnvelope
+}
+
+func CookieProfile() string {
+	return c.CookieProfile
+}
+
+func CookieDomain() string {
+	return c.CookieDomain
+}
+
+func EnableTLS() bool {
+	return c.EnableTLS
+}
+
+func TLSCertFile() string {
+	return c.TLSCertFile
+}
+
+func TLSKeyFile() string {
+	return c.TLSKeyFile
+}
+
+func TLSExpiryWarningDays() int {
+	return c.TLSExpiryWarningDays
+}
+
+func TLSSessionTickets() bool {
+	return c.TLSSessionTickets
+}
+
+func TLSTicketRotation() int {
+	return c.TLSTicketRotation
+}
+
+// TLSEarlyData returns whether HTTP/3 accepts 0-RTT requests from resumed sessions.
+func TLSEarlyData() bool {
+	return c.TLSEarlyData
+}
+
+// TLSMinVersion returns the minimum TLS version accepted.
+func TLSMinVersion() string {
+	return c.TLSMinVersion
+}
+
+// TLSCipherSuites returns the names of the TLS 1.2 cipher suites accepted.
+func TLSCipherSuites() []string {
+	return c.TLSCipherSuites
+}
+
+// TLSClientAuth returns the policy for client certificates.
+func TLSClientAuth() string {
+	return c.TLSClientAuth
+}
+
+// TLSClientCAFile returns the PEM file of the client certificate authorities.
+func TLSClientCAFile() string {
+	return c.TLSClientCAFile
+}
+
+// EnableAutocert returns whether certificates are obtained over ACME.
+func EnableAutocert() bool {
+	return c.EnableAutocert
+}
+
+// AutocertDomains returns the domains certificates are obtained for.
+func AutocertDomains() []string {
+	return c.AutocertDomains
+}
+
+// AutocertCacheDir returns the directory ACME certificates are cached in.
+func AutocertCacheDir() string {
+	return c.AutocertCacheDir
+}
+
+// AutocertEmail returns the contact address of the ACME account.
+func AutocertEmail() string {
+	return c.AutocertEmail
+}
+
+// AutocertHTTPAddress returns the address answering HTTP-01 challenges.
+func AutocertHTTPAddress() string {
+	return c.AutocertHTTPAddress
+}
+
+// AutocertDirectoryURL returns the directory of the ACME certificate authority.
+func AutocertDirectoryURL() string {
+	return c.AutocertDirectoryURL
+}
+
+func EnableOCSPStapling() bool {
+	return c.EnableOCSPStapling
+}
+
+func EnableSPIFFE() bool {
+	return c.EnableSPIFFE
+}
+
+func SPIFFEDir() string {
+	return c.SPIFFEDir
+}
+
+func SPIFFETrustDomain() string {
+	return c.SPIFFETrustDomain
+}
+
+func EnableUpgrade() bool {
+	return c.EnableUpgrade
+}
+
+// EnableH2C returns whether HTTP/2 is served over cleartext connections.
+func EnableH2C() bool {
+	return c.EnableH2C
+}
+
+// EnableHTTP3 returns whether the main listener is also served over HTTP/3.
+func EnableHTTP3() bool {
+	return c.EnableHTTP3
+}
+
+func ServiceName() string {
+	return c.ServiceName
+}
+
+func EnableProxyProtocol() bool {
+	return c.EnableProxyProtocol
+}
+
+func ProxyProtocolTrusted() []string {
+	return c.ProxyProtocolTrusted
+}
+
+func ProxyProtocolTimeout() int {
+	return c.ProxyProtocolTimeout
+}
+
+func ConnectionRate() float64 {
+	return c.ConnectionRate
+}
+
+func ConnectionBurst() int {
+	return c.ConnectionBurst
+}
+
+func MaxConnectionsPerIP() int {
+	return c.MaxConnectionsPerIP
+}
+
+func Listeners() map[string]string {
+	return c.Listeners
+}
+
+func RewriteRules() []RewriteRule {
+	return c.RewriteRules
+}
+
+func DefaultHeaders() map[string]string {
+	return c.DefaultHeaders
+}
+
+func ServerHeader() string {
+	return c.ServerHeader
+}
+
+func HideServerHeader() bool {
+	return c.HideServerHeader
+}
+
+// DisableRecovery returns whether panicking handlers are left to abort their connection.
+func DisableRecovery() bool {
+	return c.DisableRecovery
+}
+
+// RecoveryBody returns the JSON body of responses to panicking handlers.
+func RecoveryBody() string {
+	return c.RecoveryBody
+}
+
+func EnableMetrics() bool {
+	return c.EnableMetrics
+}
+
+// MetricsPath returns the path at which metrics are served in the Prometheus text format.
+func MetricsPath() string {
+	return c.MetricsPath
+}
+
+// ServiceVersion returns the version of the service.
+func ServiceVersion() string {
+	return c.ServiceVersion
+}
+
+func EnableProfiling() bool {
+	return c.EnableProfiling
+}
+
+// ProfilingEndpoint returns the Pyroscope-compatible endpoint profiles are pushed to.
+func ProfilingEndpoint() string {
+	return c.ProfilingEndpoint
+}
+
+// ProfilingInterval returns the seconds profiled for each push.
+func ProfilingInterval() int {
+	return c.ProfilingInterval
+}
+
+// ProfilingTags returns the tags of pushed profiles.
+func ProfilingTags() map[string]string {
+	return c.ProfilingTags
+}
+
+func EnablePprof() bool {
+	return c.EnablePprof
+}
+
+// PprofPrefix returns the path under which the net/http/pprof handlers are served.
+func PprofPrefix() string {
+	return c.PprofPrefix
+}
+
+// PprofListener returns the name of the listener serving the pprof handlers.
+func PprofListener() string {
+	return c.PprofListener
+}
+
+// PprofPublic returns whether pprof may be served on the main listener.
+func PprofPublic() bool {
+	return c.PprofPublic
+}
+
+// MetricsExporter returns how metrics are exported.
+func MetricsExporter() string {
+	return c.MetricsExporter
+}
+
+// StatsDAddress returns the address of the StatsD agent metrics are pushed to.
+func StatsDAddress() string {
+	return c.StatsDAddress
+}
+
+// StatsDPrefix returns the prefix of metrics pushed to StatsD, such as "myapp.".
+func StatsDPrefix() string {
+	return c.StatsDPrefix
+}
+
+// MetricsTenantHeader returns the header naming the tenant of a request.
+func MetricsTenantHeader() string {
+	return c.MetricsTenantHeader
+}
+
+// MetricsTenantLimit returns the number of tenants recorded.
+func MetricsTenantLimit() int {
+	return c.MetricsTenantLimit
+}
+
+// MetricsTenantRaw returns whether tenants are recorded as sent rather than hashed.
+func MetricsTenantRaw() bool {
+	return c.MetricsTenantRaw
+}
+
+// LogOutput returns where the server logs.
+func LogOutput() string {
+	return c.LogOutput
+}
+
+// SyslogNetwork returns the network of the syslog daemon.
+func SyslogNetwork() string {
+	return c.SyslogNetwork
+}
+
+func SyslogAddress() string {
+	return c.SyslogAddress
+}
+
+// OTLPEndpoint returns the base URL of the OpenTelemetry collector.
+func OTLPEndpoint() string {
+	return c.OTLPEndpoint
+}
+
+// OTLPHeaders returns the headers sent to the collector, such as for authorization.
+func OTLPHeaders() map[string]string {
+	return c.OTLPHeaders
+}
+
+// OTLPResource returns the resource attributes of exported telemetry.
+func OTLPResource() map[string]string {
+	return c.OTLPResource
+}
+
+// AdminAddress returns the address of the admin listener.
+func AdminAddress() string {
+	return c.AdminAddress
+}
+
+// MaxResponseBytes returns the limit of response sizes, or zero for none.
+func MaxResponseBytes() int64 {
+	return c.MaxResponseBytes
+}
+
+// MemoryLimitMB returns the soft memory limit of the runtime.
+func MemoryLimitMB() int {
+	return c.MemoryLimitMB
+}
+
+// WatchdogHeapMB returns the heap limit of the watchdog.
+func WatchdogHeapMB() int {
+	return c.WatchdogHeapMB
+}
+
+// WatchdogGoroutines returns the goroutine limit of the watchdog.
+func WatchdogGoroutines() int {
+	return c.WatchdogGoroutines
+}
+
+// WatchdogInterval returns the seconds between checks of the watchdog.
+func WatchdogInterval() int {
+	return c.WatchdogInterval
+}
+
+// ShutdownSignals returns the names of the signals beginning a shutdown.
+func ShutdownSignals() []string {
+	return c.ShutdownSignals
+}
+
+// LogLevel returns the minimum level logged.
+func LogLevel() string {
+	return c.LogLevel
+}
+
+// WatchConfig returns whether the configuration file is reloaded on change.
+func WatchConfig() bool {
+	return c.WatchConfig
+}
+
+// ReadTimeout returns the maximum duration for reading a request.
+func ReadTimeout() time.Duration {
+	return c.ReadTimeout.Duration()
+}
+
+// WriteTimeout returns the maximum duration for writing a response.
+func WriteTimeout() time.Duration {
+	return c.WriteTimeout.Duration()
+}
+
+// IdleTimeout returns the maximum duration to wait for the next request.
+func IdleTimeout() time.Duration {
+	return c.IdleTimeout.Duration()
+}
+
+// ShutdownTimeout returns the maximum duration to wait for shutdown.
+func ShutdownTimeout() time.Duration {
+	return c.ShutdownTimeout.Duration()
+}
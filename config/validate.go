@@ -0,0 +1,269 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the configuration for values which would fail later, such as at
+// listen time, or silently misbehave, returning an error listing every problem.
+func (cfg *Config) Validate() error {
+	var errs []error
+	fail := func(field string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 0 || port > 65535 {
+		fail("port", "%q is not a port number", cfg.Port)
+	}
+	if cfg.Address != "" && net.ParseIP(cfg.Address) == nil && !validHost(cfg.Address) {
+		fail("address", "%q is not an IP address or host name", cfg.Address)
+	}
+	oneOf(fail, "profile", cfg.Profile, "", ProfileDev, ProfileStaging, ProfileProd)
+	oneOf(fail, "cookieProfile", cfg.CookieProfile, "", "strict", "lax", "lax-dev")
+	oneOf(fail, "logOutput", cfg.LogOutput, "", LogOutputConsole, LogOutputSyslog, LogOutputJournald, LogOutputOTLP)
+	oneOf(fail, "logLevel", cfg.LogLevel, "", "trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled")
+	oneOf(fail, "metricsExporter", cfg.MetricsExporter, "", MetricsPrometheus, MetricsStatsD, MetricsDogStatsD, MetricsOTLP)
+	oneOf(fail, "pathNormalization", cfg.PathNormalization, "", NormalizeRedirect, NormalizeRewrite)
+
+	if cfg.EnableTLS {
+		if cfg.TLSCertFile == "" {
+			fail("tlsCertFile", "required when enableTls is set")
+		}
+		if cfg.TLSKeyFile == "" {
+			fail("tlsKeyFile", "required when enableTls is set")
+		}
+	}
+	oneOf(fail, "tlsMinVersion", cfg.TLSMinVersion, "", TLSVersion12, TLSVersion13)
+	oneOf(fail, "tlsClientAuth", cfg.TLSClientAuth, "", TLSClientAuthNone, TLSClientAuthRequest, TLSClientAuthRequire, TLSClientAuthVerify, TLSClientAuthRequireVerify)
+	if (cfg.TLSClientAuth == TLSClientAuthVerify || cfg.TLSClientAuth == TLSClientAuthRequireVerify) && cfg.TLSClientCAFile == "" {
+		fail("tlsClientCaFile", "required when tlsClientAuth verifies certificates")
+	}
+	for _, name := range cfg.TLSCipherSuites {
+		if !cipherSuite(name) {
+			fail("tlsCipherSuites", "%q is not a secure cipher suite", name)
+		}
+	}
+	if cfg.EnableAutocert {
+		if cfg.EnableTLS {
+			fail("enableAutocert", "cannot be set with enableTls")
+		}
+		if len(cfg.AutocertDomains) == 0 {
+			fail("autocertDomains", "required when enableAutocert is set")
+		}
+		for _, domain := range cfg.AutocertDomains {
+			if !validHost(domain) {
+				fail("autocertDomains", "%q is not a host name", domain)
+			}
+		}
+		if cfg.AutocertHTTPAddress != "" && !validAddress(cfg.AutocertHTTPAddress) {
+			fail("autocertHttpAddress", "%q is not a host:port address", cfg.AutocertHTTPAddress)
+		}
+	}
+	if cfg.EnableCORS && cfg.CORSAllowCredentials {
+		for _, origin := range cfg.CORSAllowedOrigins {
+			if origin == "*" {
+				fail("corsAllowedOrigins", "cannot contain * with corsAllowCredentials, as any site could make credentialed requests")
+			}
+		}
+	}
+	if cfg.EnableH2C && (cfg.EnableTLS || cfg.EnableAutocert || cfg.EnableSPIFFE) {
+		fail("enableH2c", "cannot be set with TLS, over which HTTP/2 is negotiated")
+	}
+	if cfg.EnableHTTP3 {
+		if !cfg.Experimental {
+			fail("enableHttp3", "requires experimental, as HTTP/3 support is experimental")
+		}
+		if !cfg.EnableTLS && !cfg.EnableAutocert && !cfg.EnableSPIFFE {
+			fail("enableHttp3", "requires TLS, over which QUIC is served")
+		}
+		if !cfg.TLSSessionTickets {
+			fail("enableHttp3", "requires tlsSessionTickets, which QUIC handshakes issue")
+		}
+	}
+	if cfg.TLSEarlyData && !cfg.EnableHTTP3 {
+		fail("tlsEarlyData", "requires enableHttp3, as early data is only accepted over QUIC")
+	}
+	if cfg.EnableProxyProtocol && len(cfg.ProxyProtocolTrusted) == 0 {
+		fail("proxyProtocolTrusted", "required when enableProxyProtocol is set, as untrusted clients could spoof their address")
+	}
+	for _, network := range cfg.ProxyProtocolTrusted {
+		if _, _, err := net.ParseCIDR(network); err != nil && net.ParseIP(network) == nil {
+			fail("proxyProtocolTrusted", "%q is not an IP address or CIDR network", network)
+		}
+	}
+	if cfg.EnableSPIFFE && cfg.SPIFFEDir == "" {
+		fail("spiffeDir", "required when enableSpiffe is set")
+	}
+	if cfg.EnableProfiling && cfg.ProfilingEndpoint == "" {
+		fail("profilingEndpoint", "required when enableProfiling is set")
+	}
+	if (cfg.LogOutput == LogOutputOTLP || cfg.MetricsExporter == MetricsOTLP) && cfg.OTLPEndpoint == "" {
+		fail("otlpEndpoint", "required when exporting over otlp")
+	}
+
+	for _, f := range []struct {
+		name  string
+		value int64
+	}{
+		{"tlsExpiryWarningDays", int64(cfg.TLSExpiryWarningDays)},
+		{"tlsTicketRotation", int64(cfg.TLSTicketRotation)},
+		{"proxyProtocolTimeout", int64(cfg.ProxyProtocolTimeout)},
+		{"connectionBurst", int64(cfg.ConnectionBurst)},
+		{"maxConnectionsPerIp", int64(cfg.MaxConnectionsPerIP)},
+		{"metricsTenantLimit", int64(cfg.MetricsTenantLimit)},
+		{"profilingInterval", int64(cfg.ProfilingInterval)},
+		{"maxResponseBytes", cfg.MaxResponseBytes},
+		{"memoryLimitMb", int64(cfg.MemoryLimitMB)},
+		{"watchdogHeapMb", int64(cfg.WatchdogHeapMB)},
+		{"watchdogGoroutines", int64(cfg.WatchdogGoroutines)},
+		{"watchdogInterval", int64(cfg.WatchdogInterval)},
+	} {
+		if f.value < 0 {
+			fail(f.name, "%d is negative", f.value)
+		}
+	}
+	for _, f := range []struct {
+		name  string
+		value Duration
+	}{
+		{"readTimeout", cfg.ReadTimeout},
+		{"writeTimeout", cfg.WriteTimeout},
+		{"idleTimeout", cfg.IdleTimeout},
+		{"shutdownTimeout", cfg.ShutdownTimeout},
+	} {
+		if f.value < 0 {
+			fail(f.name, "%s is negative", f.value)
+		}
+	}
+	if cfg.ConnectionRate < 0 {
+		fail("connectionRate", "%g is negative", cfg.ConnectionRate)
+	}
+
+	if cfg.MetricsPath != "" && !strings.HasPrefix(cfg.MetricsPath, "/") {
+		fail("metricsPath", "%q does not begin with /", cfg.MetricsPath)
+	}
+	if cfg.PprofPrefix != "" && !strings.HasPrefix(cfg.PprofPrefix, "/") {
+		fail("pprofPrefix", "%q does not begin with /", cfg.PprofPrefix)
+	}
+
+	names := make([]string, 0, len(cfg.Listeners))
+	for name := range cfg.Listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if addr := cfg.Listeners[name]; !validAddress(addr) {
+			fail("listeners."+name, "%q is not a host:port address", addr)
+		}
+	}
+	if cfg.AdminAddress != "" && !validAddress(cfg.AdminAddress) {
+		fail("adminAddress", "%q is not a host:port address", cfg.AdminAddress)
+	}
+	if _, ok := cfg.Listeners["admin"]; ok && cfg.AdminAddress != "" {
+		fail("listeners.admin", "conflicts with adminAddress")
+	}
+	if cfg.EnableUpgrade {
+		const reason = "cannot be set with enableUpgrade, as only the main listener is handed to the new process"
+		if len(cfg.Listeners) > 0 {
+			fail("listeners", reason)
+		}
+		if cfg.AdminAddress != "" {
+			fail("adminAddress", reason)
+		}
+		if cfg.EnableAutocert && cfg.AutocertHTTPAddress != "" {
+			fail("autocertHttpAddress", reason)
+		}
+		if cfg.EnableHTTP3 {
+			fail("enableHttp3", reason)
+		}
+	}
+	for _, name := range cfg.ShutdownSignals {
+		if strings.EqualFold(name, "SIGUSR2") {
+			fail("shutdownSignals", "SIGUSR2 triggers upgrades, and may not begin a shutdown")
+		}
+	}
+	if cfg.EnablePprof {
+		_, named := cfg.Listeners[cfg.PprofListener]
+		switch {
+		case cfg.PprofListener == "admin" && cfg.AdminAddress != "", named:
+		case cfg.PprofListener != "":
+			fail("pprofListener", "%q is not a configured listener", cfg.PprofListener)
+		case cfg.AdminAddress == "" && !cfg.PprofPublic:
+			fail("enablePprof", "requires adminAddress, pprofListener or pprofPublic, as it would be served on the main listener")
+		}
+	}
+
+	for i, rule := range cfg.RewriteRules {
+		field := fmt.Sprintf("rewriteRules[%d]", i)
+		if rule.From == "" {
+			fail(field, "from is required")
+		}
+		if rule.Regex {
+			if _, err := regexp.Compile(rule.From); err != nil {
+				fail(field, "from is not a regular expression: %v", err)
+			}
+		}
+		if rule.Status != 0 && (rule.Status < 300 || rule.Status > 399) {
+			fail(field, "status %d is not a redirect", rule.Status)
+		}
+	}
+
+	for _, name := range sectionNames() {
+		if v, ok := cfg.Section(name).(interface{ Validate() error }); ok {
+			if err := v.Validate(); err != nil {
+				fail(name, "%v", err)
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("Validate: invalid config:\n%w", err)
+	}
+	return nil
+}
+
+// cipherSuite returns whether the name is of a cipher suite crypto/tls considers
+// secure.
+func cipherSuite(name string) bool {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func oneOf(fail func(field string, format string, args ...interface{}), field string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	fail(field, "%q is not one of %s", value, strings.Join(allowed[1:], ", "))
+}
+
+// validAddress returns whether addr is a host:port address, the host being optional.
+func validAddress(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return false
+	}
+	return host == "" || net.ParseIP(host) != nil || validHost(host)
+}
+
+var hostPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+func validHost(host string) bool {
+	return len(host) <= 253 && hostPattern.MatchString(host)
+}
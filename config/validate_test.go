@@ -0,0 +1,113 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Defaults().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+
+	cfg := Defaults()
+	cfg.Port = "70000"
+	cfg.EnableTLS = true
+	cfg.ProxyProtocolTimeout = -1
+	cfg.EnableProxyProtocol = true
+	cfg.LogOutput = "stdout"
+	cfg.PathNormalization = "redirects"
+	cfg.ShutdownSignals = []string{"SIGTERM", "sigusr2"}
+	cfg.Listeners = map[string]string{"internal": "localhost"}
+	cfg.RewriteRules = []RewriteRule{{From: "(", Regex: true, Status: 200}}
+	cfg.TLSClientAuth = TLSClientAuthRequireVerify
+	cfg.TLSCipherSuites = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+	cfg.EnableAutocert = true
+	cfg.EnableH2C = true
+	cfg.EnableHTTP3 = true
+	cfg.EnablePprof = true
+	cfg.EnableCORS = true
+	cfg.CORSAllowedOrigins = []string{"*"}
+	cfg.CORSAllowCredentials = true
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("validated an invalid config")
+	}
+	for _, want := range []string{
+		`port: "70000" is not a port number`,
+		"tlsCertFile: required when enableTls is set",
+		"tlsKeyFile: required when enableTls is set",
+		"proxyProtocolTimeout: -1 is negative",
+		"proxyProtocolTrusted: required when enableProxyProtocol is set, as untrusted clients could spoof their address",
+		`logOutput: "stdout" is not one of console, syslog, journald, otlp`,
+		`pathNormalization: "redirects" is not one of redirect, rewrite`,
+		"shutdownSignals: SIGUSR2 triggers upgrades, and may not begin a shutdown",
+		`listeners.internal: "localhost" is not a host:port address`,
+		"rewriteRules[0]: from is not a regular expression",
+		"rewriteRules[0]: status 200 is not a redirect",
+		"tlsClientCaFile: required when tlsClientAuth verifies certificates",
+		`tlsCipherSuites: "TLS_RSA_WITH_RC4_128_SHA" is not a secure cipher suite`,
+		"enableAutocert: cannot be set with enableTls",
+		"autocertDomains: required when enableAutocert is set",
+		"enableH2c: cannot be set with TLS, over which HTTP/2 is negotiated",
+		"enableHttp3: requires experimental, as HTTP/3 support is experimental",
+		"enablePprof: requires adminAddress, pprofListener or pprofPublic, as it would be served on the main listener",
+		"corsAllowedOrigins: cannot contain * with corsAllowCredentials, as any site could make credentialed requests",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error lacks %q:\n%v", want, err)
+		}
+	}
+
+	cfg = Defaults()
+	cfg.TLSEarlyData = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tlsEarlyData: requires enableHttp3, as early data is only accepted over QUIC") {
+		t.Fatalf("got %v, want early data refused without HTTP/3", err)
+	}
+}
+
+func TestValidatePprof(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{EnablePprof: true, PprofPublic: true}, ""},
+		{Config{EnablePprof: true, AdminAddress: "127.0.0.1:9090"}, ""},
+		{Config{EnablePprof: true, Listeners: map[string]string{"debug": "127.0.0.1:6060"}, PprofListener: "debug"}, ""},
+		{Config{EnablePprof: true, PprofListener: "debug"}, `pprofListener: "debug" is not a configured listener`},
+		{Config{EnablePprof: true, PprofListener: "admin"}, `pprofListener: "admin" is not a configured listener`},
+	} {
+		cfg := Defaults()
+		cfg.EnablePprof, cfg.PprofPublic, cfg.PprofListener = tc.cfg.EnablePprof, tc.cfg.PprofPublic, tc.cfg.PprofListener
+		cfg.AdminAddress, cfg.Listeners = tc.cfg.AdminAddress, tc.cfg.Listeners
+		err := cfg.Validate()
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%+v: got %v, want %q", tc.cfg, err, tc.want)
+		}
+	}
+}
+
+func TestValidateUpgrade(t *testing.T) {
+	const reason = "cannot be set with enableUpgrade, as only the main listener is handed to the new process"
+	for _, tc := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, ""},
+		{Config{Listeners: map[string]string{"internal": "127.0.0.1:7003"}}, "listeners: " + reason},
+		{Config{AdminAddress: "127.0.0.1:9090"}, "adminAddress: " + reason},
+		{Config{EnableAutocert: true, AutocertDomains: []string{"example.com"}, AutocertHTTPAddress: "127.0.0.1:80"}, "autocertHttpAddress: " + reason},
+		{Config{Experimental: true, EnableTLS: true, TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", TLSSessionTickets: true, EnableHTTP3: true}, "enableHttp3: " + reason},
+	} {
+		cfg := Defaults()
+		cfg.EnableUpgrade, cfg.Listeners, cfg.AdminAddress = true, tc.cfg.Listeners, tc.cfg.AdminAddress
+		cfg.EnableAutocert, cfg.AutocertDomains, cfg.AutocertHTTPAddress = tc.cfg.EnableAutocert, tc.cfg.AutocertDomains, tc.cfg.AutocertHTTPAddress
+		cfg.Experimental, cfg.EnableTLS, cfg.TLSCertFile, cfg.TLSKeyFile = tc.cfg.Experimental, tc.cfg.EnableTLS, tc.cfg.TLSCertFile, tc.cfg.TLSKeyFile
+		cfg.TLSSessionTickets, cfg.EnableHTTP3 = tc.cfg.TLSSessionTickets, tc.cfg.EnableHTTP3
+		err := cfg.Validate()
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%+v: got %v, want %q", tc.cfg, err, tc.want)
+		}
+	}
+}
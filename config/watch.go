@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchSettle is how long Watch waits after an event for the writes of an editor
+// or deployment tool to settle before reading the file.
+const watchSettle = 100 * time.Millisecond
+
+// Watch calls fn with the configuration file at path whenever it changes, until the
+// context is done. Files which fail to load are passed to fn with their error.
+func Watch(ctx context.Context, path string, fn func(cfg *Config, err error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Watch: failed creating watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("Watch: failed watching directory: %w", err)
+	}
+
+	last, _ := os.Stat(path)
+	settle := time.NewTimer(watchSettle)
+	settle.Stop()
+	defer settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fn(nil, fmt.Errorf("Watch: %w", err))
+			continue
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			settle.Reset(watchSettle)
+			continue
+		case <-settle.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		fn(Read(path))
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ramchi.config.json")
+	if err := os.WriteFile(path, []byte(`{"port": "8080"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		cfg *Config
+		err error
+	}
+	results := make(chan result, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, path, func(cfg *Config, err error) {
+		results <- result{cfg, err}
+	})
+
+	// Replace the file by a rename, as editors and ConfigMaps do.
+	time.Sleep(30 * time.Millisecond)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(`{"port": "8081", "experimental": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-results:
+		if r.err != nil || r.cfg.Port != "8081" || !r.cfg.Experimental {
+			t.Fatalf("got %+v and %v, want the changed config", r.cfg, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change not seen")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"port": "x"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-results:
+		if r.err == nil {
+			t.Fatal("got no error for an invalid config")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change not seen")
+	}
+}
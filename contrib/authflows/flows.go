@@ -0,0 +1,257 @@
+package authflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/ratelimit"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "authflows").Logger()
+
+// ErrUnknownUser is returned by a Directory when no account has the email address.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Directory gives the flows access to the application's accounts.
+type Directory interface {
+	// Lookup returns the subject of the account with the email address, or ErrUnknownUser.
+	Lookup(ctx context.Context, email string) (string, error)
+	// MarkVerified records that the subject controls the email address.
+	MarkVerified(ctx context.Context, subject string, email string) error
+	// SetPassword replaces the password of the subject.
+	SetPassword(ctx context.Context, subject string, password string) error
+}
+
+// Mailer sends the messages carrying flow links.
+type Mailer interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
+
+// Message returns the subject and body of the message for a flow, given the link completing it.
+type Message func(purpose string, link string) (string, string)
+
+// Flows serves the verify-email and reset-password endpoints.
+type Flows struct {
+	Tokens    TokenStore
+	Directory Directory
+	Mailer    Mailer
+	// Limits throttles requests for messages, per email address and client IP.
+	Limits ratelimit.Store
+	// MaxRequests is the number of messages which may be requested within LimitWindow.
+	MaxRequests int64
+	LimitWindow time.Duration
+	// VerifyURL and ResetURL are the addresses of the frontend pages completing
+	// the flows, which receive the token in their token query parameter.
+	VerifyURL string
+	ResetURL  string
+	VerifyTTL time.Duration
+	ResetTTL  time.Duration
+	// MinPasswordLength is the minimum length of a new password.
+	MinPasswordLength int
+	// PasswordPolicy, when set, checks new passwords in place of MinPasswordLength,
+	// refusing them with its reasons.
+	PasswordPolicy *helpers.PasswordPolicy
+	Message        Message
+	Prefix         string
+}
+
+// New initializes the flows with conservative defaults.
+func New(tokens TokenStore, directory Directory, mailer Mailer, verifyURL string, resetURL string) *Flows {
+	return &Flows{
+		Tokens:            tokens,
+		Directory:         directory,
+		Mailer:            mailer,
+		Limits:            ratelimit.NewMemoryStore(),
+		MaxRequests:       5,
+		LimitWindow:       time.Hour,
+		VerifyURL:         verifyURL,
+		ResetURL:          resetURL,
+		VerifyTTL:         24 * time.Hour,
+		ResetTTL:          time.Hour,
+		MinPasswordLength: 10,
+		Message:           defaultMessage,
+		Prefix:            "/auth",
+	}
+}
+
+func defaultMessage(purpose string, link string) (string, string) {
+	if purpose == PurposeResetPassword {
+		return "Reset your password", "A password reset was requested for your account. Follow this link to choose a new password:\n\n" + link + "\n\nIf you did not request this, you can ignore this message."
+	}
+	return "Verify your email address", "Follow this link to verify your email address:\n\n" + link + "\n"
+}
+
+// Router returns the router serving the flow endpoints under the prefix:
+//
+//	POST {prefix}/verify-email          {"email": "..."}
+//	POST {prefix}/verify-email/confirm  {"token": "..."}
+//	POST {prefix}/reset-password         {"email": "..."}
+//	POST {prefix}/reset-password/confirm {"token": "...", "password": "..."}
+func (f *Flows) Router(opts ...router.RouterWrapper) router.Router {
+	return router.NewRouter([]router.Route{
+		router.NewPostRoute(f.Prefix+"/verify-email", true, false, f.requestHandler(PurposeVerifyEmail)),
+		router.NewPostRoute(f.Prefix+"/verify-email/confirm", true, false, f.verifyHandler),
+		router.NewPostRoute(f.Prefix+"/reset-password", true, false, f.requestHandler(PurposeResetPassword)),
+		router.NewPostRoute(f.Prefix+"/reset-password/confirm", true, false, f.resetHandler),
+	}, true, opts...)
+}
+
+// Issue creates a token for the flow and mails its link to the email address.
+func (f *Flows) Issue(ctx context.Context, purpose string, subject string, email string) error {
+	token, hash, err := NewToken()
+	if err != nil {
+		return fmt.Errorf("Issue: %w", err)
+	}
+
+	base, ttl := f.VerifyURL, f.VerifyTTL
+	if purpose == PurposeResetPassword {
+		base, ttl = f.ResetURL, f.ResetTTL
+	}
+	if err := f.Tokens.Save(ctx, Token{Hash: hash, Purpose: purpose, Subject: subject, Email: email, ExpiresAt: time.Now().Add(ttl)}); err != nil {
+		return fmt.Errorf("Issue: failed saving token: %w", err)
+	}
+
+	link, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("Issue: failed parsing link: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	title, body := f.Message(purpose, link.String())
+	if err := f.Mailer.Send(ctx, email, title, body); err != nil {
+		return fmt.Errorf("Issue: failed sending message: %w", err)
+	}
+	return nil
+}
+
+// Redeem consumes a token issued for the purpose. A token issued for another
+// purpose is refused without being consumed.
+func (f *Flows) Redeem(ctx context.Context, purpose string, token string) (Token, error) {
+	t, err := f.Tokens.Consume(ctx, HashToken(token), purpose)
+	if err != nil {
+		return Token{}, fmt.Errorf("Redeem: %w", err)
+	}
+	if !SamePurpose(t.Purpose, purpose) || time.Now().After(t.ExpiresAt) {
+		return Token{}, fmt.Errorf("Redeem: %w", ErrTokenInvalid)
+	}
+	return t, nil
+}
+
+// requestHandler issues a token for the flow. It responds 202 whether or not
+// an account exists, so that it cannot be used to discover addresses.
+func (f *Flows) requestHandler(purpose string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Email string `json:"email"`
+		}
+		if !decode(w, r, &body) {
+			return
+		}
+		if body.Email == "" {
+			helpers.JSONError(w, r, http.StatusBadRequest, "email is required")
+			return
+		}
+		email := strings.ToLower(strings.TrimSpace(body.Email))
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		count, reset, err := f.Limits.Increment(r.Context(), "authflows:"+purpose+":"+email+"|"+ip, 1, f.LimitWindow)
+		if err != nil {
+			helpers.Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		if count > f.MaxRequests {
+			w.Header().Set("Retry-After", fmt.Sprint(int(time.Until(reset).Seconds())+1))
+			helpers.Error(w, r, http.StatusTooManyRequests)
+			return
+		}
+
+		subject, err := f.Directory.Lookup(r.Context(), email)
+		if err == nil {
+			err = f.Issue(r.Context(), purpose, subject, email)
+		}
+		if err != nil && !errors.Is(err, ErrUnknownUser) {
+			log.Error().Str("Function", "requestHandler").Str("Purpose", purpose).Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed issuing token")
+			helpers.Error(w, r, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (f *Flows) verifyHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	t, err := f.Redeem(r.Context(), PurposeVerifyEmail, body.Token)
+	if err != nil {
+		helpers.JSONError(w, r, http.StatusBadRequest, "token is invalid or expired")
+		return
+	}
+	if err := f.Directory.MarkVerified(r.Context(), t.Subject, t.Email); err != nil {
+		log.Error().Str("Function", "verifyHandler").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed marking email verified")
+		helpers.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Flows) resetHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if f.PasswordPolicy != nil {
+		if err := f.PasswordPolicy.Check(body.Password); err != nil {
+			helpers.JSONError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if len([]rune(body.Password)) < f.MinPasswordLength {
+		helpers.JSONError(w, r, http.StatusBadRequest, fmt.Sprintf("password must be at least %d characters", f.MinPasswordLength))
+		return
+	}
+
+	t, err := f.Redeem(r.Context(), PurposeResetPassword, body.Token)
+	if err != nil {
+		helpers.JSONError(w, r, http.StatusBadRequest, "token is invalid or expired")
+		return
+	}
+	if err := f.Directory.SetPassword(r.Context(), t.Subject, body.Password); err != nil {
+		log.Error().Str("Function", "resetHandler").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed setting password")
+		helpers.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decode reads a JSON body of at most 64KiB, writing the error response when it is malformed.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(v); err != nil {
+		helpers.JSONError(w, r, http.StatusBadRequest, "malformed request body")
+		return false
+	}
+	return true
+}
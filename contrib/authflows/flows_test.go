@@ -0,0 +1,38 @@
+package authflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedeem(t *testing.T) {
+	ctx := context.Background()
+	f := New(NewMemoryStore(), nil, nil, "https://app.example/verify", "https://app.example/reset")
+
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Tokens.Save(ctx, Token{Hash: hash, Purpose: PurposeVerifyEmail, Subject: "alice", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Redeem(ctx, PurposeResetPassword, token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("redeemed for another purpose: got %v, want ErrTokenInvalid", err)
+	}
+	got, err := f.Redeem(ctx, PurposeVerifyEmail, token)
+	if err != nil || got.Subject != "alice" {
+		t.Fatalf("got %+v and %v, want the token left in place by the wrong purpose", got, err)
+	}
+	if _, err := f.Redeem(ctx, PurposeVerifyEmail, token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("redeemed twice: got %v, want ErrTokenInvalid", err)
+	}
+
+	token, hash, _ = NewToken()
+	f.Tokens.Save(ctx, Token{Hash: hash, Purpose: PurposeResetPassword, ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err := f.Redeem(ctx, PurposeResetPassword, token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("redeemed an expired token: got %v, want ErrTokenInvalid", err)
+	}
+}
@@ -0,0 +1,102 @@
+package authflows
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	PurposeVerifyEmail   = "verify-email"
+	PurposeResetPassword = "reset-password"
+)
+
+// ErrTokenInvalid is returned when a token is unknown, already used, expired or issued for another purpose.
+var ErrTokenInvalid = errors.New("token invalid")
+
+// Token is a single-use token, persisted by its hash so that a leaked store
+// cannot be used to complete flows.
+type Token struct {
+	Hash      string
+	Purpose   string
+	Subject   string
+	Email     string
+	ExpiresAt time.Time
+}
+
+// TokenStore persists issued tokens.
+type TokenStore interface {
+	// Save persists a token.
+	Save(ctx context.Context, t Token) error
+	// Consume removes and returns the token with the hash, or ErrTokenInvalid. A
+	// token issued for another purpose is left in place, so that submitting it to
+	// the wrong flow does not spend it.
+	Consume(ctx context.Context, hash string, purpose string) (Token, error)
+}
+
+// NewToken generates a random token, returning it and its hash.
+func NewToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("NewToken: failed reading random bytes: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash a token is persisted under.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SamePurpose compares the purposes of tokens in constant time, for stores to check
+// a token's purpose before consuming it.
+func SamePurpose(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryStore initializes a TokenStore local to the process, suitable for development and tests.
+func NewMemoryStore() TokenStore {
+	return &memoryStore{tokens: make(map[string]Token)}
+}
+
+// Save persists a token.
+func (m *memoryStore) Save(ctx context.Context, t Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for hash, existing := range m.tokens {
+		if now.After(existing.ExpiresAt) {
+			delete(m.tokens, hash)
+		}
+	}
+	m.tokens[t.Hash] = t
+	return nil
+}
+
+// Consume removes and returns the token with the hash, when issued for the purpose.
+func (m *memoryStore) Consume(ctx context.Context, hash string, purpose string) (Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tokens[hash]
+	if !ok || !SamePurpose(t.Purpose, purpose) {
+		return Token{}, ErrTokenInvalid
+	}
+	delete(m.tokens, hash)
+	return t, nil
+}
@@ -0,0 +1,214 @@
+// Package outbox implements the transactional outbox: handlers enqueue events in
+// the transaction of their request, and a relay publishes them once committed.
+// Events are published at least once; consumers deduplicate them by key.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Etwodev/ramchi/scheduler"
+	"github.com/Etwodev/ramchi/sqltx"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "outbox").Logger()
+
+// ErrNoTransaction is returned when enqueuing within a request not served by sqltx.
+var ErrNoTransaction = errors.New("request has no transaction")
+
+// Schema creates the outbox table for PostgreSQL; other databases need the types
+// adapted. The table name must match Outbox.Table.
+const Schema = `CREATE TABLE IF NOT EXISTS ramchi_outbox (
+	dedup_key VARCHAR(255) PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	payload BYTEA NOT NULL,
+	created TIMESTAMP NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt TIMESTAMP NOT NULL,
+	last_error TEXT,
+	published TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS ramchi_outbox_pending ON ramchi_outbox (next_attempt) WHERE published IS NULL;`
+
+// Event is a message relayed to the publisher.
+type Event struct {
+	// Key deduplicates the event, as it may be published more than once.
+	Key      string
+	Topic    string
+	Payload  []byte
+	Created  time.Time
+	Attempts int
+}
+
+// Publisher delivers events, such as to an event bus or webhook.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, e Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// Outbox stores events in a table, and relays them to a publisher.
+type Outbox struct {
+	DB        *sql.DB
+	Publisher Publisher
+	Table     string
+	// Placeholder returns the nth bind parameter, which differs between drivers.
+	Placeholder func(n int) string
+	// Interval is how often the relay runs as a scheduled job.
+	Interval time.Duration
+	// Batch bounds the events published per relay.
+	Batch int
+	// MaxAttempts bounds the publishing of an event, after which it is left
+	// unpublished in the table for inspection.
+	MaxAttempts int
+	// Base and Max bound the backoff between attempts, which doubles with each.
+	Base time.Duration
+	Max  time.Duration
+	// Retention is how long published events are kept.
+	Retention time.Duration
+}
+
+// New initializes an outbox over the "ramchi_outbox" table, using PostgreSQL
+// placeholders, relaying up to 100 events every second with 10 attempts each,
+// backing off from 1s up to 10m, and keeping published events for a day.
+func New(db *sql.DB, publisher Publisher) *Outbox {
+	return &Outbox{
+		DB:          db,
+		Publisher:   publisher,
+		Table:       "ramchi_outbox",
+		Placeholder: Dollar,
+		Interval:    time.Second,
+		Batch:       100,
+		MaxAttempts: 10,
+		Base:        time.Second,
+		Max:         10 * time.Minute,
+		Retention:   24 * time.Hour,
+	}
+}
+
+// Dollar numbers placeholders as PostgreSQL does: $1, $2...
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Question uses placeholders as MySQL and SQLite do: ?.
+func Question(n int) string {
+	return "?"
+}
+
+// Enqueue stores the event within the transaction, so that it is published if,
+// and only if, the transaction commits. An empty key is generated.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, topic string, key string, payload []byte) error {
+	if key == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("Enqueue: failed generating key: %w", err)
+		}
+		key = hex.EncodeToString(b)
+	}
+	now := time.Now().UTC()
+	_, err := tx.ExecContext(ctx, o.query("INSERT INTO %s (dedup_key, topic, payload, created, next_attempt) VALUES (%s, %s, %s, %s, %s)", 5), key, topic, payload, now, now)
+	if err != nil {
+		return fmt.Errorf("Enqueue: failed inserting event: %w", err)
+	}
+	return nil
+}
+
+// EnqueueRequest stores the event within the transaction of the request, begun by sqltx.
+func (o *Outbox) EnqueueRequest(r *http.Request, topic string, key string, payload []byte) error {
+	tx := sqltx.Tx(r.Context())
+	if tx == nil {
+		return fmt.Errorf("EnqueueRequest: %w", ErrNoTransaction)
+	}
+	return o.Enqueue(r.Context(), tx, topic, key, payload)
+}
+
+// Job returns the relay as a singleton job, so that one replica relays at a time.
+func (o *Outbox) Job() scheduler.Job {
+	return scheduler.Job{Name: "outbox", Every: o.Interval, Singleton: true, Run: o.Relay}
+}
+
+// Relay publishes a batch of pending events, in the order they were enqueued,
+// rescheduling those failing with backoff, and deletes expired published events.
+func (o *Outbox) Relay(ctx context.Context) error {
+	now := time.Now().UTC()
+	rows, err := o.DB.QueryContext(ctx, o.query("SELECT dedup_key, topic, payload, created, attempts FROM %s WHERE published IS NULL AND attempts < %s AND next_attempt <= %s ORDER BY created LIMIT %s", 3), o.MaxAttempts, now, o.Batch)
+	if err != nil {
+		return fmt.Errorf("Relay: failed querying events: %w", err)
+	}
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.Key, &e.Topic, &e.Payload, &e.Created, &e.Attempts); err != nil {
+			rows.Close()
+			return fmt.Errorf("Relay: failed scanning event: %w", err)
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Relay: failed reading events: %w", err)
+	}
+
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Relay: %w", ctx.Err())
+		}
+		if err := o.Publisher.Publish(ctx, e); err != nil {
+			e.Attempts++
+			if e.Attempts >= o.MaxAttempts {
+				log.Error().Str("Function", "Relay").Str("Key", e.Key).Str("Topic", e.Topic).Err(err).Msg("Event exhausted its attempts")
+			} else {
+				log.Warn().Str("Function", "Relay").Str("Key", e.Key).Str("Topic", e.Topic).Int("Attempts", e.Attempts).Err(err).Msg("Failed publishing event")
+			}
+			_, uerr := o.DB.ExecContext(ctx, o.query("UPDATE %s SET attempts = %s, next_attempt = %s, last_error = %s WHERE dedup_key = %s", 4), e.Attempts, time.Now().UTC().Add(o.backoff(e.Attempts)), err.Error(), e.Key)
+			if uerr != nil {
+				return fmt.Errorf("Relay: failed rescheduling event: %w", uerr)
+			}
+			continue
+		}
+		// Should this fail, the event is published again, which its key deduplicates.
+		if _, err := o.DB.ExecContext(ctx, o.query("UPDATE %s SET published = %s WHERE dedup_key = %s", 2), time.Now().UTC(), e.Key); err != nil {
+			return fmt.Errorf("Relay: failed marking event published: %w", err)
+		}
+	}
+
+	if _, err := o.DB.ExecContext(ctx, o.query("DELETE FROM %s WHERE published < %s", 1), now.Add(-o.Retention)); err != nil {
+		return fmt.Errorf("Relay: failed deleting published events: %w", err)
+	}
+	return nil
+}
+
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.Base
+	for i := 1; i < attempts && d < o.Max; i++ {
+		d *= 2
+	}
+	if d > o.Max {
+		d = o.Max
+	}
+	return d
+}
+
+// query formats the statement with the table name and n placeholders.
+func (o *Outbox) query(format string, n int) string {
+	args := []interface{}{o.Table}
+	for i := 1; i <= n; i++ {
+		args = append(args, o.Placeholder(i))
+	}
+	return fmt.Sprintf(format, args...)
+}
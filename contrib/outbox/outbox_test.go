@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if r.Header.Get("X-Outbox-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	e := Event{Key: "order-1", Topic: "orders", Payload: []byte(`{"id":1}`)}
+	if err := NewWebhook(srv.URL, secret).Publish(context.Background(), e); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got.Get("Idempotency-Key") != "order-1" || got.Get("X-Outbox-Topic") != "orders" {
+		t.Fatalf("unexpected headers: %v", got)
+	}
+	if err := NewWebhook(srv.URL, []byte("wrong")).Publish(context.Background(), e); err == nil {
+		t.Fatal("Publish with wrong secret: want error")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	o := New(nil, nil)
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: 10 * time.Minute} {
+		if got := o.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
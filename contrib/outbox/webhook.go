@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook publishes events by POSTing their payload to a URL. The event key is sent
+// as the Idempotency-Key header and its topic as X-Outbox-Topic. When the secret is
+// set, X-Outbox-Signature carries "sha256=" and the hex HMAC-SHA256 of the payload.
+type Webhook struct {
+	URL         string
+	Secret      []byte
+	ContentType string
+	Client      *http.Client
+}
+
+// NewWebhook initializes a webhook publisher sending JSON payloads.
+func NewWebhook(url string, secret []byte) *Webhook {
+	return &Webhook{URL: url, Secret: secret, ContentType: "application/json", Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Publish fails unless the webhook answers with a 2xx status.
+func (w *Webhook) Publish(ctx context.Context, e Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(e.Payload))
+	if err != nil {
+		return fmt.Errorf("Publish: failed creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.ContentType)
+	req.Header.Set("Idempotency-Key", e.Key)
+	req.Header.Set("X-Outbox-Topic", e.Topic)
+	if len(w.Secret) > 0 {
+		mac := hmac.New(sha256.New, w.Secret)
+		mac.Write(e.Payload)
+		req.Header.Set("X-Outbox-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Publish: failed calling webhook: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Publish: webhook returned %s", res.Status)
+	}
+	return nil
+}
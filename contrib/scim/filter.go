@@ -0,0 +1,392 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a parsed SCIM filter expression (RFC 7644, section 3.4.2.2).
+type Filter interface {
+	// Match returns whether the resource, or the element of a multi-valued
+	// attribute, satisfies the filter.
+	Match(v map[string]interface{}) bool
+}
+
+// Comparison compares an attribute with a value, or tests its presence with the pr operator.
+type Comparison struct {
+	Path  []string
+	Op    string
+	Value interface{}
+}
+
+// Logical combines two filters with the and or or operator.
+type Logical struct {
+	Op          string
+	Left, Right Filter
+}
+
+// Not negates a filter.
+type Not struct {
+	Filter Filter
+}
+
+// ValuePath filters the elements of a multi-valued attribute, as in emails[type eq "work"].
+type ValuePath struct {
+	Attr   string
+	Filter Filter
+}
+
+// ParseFilter parses a SCIM filter expression.
+func ParseFilter(s string) (Filter, error) {
+	p := &parser{tokens: tokenize(s)}
+	f, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("ParseFilter: %w", err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("ParseFilter: unexpected %q", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// tokenize splits a filter into attribute paths, operators, values and brackets.
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch ch := s[i]; {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')' || ch == '[' || ch == ']':
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) or() (Filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{"or", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{"and", left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Filter, error) {
+	if strings.EqualFold(p.peek(), "not") {
+		p.next()
+		if p.peek() != "(" {
+			return nil, fmt.Errorf("expected ( after not")
+		}
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{f}, nil
+	}
+
+	if p.peek() == "(" {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("expected )")
+		}
+		return f, nil
+	}
+
+	attr := p.next()
+	if attr == "" {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	if p.peek() == "[" {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != "]" {
+			return nil, fmt.Errorf("expected ]")
+		}
+		return ValuePath{attr, f}, nil
+	}
+
+	op := strings.ToLower(p.next())
+	path := splitPath(attr)
+	switch op {
+	case "pr":
+		return Comparison{Path: path, Op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "lt", "ge", "le":
+		value, err := parseValue(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return Comparison{Path: path, Op: op, Value: value}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// splitPath splits an attribute path into its names, dropping any schema URN prefix.
+func splitPath(attr string) []string {
+	if i := strings.LastIndex(attr, ":"); i >= 0 {
+		attr = attr[i+1:]
+	}
+	return strings.Split(attr, ".")
+}
+
+func parseValue(t string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(t, `"`):
+		s, err := strconv.Unquote(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", t)
+		}
+		return s, nil
+	case strings.EqualFold(t, "true"):
+		return true, nil
+	case strings.EqualFold(t, "false"):
+		return false, nil
+	case strings.EqualFold(t, "null"):
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", t)
+	}
+	return n, nil
+}
+
+// Match implements the Filter interface.
+func (l Logical) Match(v map[string]interface{}) bool {
+	if l.Op == "and" {
+		return l.Left.Match(v) && l.Right.Match(v)
+	}
+	return l.Left.Match(v) || l.Right.Match(v)
+}
+
+// Match implements the Filter interface.
+func (n Not) Match(v map[string]interface{}) bool {
+	return !n.Filter.Match(v)
+}
+
+// Match implements the Filter interface.
+func (vp ValuePath) Match(v map[string]interface{}) bool {
+	for _, element := range elements(lookup(v, vp.Attr)) {
+		if m, ok := element.(map[string]interface{}); ok && vp.Filter.Match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match implements the Filter interface. Comparisons against a multi-valued
+// attribute match when any of its values match.
+func (c Comparison) Match(v map[string]interface{}) bool {
+	for _, actual := range resolve(v, c.Path) {
+		if c.compare(actual) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Comparison) compare(actual interface{}) bool {
+	if c.Op == "pr" {
+		switch a := actual.(type) {
+		case nil:
+			return false
+		case string:
+			return a != ""
+		case []interface{}:
+			return len(a) > 0
+		}
+		return true
+	}
+
+	switch expected := c.Value.(type) {
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			return c.Op == "ne"
+		}
+		if ta, err := time.Parse(time.RFC3339, a); err == nil {
+			if te, err := time.Parse(time.RFC3339, expected); err == nil {
+				return order(c.Op, ta.Compare(te))
+			}
+		}
+		a, e := strings.ToLower(a), strings.ToLower(expected)
+		switch c.Op {
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		}
+		return order(c.Op, strings.Compare(a, e))
+	case float64:
+		a, ok := number(actual)
+		if !ok {
+			return c.Op == "ne"
+		}
+		switch {
+		case a < expected:
+			return order(c.Op, -1)
+		case a > expected:
+			return order(c.Op, 1)
+		}
+		return order(c.Op, 0)
+	case bool:
+		a, ok := actual.(bool)
+		if c.Op == "ne" {
+			return !ok || a != expected
+		}
+		return c.Op == "eq" && ok && a == expected
+	case nil:
+		if c.Op == "ne" {
+			return actual != nil
+		}
+		return c.Op == "eq" && actual == nil
+	}
+	return false
+}
+
+func order(op string, cmp int) bool {
+	switch op {
+	case "eq":
+		return cmp == 0
+	case "ne":
+		return cmp != 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+	return false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// lookup returns the attribute of v, matching its name case-insensitively.
+func lookup(v map[string]interface{}, name string) interface{} {
+	if value, ok := v[name]; ok {
+		return value
+	}
+	for k, value := range v {
+		if strings.EqualFold(k, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// resolve returns the values at the path, descending into multi-valued attributes.
+func resolve(v map[string]interface{}, path []string) []interface{} {
+	values := []interface{}{v}
+	for _, name := range path {
+		var next []interface{}
+		for _, value := range values {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			next = append(next, elements(lookup(m, name))...)
+		}
+		values = next
+	}
+	if len(values) == 0 {
+		return []interface{}{nil}
+	}
+	return values
+}
+
+// elements returns the values of a multi-valued attribute, or the single value otherwise.
+func elements(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	if v == nil {
+		return nil
+	}
+	return []interface{}{v}
+}
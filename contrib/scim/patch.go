@@ -0,0 +1,178 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPath is returned by Patch when an operation's path is malformed or missing.
+var ErrInvalidPath = errors.New("invalid path")
+
+// ErrNoTarget is returned by Patch when an operation's filter matches no values.
+var ErrNoTarget = errors.New("no target matched")
+
+// PatchOp is an operation of a SCIM PATCH request (RFC 7644, section 3.5.2).
+type PatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// patchPath is a parsed PATCH path: attr, attr.sub, attr[filter] or attr[filter].sub.
+type patchPath struct {
+	attr   string
+	filter Filter
+	sub    string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	if i := strings.LastIndex(path, ":"); i >= 0 && !strings.Contains(path[:i], "[") {
+		path = path[i+1:]
+	}
+
+	p := &patchPath{}
+	if open := strings.Index(path, "["); open >= 0 {
+		end := strings.LastIndex(path, "]")
+		if end < open {
+			return nil, fmt.Errorf("parsePatchPath: unterminated filter in %q: %w", path, ErrInvalidPath)
+		}
+		f, err := ParseFilter(path[open+1 : end])
+		if err != nil {
+			return nil, fmt.Errorf("parsePatchPath: %w: %v", ErrInvalidPath, err)
+		}
+		p.attr, p.filter = path[:open], f
+		p.sub = strings.TrimPrefix(path[end+1:], ".")
+		return p, nil
+	}
+
+	p.attr, p.sub, _ = strings.Cut(path, ".")
+	return p, nil
+}
+
+// Patch applies the operations to the resource in place. Attribute names are
+// matched case-insensitively, and adding to a multi-valued attribute appends.
+func Patch(res map[string]interface{}, ops []PatchOp) error {
+	for _, op := range ops {
+		var value interface{}
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return fmt.Errorf("Patch: invalid value: %w", err)
+			}
+		}
+
+		kind := strings.ToLower(op.Op)
+		if op.Path == "" {
+			if kind == "remove" {
+				return fmt.Errorf("Patch: remove requires a path: %w", ErrInvalidPath)
+			}
+			attrs, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("Patch: %s without a path requires an object value", kind)
+			}
+			for k, v := range attrs {
+				if err := apply(res, kind, &patchPath{attr: k}, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return fmt.Errorf("Patch: %w", err)
+		}
+		if err := apply(res, kind, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(res map[string]interface{}, kind string, p *patchPath, value interface{}) error {
+	key := keyOf(res, p.attr)
+
+	if p.filter != nil {
+		list, _ := res[key].([]interface{})
+		var kept []interface{}
+		matched := 0
+		for _, element := range list {
+			m, ok := element.(map[string]interface{})
+			if !ok || !p.filter.Match(m) {
+				kept = append(kept, element)
+				continue
+			}
+			matched++
+			switch {
+			case kind == "remove" && p.sub == "":
+				continue
+			case kind == "remove":
+				delete(m, keyOf(m, p.sub))
+			case p.sub != "":
+				m[keyOf(m, p.sub)] = value
+			default:
+				if v, ok := value.(map[string]interface{}); ok {
+					for k, sv := range v {
+						m[keyOf(m, k)] = sv
+					}
+				}
+			}
+			kept = append(kept, m)
+		}
+		if matched == 0 {
+			return fmt.Errorf("Patch: %q: %w", p.attr, ErrNoTarget)
+		}
+		res[key] = kept
+		return nil
+	}
+
+	if p.sub != "" {
+		parent, ok := res[key].(map[string]interface{})
+		if !ok {
+			if kind == "remove" {
+				return nil
+			}
+			parent = map[string]interface{}{}
+			res[key] = parent
+		}
+		return apply(parent, kind, &patchPath{attr: p.sub}, value)
+	}
+
+	switch kind {
+	case "remove":
+		delete(res, key)
+	case "add":
+		if existing, ok := res[key].([]interface{}); ok {
+			res[key] = append(existing, elements(value)...)
+		} else if existing, ok := res[key].(map[string]interface{}); ok {
+			if v, ok := value.(map[string]interface{}); ok {
+				for k, sv := range v {
+					existing[keyOf(existing, k)] = sv
+				}
+			} else {
+				res[key] = value
+			}
+		} else {
+			res[key] = value
+		}
+	case "replace":
+		res[key] = value
+	default:
+		return fmt.Errorf("Patch: unknown operation %q", kind)
+	}
+	return nil
+}
+
+// keyOf returns the existing key of m matching name case-insensitively, or name.
+func keyOf(m map[string]interface{}, name string) string {
+	if _, ok := m[name]; ok {
+		return name
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
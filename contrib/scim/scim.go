@@ -0,0 +1,448 @@
+// Package scim serves SCIM 2.0 (RFC 7643, RFC 7644) user and group provisioning
+// endpoints over a pluggable Store.
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "scim").Logger()
+
+const (
+	ResourceUser  = "User"
+	ResourceGroup = "Group"
+)
+
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+// Authenticator reports whether the request is from an authorized provisioning client,
+// typically by checking its bearer token.
+type Authenticator func(r *http.Request) bool
+
+// SCIM serves the provisioning endpoints.
+type SCIM struct {
+	Store         Store
+	Authenticator Authenticator
+	// MaxResults caps the number of resources returned by a list request.
+	MaxResults int
+	Prefix     string
+}
+
+// New initializes the endpoints under /scim/v2.
+func New(store Store, authenticator Authenticator) *SCIM {
+	return &SCIM{
+		Store:         store,
+		Authenticator: authenticator,
+		MaxResults:    200,
+		Prefix:        "/scim/v2",
+	}
+}
+
+// BearerToken returns an Authenticator accepting requests bearing the token.
+func BearerToken(token string) Authenticator {
+	return func(r *http.Request) bool {
+		auth := r.Header.Get("Authorization")
+		return len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") && subtle.ConstantTimeCompare([]byte(auth[7:]), []byte(token)) == 1
+	}
+}
+
+// Router returns the router serving the SCIM endpoints under the prefix:
+//
+//	GET, POST              {prefix}/Users
+//	GET, PUT, PATCH, DELETE {prefix}/Users/{id}
+//	GET, POST              {prefix}/Groups
+//	GET, PUT, PATCH, DELETE {prefix}/Groups/{id}
+//	GET                    {prefix}/ServiceProviderConfig
+//	GET                    {prefix}/ResourceTypes
+//	GET                    {prefix}/Schemas
+func (s *SCIM) Router(opts ...router.RouterWrapper) router.Router {
+	var routes []router.Route
+	for _, kind := range []string{ResourceUser, ResourceGroup} {
+		path := s.Prefix + "/" + kind + "s"
+		routes = append(routes,
+			router.NewGetRoute(path, true, false, s.authenticate(s.listHandler(kind))),
+			router.NewPostRoute(path, true, false, s.authenticate(s.createHandler(kind))),
+			router.NewGetRoute(path+"/{id}", true, false, s.authenticate(s.getHandler(kind))),
+			router.NewPutRoute(path+"/{id}", true, false, s.authenticate(s.replaceHandler(kind))),
+			router.NewRoute(http.MethodPatch, path+"/{id}", true, false, s.authenticate(s.patchHandler(kind))),
+			router.NewDeleteRoute(path+"/{id}", true, false, s.authenticate(s.deleteHandler(kind))),
+		)
+	}
+	routes = append(routes,
+		router.NewGetRoute(s.Prefix+"/ServiceProviderConfig", true, false, s.authenticate(s.configHandler)),
+		router.NewGetRoute(s.Prefix+"/ResourceTypes", true, false, s.authenticate(s.resourceTypesHandler)),
+		router.NewGetRoute(s.Prefix+"/Schemas", true, false, s.authenticate(s.schemasHandler)),
+	)
+	return router.NewRouter(routes, true, opts...)
+}
+
+func (s *SCIM) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Authenticator == nil || !s.Authenticator(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			Error(w, http.StatusUnauthorized, "", "authentication required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *SCIM) listHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		q := Query{
+			SortBy:     params.Get("sortBy"),
+			Descending: params.Get("sortOrder") == "descending",
+			StartIndex: 1,
+			Count:      s.MaxResults,
+		}
+		if f := params.Get("filter"); f != "" {
+			filter, err := ParseFilter(f)
+			if err != nil {
+				Error(w, http.StatusBadRequest, "invalidFilter", err.Error())
+				return
+			}
+			q.Filter = filter
+		}
+		if v := params.Get("startIndex"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				Error(w, http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+				return
+			}
+			if n > 1 {
+				q.StartIndex = n
+			}
+		}
+		if v := params.Get("count"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				Error(w, http.StatusBadRequest, "invalidValue", "count must be an integer")
+				return
+			}
+			if n < 0 {
+				n = 0
+			}
+			if n < q.Count {
+				q.Count = n
+			}
+		}
+
+		resources, total, err := s.Store.List(r.Context(), kind, q)
+		if err != nil {
+			s.storeError(w, r, "listHandler", err)
+			return
+		}
+		for _, res := range resources {
+			s.meta(r, kind, res)
+		}
+		write(w, http.StatusOK, map[string]interface{}{
+			"schemas":      []string{SchemaListResponse},
+			"totalResults": total,
+			"startIndex":   q.StartIndex,
+			"itemsPerPage": len(resources),
+			"Resources":    resources,
+		})
+	}
+}
+
+func (s *SCIM) createHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, ok := decodeResource(w, r, kind)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		res["meta"] = map[string]interface{}{"created": now, "lastModified": now}
+
+		res, err := s.Store.Create(r.Context(), kind, res)
+		if err != nil {
+			s.storeError(w, r, "createHandler", err)
+			return
+		}
+		s.meta(r, kind, res)
+		w.Header().Set("Location", s.location(r, kind, res))
+		write(w, http.StatusCreated, res)
+	}
+}
+
+func (s *SCIM) getHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := s.Store.Get(r.Context(), kind, helpers.URLParam(r, "id"))
+		if err != nil {
+			s.storeError(w, r, "getHandler", err)
+			return
+		}
+		s.meta(r, kind, res)
+		write(w, http.StatusOK, res)
+	}
+}
+
+func (s *SCIM) replaceHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, ok := decodeResource(w, r, kind)
+		if !ok {
+			return
+		}
+		s.replace(w, r, "replaceHandler", kind, res)
+	}
+}
+
+func (s *SCIM) patchHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Schemas    []string  `json:"schemas"`
+			Operations []PatchOp `json:"Operations"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			Error(w, http.StatusBadRequest, "invalidSyntax", "malformed request body")
+			return
+		}
+		if !contains(body.Schemas, SchemaPatchOp) {
+			Error(w, http.StatusBadRequest, "invalidSyntax", "request is not a PatchOp message")
+			return
+		}
+
+		res, err := s.Store.Get(r.Context(), kind, helpers.URLParam(r, "id"))
+		if err != nil {
+			s.storeError(w, r, "patchHandler", err)
+			return
+		}
+		if err := Patch(res, body.Operations); err != nil {
+			Error(w, http.StatusBadRequest, scimType(err), err.Error())
+			return
+		}
+		if msg := validate(kind, res); msg != "" {
+			Error(w, http.StatusBadRequest, "invalidValue", msg)
+			return
+		}
+		s.replace(w, r, "patchHandler", kind, res)
+	}
+}
+
+// replace stores the resource with the ID in the path, preserving its created time.
+func (s *SCIM) replace(w http.ResponseWriter, r *http.Request, function string, kind string, res Resource) {
+	id := helpers.URLParam(r, "id")
+	existing, err := s.Store.Get(r.Context(), kind, id)
+	if err != nil {
+		s.storeError(w, r, function, err)
+		return
+	}
+	meta, _ := existing["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta["lastModified"] = time.Now().UTC().Format(time.RFC3339)
+	res["meta"] = meta
+
+	res, err = s.Store.Replace(r.Context(), kind, id, res)
+	if err != nil {
+		s.storeError(w, r, function, err)
+		return
+	}
+	s.meta(r, kind, res)
+	write(w, http.StatusOK, res)
+}
+
+func (s *SCIM) deleteHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Store.Delete(r.Context(), kind, helpers.URLParam(r, "id")); err != nil {
+			s.storeError(w, r, "deleteHandler", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *SCIM) configHandler(w http.ResponseWriter, r *http.Request) {
+	write(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": s.MaxResults},
+		"changePassword": map[string]interface{}{"supported": false},
+		"sort":           map[string]interface{}{"supported": true},
+		"etag":           map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication scheme using the OAuth Bearer Token Standard",
+		}},
+	})
+}
+
+func (s *SCIM) resourceTypesHandler(w http.ResponseWriter, r *http.Request) {
+	types := []map[string]interface{}{
+		{"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"}, "id": ResourceUser, "name": ResourceUser, "endpoint": "/Users", "schema": SchemaUser},
+		{"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"}, "id": ResourceGroup, "name": ResourceGroup, "endpoint": "/Groups", "schema": SchemaGroup},
+	}
+	write(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{SchemaListResponse},
+		"totalResults": len(types),
+		"startIndex":   1,
+		"itemsPerPage": len(types),
+		"Resources":    types,
+	})
+}
+
+func (s *SCIM) schemasHandler(w http.ResponseWriter, r *http.Request) {
+	schemas := []map[string]interface{}{
+		{"id": SchemaUser, "name": ResourceUser, "attributes": userAttributes},
+		{"id": SchemaGroup, "name": ResourceGroup, "attributes": groupAttributes},
+	}
+	write(w, http.StatusOK, map[string]interface{}{
+		"schemas":      []string{SchemaListResponse},
+		"totalResults": len(schemas),
+		"startIndex":   1,
+		"itemsPerPage": len(schemas),
+		"Resources":    schemas,
+	})
+}
+
+// meta completes the meta attribute of a resource for the response.
+func (s *SCIM) meta(r *http.Request, kind string, res Resource) {
+	meta, _ := res["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = make(map[string]interface{})
+		res["meta"] = meta
+	}
+	meta["resourceType"] = kind
+	meta["location"] = s.location(r, kind, res)
+	if _, ok := res["schemas"]; !ok {
+		res["schemas"] = []string{schemaOf(kind)}
+	}
+}
+
+func (s *SCIM) location(r *http.Request, kind string, res Resource) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/%ss/%v", scheme, r.Host, s.Prefix, kind, res["id"])
+}
+
+func (s *SCIM) storeError(w http.ResponseWriter, r *http.Request, function string, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		Error(w, http.StatusNotFound, "", "resource not found")
+	case errors.Is(err, ErrConflict):
+		Error(w, http.StatusConflict, "uniqueness", err.Error())
+	default:
+		log.Error().Str("Function", function).Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed accessing store")
+		Error(w, http.StatusInternalServerError, "", "internal error")
+	}
+}
+
+// Error writes a SCIM error response. The scimType is omitted when empty.
+func Error(w http.ResponseWriter, status int, scimType string, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{SchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	write(w, status, body)
+}
+
+func write(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Str("Function", "write").Err(err).Msg("Failed writing response")
+	}
+}
+
+// decodeResource reads a resource of at most 1MiB, writing the error response when it is invalid.
+func decodeResource(w http.ResponseWriter, r *http.Request, kind string) (Resource, bool) {
+	var res Resource
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&res); err != nil || res == nil {
+		Error(w, http.StatusBadRequest, "invalidSyntax", "malformed request body")
+		return nil, false
+	}
+	delete(res, "id")
+	delete(res, "meta")
+	if msg := validate(kind, res); msg != "" {
+		Error(w, http.StatusBadRequest, "invalidValue", msg)
+		return nil, false
+	}
+	return res, true
+}
+
+// validate returns a message describing why the resource is invalid, or an empty string.
+func validate(kind string, res Resource) string {
+	required := "userName"
+	if kind == ResourceGroup {
+		required = "displayName"
+	}
+	if v, _ := lookup(res, required).(string); strings.TrimSpace(v) == "" {
+		return required + " is required"
+	}
+	return ""
+}
+
+func schemaOf(kind string) string {
+	if kind == ResourceGroup {
+		return SchemaGroup
+	}
+	return SchemaUser
+}
+
+// scimType classifies a patch error for the error response.
+func scimType(err error) string {
+	if errors.Is(err, ErrInvalidPath) {
+		return "invalidPath"
+	}
+	if errors.Is(err, ErrNoTarget) {
+		return "noTarget"
+	}
+	return "invalidValue"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var userAttributes = []map[string]interface{}{
+	{"name": "userName", "type": "string", "multiValued": false, "required": true, "caseExact": false, "uniqueness": "server"},
+	{"name": "name", "type": "complex", "multiValued": false, "required": false},
+	{"name": "displayName", "type": "string", "multiValued": false, "required": false},
+	{"name": "active", "type": "boolean", "multiValued": false, "required": false},
+	{"name": "emails", "type": "complex", "multiValued": true, "required": false},
+	{"name": "groups", "type": "complex", "multiValued": true, "required": false, "mutability": "readOnly"},
+	{"name": "externalId", "type": "string", "multiValued": false, "required": false, "caseExact": true},
+}
+
+var groupAttributes = []map[string]interface{}{
+	{"name": "displayName", "type": "string", "multiValued": false, "required": true, "uniqueness": "server"},
+	{"name": "members", "type": "complex", "multiValued": true, "required": false},
+	{"name": "externalId", "type": "string", "multiValued": false, "required": false, "caseExact": true},
+}
@@ -0,0 +1,88 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func resource(t *testing.T, s string) Resource {
+	var res Resource
+	if err := json.Unmarshal([]byte(s), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestFilter(t *testing.T) {
+	res := resource(t, `{
+		"userName": "Bjensen",
+		"active": true,
+		"name": {"familyName": "Jensen"},
+		"emails": [{"type": "work", "value": "bjensen@example.com"}, {"type": "home", "value": "babs@example.org"}],
+		"meta": {"lastModified": "2024-05-01T00:00:00Z"}
+	}`)
+
+	cases := map[string]bool{
+		`userName eq "bjensen"`: true,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "bj"`: true,
+		`name.familyName co "ens" and active eq true`:                 true,
+		`emails[type eq "work" and value ew "example.com"]`:           true,
+		`emails[type eq "work" and value ew "example.org"]`:           false,
+		`emails.value ew "example.org"`:                               true,
+		`not (userName eq "bjensen") or title pr`:                     false,
+		`meta.lastModified gt "2024-01-01T00:00:00Z"`:                 true,
+		`title pr`: false,
+	}
+	for expr, want := range cases {
+		f, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if got := f.Match(res); got != want {
+			t.Errorf("%s: expected %v, got %v", expr, want, got)
+		}
+	}
+
+	if _, err := ParseFilter(`userName eq`); err == nil {
+		t.Fatal("expected incomplete filter to be rejected")
+	}
+}
+
+func TestPatch(t *testing.T) {
+	res := resource(t, `{
+		"userName": "bjensen",
+		"emails": [{"type": "work", "value": "bjensen@example.com"}],
+		"members": [{"value": "1"}, {"value": "2"}]
+	}`)
+
+	ops := []PatchOp{
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"barbara@example.com"`)},
+		{Op: "add", Path: "emails", Value: json.RawMessage(`[{"type": "home", "value": "babs@example.org"}]`)},
+		{Op: "remove", Path: `members[value eq "2"]`},
+		{Op: "Replace", Value: json.RawMessage(`{"active": false, "name": {"givenName": "Barbara"}}`)},
+	}
+	if err := Patch(res, ops); err != nil {
+		t.Fatal(err)
+	}
+
+	want := resource(t, `{
+		"userName": "bjensen",
+		"active": false,
+		"name": {"givenName": "Barbara"},
+		"emails": [{"type": "work", "value": "barbara@example.com"}, {"type": "home", "value": "babs@example.org"}],
+		"members": [{"value": "1"}]
+	}`)
+	got, _ := json.Marshal(res)
+	expected, _ := json.Marshal(want)
+	if string(got) != string(expected) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+
+	if err := Patch(res, []PatchOp{{Op: "remove"}}); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("expected remove without a path to be rejected, got %v", err)
+	}
+	if err := Patch(res, []PatchOp{{Op: "remove", Path: `members[value eq "9"]`}}); !errors.Is(err, ErrNoTarget) {
+		t.Fatalf("expected unmatched filter to be rejected, got %v", err)
+	}
+}
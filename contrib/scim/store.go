@@ -0,0 +1,191 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by a Store when no resource has the ID.
+var ErrNotFound = errors.New("resource not found")
+
+// ErrConflict is returned by a Store when a resource would duplicate a unique attribute.
+var ErrConflict = errors.New("resource conflicts with an existing resource")
+
+// Resource is a SCIM resource in its JSON representation.
+type Resource = map[string]interface{}
+
+// Query selects a page of the resources of a type.
+type Query struct {
+	// Filter is nil when every resource is selected.
+	Filter     Filter
+	SortBy     string
+	Descending bool
+	// StartIndex is 1-based, as in SCIM.
+	StartIndex int
+	Count      int
+}
+
+// Store persists resources by type, such as User or Group.
+type Store interface {
+	// Create persists a new resource, assigning its id.
+	Create(ctx context.Context, kind string, res Resource) (Resource, error)
+	// Get returns the resource with the ID, or ErrNotFound.
+	Get(ctx context.Context, kind string, id string) (Resource, error)
+	// Replace replaces the resource with the ID, or returns ErrNotFound.
+	Replace(ctx context.Context, kind string, id string, res Resource) (Resource, error)
+	// Delete removes the resource with the ID, or returns ErrNotFound.
+	Delete(ctx context.Context, kind string, id string) error
+	// List returns the page of resources selected by the query, and the total number selected.
+	List(ctx context.Context, kind string, q Query) ([]Resource, int, error)
+}
+
+type memoryStore struct {
+	mu        sync.RWMutex
+	resources map[string]map[string]Resource
+	unique    map[string]string
+}
+
+// NewMemoryStore initializes a Store local to the process, enforcing the uniqueness
+// of userName for users and displayName for groups.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		resources: make(map[string]map[string]Resource),
+		unique:    map[string]string{ResourceUser: "userName", ResourceGroup: "displayName"},
+	}
+}
+
+// Create persists a new resource.
+func (m *memoryStore) Create(ctx context.Context, kind string, res Resource) (Resource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conflicts(kind, "", res) {
+		return nil, ErrConflict
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("Create: failed reading random bytes: %w", err)
+	}
+	res = clone(res)
+	res["id"] = hex.EncodeToString(b)
+	if m.resources[kind] == nil {
+		m.resources[kind] = make(map[string]Resource)
+	}
+	m.resources[kind][res["id"].(string)] = res
+	return clone(res), nil
+}
+
+// Get returns the resource with the ID.
+func (m *memoryStore) Get(ctx context.Context, kind string, id string) (Resource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res, ok := m.resources[kind][id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(res), nil
+}
+
+// Replace replaces the resource with the ID.
+func (m *memoryStore) Replace(ctx context.Context, kind string, id string, res Resource) (Resource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.resources[kind][id]; !ok {
+		return nil, ErrNotFound
+	}
+	if m.conflicts(kind, id, res) {
+		return nil, ErrConflict
+	}
+	res = clone(res)
+	res["id"] = id
+	m.resources[kind][id] = res
+	return clone(res), nil
+}
+
+// Delete removes the resource with the ID.
+func (m *memoryStore) Delete(ctx context.Context, kind string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.resources[kind][id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.resources[kind], id)
+	return nil
+}
+
+// List returns the page of resources selected by the query.
+func (m *memoryStore) List(ctx context.Context, kind string, q Query) ([]Resource, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var selected []Resource
+	for _, res := range m.resources[kind] {
+		if q.Filter == nil || q.Filter.Match(res) {
+			selected = append(selected, res)
+		}
+	}
+
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		a := fmt.Sprint(resolve(selected[i], splitPath(sortBy))[0])
+		b := fmt.Sprint(resolve(selected[j], splitPath(sortBy))[0])
+		if q.Descending {
+			return strings.ToLower(a) > strings.ToLower(b)
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+
+	total := len(selected)
+	start := q.StartIndex - 1
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if q.Count >= 0 && start+q.Count < end {
+		end = start + q.Count
+	}
+
+	page := make([]Resource, 0, end-start)
+	for _, res := range selected[start:end] {
+		page = append(page, clone(res))
+	}
+	return page, total, nil
+}
+
+func (m *memoryStore) conflicts(kind string, id string, res Resource) bool {
+	attr, ok := m.unique[kind]
+	if !ok {
+		return false
+	}
+	value, _ := lookup(res, attr).(string)
+	for existingID, existing := range m.resources[kind] {
+		if v, _ := lookup(existing, attr).(string); existingID != id && strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// clone deep copies a resource through its JSON representation.
+func clone(res Resource) Resource {
+	b, _ := json.Marshal(res)
+	var out Resource
+	_ = json.Unmarshal(b, &out)
+	return out
+}
@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Certificate is a certificate issued by a PKI secrets engine.
+type Certificate struct {
+	// CertificatePEM holds the certificate followed by its issuing chain.
+	CertificatePEM string
+	PrivateKeyPEM  string
+	Expiration     time.Time
+}
+
+// Issue issues a certificate for the common name from the role of the PKI engine
+// mounted at mount, such as pki.
+func (cl *Client) Issue(ctx context.Context, mount string, role string, commonName string, ttl time.Duration) (*Certificate, error) {
+	secret, err := cl.Write(ctx, fmt.Sprintf("%s/issue/%s", mount, role), map[string]interface{}{
+		"common_name": commonName,
+		"ttl":         ttl.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Issue: %w", err)
+	}
+
+	cert, _ := secret.Data["certificate"].(string)
+	key, _ := secret.Data["private_key"].(string)
+	if cert == "" || key == "" {
+		return nil, fmt.Errorf("Issue: response carries no certificate")
+	}
+	chain := []string{cert}
+	if ca, ok := secret.Data["ca_chain"].([]interface{}); ok {
+		for _, pem := range ca {
+			if s, ok := pem.(string); ok {
+				chain = append(chain, s)
+			}
+		}
+	} else if ca, ok := secret.Data["issuing_ca"].(string); ok {
+		chain = append(chain, ca)
+	}
+	expiration, _ := secret.Data["expiration"].(float64)
+
+	return &Certificate{
+		CertificatePEM: strings.Join(chain, "\n") + "\n",
+		PrivateKeyPEM:  key + "\n",
+		Expiration:     time.Unix(int64(expiration), 0),
+	}, nil
+}
+
+// ServeCertificate issues a certificate to certFile and keyFile, reissuing it when
+// two thirds of its lifetime has elapsed, until the context is done.
+func (cl *Client) ServeCertificate(ctx context.Context, mount string, role string, commonName string, ttl time.Duration, certFile string, keyFile string) error {
+	cert, err := cl.Issue(ctx, mount, role, commonName, ttl)
+	if err != nil {
+		return fmt.Errorf("ServeCertificate: %w", err)
+	}
+	if err := writeCertificate(cert, certFile, keyFile); err != nil {
+		return fmt.Errorf("ServeCertificate: %w", err)
+	}
+
+	go func() {
+		for {
+			wait := time.Until(cert.Expiration) * 2 / 3
+			if wait < time.Minute {
+				wait = time.Minute
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+
+			next, err := cl.Issue(ctx, mount, role, commonName, ttl)
+			if err == nil {
+				err = writeCertificate(next, certFile, keyFile)
+			}
+			if err != nil {
+				log.Error().Str("Function", "ServeCertificate").Str("CommonName", commonName).Err(err).Msg("Failed reissuing certificate")
+				continue
+			}
+			cert = next
+			log.Debug().Str("Function", "ServeCertificate").Str("CommonName", commonName).Time("Expiration", cert.Expiration).Msg("Reissued certificate")
+		}
+	}()
+	return nil
+}
+
+// writeCertificate replaces the key and certificate files atomically, key first,
+// so that the server never reads a certificate without its key.
+func writeCertificate(cert *Certificate, certFile string, keyFile string) error {
+	if err := writeAtomic(keyFile, []byte(cert.PrivateKeyPEM), 0600); err != nil {
+		return fmt.Errorf("writeCertificate: %w", err)
+	}
+	if err := writeAtomic(certFile, []byte(cert.CertificatePEM), 0644); err != nil {
+		return fmt.Errorf("writeCertificate: %w", err)
+	}
+	return nil
+}
+
+func writeAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("writeAtomic: failed creating file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeAtomic: failed writing file: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeAtomic: failed setting permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writeAtomic: failed closing file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("writeAtomic: failed replacing file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// Package vault reads secrets and issues TLS certificates from HashiCorp Vault,
+// renewing their leases, for use in the config and by the TLS server.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	c "github.com/Etwodev/ramchi/config"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "vault").Logger()
+
+// ErrNotFound is returned when no secret or field exists at the path.
+var ErrNotFound = errors.New("secret not found")
+
+// Secret is a secret read from Vault.
+type Secret struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// Field returns a field of the secret's data, looking inside the data of KV
+// version 2 secrets.
+func (s *Secret) Field(name string) (interface{}, bool) {
+	data := s.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, kv2 := data["metadata"]; kv2 {
+			data = inner
+		}
+	}
+	v, ok := data[name]
+	return v, ok
+}
+
+// Client calls the Vault HTTP API.
+type Client struct {
+	Address   string
+	Token     string
+	Namespace string
+	HTTP      *http.Client
+}
+
+// NewClient initializes a client of the Vault server at the address with the token,
+// defaulting to the VAULT_ADDR and VAULT_TOKEN environment variables when empty.
+func NewClient(address string, token string) *Client {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	return &Client{
+		Address:   strings.TrimSuffix(address, "/"),
+		Token:     token,
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		HTTP:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Read reads the secret at the path, such as secret/data/app.
+func (cl *Client) Read(ctx context.Context, path string) (*Secret, error) {
+	secret, err := cl.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+	return secret, nil
+}
+
+// Write writes the data to the path, returning the response secret, if any.
+func (cl *Client) Write(ctx context.Context, path string, data interface{}) (*Secret, error) {
+	secret, err := cl.do(ctx, http.MethodPost, "/v1/"+strings.TrimPrefix(path, "/"), data)
+	if err != nil {
+		return nil, fmt.Errorf("Write: %w", err)
+	}
+	return secret, nil
+}
+
+// Renew extends the lease of a secret by the increment, returning its new duration.
+func (cl *Client) Renew(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	secret, err := cl.do(ctx, http.MethodPut, "/v1/sys/leases/renew", map[string]interface{}{"lease_id": leaseID, "increment": int(increment.Seconds())})
+	if err != nil {
+		return 0, fmt.Errorf("Renew: %w", err)
+	}
+	return time.Duration(secret.LeaseDuration) * time.Second, nil
+}
+
+// RenewToken extends the lease of the client's token, returning its new duration.
+func (cl *Client) RenewToken(ctx context.Context) (time.Duration, error) {
+	secret, err := cl.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", map[string]interface{}{})
+	if err != nil {
+		return 0, fmt.Errorf("RenewToken: %w", err)
+	}
+	if secret.Auth == nil {
+		return 0, errors.New("RenewToken: response carries no auth")
+	}
+	return time.Duration(secret.Auth.LeaseDuration) * time.Second, nil
+}
+
+// KeepAlive renews the lease when two thirds of it has elapsed, until the context
+// is done or it can no longer be renewed. A leaseID of "" renews the client's token.
+func (cl *Client) KeepAlive(ctx context.Context, leaseID string, duration time.Duration) {
+	for duration > 0 {
+		select {
+		case <-time.After(duration * 2 / 3):
+		case <-ctx.Done():
+			return
+		}
+
+		var err error
+		if leaseID == "" {
+			duration, err = cl.RenewToken(ctx)
+		} else {
+			duration, err = cl.Renew(ctx, leaseID, duration)
+		}
+		if err != nil {
+			log.Error().Str("Function", "KeepAlive").Str("Lease", leaseID).Err(err).Msg("Failed renewing lease")
+			return
+		}
+	}
+}
+
+// Resolver returns a config resolver for references of the form path#field, such
+// as ${vault:secret/data/app#password}. Leases of the secrets read are renewed
+// until the context is done.
+func (cl *Client) Resolver(ctx context.Context) c.Resolver {
+	return func(ref string) (string, error) {
+		path, field, ok := strings.Cut(ref, "#")
+		if !ok {
+			return "", fmt.Errorf("Resolver: reference %q names no field", ref)
+		}
+		secret, err := cl.Read(ctx, path)
+		if err != nil {
+			return "", fmt.Errorf("Resolver: %w", err)
+		}
+		v, ok := secret.Field(field)
+		if !ok {
+			return "", fmt.Errorf("Resolver: %s: %w", ref, ErrNotFound)
+		}
+		if secret.Renewable && secret.LeaseID != "" {
+			go cl.KeepAlive(ctx, secret.LeaseID, time.Duration(secret.LeaseDuration)*time.Second)
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Register registers the client's resolver under the vault scheme. It must be
+// called before the config is loaded.
+func (cl *Client) Register(ctx context.Context) {
+	c.RegisterResolver("vault", cl.Resolver(ctx))
+}
+
+func (cl *Client) do(ctx context.Context, method string, path string, body interface{}) (*Secret, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("do: failed marshalling body: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cl.Address+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("do: failed creating request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", cl.Token)
+	req.Header.Set("X-Vault-Request", "true")
+	if cl.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", cl.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := cl.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: failed sending request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("do: %s: %w", path, ErrNotFound)
+	}
+	if res.StatusCode == http.StatusNoContent {
+		return &Secret{}, nil
+	}
+	if res.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&e)
+		return nil, fmt.Errorf("do: vault returned %s: %s", res.Status, strings.Join(e.Errors, "; "))
+	}
+
+	var secret Secret
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("do: failed decoding response: %w", err)
+	}
+	return &secret, nil
+}
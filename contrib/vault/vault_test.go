@@ -0,0 +1,120 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVault serves the parts of the Vault API used by the client, refusing
+// requests without the token.
+func fakeVault(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		var body map[string]interface{}
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&body)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/secret/data/app":
+			w.Write([]byte(`{"data": {"data": {"password": "hunter2", "port": 5432}, "metadata": {"version": 3}}}`))
+		case "PUT /v1/sys/leases/renew":
+			if body["lease_id"] != "database/creds/app/abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"lease_id": "database/creds/app/abc", "lease_duration": 3600, "renewable": true}`))
+		case "POST /v1/pki/issue/web":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"certificate": "-----CERT " + body["common_name"].(string) + "-----",
+				"private_key": "-----KEY-----",
+				"ca_chain":    []string{"-----INTERMEDIATE-----", "-----ROOT-----"},
+				"expiration":  time.Now().Add(time.Hour).Unix(),
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolver(t *testing.T) {
+	cl := NewClient(fakeVault(t).URL, "root")
+	resolve := cl.Resolver(context.Background())
+
+	if v, err := resolve("secret/data/app#password"); err != nil || v != "hunter2" {
+		t.Fatalf("got %q and %v, want the KV version 2 field", v, err)
+	}
+	if v, err := resolve("secret/data/app#port"); err != nil || v != "5432" {
+		t.Fatalf("got %q and %v, want the number formatted", v, err)
+	}
+	for _, ref := range []string{"secret/data/app#missing", "secret/data/other#password"} {
+		if _, err := resolve(ref); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: got %v, want ErrNotFound", ref, err)
+		}
+	}
+	if _, err := resolve("secret/data/app"); err == nil {
+		t.Fatal("resolved a reference naming no field")
+	}
+
+	cl.Token = "stolen"
+	if _, err := resolve("secret/data/app#password"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("got %v, want Vault's error", err)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	cl := NewClient(fakeVault(t).URL, "root")
+	d, err := cl.Renew(context.Background(), "database/creds/app/abc", time.Hour)
+	if err != nil || d != time.Hour {
+		t.Fatalf("got %v and %v, want the lease renewed for an hour", d, err)
+	}
+}
+
+func TestServeCertificate(t *testing.T) {
+	cl := NewClient(fakeVault(t).URL, "root")
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := cl.ServeCertificate(ctx, "pki", "web", "api.example.com", time.Hour, certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "-----CERT api.example.com-----\n-----INTERMEDIATE-----\n-----ROOT-----\n"; string(cert) != want {
+		t.Fatalf("got certificate %q, want %q", cert, want)
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("got key permissions %v, want 0600", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("got %d files, want the temporary files removed", len(entries))
+	}
+
+	if err := cl.ServeCertificate(ctx, "pki", "missing", "api.example.com", time.Hour, certFile, keyFile); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound for an unknown role", err)
+	}
+}
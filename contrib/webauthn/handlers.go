@@ -0,0 +1,339 @@
+package webauthn
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+	"github.com/Etwodev/ramchi/session"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "webauthn").Logger()
+
+const (
+	// SessionUser is the session key the signed in user ID is stored under, base64url encoded.
+	SessionUser      = "webauthn.user"
+	sessionChallenge = "webauthn.challenge"
+)
+
+// ErrUnknownUser is returned by Users when no user matches.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Users gives the ceremonies access to the application's accounts.
+type Users interface {
+	// Current returns the signed in user registering a credential.
+	Current(r *http.Request) (User, error)
+	// ByID returns the user with the ID, or ErrUnknownUser.
+	ByID(ctx context.Context, id []byte) (User, error)
+	// ByName returns the user with the name, or ErrUnknownUser.
+	ByName(ctx context.Context, name string) (User, error)
+}
+
+// Credentials persists the credentials registered to users.
+type Credentials interface {
+	Add(ctx context.Context, c Credential) error
+	// ByID returns the credential with the ID, or ErrUnknownCredential.
+	ByID(ctx context.Context, id []byte) (Credential, error)
+	ByUser(ctx context.Context, userID []byte) ([]Credential, error)
+	Update(ctx context.Context, c Credential) error
+}
+
+// ErrUnknownCredential is returned by Credentials when no credential has the ID.
+var ErrUnknownCredential = errors.New("unknown credential")
+
+// WebAuthn serves the registration and login ceremonies, keeping their
+// challenges in the session of the client performing them.
+type WebAuthn struct {
+	Config
+	Users       Users
+	Credentials Credentials
+	Sessions    *session.Manager
+	// OnLogin is called with the renewed session after a successful login, before it
+	// is saved. By default the user ID is stored under SessionUser.
+	OnLogin func(w http.ResponseWriter, r *http.Request, s *session.Session, u User) error
+	Prefix  string
+
+	// decoyKey derives the credentials offered for unknown users.
+	decoyKey []byte
+}
+
+// New initializes the ceremonies for the relying party.
+func New(config Config, users Users, credentials Credentials, sessions *session.Manager) *WebAuthn {
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Minute
+	}
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &WebAuthn{Config: config, Users: users, Credentials: credentials, Sessions: sessions, Prefix: "/webauthn", decoyKey: key}
+}
+
+// decoy returns the credential offered for a name with no credentials, derived from
+// it so that it is stable across requests, so that login options do not reveal
+// whether an account exists.
+func (wa *WebAuthn) decoy(name string) Credential {
+	mac := hmac.New(sha256.New, wa.decoyKey)
+	mac.Write([]byte(name))
+	return Credential{ID: mac.Sum(nil)}
+}
+
+// Router returns the router serving the ceremonies under the prefix:
+//
+//	POST {prefix}/register/begin   returns CreationOptions for the signed in user
+//	POST {prefix}/register/finish  accepts a RegistrationResponse
+//	POST {prefix}/login/begin      accepts an optional {"name": "..."}, returns RequestOptions
+//	POST {prefix}/login/finish     accepts an AssertionResponse and signs the user in
+func (wa *WebAuthn) Router(opts ...router.RouterWrapper) router.Router {
+	return router.NewRouter([]router.Route{
+		router.NewPostRoute(wa.Prefix+"/register/begin", true, false, wa.beginRegistration),
+		router.NewPostRoute(wa.Prefix+"/register/finish", true, false, wa.finishRegistration),
+		router.NewPostRoute(wa.Prefix+"/login/begin", true, false, wa.beginLogin),
+		router.NewPostRoute(wa.Prefix+"/login/finish", true, false, wa.finishLogin),
+	}, true, opts...)
+}
+
+// SignedIn returns the ID of the user signed in with a passkey, if any.
+func (wa *WebAuthn) SignedIn(r *http.Request) ([]byte, bool) {
+	s, err := wa.Sessions.Load(r)
+	if err != nil {
+		return nil, false
+	}
+	v, ok := s.Get(SessionUser)
+	if !ok {
+		return nil, false
+	}
+	id, err := base64.RawURLEncoding.DecodeString(v)
+	return id, err == nil
+}
+
+func (wa *WebAuthn) beginRegistration(w http.ResponseWriter, r *http.Request) {
+	u, err := wa.Users.Current(r)
+	if err != nil {
+		helpers.Error(w, r, http.StatusUnauthorized)
+		return
+	}
+	existing, err := wa.Credentials.ByUser(r.Context(), u.ID)
+	if err != nil {
+		wa.fail(w, r, "beginRegistration", err)
+		return
+	}
+
+	opts, err := wa.Config.BeginRegistration(u, existing)
+	if err != nil {
+		wa.fail(w, r, "beginRegistration", err)
+		return
+	}
+	if err := wa.storeChallenge(w, r, "register", opts.Challenge); err != nil {
+		wa.fail(w, r, "beginRegistration", err)
+		return
+	}
+	helpers.JSON(w, r, http.StatusOK, opts)
+}
+
+func (wa *WebAuthn) finishRegistration(w http.ResponseWriter, r *http.Request) {
+	u, err := wa.Users.Current(r)
+	if err != nil {
+		helpers.Error(w, r, http.StatusUnauthorized)
+		return
+	}
+
+	var res RegistrationResponse
+	if !decode(w, r, &res) {
+		return
+	}
+	challenge, ok := wa.takeChallenge(w, r, "register")
+	if !ok {
+		return
+	}
+
+	cred, err := wa.Config.FinishRegistration(u, challenge, &res)
+	if err != nil {
+		log.Warn().Str("Function", "finishRegistration").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Registration refused")
+		helpers.JSONError(w, r, http.StatusBadRequest, "registration could not be verified")
+		return
+	}
+	// A credential ID registered to anyone already is refused, as its registration
+	// would take over the credential.
+	if _, err := wa.Credentials.ByID(r.Context(), cred.ID); err == nil {
+		log.Warn().Str("Function", "finishRegistration").Str("RequestID", helpers.RequestID(r)).Msg("Registration of a known credential refused")
+		helpers.JSONError(w, r, http.StatusBadRequest, "registration could not be verified")
+		return
+	} else if !errors.Is(err, ErrUnknownCredential) {
+		wa.fail(w, r, "finishRegistration", err)
+		return
+	}
+	if err := wa.Credentials.Add(r.Context(), *cred); err != nil {
+		wa.fail(w, r, "finishRegistration", err)
+		return
+	}
+	helpers.JSON(w, r, http.StatusCreated, map[string]Binary{"id": cred.ID})
+}
+
+func (wa *WebAuthn) beginLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 && !decode(w, r, &body) {
+		return
+	}
+
+	var allowed []Credential
+	if body.Name != "" {
+		u, err := wa.Users.ByName(r.Context(), body.Name)
+		if err != nil && !errors.Is(err, ErrUnknownUser) {
+			wa.fail(w, r, "beginLogin", err)
+			return
+		}
+		if err == nil {
+			if allowed, err = wa.Credentials.ByUser(r.Context(), u.ID); err != nil {
+				wa.fail(w, r, "beginLogin", err)
+				return
+			}
+		}
+		if len(allowed) == 0 {
+			allowed = []Credential{wa.decoy(body.Name)}
+		}
+	}
+
+	opts, err := wa.Config.BeginLogin(allowed)
+	if err != nil {
+		wa.fail(w, r, "beginLogin", err)
+		return
+	}
+	if err := wa.storeChallenge(w, r, "login", opts.Challenge); err != nil {
+		wa.fail(w, r, "beginLogin", err)
+		return
+	}
+	helpers.JSON(w, r, http.StatusOK, opts)
+}
+
+func (wa *WebAuthn) finishLogin(w http.ResponseWriter, r *http.Request) {
+	var res AssertionResponse
+	if !decode(w, r, &res) {
+		return
+	}
+	challenge, ok := wa.takeChallenge(w, r, "login")
+	if !ok {
+		return
+	}
+
+	refuse := func(err error) {
+		log.Warn().Str("Function", "finishLogin").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Login refused")
+		helpers.JSONError(w, r, http.StatusUnauthorized, "login could not be verified")
+	}
+
+	cred, err := wa.Credentials.ByID(r.Context(), res.RawID)
+	if err != nil {
+		if errors.Is(err, ErrUnknownCredential) {
+			refuse(err)
+		} else {
+			wa.fail(w, r, "finishLogin", err)
+		}
+		return
+	}
+	count, err := wa.Config.FinishLogin(cred, challenge, &res)
+	if err != nil {
+		refuse(err)
+		return
+	}
+	u, err := wa.Users.ByID(r.Context(), cred.UserID)
+	if err != nil {
+		refuse(err)
+		return
+	}
+
+	cred.SignCount = count
+	if err := wa.Credentials.Update(r.Context(), cred); err != nil {
+		wa.fail(w, r, "finishLogin", err)
+		return
+	}
+
+	s, err := wa.Sessions.Load(r)
+	if err != nil {
+		wa.fail(w, r, "finishLogin", err)
+		return
+	}
+	if wa.OnLogin != nil {
+		err = wa.OnLogin(w, r, s, u)
+	} else {
+		s.Set(SessionUser, base64.RawURLEncoding.EncodeToString(u.ID))
+	}
+	if err == nil {
+		err = wa.Sessions.Renew(w, r, s)
+	}
+	if err != nil {
+		wa.fail(w, r, "finishLogin", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// storeChallenge keeps the challenge of a ceremony in the client's session until it expires.
+func (wa *WebAuthn) storeChallenge(w http.ResponseWriter, r *http.Request, ceremony string, challenge []byte) error {
+	s, err := wa.Sessions.Load(r)
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(wa.Timeout).Unix()
+	s.Set(sessionChallenge, ceremony+"|"+base64.RawURLEncoding.EncodeToString(challenge)+"|"+strconv.FormatInt(expires, 10))
+	return wa.Sessions.Save(w, r, s)
+}
+
+// takeChallenge removes the challenge of a ceremony from the client's session,
+// writing the error response when there is no unexpired challenge.
+func (wa *WebAuthn) takeChallenge(w http.ResponseWriter, r *http.Request, ceremony string) ([]byte, bool) {
+	s, err := wa.Sessions.Load(r)
+	if err != nil {
+		wa.fail(w, r, "takeChallenge", err)
+		return nil, false
+	}
+	v, _ := s.Get(sessionChallenge)
+	s.Delete(sessionChallenge)
+	if err := wa.Sessions.Save(w, r, s); err != nil {
+		wa.fail(w, r, "takeChallenge", err)
+		return nil, false
+	}
+
+	parts := strings.Split(v, "|")
+	if len(parts) != 3 || parts[0] != ceremony {
+		helpers.JSONError(w, r, http.StatusBadRequest, "no "+ceremony+" ceremony in progress")
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		helpers.JSONError(w, r, http.StatusBadRequest, ceremony+" ceremony expired")
+		return nil, false
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		helpers.JSONError(w, r, http.StatusBadRequest, "no "+ceremony+" ceremony in progress")
+		return nil, false
+	}
+	return challenge, true
+}
+
+func (wa *WebAuthn) fail(w http.ResponseWriter, r *http.Request, function string, err error) {
+	log.Error().Str("Function", function).Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Ceremony failed")
+	helpers.Error(w, r, http.StatusInternalServerError)
+}
+
+// decode reads a JSON body of at most 64KiB, writing the error response when it is malformed.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(v); err != nil {
+		helpers.JSONError(w, r, http.StatusBadRequest, "malformed request body")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/session"
+)
+
+type testUsers struct {
+	current User
+	byName  map[string]User
+}
+
+func (u *testUsers) Current(r *http.Request) (User, error) {
+	return u.current, nil
+}
+
+func (u *testUsers) ByID(ctx context.Context, id []byte) (User, error) {
+	for _, user := range u.byName {
+		if bytes.Equal(user.ID, id) {
+			return user, nil
+		}
+	}
+	return User{}, ErrUnknownUser
+}
+
+func (u *testUsers) ByName(ctx context.Context, name string) (User, error) {
+	if user, ok := u.byName[name]; ok {
+		return user, nil
+	}
+	return User{}, ErrUnknownUser
+}
+
+type testCredentials []Credential
+
+func (c *testCredentials) Add(ctx context.Context, cred Credential) error {
+	*c = append(*c, cred)
+	return nil
+}
+
+func (c *testCredentials) ByID(ctx context.Context, id []byte) (Credential, error) {
+	for _, cred := range *c {
+		if bytes.Equal(cred.ID, id) {
+			return cred, nil
+		}
+	}
+	return Credential{}, ErrUnknownCredential
+}
+
+func (c *testCredentials) ByUser(ctx context.Context, userID []byte) ([]Credential, error) {
+	var creds []Credential
+	for _, cred := range *c {
+		if bytes.Equal(cred.UserID, userID) {
+			creds = append(creds, cred)
+		}
+	}
+	return creds, nil
+}
+
+func (c *testCredentials) Update(ctx context.Context, cred Credential) error {
+	return nil
+}
+
+// ceremony calls a handler with the body and the session cookies of the client,
+// keeping the cookies it sets.
+func ceremony(t *testing.T, h http.HandlerFunc, cookies *[]*http.Cookie, body interface{}) *httptest.ResponseRecorder {
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	for _, c := range *cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if set := rec.Result().Cookies(); len(set) > 0 {
+		*cookies = set
+	}
+	return rec
+}
+
+func TestRegistrationRefusesKnownCredential(t *testing.T) {
+	alice, bob := User{ID: []byte("user-1"), Name: "alice"}, User{ID: []byte("user-2"), Name: "bob"}
+	users := &testUsers{byName: map[string]User{"alice": alice, "bob": bob}}
+	creds := &testCredentials{}
+	wa := New(Config{RPID: "example.com", RPName: "Example", Origins: []string{"https://example.com"}}, users, creds, session.NewManager(session.NewMemoryStore()))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &authenticator{key: key, id: []byte("credential-1")}
+
+	register := func(u User) int {
+		users.current = u
+		var cookies []*http.Cookie
+		var opts CreationOptions
+		if err := json.Unmarshal(ceremony(t, wa.beginRegistration, &cookies, nil).Body.Bytes(), &opts); err != nil {
+			t.Fatal(err)
+		}
+		att := append(cborHead(5, 3), cborText("fmt")...)
+		att = append(att, cborText("none")...)
+		att = append(att, cborText("attStmt")...)
+		att = append(att, cborHead(5, 0)...)
+		att = append(att, cborText("authData")...)
+		att = append(att, cborBytes(a.authData("example.com", true))...)
+
+		var reg RegistrationResponse
+		reg.RawID = a.id
+		reg.Response.ClientDataJSON = clientData(t, "webauthn.create", opts.Challenge, "https://example.com")
+		reg.Response.AttestationObject = att
+		return ceremony(t, wa.finishRegistration, &cookies, reg).Code
+	}
+
+	if code := register(alice); code != http.StatusCreated {
+		t.Fatalf("got %d registering a new credential, want 201", code)
+	}
+	if code := register(bob); code != http.StatusBadRequest {
+		t.Fatalf("got %d registering a known credential, want 400", code)
+	}
+	if len(*creds) != 1 || !bytes.Equal((*creds)[0].UserID, alice.ID) {
+		t.Fatalf("got credentials %+v, want alice's alone", *creds)
+	}
+}
+
+func TestLoginOptionsHideUnknownUsers(t *testing.T) {
+	users := &testUsers{byName: map[string]User{"alice": {ID: []byte("user-1"), Name: "alice"}}}
+	creds := &testCredentials{{ID: []byte("credential-1"), UserID: []byte("user-1")}}
+	wa := New(Config{RPID: "example.com"}, users, creds, session.NewManager(session.NewMemoryStore()))
+
+	options := func(name string) RequestOptions {
+		var cookies []*http.Cookie
+		rec := ceremony(t, wa.beginLogin, &cookies, map[string]string{"name": name})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got %d, want 200", name, rec.Code)
+		}
+		var opts RequestOptions
+		if err := json.Unmarshal(rec.Body.Bytes(), &opts); err != nil {
+			t.Fatal(err)
+		}
+		return opts
+	}
+
+	known, unknown, again := options("alice"), options("mallory"), options("mallory")
+	if len(known.AllowCredentials) != 1 || len(unknown.AllowCredentials) != 1 {
+		t.Fatalf("got %d and %d credentials, want one each", len(known.AllowCredentials), len(unknown.AllowCredentials))
+	}
+	if !bytes.Equal(unknown.AllowCredentials[0].ID, again.AllowCredentials[0].ID) {
+		t.Fatal("got different credentials for the same unknown user")
+	}
+	if bytes.Equal(unknown.AllowCredentials[0].ID, options("eve").AllowCredentials[0].ID) {
+		t.Fatal("got the same credential for different unknown users")
+	}
+	if strings.Contains(string(unknown.AllowCredentials[0].ID), "credential") {
+		t.Fatal("offered a real credential to an unknown user")
+	}
+}
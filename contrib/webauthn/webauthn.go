@@ -0,0 +1,354 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// User verification requirements.
+const (
+	VerificationRequired    = "required"
+	VerificationPreferred   = "preferred"
+	VerificationDiscouraged = "discouraged"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// ErrVerification is returned when a ceremony response fails verification.
+var ErrVerification = errors.New("webauthn verification failed")
+
+// Binary is a byte slice encoded as unpadded base64url in JSON, as used by the WebAuthn JSON encodings.
+type Binary []byte
+
+// MarshalJSON implements the json.Marshaler interface.
+func (b Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting padded and unpadded encodings.
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// User is an account credentials are registered to.
+type User struct {
+	// ID is an opaque identifier of at most 64 bytes, which must not contain personal data.
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is a public key credential registered to a user.
+type Credential struct {
+	ID         []byte
+	UserID     []byte
+	PublicKey  []byte
+	SignCount  uint32
+	Transports []string
+	CreatedAt  time.Time
+}
+
+// Config identifies the relying party.
+type Config struct {
+	// RPID is the domain credentials are scoped to, such as example.com.
+	RPID   string
+	RPName string
+	// Origins lists the origins ceremonies may be performed from, such as https://example.com.
+	Origins          []string
+	Timeout          time.Duration
+	UserVerification string
+}
+
+type credentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Binary   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CreationOptions are passed to navigator.credentials.create, after
+// PublicKeyCredential.parseCreationOptionsFromJSON.
+type CreationOptions struct {
+	RP struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          Binary `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	Challenge        Binary `json:"challenge"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get, after
+// PublicKeyCredential.parseRequestOptionsFromJSON.
+type RequestOptions struct {
+	Challenge        Binary                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the JSON encoding of the credential returned by navigator.credentials.create.
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    Binary `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Binary   `json:"clientDataJSON"`
+		AttestationObject Binary   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON encoding of the credential returned by navigator.credentials.get.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    Binary `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Binary `json:"clientDataJSON"`
+		AuthenticatorData Binary `json:"authenticatorData"`
+		Signature         Binary `json:"signature"`
+		UserHandle        Binary `json:"userHandle"`
+	} `json:"response"`
+}
+
+// BeginRegistration returns the options for registering a credential to the user,
+// excluding the credentials already registered to them.
+func (c *Config) BeginRegistration(u User, existing []Credential) (*CreationOptions, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("BeginRegistration: %w", err)
+	}
+
+	opts := &CreationOptions{Challenge: challenge, Timeout: c.Timeout.Milliseconds(), Attestation: "none"}
+	opts.RP.ID, opts.RP.Name = c.RPID, c.RPName
+	opts.User.ID, opts.User.Name, opts.User.DisplayName = u.ID, u.Name, u.DisplayName
+	for _, alg := range []int{AlgES256, AlgEdDSA, AlgRS256} {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{"public-key", alg})
+	}
+	opts.ExcludeCredentials = descriptors(existing)
+	opts.AuthenticatorSelection.ResidentKey = "preferred"
+	opts.AuthenticatorSelection.UserVerification = c.userVerification()
+	return opts, nil
+}
+
+// FinishRegistration verifies the response to the registration options issued with
+// the challenge, returning the credential to persist. Attestation statements are not
+// verified: credentials are trusted on first use, as is usual for passkeys.
+func (c *Config) FinishRegistration(u User, challenge []byte, res *RegistrationResponse) (*Credential, error) {
+	if err := c.verifyClientData(res.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, fmt.Errorf("FinishRegistration: %w", err)
+	}
+
+	v, _, err := decodeCBOR(res.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("FinishRegistration: %w: %v", ErrVerification, err)
+	}
+	att, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("FinishRegistration: %w: malformed attestation object", ErrVerification)
+	}
+	authData, _ := att["authData"].([]byte)
+
+	ad, err := c.parseAuthData(authData)
+	if err != nil {
+		return nil, fmt.Errorf("FinishRegistration: %w", err)
+	}
+	if ad.credentialID == nil {
+		return nil, fmt.Errorf("FinishRegistration: %w: no attested credential", ErrVerification)
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, fmt.Errorf("FinishRegistration: %w: %v", ErrVerification, err)
+	}
+
+	return &Credential{
+		ID:         ad.credentialID,
+		UserID:     u.ID,
+		PublicKey:  ad.publicKey,
+		SignCount:  ad.signCount,
+		Transports: res.Response.Transports,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// BeginLogin returns the options for asserting one of the credentials. When no
+// credentials are given, the authenticator offers its discoverable credentials.
+func (c *Config) BeginLogin(allowed []Credential) (*RequestOptions, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("BeginLogin: %w", err)
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout.Milliseconds(),
+		RPID:             c.RPID,
+		AllowCredentials: descriptors(allowed),
+		UserVerification: c.userVerification(),
+	}, nil
+}
+
+// FinishLogin verifies the response to the request options issued with the challenge
+// against the stored credential, returning the sign count to persist.
+func (c *Config) FinishLogin(cred Credential, challenge []byte, res *AssertionResponse) (uint32, error) {
+	if !bytes.Equal(res.RawID, cred.ID) {
+		return 0, fmt.Errorf("FinishLogin: %w: credential mismatch", ErrVerification)
+	}
+	if len(res.Response.UserHandle) > 0 && !bytes.Equal(res.Response.UserHandle, cred.UserID) {
+		return 0, fmt.Errorf("FinishLogin: %w: user handle mismatch", ErrVerification)
+	}
+	if err := c.verifyClientData(res.Response.ClientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, fmt.Errorf("FinishLogin: %w", err)
+	}
+
+	ad, err := c.parseAuthData(res.Response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("FinishLogin: %w", err)
+	}
+
+	key, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("FinishLogin: %w", err)
+	}
+	clientHash := sha256.Sum256(res.Response.ClientDataJSON)
+	signed := append(append([]byte{}, res.Response.AuthenticatorData...), clientHash[:]...)
+	if err := key.verify(signed, res.Response.Signature); err != nil {
+		return 0, fmt.Errorf("FinishLogin: %w: %v", ErrVerification, err)
+	}
+
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, fmt.Errorf("FinishLogin: %w: sign count did not increase, the authenticator may be cloned", ErrVerification)
+	}
+	return ad.signCount, nil
+}
+
+func (c *Config) userVerification() string {
+	if c.UserVerification == "" {
+		return VerificationPreferred
+	}
+	return c.UserVerification
+}
+
+// verifyClientData checks the type, challenge and origin of the client data.
+func (c *Config) verifyClientData(raw []byte, typ string, challenge []byte) error {
+	var cd struct {
+		Type      string `json:"type"`
+		Challenge Binary `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: malformed client data", ErrVerification)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("%w: unexpected client data type %q", ErrVerification, cd.Type)
+	}
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(cd.Challenge, challenge) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerification)
+	}
+	for _, o := range c.Origins {
+		if cd.Origin == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unexpected origin %q", ErrVerification, cd.Origin)
+}
+
+type authData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthData decodes authenticator data, checking its relying party and flags.
+func (c *Config) parseAuthData(b []byte) (*authData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrVerification)
+	}
+	rpHash := sha256.Sum256([]byte(c.RPID))
+	if subtle.ConstantTimeCompare(b[:32], rpHash[:]) != 1 {
+		return nil, fmt.Errorf("%w: relying party mismatch", ErrVerification)
+	}
+
+	ad := &authData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	if ad.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user not present", ErrVerification)
+	}
+	if c.UserVerification == VerificationRequired && ad.flags&flagUserVerified == 0 {
+		return nil, fmt.Errorf("%w: user not verified", ErrVerification)
+	}
+
+	if ad.flags&flagAttested != 0 {
+		rest := b[37:]
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data too short", ErrVerification)
+		}
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < n {
+			return nil, fmt.Errorf("%w: credential id truncated", ErrVerification)
+		}
+		ad.credentialID, rest = append([]byte{}, rest[:n]...), rest[n:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrVerification, err)
+		}
+		ad.publicKey = append([]byte{}, rest[:len(rest)-len(after)]...)
+	}
+	return ad, nil
+}
+
+func descriptors(creds []Credential) []credentialDescriptor {
+	out := make([]credentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		out = append(out, credentialDescriptor{Type: "public-key", ID: cred.ID, Transports: cred.Transports})
+	}
+	return out
+}
+
+func newChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("newChallenge: failed reading random bytes: %w", err)
+	}
+	return b, nil
+}
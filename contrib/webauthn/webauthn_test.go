@@ -0,0 +1,149 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// cborHead encodes the initial bytes of a CBOR item.
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+	return cborHead(0, n)
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, len(s)), s...)
+}
+
+type authenticator struct {
+	key   *ecdsa.PrivateKey
+	id    []byte
+	count uint32
+}
+
+func (a *authenticator) authData(rpID string, attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(rpID))
+	b := append([]byte{}, rpHash[:]...)
+	flags := byte(flagUserPresent | flagUserVerified)
+	if attested {
+		flags |= flagAttested
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, a.count)
+	if attested {
+		b = append(b, make([]byte, 16)...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+		b = append(b, a.id...)
+		cose := append(cborHead(5, 5), cborInt(1)...)
+		cose = append(cose, cborInt(2)...)
+		cose = append(cose, cborInt(3)...)
+		cose = append(cose, cborInt(AlgES256)...)
+		cose = append(cose, cborInt(-1)...)
+		cose = append(cose, cborInt(1)...)
+		cose = append(cose, cborInt(-2)...)
+		cose = append(cose, cborBytes(a.key.X.FillBytes(make([]byte, 32)))...)
+		cose = append(cose, cborInt(-3)...)
+		cose = append(cose, cborBytes(a.key.Y.FillBytes(make([]byte, 32)))...)
+		b = append(b, cose...)
+	}
+	return b
+}
+
+func clientData(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	b, err := json.Marshal(map[string]interface{}{"type": typ, "challenge": Binary(challenge), "origin": origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCeremonies(t *testing.T) {
+	c := &Config{RPID: "example.com", RPName: "Example", Origins: []string{"https://example.com"}}
+	u := User{ID: []byte("user-1"), Name: "alice"}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &authenticator{key: key, id: []byte("credential-1")}
+
+	creation, err := c.BeginRegistration(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	att := append(cborHead(5, 3), cborText("fmt")...)
+	att = append(att, cborText("none")...)
+	att = append(att, cborText("attStmt")...)
+	att = append(att, cborHead(5, 0)...)
+	att = append(att, cborText("authData")...)
+	att = append(att, cborBytes(a.authData(c.RPID, true))...)
+
+	var reg RegistrationResponse
+	reg.RawID = a.id
+	reg.Response.ClientDataJSON = clientData(t, "webauthn.create", creation.Challenge, "https://example.com")
+	reg.Response.AttestationObject = att
+
+	cred, err := c.FinishRegistration(u, creation.Challenge, &reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := c.BeginLogin([]Credential{*cred})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert := func(challenge []byte, origin string) *AssertionResponse {
+		a.count++
+		var res AssertionResponse
+		res.RawID = a.id
+		res.Response.ClientDataJSON = clientData(t, "webauthn.get", challenge, origin)
+		res.Response.AuthenticatorData = a.authData(c.RPID, false)
+		hash := sha256.Sum256(res.Response.ClientDataJSON)
+		digest := sha256.Sum256(append(append([]byte{}, res.Response.AuthenticatorData...), hash[:]...))
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Response.Signature = sig
+		return &res
+	}
+
+	count, err := c.FinishLogin(*cred, request.Challenge, assert(request.Challenge, "https://example.com"))
+	if err != nil || count != 1 {
+		t.Fatalf("expected login to succeed, got %d %v", count, err)
+	}
+
+	if _, err := c.FinishLogin(*cred, request.Challenge, assert(request.Challenge, "https://evil.example")); !errors.Is(err, ErrVerification) {
+		t.Fatalf("expected foreign origin to be refused, got %v", err)
+	}
+
+	cred.SignCount = 10
+	if _, err := c.FinishLogin(*cred, request.Challenge, assert(request.Challenge, "https://example.com")); !errors.Is(err, ErrVerification) {
+		t.Fatalf("expected stale sign count to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/cache"
+)
+
+// CacheAside caches the values of a source in a backend for Cached, sharing the
+// fetch of a missing key between concurrent callers. Its settings may be changed
+// before it is first used.
+type CacheAside struct {
+	backend cache.Backend
+
+	// NegativeTTL is how long the absence of a value, reported by a fetch function
+	// returning cache.ErrNotFound, is cached.
+	NegativeTTL time.Duration
+	// Jitter is the fraction by which TTLs are randomly shortened, so that values
+	// cached together do not expire together.
+	Jitter float64
+	// FetchTimeout bounds each fetch. Fetches run detached from the context of the
+	// caller starting them, so that it cancelling does not fail the callers
+	// waiting on the same fetch.
+	FetchTimeout time.Duration
+
+	flightsMu sync.Mutex
+	flights   map[string]*flight
+}
+
+// NewCacheAside initializes a CacheAside storing values in the backend, caching
+// absences for 30 seconds, shortening TTLs by up to a tenth and bounding fetches
+// to 10 seconds.
+func NewCacheAside(backend cache.Backend) *CacheAside {
+	return &CacheAside{
+		backend:      backend,
+		NegativeTTL:  30 * time.Second,
+		Jitter:       0.1,
+		FetchTimeout: 10 * time.Second,
+		flights:      make(map[string]*flight),
+	}
+}
+
+// flight is a fetch in progress, shared by concurrent callers for its key.
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// negative marks a cached absence. Values are JSON, so never begin with it.
+const negative = "\x00"
+
+// Cached returns the value of the key from the cache, calling fetch and caching its
+// result for the ttl when absent. Concurrent misses of a key share one fetch.
+func Cached[T any](ctx context.Context, c *CacheAside, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	b, ok, err := c.backend.Get(ctx, key)
+	if err != nil {
+		b, ok = nil, false
+	}
+	if !ok {
+		b, err = c.fetchOnce(ctx, key, ttl, func(ctx context.Context) ([]byte, error) {
+			v, err := fetch(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(v)
+		})
+		if err != nil {
+			return value, fmt.Errorf("Cached: %w", err)
+		}
+	}
+
+	if string(b) == negative {
+		return value, fmt.Errorf("Cached: %w", cache.ErrNotFound)
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return value, fmt.Errorf("Cached: failed unmarshalling value: %w", err)
+	}
+	return value, nil
+}
+
+// fetchOnce fetches and caches the key, joining a fetch of it already in progress.
+// Every caller, the one starting the fetch included, stops waiting when its own
+// context is done.
+func (c *CacheAside) fetchOnce(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	c.flightsMu.Lock()
+	f, ok := c.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		c.flights[key] = f
+		go c.run(context.WithoutCancel(ctx), key, ttl, f, fetch)
+	}
+	c.flightsMu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run runs the fetch of the flight, caching its result.
+func (c *CacheAside) run(ctx context.Context, key string, ttl time.Duration, f *flight, fetch func(ctx context.Context) ([]byte, error)) {
+	if c.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.FetchTimeout)
+		defer cancel()
+	}
+	defer func() {
+		// Should fetch panic, waiters are released with an error, as the panic
+		// cannot reach a handler to recover it from this goroutine.
+		if p := recover(); p != nil {
+			f.value, f.err = nil, fmt.Errorf("fetchOnce: fetch panicked: %v", p)
+		}
+		c.flightsMu.Lock()
+		delete(c.flights, key)
+		c.flightsMu.Unlock()
+		close(f.done)
+	}()
+
+	f.value, f.err = fetch(ctx)
+	switch {
+	case errors.Is(f.err, cache.ErrNotFound):
+		f.value, f.err = []byte(negative), nil
+		_ = c.backend.Set(ctx, key, f.value, c.jitter(c.NegativeTTL))
+	case f.err == nil:
+		_ = c.backend.Set(ctx, key, f.value, c.jitter(ttl))
+	}
+}
+
+func (c *CacheAside) jitter(ttl time.Duration) time.Duration {
+	if c.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(rand.Float64()*c.Jitter*float64(ttl))
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/cache"
+)
+
+func TestCached(t *testing.T) {
+	c := NewCacheAside(cache.NewMemory(100))
+
+	var calls int32
+	fetch := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := Cached(context.Background(), c, "key", time.Minute, fetch); err != nil || v != "value" {
+				t.Errorf("unexpected result %q %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := Cached(context.Background(), c, "key", time.Minute, fetch); err != nil || calls != 1 {
+		t.Fatalf("expected one fetch for concurrent and later calls, got %d", calls)
+	}
+
+	calls = 0
+	missing := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, cache.ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Cached(context.Background(), c, "missing", time.Minute, missing); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("expected absence to be returned, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected absence to be cached, got %d fetches", calls)
+	}
+}
+
+func TestCachedDetachesFetch(t *testing.T) {
+	c := NewCacheAside(cache.NewMemory(100))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (string, error) {
+		close(started)
+		<-release
+		return "value", ctx.Err()
+	}
+
+	leader, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := Cached(leader, c, "key", time.Minute, fetch)
+		errs <- err
+	}()
+	<-started
+
+	waiter := make(chan string, 1)
+	go func() {
+		v, _ := Cached(context.Background(), c, "key", time.Minute, fetch)
+		waiter <- v
+	}()
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader got %v, want its own cancellation", err)
+	}
+	close(release)
+	if v := <-waiter; v != "value" {
+		t.Fatalf("waiter got %q, want the value despite the leader cancelling", v)
+	}
+}
+
+func TestCachedRecoversPanic(t *testing.T) {
+	c := NewCacheAside(cache.NewMemory(100))
+	_, err := Cached(context.Background(), c, "key", time.Minute, func(ctx context.Context) (string, error) {
+		panic("boom")
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("got %v, want the panic as an error", err)
+	}
+}
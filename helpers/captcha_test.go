@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyCaptcha(t *testing.T) {
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("secret") != "secret" || r.PostFormValue("remoteip") != "203.0.113.7" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-secret"]}`))
+			return
+		}
+		switch r.PostFormValue("response") {
+		case "solved":
+			w.Write([]byte(`{"success": true, "score": 0.9}`))
+		case "bot":
+			w.Write([]byte(`{"success": true, "score": 0.1}`))
+		case "outage":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer siteverify.Close()
+
+	provider := NewTurnstile("secret")
+	provider.VerifyURL = siteverify.URL
+	provider.MinScore = 0.5
+
+	ctx := context.Background()
+	if err := VerifyCaptcha(ctx, provider, "solved", "203.0.113.7"); err != nil {
+		t.Fatalf("solved: got %v, want success", err)
+	}
+	for _, token := range []string{"", "bot", "forged"} {
+		if err := VerifyCaptcha(ctx, provider, token, "203.0.113.7"); !errors.Is(err, ErrCaptchaFailed) {
+			t.Fatalf("%q: got %v, want ErrCaptchaFailed", token, err)
+		}
+	}
+	if err := VerifyCaptcha(ctx, provider, "outage", "203.0.113.7"); err == nil || errors.Is(err, ErrCaptchaFailed) {
+		t.Fatalf("outage: got %v, want an error other than ErrCaptchaFailed", err)
+	}
+}
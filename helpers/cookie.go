@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"net/http"
+	"time"
+
+	c "github.com/Etwodev/ramchi/config"
+)
+
+const (
+	// CookieStrict is the default profile: cookies are only sent over HTTPS,
+	// are hidden from scripts, and are never sent on cross-site requests.
+	CookieStrict = "strict"
+	// CookieLax behaves like CookieStrict, but cookies are sent on top-level
+	// cross-site navigations, as needed by OAuth and SSO redirects.
+	CookieLax = "lax"
+	// CookieLaxDev relaxes CookieLax for local development over plain HTTP.
+	CookieLaxDev = "lax-dev"
+)
+
+// CookieProfile holds the security attributes applied to cookies.
+type CookieProfile struct {
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+	// Domain scopes cookies to a domain and its subdomains,
+	// an empty domain restricts them to the exact host.
+	Domain string
+}
+
+var cookieProfiles = map[string]CookieProfile{
+	CookieStrict: {Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+	CookieLax:    {Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode},
+	CookieLaxDev: {Secure: false, HttpOnly: true, SameSite: http.SameSiteLaxMode},
+}
+
+// Profile returns the cookie profile selected by the CookieProfile of the
+// configuration serving the request, scoped to its CookieDomain. An empty or
+// unknown profile, or a request served without a configuration, selects
+// CookieStrict.
+func Profile(r *http.Request) CookieProfile {
+	cfg := c.FromContext(r.Context())
+	if cfg == nil {
+		return cookieProfiles[CookieStrict]
+	}
+	p, ok := cookieProfiles[cfg.CookieProfile]
+	if !ok {
+		p = cookieProfiles[CookieStrict]
+	}
+	if cfg.CookieProfile != CookieLaxDev {
+		p.Domain = cfg.CookieDomain
+	}
+	return p
+}
+
+// NewCookie returns a cookie with the attributes of the profile configured for the
+// request. A positive maxAge sets the lifetime in seconds, zero makes it a session
+// cookie.
+func NewCookie(r *http.Request, name string, value string, maxAge int) *http.Cookie {
+	p := Profile(r)
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   p.Domain,
+		MaxAge:   maxAge,
+		Secure:   p.Secure,
+		HttpOnly: p.HttpOnly,
+		SameSite: p.SameSite,
+	}
+	if maxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+	return cookie
+}
+
+// SetCookie sets a cookie with the attributes of the profile configured for the
+// request.
+func SetCookie(w http.ResponseWriter, r *http.Request, name string, value string, maxAge int) {
+	http.SetCookie(w, NewCookie(r, name, value, maxAge))
+}
+
+// ClearCookie expires a cookie set by SetCookie.
+func ClearCookie(w http.ResponseWriter, r *http.Request, name string) {
+	cookie := NewCookie(r, name, "", -1)
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/Etwodev/ramchi/config"
+)
+
+func TestCookieProfiles(t *testing.T) {
+	request := func(cfg *c.Config) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cfg != nil {
+			r = r.WithContext(c.WithContext(r.Context(), cfg))
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		cfg      *c.Config
+		secure   bool
+		sameSite http.SameSite
+		domain   string
+	}{
+		{nil, true, http.SameSiteStrictMode, ""},
+		{&c.Config{CookieProfile: "unknown"}, true, http.SameSiteStrictMode, ""},
+		{&c.Config{CookieProfile: CookieLax, CookieDomain: "example.com"}, true, http.SameSiteLaxMode, "example.com"},
+		{&c.Config{CookieProfile: CookieLaxDev, CookieDomain: "example.com"}, false, http.SameSiteLaxMode, ""},
+	} {
+		cookie := NewCookie(request(tc.cfg), "session", "id", 60)
+		if cookie.Secure != tc.secure || cookie.SameSite != tc.sameSite || cookie.Domain != tc.domain || !cookie.HttpOnly {
+			t.Errorf("%+v: got %+v", tc.cfg, cookie)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	ClearCookie(rec, request(nil), "session")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 || !cookies[0].Secure {
+		t.Fatalf("got %+v, want the cookie expired with the profile's attributes", cookies)
+	}
+}
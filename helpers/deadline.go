@@ -0,0 +1,13 @@
+package helpers
+
+import (
+	"net/http"
+	"time"
+)
+
+// ExtendDeadline allows the handler the duration from now to finish writing its
+// response, moving both the write deadline of the connection and the deadline of
+// the timeout middleware, for handlers which know they are slow, such as exports.
+func ExtendDeadline(w http.ResponseWriter, d time.Duration) error {
+	return http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d))
+}
@@ -0,0 +1,222 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDecimal is returned by ParseDecimal for values which are not decimal
+// numbers.
+var ErrInvalidDecimal = errors.New("helpers: invalid decimal")
+
+// Decimal is an exact decimal number, such as an amount of money, which float64
+// cannot represent: 0.1 + 0.2 is 0.3. It is encoded in JSON as a string, so that
+// clients do not parse it as a float either, and decoded from a string or number.
+// The zero value is 0.
+type Decimal struct {
+	value *big.Int
+	scale int32
+}
+
+// NewDecimal returns the decimal value * 10^-scale, such as 1234 and 2 for 12.34.
+func NewDecimal(value int64, scale int32) Decimal {
+	return Decimal{value: big.NewInt(value), scale: scale}
+}
+
+// ParseDecimal parses a decimal number, such as 12.34, -0.5 or 1e3.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exp, err = strconv.ParseInt(s[i+1:], 10, 32); err != nil {
+			return Decimal{}, ErrInvalidDecimal
+		}
+		mantissa = s[:i]
+	}
+
+	digits := strings.TrimLeft(mantissa, "+-")
+	if len(mantissa)-len(digits) > 1 {
+		return Decimal{}, ErrInvalidDecimal
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole+frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return Decimal{}, ErrInvalidDecimal
+	}
+
+	value, _ := new(big.Int).SetString(whole+frac, 10)
+	if strings.HasPrefix(mantissa, "-") {
+		value.Neg(value)
+	}
+	scale := int64(len(frac)) - exp
+	if scale < 0 {
+		value.Mul(value, pow10(-scale))
+		scale = 0
+	}
+	if scale > 1<<20 {
+		return Decimal{}, ErrInvalidDecimal
+	}
+	return Decimal{value: value, scale: int32(scale)}, nil
+}
+
+// MustDecimal parses a decimal number as ParseDecimal does, panicking if it is
+// invalid, such as for constants.
+func MustDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(fmt.Sprintf("helpers: %q is not a decimal", s))
+	}
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// rescale returns the value of d with the scale, which is at least that of d.
+func (d Decimal) rescale(scale int32) *big.Int {
+	return new(big.Int).Mul(d.int(), pow10(int64(scale-d.scale)))
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	scale := max(d.scale, o.scale)
+	return Decimal{value: new(big.Int).Add(d.rescale(scale), o.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d.Add(o.Neg())
+}
+
+// Mul returns d * o.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+// Div returns d / o rounded half to even to the places after the decimal point,
+// panicking if o is zero.
+func (d Decimal) Div(o Decimal, places int32) Decimal {
+	if o.Sign() == 0 {
+		panic("helpers: division of a decimal by zero")
+	}
+	// d / o = d.value * 10^(o.scale - d.scale) / o.value, scaled by 10^places.
+	num, den := new(big.Int).Set(d.int()), new(big.Int).Set(o.int())
+	if shift := int64(o.scale) - int64(d.scale) + int64(places); shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{value: roundHalfEven(num, den), scale: places}
+}
+
+// Round returns d rounded half to even to the places after the decimal point, as
+// accounting rounds, so that 0.125 and 0.135 round to 0.12 and 0.14.
+func (d Decimal) Round(places int32) Decimal {
+	if d.scale <= places {
+		return d
+	}
+	return Decimal{value: roundHalfEven(new(big.Int).Set(d.int()), pow10(int64(d.scale-places))), scale: places}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{value: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or 1 as d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Cmp returns -1, 0 or 1 as d is less than, equal to or greater than o.
+func (d Decimal) Cmp(o Decimal) int {
+	scale := max(d.scale, o.scale)
+	return d.rescale(scale).Cmp(o.rescale(scale))
+}
+
+// Equal returns whether d and o are the same number, whatever their scale.
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+// String returns the decimal with its scale, such as 12.30.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	} else if d.scale < 0 && d.int().Sign() != 0 {
+		digits += strings.Repeat("0", int(-d.scale))
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalText encodes the decimal as String does.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a decimal number, such as from a query parameter.
+func (d *Decimal) UnmarshalText(b []byte) error {
+	parsed, err := ParseDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON encodes the decimal as a string, such as "12.30".
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a decimal from a string, or from a number without the
+// loss of precision of float64.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		s = string(b)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// roundHalfEven returns num / den rounded half to even, den being positive or
+// negative.
+func roundHalfEven(num, den *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	cmp := new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(den))
+	if cmp > 0 || (cmp == 0 && q.Bit(0) == 1) {
+		if (num.Sign() < 0) != (den.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(n), nil)
+}
@@ -0,0 +1,18 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+)
+
+// StatusClientClosedRequest is the non-standard status, after nginx, recorded for
+// requests whose client disconnected before the response was written.
+const StatusClientClosedRequest = 499
+
+// ClientGone reports whether the request's client has disconnected, cancelling its
+// context, so that handlers may abandon work nobody will receive, and so that such
+// requests are told apart from server errors. Contexts cancelled with another cause,
+// such as by the timeout middleware, are not disconnects.
+func ClientGone(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), context.Canceled)
+}
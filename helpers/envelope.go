@@ -0,0 +1,71 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Envelope is the uniform shape of responses written in envelope mode.
+type Envelope struct {
+	Data  interface{}            `json:"data"`
+	Error *EnvelopeError         `json:"error"`
+	Meta  map[string]interface{} `json:"meta"`
+}
+
+// EnvelopeError describes the error of a failed response in envelope mode.
+type EnvelopeError struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+	TraceID   string `json:"traceId,omitempty"`
+}
+
+// Pagination describes the page of a collection returned by a response.
+type Pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"perPage"`
+	Total      int `json:"total"`
+	TotalPages int `json:"totalPages"`
+}
+
+// EnvelopeState tracks the envelope of a single request.
+type EnvelopeState struct {
+	// Meta is written as the meta member of the envelope.
+	Meta map[string]interface{}
+	// Written reports whether the response has already been enveloped by a helper.
+	Written bool
+}
+
+type envelopeKey struct{}
+
+// WithEnvelope returns a context in which responses are written in envelope mode.
+func WithEnvelope(ctx context.Context) (context.Context, *EnvelopeState) {
+	state := &EnvelopeState{Meta: make(map[string]interface{})}
+	return context.WithValue(ctx, envelopeKey{}, state), state
+}
+
+// EnvelopeFromContext returns the envelope state of the request, or nil when
+// envelope mode is not enabled.
+func EnvelopeFromContext(ctx context.Context) *EnvelopeState {
+	state, _ := ctx.Value(envelopeKey{}).(*EnvelopeState)
+	return state
+}
+
+// SetMeta sets a member of the envelope meta for the request.
+// It has no effect when envelope mode is not enabled.
+func SetMeta(r *http.Request, key string, value interface{}) {
+	if state := EnvelopeFromContext(r.Context()); state != nil {
+		state.Meta[key] = value
+	}
+}
+
+// Paginate records the pagination of the response, as the pagination member of
+// the envelope meta and in the X-Total-Count header.
+func Paginate(w http.ResponseWriter, r *http.Request, p Pagination) {
+	if p.PerPage > 0 {
+		p.TotalPages = (p.Total + p.PerPage - 1) / p.PerPage
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(p.Total))
+	SetMeta(r, "pagination", p)
+}
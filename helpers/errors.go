@@ -0,0 +1,83 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	c "github.com/Etwodev/ramchi/config"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	TraceIDHeader   = "X-Trace-Id"
+)
+
+// ErrorResponse is the body written by Error
+// when config.ErrorRequestID is enabled.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Status    int    `json:"status"`
+	RequestID string `json:"requestId,omitempty"`
+	TraceID   string `json:"traceId,omitempty"`
+}
+
+// Error writes the error response for the status code. When config.ErrorRequestID
+// is enabled the response is JSON and carries the request and trace IDs, both in
+// the body and in the X-Request-Id and X-Trace-Id headers. In envelope mode the
+// response is written as the error member of an Envelope.
+func Error(w http.ResponseWriter, r *http.Request, code int) {
+	envelope := EnvelopeFromContext(r.Context()) != nil
+	cfg := c.FromContext(r.Context())
+	withID := cfg != nil && cfg.ErrorRequestID
+	if !withID && !envelope {
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	e := &EnvelopeError{Status: code, Message: http.StatusText(code)}
+	if withID {
+		e.RequestID, e.TraceID = RequestID(r), TraceID(r)
+		if e.RequestID != "" {
+			w.Header().Set(RequestIDHeader, e.RequestID)
+		}
+		if e.TraceID != "" {
+			w.Header().Set(TraceIDHeader, e.TraceID)
+		}
+	}
+
+	if envelope {
+		EnvelopeJSONError(w, r, e)
+		return
+	}
+
+	body, err := json.Marshal(ErrorResponse{Error: e.Message, Status: code, RequestID: e.RequestID, TraceID: e.TraceID})
+	if err != nil {
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// RequestID returns the ID assigned to the request by a request ID middleware,
+// falling back to the X-Request-Id header sent by the client or proxy.
+func RequestID(r *http.Request) string {
+	if id := chimw.GetReqID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(RequestIDHeader)
+}
+
+// TraceID returns the trace ID propagated in the W3C traceparent header, if any.
+func TraceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FieldsParam is the query parameter listing the fields of a sparse fieldset, such
+// as ?fields=id,name,author.name.
+const FieldsParam = "fields"
+
+// RequestedFields returns the fields requested by the fields query parameter, in
+// order and without duplicates, or nil when the full representation is requested.
+func RequestedFields(r *http.Request) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, param := range r.URL.Query()[FieldsParam] {
+		for _, f := range strings.Split(param, ",") {
+			if f = strings.TrimSpace(f); f != "" && !seen[f] {
+				seen[f] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+type allowedFieldsKey struct{}
+
+// WithAllowedFields returns a context in which only the fields, or the fields
+// nested within them, may be requested, as set by middleware.Fields for a route.
+func WithAllowedFields(ctx context.Context, allowed []string) context.Context {
+	return context.WithValue(ctx, allowedFieldsKey{}, allowed)
+}
+
+// DisallowedField returns the first requested field which is not allowed by the
+// allowlist of the context, and whether there is one. Without an allowlist, every
+// field is allowed.
+func DisallowedField(r *http.Request) (string, bool) {
+	allowed, ok := r.Context().Value(allowedFieldsKey{}).([]string)
+	if !ok {
+		return "", false
+	}
+	for _, f := range RequestedFields(r) {
+		if !fieldAllowed(f, allowed) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+func fieldAllowed(field string, allowed []string) bool {
+	for _, a := range allowed {
+		if field == a || strings.HasPrefix(field, a+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONFields writes v as JSON as JSON does, keeping only the fields requested by the
+// fields query parameter, so that clients such as mobile apps may fetch smaller
+// payloads. Requests for fields outside the allowlist of the route respond 400 Bad
+// Request, naming the field.
+func JSONFields(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	fields := RequestedFields(r)
+	if len(fields) == 0 {
+		JSON(w, r, code, v)
+		return
+	}
+	if f, ok := DisallowedField(r); ok {
+		JSONError(w, r, http.StatusBadRequest, fmt.Sprintf("field %q may not be requested", f))
+		return
+	}
+
+	body, err := json.Marshal(v)
+	if err == nil {
+		body, err = FilterFields(body, fields)
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	JSON(w, r, code, json.RawMessage(body))
+}
+
+// FilterFields keeps only the fields of a JSON object, or of each object of a JSON
+// array, with the names or dotted paths given, such as id or author.name. Other
+// values are returned unchanged.
+func FilterFields(body []byte, fields []string) ([]byte, error) {
+	tree := make(map[string]interface{})
+	for _, f := range fields {
+		node := tree
+		parts := strings.Split(f, ".")
+		for i, part := range parts {
+			if i == len(parts)-1 {
+				node[part] = true
+				break
+			}
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				if node[part] == true {
+					break
+				}
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	filtered, err := filterValue(json.RawMessage(body), tree)
+	if err != nil {
+		return nil, fmt.Errorf("FilterFields: %w", err)
+	}
+	return filtered, nil
+}
+
+// filterValue filters the objects of the raw value to the fields of the tree, in
+// which true keeps a field whole and a map keeps the fields nested within it.
+func filterValue(raw json.RawMessage, tree map[string]interface{}) (json.RawMessage, error) {
+	switch first(raw) {
+	case '{':
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &members); err != nil {
+			return nil, err
+		}
+		kept := make(map[string]json.RawMessage, len(tree))
+		for name, sub := range tree {
+			value, ok := members[name]
+			if !ok {
+				continue
+			}
+			if nested, ok := sub.(map[string]interface{}); ok {
+				var err error
+				if value, err = filterValue(value, nested); err != nil {
+					return nil, err
+				}
+			}
+			kept[name] = value
+		}
+		return json.Marshal(kept)
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			filtered, err := filterValue(item, tree)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = filtered
+		}
+		return json.Marshal(items)
+	}
+	return raw, nil
+}
+
+func first(raw []byte) byte {
+	raw = bytes.TrimLeft(raw, " \t\r\n")
+	if len(raw) == 0 {
+		return 0
+	}
+	return raw[0]
+}
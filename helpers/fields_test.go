@@ -0,0 +1,40 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFilterFields(t *testing.T) {
+	body := `[{"id":1,"name":"a","secret":"x","author":{"name":"b","email":"c"}},{"id":2,"name":"d"}]`
+	got, err := FilterFields([]byte(body), []string{"id", "author.name", "missing"})
+	if err != nil {
+		t.Fatalf("FilterFields: %v", err)
+	}
+	if want := `[{"author":{"name":"b"},"id":1},{"id":2}]`; string(got) != want {
+		t.Fatalf("FilterFields = %s, want %s", got, want)
+	}
+	if got, _ := FilterFields([]byte(`"text"`), []string{"id"}); string(got) != `"text"` {
+		t.Fatalf("FilterFields of a string = %s, want it unchanged", got)
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	item := map[string]interface{}{"id": 1, "name": "a", "secret": "x"}
+	for target, want := range map[string]string{
+		"/items":                          `{"id":1,"name":"a","secret":"x"}`,
+		"/items?fields=id":                `{"id":1}`,
+		"/items?fields=id&fields=name,id": `{"id":1,"name":"a"}`,
+		"/items?fields=secret":            `{"error":"field \"secret\" may not be requested"}`,
+	} {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r = r.WithContext(WithAllowedFields(r.Context(), []string{"id", "name"}))
+		w := httptest.NewRecorder()
+		JSONFields(w, r, http.StatusOK, item)
+		if got := strings.TrimSpace(w.Body.String()); got != want {
+			t.Fatalf("JSONFields(%s) = %s, want %s", target, got, want)
+		}
+	}
+}
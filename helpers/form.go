@@ -0,0 +1,266 @@
+package helpers
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxFormMemory is the size of a multipart form held in memory by BindForm, the
+// rest of its files being stored on disk.
+const maxFormMemory = 32 << 20
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	decimalType    = reflect.TypeOf(Decimal{})
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// EncodeQuery encodes the fields of a struct as query parameters, such as for the
+// requests of a client, named as BindForm binds them. Slices are encoded as repeated
+// parameters, times as RFC 3339, and types implementing encoding.TextMarshaler as
+// their text. Fields tagged omitempty are omitted when empty.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("EncodeQuery: %s is not a struct", rv.Type())
+	}
+
+	values := url.Values{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, omitEmpty, ok := formField(rt.Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		if omitEmpty && field.IsZero() {
+			continue
+		}
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < field.Len(); j++ {
+				s, err := formatValue(field.Index(j))
+				if err != nil {
+					return nil, fmt.Errorf("EncodeQuery: %s: %w", name, err)
+				}
+				values.Add(name, s)
+			}
+			continue
+		}
+		s, err := formatValue(field)
+		if err != nil {
+			return nil, fmt.Errorf("EncodeQuery: %s: %w", name, err)
+		}
+		values.Set(name, s)
+	}
+	return values, nil
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	case durationType:
+		return v.Interface().(time.Duration).String(), nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// BindForm binds the fields of a form request body, and of its query, to dst, a
+// pointer to a struct, by their form tag or json name.
+func BindForm(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindForm: %T is not a pointer to a struct", dst)
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	if mt == "multipart/form-data" {
+		err = r.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return fmt.Errorf("BindForm: failed parsing form: %w", err)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, ok := formField(rt.Field(i))
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+
+		if field.Type() == fileHeaderType || (field.Kind() == reflect.Slice && field.Type().Elem() == fileHeaderType) {
+			if r.MultipartForm == nil || len(r.MultipartForm.File[name]) == 0 {
+				continue
+			}
+			files := r.MultipartForm.File[name]
+			if field.Kind() == reflect.Slice {
+				field.Set(reflect.ValueOf(files))
+			} else {
+				field.Set(reflect.ValueOf(files[0]))
+			}
+			continue
+		}
+
+		values, present := r.Form[name]
+		if !present {
+			continue
+		}
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+			s := reflect.MakeSlice(field.Type(), len(values), len(values))
+			for j, value := range values {
+				if err := ParseValue(s.Index(j), value); err != nil {
+					return fmt.Errorf("form field %s: %w", name, err)
+				}
+			}
+			field.Set(s)
+			continue
+		}
+		if err := ParseValue(field, values[0]); err != nil {
+			return fmt.Errorf("form field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ParseValue sets v, or the value v points to, from a form, path or query
+// parameter.
+func ParseValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case timeType:
+		t, err := ParseTime(value, time.UTC)
+		if err != nil {
+			return errors.New("must be a time, such as 2006-01-02T15:04:05Z or 2006-01-02")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration, such as 15m or 2h")
+		}
+		v.SetInt(int64(d))
+		return nil
+	case decimalType:
+		d, err := ParseDecimal(value)
+		if err != nil {
+			return errors.New("must be a decimal number, such as 12.30")
+		}
+		v.Set(reflect.ValueOf(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return errors.New("is invalid")
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := parseFormBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseFormBool parses a boolean, accepting "on", as sent for checked checkboxes.
+func parseFormBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off", "":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// formField returns the name a struct field is bound under, from its form tag or
+// its json tag, whether it is omitted when empty, and whether it is bound at all.
+func formField(f reflect.StructField) (string, bool, bool) {
+	if tag, ok := f.Tag.Lookup("form"); ok {
+		if tag == "-" || !f.IsExported() {
+			return "", false, false
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		return name, strings.Contains(opts, "omitempty"), true
+	}
+	return jsonField(f)
+}
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type searchForm struct {
+	Query  string        `json:"q"`
+	Tags   []string      `json:"tags,omitempty"`
+	Page   int           `json:"page,omitempty"`
+	Exact  bool          `form:"exact,omitempty"`
+	Since  time.Time     `json:"since"`
+	Within time.Duration `json:"within"`
+	Price  Decimal       `json:"price"`
+	Secret string        `json:"-"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	values, err := EncodeQuery(&searchForm{
+		Query:  "go & chi",
+		Tags:   []string{"a", "b"},
+		Since:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Within: 90 * time.Minute,
+		Price:  MustDecimal("9.99"),
+		Secret: "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "price=9.99&q=go+%26+chi&since=2024-02-01T00%3A00%3A00Z&tags=a&tags=b&within=1h30m0s"
+	if got := values.Encode(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if _, err := EncodeQuery(42); err == nil {
+		t.Fatal("encoded an int")
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/?page=2", strings.NewReader("q=go&tags=a&tags=b&exact=on&since=2024-02-01&within=2h&price=9.99"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var f searchForm
+	if err := BindForm(r, &f); err != nil {
+		t.Fatalf("BindForm: %v", err)
+	}
+	if f.Query != "go" || len(f.Tags) != 2 || f.Page != 2 || !f.Exact || f.Since.Day() != 1 || f.Within != 2*time.Hour || f.Price.String() != "9.99" {
+		t.Fatalf("got %+v", f)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("page=two"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := BindForm(r, &f); err == nil || err.Error() != "form field page: must be an integer" {
+		t.Fatalf("got %v, want the invalid field named", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("q", "upload")
+	fw, _ := mw.CreateFormFile("avatar", "me.png")
+	fw.Write([]byte("png"))
+	mw.Close()
+	r = httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	var upload struct {
+		Query  string                `json:"q"`
+		Avatar *multipart.FileHeader `form:"avatar"`
+	}
+	if err := BindForm(r, &upload); err != nil {
+		t.Fatalf("BindForm: %v", err)
+	}
+	if upload.Query != "upload" || upload.Avatar == nil || upload.Avatar.Filename != "me.png" {
+		t.Fatalf("got %+v", upload)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	var v struct {
+		Exact bool
+		Price Decimal
+		Addr  netip.Addr
+		Page  *int
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	for _, tc := range []struct {
+		field, value, err string
+	}{
+		{"Exact", "maybe", "must be a boolean"},
+		{"Exact", "on", ""},
+		{"Price", "cheap", "must be a decimal number, such as 12.30"},
+		{"Price", "9.99", ""},
+		{"Addr", "home", "is invalid"},
+		{"Addr", "192.0.2.1", ""},
+		{"Page", "3", ""},
+	} {
+		err := ParseValue(rv.FieldByName(tc.field), tc.value)
+		if (err == nil && tc.err != "") || (err != nil && err.Error() != tc.err) {
+			t.Errorf("%s=%s: got %v, want %q", tc.field, tc.value, err, tc.err)
+		}
+	}
+	if !v.Exact || v.Price.String() != "9.99" || v.Addr.String() != "192.0.2.1" || v.Page == nil || *v.Page != 3 {
+		t.Fatalf("got %+v", v)
+	}
+}
@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TokenInfo is the response of an OAuth 2.0 token introspection endpoint (RFC 7662).
+type TokenInfo struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	// Aud is either a string or a list of strings.
+	Aud interface{} `json:"aud,omitempty"`
+}
+
+// HasScope reports whether the token was granted the scope.
+func (t *TokenInfo) HasScope(scope string) bool {
+	for _, s := range strings.Fields(t.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenInfoKey struct{}
+
+// WithTokenInfo returns a context carrying the introspected bearer token of the request.
+func WithTokenInfo(ctx context.Context, info *TokenInfo) context.Context {
+	return context.WithValue(ctx, tokenInfoKey{}, info)
+}
+
+// Token returns the introspected bearer token the request was authorized with, or nil.
+func Token(r *http.Request) *TokenInfo {
+	info, _ := r.Context().Value(tokenInfoKey{}).(*TokenInfo)
+	return info
+}
+
+// BearerToken returns the token of the request's Authorization header, or an empty string.
+func BearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Link is a hypermedia link to a related resource.
+type Link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// Links maps link relations, such as self or next, to links.
+type Links map[string]Link
+
+type routesKey struct{}
+
+// WithRoutes returns a context in which URLFor resolves route names to the patterns.
+func WithRoutes(ctx context.Context, patterns map[string]string) context.Context {
+	return context.WithValue(ctx, routesKey{}, patterns)
+}
+
+// URLFor returns the path of the named route, substituting its parameters
+// with the values given as key/value pairs.
+func URLFor(r *http.Request, name string, params ...string) (string, error) {
+	patterns, _ := r.Context().Value(routesKey{}).(map[string]string)
+	pattern, ok := patterns[name]
+	if !ok {
+		return "", fmt.Errorf("URLFor: unknown route %q", name)
+	}
+	return expand(pattern, params...)
+}
+
+// LinkFor returns a link to the named route.
+func LinkFor(r *http.Request, name string, params ...string) (Link, error) {
+	href, err := URLFor(r, name, params...)
+	if err != nil {
+		return Link{}, fmt.Errorf("LinkFor: failed building url: %w", err)
+	}
+	return Link{Href: href}, nil
+}
+
+type linked struct {
+	v     interface{}
+	links Links
+}
+
+// WithLinks returns v with links added as its _links member when marshaled.
+// v must marshal to a JSON object.
+func WithLinks(v interface{}, links Links) json.Marshaler {
+	return linked{v, links}
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (l linked) MarshalJSON() ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	body, err := json.Marshal(l.v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("WithLinks: resource is not a json object: %w", err)
+	}
+	if fields["_links"], err = json.Marshal(l.links); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// PaginationLinks returns the self, first, last, prev and next links of a paginated
+// collection, built from the request URL with its page query parameter replaced.
+func PaginationLinks(r *http.Request, p Pagination) Links {
+	page := func(n int) Link {
+		u := *r.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(n))
+		if p.PerPage > 0 {
+			q.Set("perPage", strconv.Itoa(p.PerPage))
+		}
+		u.RawQuery = q.Encode()
+		return Link{Href: u.RequestURI()}
+	}
+
+	last := 1
+	if p.PerPage > 0 && p.Total > 0 {
+		last = (p.Total + p.PerPage - 1) / p.PerPage
+	}
+
+	links := Links{"self": page(p.Page), "first": page(1), "last": page(last)}
+	if p.Page > 1 {
+		links["prev"] = page(p.Page - 1)
+	}
+	if p.Page < last {
+		links["next"] = page(p.Page + 1)
+	}
+	return links
+}
+
+// expand builds a path from a route pattern, substituting each {param} and
+// the trailing * wildcard with the values given as key/value pairs.
+func expand(pattern string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("expand: odd number of params for %q", pattern)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			end := closingBrace(pattern, i)
+			if end < 0 {
+				return "", fmt.Errorf("expand: unterminated parameter in %q", pattern)
+			}
+			key, _, _ := strings.Cut(pattern[i+1:end], ":")
+			value, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("expand: missing parameter %q for %q", key, pattern)
+			}
+			b.WriteString(url.PathEscape(value))
+			i = end
+		case '*':
+			value := values["*"]
+			segments := strings.Split(value, "/")
+			for j := range segments {
+				segments[j] = url.PathEscape(segments[j])
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		default:
+			b.WriteByte(pattern[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the brace closing the parameter opened at
+// start, allowing for braces nested inside regular expressions.
+func closingBrace(pattern string, start int) int {
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
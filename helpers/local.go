@@ -13,3 +13,14 @@ func URLParam(r *http.Request, key string) string {
 	}
 	return ""
 }
+
+// RoutePattern returns the pattern of the route matched by the request, such as
+// /users/{id}, or an empty string when no route matched. Unlike the path, it has
+// bounded cardinality, so suits log fields and metric labels. It is complete only
+// once routing has finished, so middlewares should read it after calling the next handler.
+func RoutePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+var defaultLogger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "request").Logger()
+
+// Logger returns the logger of the request, which the server carries in its context
+// and middlewares such as middleware.RequestID enrich, or a console logger.
+func Logger(r *http.Request) *zerolog.Logger {
+	// Without a logger in the context, zerolog returns the one of an empty context.
+	if l := zerolog.Ctx(r.Context()); l != zerolog.Ctx(context.Background()) {
+		return l
+	}
+	return &defaultLogger
+}
@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrInvalidCurrency is returned for currencies which are not ISO 4217 codes.
+	ErrInvalidCurrency = errors.New("helpers: invalid currency")
+	// ErrInvalidAmount is returned for amounts with more decimal places than the
+	// minor unit of their currency, such as 1.005 USD.
+	ErrInvalidAmount = errors.New("helpers: invalid amount")
+	// ErrCurrencyMismatch is returned by arithmetic on amounts of two currencies.
+	ErrCurrencyMismatch = errors.New("helpers: currency mismatch")
+)
+
+// currencies maps ISO 4217 codes to the decimal places of their minor unit.
+var currencies = map[string]int32{
+	"AED": 2, "ARS": 2, "AUD": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KES": 2, "KRW": 0, "KWD": 3, "MAD": 2, "MXN": 2, "MYR": 2, "NGN": 2, "NOK": 2,
+	"NZD": 2, "OMR": 3, "PEN": 2, "PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2, "RON": 2,
+	"RSD": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "UAH": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// CurrencyPlaces returns the decimal places of the minor unit of the ISO 4217
+// currency, such as 2 for USD and 0 for JPY, and whether the currency is known.
+func CurrencyPlaces(currency string) (int32, bool) {
+	places, ok := currencies[strings.ToUpper(currency)]
+	return places, ok
+}
+
+// Money is an amount in a currency, encoded in JSON as
+// {"amount": "12.30", "currency": "EUR"}. Its amount never has more decimal
+// places than the minor unit of its currency.
+type Money struct {
+	Amount   Decimal
+	Currency string
+}
+
+// NewMoney returns the amount in the ISO 4217 currency, such as "12.30" and "EUR".
+// The error wraps ErrInvalidCurrency, ErrInvalidDecimal or ErrInvalidAmount.
+func NewMoney(amount string, currency string) (Money, error) {
+	d, err := ParseDecimal(amount)
+	if err != nil {
+		return Money{}, fmt.Errorf("NewMoney: %q is not an amount: %w", amount, err)
+	}
+	m := Money{Amount: d, Currency: strings.ToUpper(currency)}
+	if err := m.Validate(); err != nil {
+		return Money{}, fmt.Errorf("NewMoney: %w", err)
+	}
+	return m.normalize(), nil
+}
+
+// FromMinor returns the amount of minor units in the currency, such as 1230 cents
+// for 12.30 USD.
+func FromMinor(minor int64, currency string) (Money, error) {
+	places, ok := CurrencyPlaces(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("FromMinor: %q: %w", currency, ErrInvalidCurrency)
+	}
+	return Money{Amount: NewDecimal(minor, places), Currency: strings.ToUpper(currency)}, nil
+}
+
+// Validate returns an error if the currency is unknown, or the amount has more
+// decimal places than its minor unit, so that it may be called by request types
+// validating themselves.
+func (m Money) Validate() error {
+	places, ok := CurrencyPlaces(m.Currency)
+	if !ok {
+		return fmt.Errorf("%q is not an ISO 4217 currency: %w", m.Currency, ErrInvalidCurrency)
+	}
+	if !m.Amount.Round(places).Equal(m.Amount) {
+		return fmt.Errorf("%s has more than %d decimal places for %s: %w", m.Amount, places, m.Currency, ErrInvalidAmount)
+	}
+	return nil
+}
+
+// normalize returns the money with its amount at the scale of its minor unit.
+func (m Money) normalize() Money {
+	places, _ := CurrencyPlaces(m.Currency)
+	if m.Amount.scale < places {
+		m.Amount = Decimal{value: m.Amount.rescale(places), scale: places}
+	} else {
+		m.Amount = m.Amount.Round(places)
+	}
+	return m
+}
+
+// Minor returns the amount in minor units, such as 1230 for 12.30 USD. It is
+// exact for amounts which fit in an int64.
+func (m Money) Minor() int64 {
+	return m.normalize().Amount.int().Int64()
+}
+
+// Add returns m + o, which must be of the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if !strings.EqualFold(m.Currency, o.Currency) {
+		return Money{}, fmt.Errorf("Add: %s and %s: %w", m.Currency, o.Currency, ErrCurrencyMismatch)
+	}
+	return Money{Amount: m.Amount.Add(o.Amount), Currency: m.Currency}.normalize(), nil
+}
+
+// Sub returns m - o, which must be of the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if !strings.EqualFold(m.Currency, o.Currency) {
+		return Money{}, fmt.Errorf("Sub: %s and %s: %w", m.Currency, o.Currency, ErrCurrencyMismatch)
+	}
+	return Money{Amount: m.Amount.Sub(o.Amount), Currency: m.Currency}.normalize(), nil
+}
+
+// Mul returns m multiplied by the factor, such as a tax rate, rounded half to
+// even to the minor unit of its currency.
+func (m Money) Mul(factor Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor), Currency: m.Currency}.normalize()
+}
+
+// Split divides m into n parts differing by at most one minor unit, the first
+// parts taking the remainder, so that the parts always sum to m.
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	m = m.normalize()
+	q, r := new(big.Int).QuoRem(m.Amount.int(), big.NewInt(int64(n)), new(big.Int))
+	step := int64(r.Sign())
+	remainder := new(big.Int).Abs(r).Int64()
+
+	parts := make([]Money, n)
+	for i := range parts {
+		v := new(big.Int).Set(q)
+		if int64(i) < remainder {
+			v.Add(v, big.NewInt(step))
+		}
+		parts[i] = Money{Amount: Decimal{value: v, scale: m.Amount.scale}, Currency: m.Currency}
+	}
+	return parts
+}
+
+// String returns the amount and currency, such as 12.30 EUR.
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Amount   Decimal `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// MarshalJSON encodes the money as {"amount": "12.30", "currency": "EUR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes money encoded as MarshalJSON does, returning an error for
+// an unknown currency or an amount more precise than its minor unit.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	decoded := Money{Amount: v.Amount, Currency: strings.ToUpper(v.Currency)}
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+	*m = decoded.normalize()
+	return nil
+}
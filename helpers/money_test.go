@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	a, b := MustDecimal("0.1"), MustDecimal("0.2")
+	if got := a.Add(b); !got.Equal(MustDecimal("0.3")) || got.String() != "0.3" {
+		t.Fatalf("0.1 + 0.2 = %s", got)
+	}
+	for _, tc := range []struct{ got, want string }{
+		{MustDecimal("12.5").Sub(MustDecimal("20")).String(), "-7.5"},
+		{MustDecimal("1.5").Mul(MustDecimal("-0.25")).String(), "-0.375"},
+		{MustDecimal("10").Div(MustDecimal("3"), 4).String(), "3.3333"},
+		{MustDecimal("-2").Div(MustDecimal("3"), 2).String(), "-0.67"},
+		{MustDecimal("0.125").Round(2).String(), "0.12"},
+		{MustDecimal("0.135").Round(2).String(), "0.14"},
+		{MustDecimal("-0.125").Round(2).String(), "-0.12"},
+		{MustDecimal("1.2e3").String(), "1200"},
+		{MustDecimal("15e-3").String(), "0.015"},
+		{MustDecimal(".5").String(), "0.5"},
+		{Decimal{}.String(), "0"},
+	} {
+		if tc.got != tc.want {
+			t.Fatalf("got %s, want %s", tc.got, tc.want)
+		}
+	}
+	for _, s := range []string{"", "abc", "1.2.3", "--1", "1e", "NaN", "."} {
+		if _, err := ParseDecimal(s); err != ErrInvalidDecimal {
+			t.Fatalf("ParseDecimal(%q) = %v, want ErrInvalidDecimal", s, err)
+		}
+	}
+
+	var v struct{ Price, Rate Decimal }
+	if err := json.Unmarshal([]byte(`{"Price": "19.99", "Rate": 0.07}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(v); string(b) != `{"Price":"19.99","Rate":"0.07"}` {
+		t.Fatalf("got %s", b)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	price, err := NewMoney("19.9", "usd")
+	if err != nil || price.String() != "19.90 USD" || price.Minor() != 1990 {
+		t.Fatalf("NewMoney = %v, %v", price, err)
+	}
+	if _, err := NewMoney("1.005", "USD"); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("got %v, want ErrInvalidAmount", err)
+	}
+	if _, err := NewMoney("1", "XXX"); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("got %v, want ErrInvalidCurrency", err)
+	}
+	if yen, _ := FromMinor(500, "JPY"); yen.String() != "500 JPY" {
+		t.Fatalf("got %s", yen)
+	}
+
+	tax := price.Mul(MustDecimal("0.0825"))
+	total, err := price.Add(tax)
+	if err != nil || total.String() != "21.54 USD" {
+		t.Fatalf("total = %v, %v", total, err)
+	}
+	if _, err := price.Add(Money{Amount: MustDecimal("1"), Currency: "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("got %v, want ErrCurrencyMismatch", err)
+	}
+
+	var parts []string
+	for _, p := range mustMoney("100.00", "EUR").Split(3) {
+		parts = append(parts, p.Amount.String())
+	}
+	if strings.Join(parts, " ") != "33.34 33.33 33.33" {
+		t.Fatalf("split into %v", parts)
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount": "12.3", "currency": "eur"}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(m); string(b) != `{"amount":"12.30","currency":"EUR"}` {
+		t.Fatalf("got %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": "12.345", "currency": "EUR"}`), &m); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("got %v, want ErrInvalidAmount", err)
+	}
+}
+
+func mustMoney(amount, currency string) Money {
+	m, err := NewMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+)
+
+// observation holds the sample rate of a request's route, set once it is routed,
+// and the sampling decision shared by logging, metrics and tracing.
+type observation struct {
+	rate    float64
+	set     bool
+	decided bool
+	sampled bool
+}
+
+type observationKey struct{}
+
+// WithObservation returns a context in which the route of the request may set its
+// sample rate. The server adds it to every request.
+func WithObservation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, observationKey{}, &observation{})
+}
+
+// SetSampleRate sets the fraction of the request's route to be observed, from 0 for
+// none to 1 for all, such as for health checks and metrics scrapes. A rate set by a
+// route takes precedence over that of its router.
+func SetSampleRate(r *http.Request, rate float64) {
+	if o, ok := r.Context().Value(observationKey{}).(*observation); ok && !o.decided {
+		o.rate, o.set = rate, true
+	}
+}
+
+// Observed reports whether access logging, metrics and tracing should record the
+// request. It is decided once per request, so that a sampled request is recorded
+// by all of them, and should be read after the request is routed, such as once the
+// next handler has returned.
+func Observed(r *http.Request) bool {
+	o, ok := r.Context().Value(observationKey{}).(*observation)
+	if !ok || !o.set {
+		return true
+	}
+	if !o.decided {
+		o.decided = true
+		o.sampled = o.rate >= 1 || (o.rate > 0 && rand.Float64() < o.rate)
+	}
+	return o.sampled
+}
@@ -0,0 +1,140 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrMissingParam is returned when a URL parameter is absent or empty.
+var ErrMissingParam = errors.New("missing parameter")
+
+// ErrInvalidParam is returned when a URL parameter cannot be parsed.
+var ErrInvalidParam = errors.New("invalid parameter")
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Wildcard returns the remainder of the path matched by a trailing * wildcard,
+// such as "a/b.txt" for /files/a/b.txt under /files/*.
+func Wildcard(r *http.Request) string {
+	return chi.URLParam(r, "*")
+}
+
+// URLParamInt returns the URL parameter as an integer. The error wraps
+// ErrMissingParam or ErrInvalidParam, naming the parameter.
+func URLParamInt(r *http.Request, key string) (int, error) {
+	value := URLParam(r, key)
+	if value == "" {
+		return 0, fmt.Errorf("URLParamInt: %s: %w", key, ErrMissingParam)
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("URLParamInt: %s must be an integer: %w", key, ErrInvalidParam)
+	}
+	return n, nil
+}
+
+// URLParamUUID returns the URL parameter as a UUID in its canonical, lower case
+// form. The error wraps ErrMissingParam or ErrInvalidParam, naming the parameter.
+func URLParamUUID(r *http.Request, key string) (string, error) {
+	value := URLParam(r, key)
+	if value == "" {
+		return "", fmt.Errorf("URLParamUUID: %s: %w", key, ErrMissingParam)
+	}
+	if !uuidPattern.MatchString(value) {
+		return "", fmt.Errorf("URLParamUUID: %s must be a UUID: %w", key, ErrInvalidParam)
+	}
+	return strings.ToLower(value), nil
+}
+
+// PathInt returns the path parameter as an integer, as URLParamInt does.
+func PathInt(r *http.Request, key string) (int, error) {
+	return URLParamInt(r, key)
+}
+
+// PathUUID returns the path parameter as a canonical UUID, as URLParamUUID does.
+func PathUUID(r *http.Request, key string) (string, error) {
+	return URLParamUUID(r, key)
+}
+
+// PathDate returns the path parameter as a date of the form 2006-01-02, at midnight
+// UTC. The error wraps ErrMissingParam or ErrInvalidParam, naming the parameter.
+func PathDate(r *http.Request, key string) (time.Time, error) {
+	value := URLParam(r, key)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("PathDate: %s: %w", key, ErrMissingParam)
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("PathDate: %s must be a date of the form YYYY-MM-DD: %w", key, ErrInvalidParam)
+	}
+	return t, nil
+}
+
+// paramPanic carries the error of a MustPath function to RecoverParams.
+type paramPanic struct {
+	err error
+}
+
+// MustPathInt returns the path parameter as an integer or, when it is invalid,
+// short-circuits the handler with 400 Bad Request through RecoverParams.
+func MustPathInt(r *http.Request, key string) int {
+	n, err := PathInt(r, key)
+	if err != nil {
+		panic(paramPanic{err})
+	}
+	return n
+}
+
+// MustPathUUID returns the path parameter as a canonical UUID or, when it is
+// invalid, short-circuits the handler with 400 Bad Request through RecoverParams.
+func MustPathUUID(r *http.Request, key string) string {
+	id, err := PathUUID(r, key)
+	if err != nil {
+		panic(paramPanic{err})
+	}
+	return id
+}
+
+// MustPathDate returns the path parameter as a date or, when it is invalid,
+// short-circuits the handler with 400 Bad Request through RecoverParams.
+func MustPathDate(r *http.Request, key string) time.Time {
+	t, err := PathDate(r, key)
+	if err != nil {
+		panic(paramPanic{err})
+	}
+	return t
+}
+
+// ParamError writes the 400 Bad Request problem response for an error returned by
+// the Path functions, detailing which parameter was invalid.
+func ParamError(w http.ResponseWriter, r *http.Request, err error) {
+	detail := strings.TrimSuffix(err.Error(), ": "+ErrInvalidParam.Error())
+	if _, after, ok := strings.Cut(detail, ": "); ok {
+		detail = after
+	}
+	Problem(w, r, http.StatusBadRequest, detail)
+}
+
+// RecoverParams answers handlers short-circuited by a MustPath function with
+// ParamError. Other panics are propagated. The server registers it on every route.
+func RecoverParams(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				p, ok := v.(paramPanic)
+				if !ok {
+					panic(v)
+				}
+				ParamError(w, r, p.err)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
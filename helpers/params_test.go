@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestURLParams(t *testing.T) {
+	var id int
+	var uuid, rest string
+	var idErr, uuidErr error
+
+	m := chi.NewRouter()
+	m.Get("/items/{id}/{uuid}/*", func(w http.ResponseWriter, r *http.Request) {
+		id, idErr = URLParamInt(r, "id")
+		uuid, uuidErr = URLParamUUID(r, "uuid")
+		rest = Wildcard(r)
+	})
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42/1B4E28BA-2FA1-11D2-883F-0016D3CCA427/a/b.txt", nil))
+	if idErr != nil || id != 42 {
+		t.Errorf("URLParamInt: got %d, %v", id, idErr)
+	}
+	if uuidErr != nil || uuid != "1b4e28ba-2fa1-11d2-883f-0016d3cca427" {
+		t.Errorf("URLParamUUID: got %q, %v", uuid, uuidErr)
+	}
+	if rest != "a/b.txt" {
+		t.Errorf("Wildcard: got %q", rest)
+	}
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/x/not-a-uuid/", nil))
+	if !errors.Is(idErr, ErrInvalidParam) || !errors.Is(uuidErr, ErrInvalidParam) {
+		t.Errorf("invalid params: got %v and %v", idErr, uuidErr)
+	}
+}
+
+func TestMustPath(t *testing.T) {
+	m := chi.NewRouter()
+	m.Use(RecoverParams)
+	m.Get("/reports/{day}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(MustPathDate(r, "day").Weekday().String()))
+	})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/2024-03-01", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "Friday" {
+		t.Errorf("valid date: got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reports/yesterday", nil))
+	want := `{"type":"about:blank","title":"Bad Request","status":400,"detail":"day must be a date of the form YYYY-MM-DD","instance":"/reports/yesterday"}`
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" || w.Body.String() != want {
+		t.Errorf("invalid date: got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,449 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrWeakPassword is wrapped by the errors of PasswordPolicy.Check.
+var ErrWeakPassword = errors.New("helpers: weak password")
+
+// PasswordError lists the reasons a password was rejected, each fit to be shown to
+// the user choosing it.
+type PasswordError struct {
+	Reasons []string
+}
+
+func (e *PasswordError) Error() string {
+	return "password " + strings.Join(e.Reasons, "; ")
+}
+
+func (e *PasswordError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// PasswordPolicy is a policy for passwords chosen by users. Rather than a list of
+// required symbols, which users satisfy predictably, it favours length and an
+// estimate of the guesses needed to crack the password, as NIST SP 800-63B does.
+type PasswordPolicy struct {
+	// MinLength and MaxLength bound the length of the password in characters.
+	// A MaxLength of 0 is unbounded.
+	MinLength int
+	MaxLength int
+	// MinClasses is the number of classes of characters which must be present:
+	// lowercase and uppercase letters, digits, and symbols.
+	MinClasses int
+	// RequireUpper, RequireLower, RequireDigit and RequireSymbol require a
+	// character of their class.
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinEntropy is the minimum estimated entropy in bits, from PasswordStrength.
+	MinEntropy float64
+	// Banned are refused passwords, compared case-insensitively, in addition to
+	// the common passwords when BanCommon is set.
+	Banned    []string
+	BanCommon bool
+}
+
+// DefaultPasswordPolicy accepts passwords of 10 to 128 characters, estimated at 40
+// bits or more, which are neither common passwords nor the user's inputs.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 10, MaxLength: 128, MinEntropy: 40, BanCommon: true}
+
+// Check returns a *PasswordError listing every reason the password fails the
+// policy, or nil. The user's inputs, such as their name or email address, are
+// treated as words an attacker would guess first, and may not be the password.
+func (p PasswordPolicy) Check(password string, userInputs ...string) error {
+	var reasons []string
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d characters", p.MaxLength))
+	}
+
+	classes := passwordClasses(password)
+	for _, req := range []struct {
+		required bool
+		class    int
+		reason   string
+	}{
+		{p.RequireLower, classLower, "must contain a lowercase letter"},
+		{p.RequireUpper, classUpper, "must contain an uppercase letter"},
+		{p.RequireDigit, classDigit, "must contain a digit"},
+		{p.RequireSymbol, classSymbol, "must contain a symbol"},
+	} {
+		if req.required && classes&req.class == 0 {
+			reasons = append(reasons, req.reason)
+		}
+	}
+	if count := bitCount(classes); count < p.MinClasses {
+		reasons = append(reasons, fmt.Sprintf("must mix at least %d of lowercase, uppercase, digits and symbols", p.MinClasses))
+	}
+
+	lower := strings.ToLower(password)
+	banned := false
+	for _, b := range p.Banned {
+		banned = banned || strings.EqualFold(b, password)
+	}
+	if _, common := commonPasswordRank[lower]; banned || (p.BanCommon && common) {
+		reasons = append(reasons, "is too common")
+	}
+	for _, input := range userInputs {
+		if input != "" && strings.EqualFold(input, password) {
+			reasons = append(reasons, "must not be your name or email address")
+			break
+		}
+	}
+
+	if p.MinEntropy > 0 {
+		if s := PasswordStrength(password, userInputs...); s.Entropy < p.MinEntropy {
+			reason := "is too easy to guess"
+			if s.Feedback != "" {
+				reason += ": " + s.Feedback
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordError{Reasons: reasons}
+	}
+	return nil
+}
+
+// ValidatePassword checks the password against DefaultPasswordPolicy, such as from
+// the Validate method of a request type.
+func ValidatePassword(password string, userInputs ...string) error {
+	return DefaultPasswordPolicy.Check(password, userInputs...)
+}
+
+// ValidatePasswords checks the string fields of the struct v points to which are
+// tagged `password`, listing the fields of the user's inputs, such as
+// `password:"Name,Email"`, against DefaultPasswordPolicy.
+func ValidatePasswords(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		tag, ok := rt.Field(i).Tag.Lookup("password")
+		if !ok || rv.Field(i).Kind() != reflect.String {
+			continue
+		}
+		var inputs []string
+		for _, name := range strings.Split(tag, ",") {
+			if f := rv.FieldByName(strings.TrimSpace(name)); f.IsValid() && f.Kind() == reflect.String {
+				inputs = append(inputs, f.String())
+			}
+		}
+		if err := ValidatePassword(rv.Field(i).String(), inputs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Strength is the estimated strength of a password.
+type Strength struct {
+	// Entropy is the estimated entropy in bits, the base 2 logarithm of the
+	// guesses needed to find the password.
+	Entropy float64
+	// Score rates the entropy from 0, guessed at once, to 4, out of reach.
+	Score int
+	// Feedback describes the weakest pattern found, such as "avoid sequences
+	// like abc or 123", or is empty.
+	Feedback string
+}
+
+// PasswordStrength estimates the strength of the password as zxcvbn does: the
+// password is split into the patterns an attacker guesses first, such as common
+// passwords and words, keyboard walks, sequences, repeats and years, and the
+// entropy is that of the cheapest split, rather than that of random characters.
+func PasswordStrength(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Strength{Feedback: "use a few words, avoiding common phrases"}
+	}
+	lower := []rune(strings.ToLower(password))
+	unleet := []rune(unleetReplacer.Replace(string(lower)))
+	pool := math.Log2(float64(passwordPool(passwordClasses(password), runes)))
+
+	dictionary := make(map[string]int, len(userInputs))
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			if len([]rune(part)) >= 3 {
+				dictionary[part] = 1
+			}
+		}
+	}
+
+	// best[i] is the entropy of the cheapest split of runes[:i].
+	best := make([]float64, len(runes)+1)
+	feedback := make([]string, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i], feedback[i] = best[i-1]+pool, feedback[i-1]
+		for j := 0; j < i; j++ {
+			bits, fb := patternEntropy(runes[j:i], lower[j:i], unleet[j:i], dictionary)
+			if bits >= 0 && best[j]+bits < best[i] {
+				best[i] = best[j] + bits
+				feedback[i] = fb
+				if fb == "" {
+					feedback[i] = feedback[j]
+				}
+			}
+		}
+	}
+
+	s := Strength{Entropy: best[len(runes)], Feedback: feedback[len(runes)]}
+	for _, threshold := range []float64{20, 30, 40, 60} {
+		if s.Entropy >= threshold {
+			s.Score++
+		}
+	}
+	if s.Score == 4 {
+		s.Feedback = ""
+	}
+	return s
+}
+
+// patternEntropy returns the entropy of the token as the cheapest pattern it
+// matches, and feedback about the pattern, or -1 if it matches none.
+func patternEntropy(token, lower, unleet []rune, dictionary map[string]int) (float64, string) {
+	n := len(token)
+	if n < 3 {
+		return -1, ""
+	}
+	bits, feedback := -1.0, ""
+	consider := func(b float64, fb string) {
+		if bits < 0 || b < bits {
+			bits, feedback = b, fb
+		}
+	}
+
+	if rank, ok := wordRank(string(lower), string(unleet), dictionary); ok {
+		b := math.Log2(float64(rank)) + capitalizationEntropy(token)
+		if string(unleet) != string(lower) {
+			b++
+		}
+		consider(b, "avoid common passwords and words, even with substitutions like @ for a")
+	}
+	if repeated(lower) {
+		consider(math.Log2(float64(charPool(lower[0])))+math.Log2(float64(n)), "avoid repeated characters like aaa")
+	}
+	if step := sequenceStep(lower); step != 0 {
+		b := math.Log2(float64(charPool(lower[0]))) + math.Log2(float64(n))
+		if step < 0 {
+			b++
+		}
+		consider(b, "avoid sequences like abc or 123")
+	}
+	if keyboardWalk(lower) {
+		consider(math.Log2(float64(len(keyboardRows)*20))+math.Log2(float64(n)), "avoid keyboard patterns like qwerty")
+	}
+	if n == 4 && (string(lower[:2]) == "19" || string(lower[:2]) == "20") && isDigits(lower) {
+		consider(math.Log2(200), "avoid years, which are easy to guess")
+	}
+	return bits, feedback
+}
+
+func wordRank(lower, unleet string, dictionary map[string]int) (int, bool) {
+	if _, ok := dictionary[lower]; ok {
+		return 1, true
+	}
+	for _, w := range []string{lower, unleet} {
+		if rank, ok := commonPasswordRank[w]; ok {
+			return rank, true
+		}
+		if rank, ok := commonWordRank[w]; ok {
+			return len(commonPasswords) + rank, true
+		}
+	}
+	return 0, false
+}
+
+// capitalizationEntropy returns the bits added by the capitals of the token: one
+// for a capital first or all capitals, and a bit per capital otherwise.
+func capitalizationEntropy(token []rune) float64 {
+	upper := 0
+	for _, r := range token {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case upper == len(token) || (upper == 1 && unicode.IsUpper(token[0])):
+		return 1
+	}
+	return float64(upper)
+}
+
+func repeated(token []rune) bool {
+	for _, r := range token[1:] {
+		if r != token[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// sequenceStep returns 1 or -1 for ascending or descending sequences of letters or
+// digits, such as abc or 321, and 0 otherwise.
+func sequenceStep(token []rune) int {
+	step := int(token[1] - token[0])
+	if step != 1 && step != -1 {
+		return 0
+	}
+	for i := 1; i < len(token); i++ {
+		if int(token[i]-token[i-1]) != step || charPool(token[i]) != charPool(token[0]) || charPool(token[0]) > 26 {
+			return 0
+		}
+	}
+	return step
+}
+
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./", "qazwsxedcrfvtgbyhnujmikolp"}
+
+func keyboardWalk(token []rune) bool {
+	s := string(token)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) || strings.Contains(reverse(row), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func isDigits(token []rune) bool {
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	classLower = 1 << iota
+	classUpper
+	classDigit
+	classSymbol
+)
+
+func passwordClasses(password string) int {
+	classes := 0
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes |= classLower
+		case unicode.IsUpper(r):
+			classes |= classUpper
+		case unicode.IsDigit(r):
+			classes |= classDigit
+		default:
+			classes |= classSymbol
+		}
+	}
+	return classes
+}
+
+func bitCount(n int) int {
+	count := 0
+	for ; n > 0; n &= n - 1 {
+		count++
+	}
+	return count
+}
+
+// passwordPool returns the number of characters a brute force attack on the
+// password would try for each of its characters.
+func passwordPool(classes int, runes []rune) int {
+	pool := 0
+	for class, size := range map[int]int{classLower: 26, classUpper: 26, classDigit: 10, classSymbol: 33} {
+		if classes&class != 0 {
+			pool += size
+		}
+	}
+	for _, r := range runes {
+		if r > unicode.MaxASCII {
+			return pool + 100
+		}
+	}
+	return pool
+}
+
+// charPool returns the size of the class of the character.
+func charPool(r rune) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return 10
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	}
+	return 33
+}
+
+var unleetReplacer = strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "6", "g", "1", "i", "!", "i", "0", "o", "5", "s", "$", "s", "7", "t", "+", "t", "2", "z")
+
+// commonPasswords are among the most used passwords, most used first.
+var commonPasswords = strings.Fields(`
+	123456 password 12345678 qwerty 123456789 12345 1234 111111 1234567 dragon
+	123123 baseball abc123 football monkey letmein 696969 shadow master 666666
+	qwertyuiop 123321 mustang 1234567890 michael 654321 superman 1qaz2wsx 7777777 121212
+	000000 qazwsx 123qwe killer trustno1 jordan jennifer zxcvbnm asdfgh hunter
+	buster soccer harley batman andrew tigger sunshine iloveyou 2000 charlie
+	robert thomas hockey ranger daniel starwars klaster 112233 george computer
+	michelle jessica pepper 1111 zxcvbn 555555 11111111 131313 freedom 777777
+	pass maggie 159753 aaaaaa ginger princess joshua cheese amanda summer
+	love ashley nicole chelsea biteme matthew access yankees 987654321 dallas
+	austin thunder taylor matrix welcome admin administrator passw0rd password1 qwerty123
+	welcome1 p@ssw0rd changeme secret letmein1 football1 iloveyou1 monkey1 login abcdef
+`)
+
+// commonWords are common words and names, which passwords are often built from.
+var commonWords = strings.Fields(`
+	love baby angel family friend forever flower happy heart honey lucky magic
+	money music orange purple secret silver smile spring star sugar summer sweet
+	winter yellow black blue green red white house horse dog cat tiger lion
+	eagle apple banana cherry coffee chocolate pizza beach ocean river mountain
+	dream hello world house light night power queen king prince rose super
+	company spring autumn welcome test user guest root admin server office
+	january february march april june july august september october november december
+	monday friday sunday london paris berlin john james david mary linda maria
+`)
+
+var commonPasswordRank, commonWordRank = ranks(commonPasswords), ranks(commonWords)
+
+func ranks(words []string) map[string]int {
+	m := make(map[string]int, len(words))
+	for i, w := range words {
+		if _, ok := m[w]; !ok {
+			m[w] = i + 1
+		}
+	}
+	return m
+}
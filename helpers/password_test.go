@@ -0,0 +1,87 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	for _, tc := range []struct {
+		password string
+		max      int
+		min      int
+	}{
+		{"password", 0, 0},
+		{"P@ssw0rd", 0, 0},
+		{"qwerty123", 0, 0},
+		{"aaaaaaaaaaaa", 0, 0},
+		{"abcdefgh1234", 1, 0},
+		{"Summer2019", 2, 0},
+		{"correct horse battery staple", 4, 4},
+		{"Tr0ub4dor&3", 4, 3},
+		{"x7#Kq!v9Lm2$", 4, 4},
+	} {
+		s := PasswordStrength(tc.password)
+		if s.Score < tc.min || s.Score > tc.max {
+			t.Errorf("PasswordStrength(%q) = %+v, want a score from %d to %d", tc.password, s, tc.min, tc.max)
+		}
+	}
+	if s := PasswordStrength("abcdefgh"); !strings.Contains(s.Feedback, "sequences") {
+		t.Fatalf("got feedback %q, want it to mention sequences", s.Feedback)
+	}
+	if PasswordStrength("jsmithjsmith", "J. Smith <jsmith@example.com>").Entropy >= PasswordStrength("jsmithjsmith").Entropy {
+		t.Fatal("user inputs did not weaken the password")
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	if err := ValidatePassword("correct horse battery staple"); err != nil {
+		t.Fatalf("ValidatePassword: %v", err)
+	}
+
+	err := ValidatePassword("Password1")
+	var pe *PasswordError
+	if !errors.As(err, &pe) || !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("got %v, want a PasswordError", err)
+	}
+	want := []string{"must be at least 10 characters", "is too common", "is too easy to guess"}
+	if len(pe.Reasons) != len(want) {
+		t.Fatalf("got reasons %q, want %q", pe.Reasons, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(pe.Reasons[i], want[i]) {
+			t.Fatalf("got reasons %q, want %q", pe.Reasons, want)
+		}
+	}
+
+	policy := PasswordPolicy{MinLength: 8, MinClasses: 3, RequireSymbol: true, Banned: []string{"Ramchi!2024"}}
+	if err := policy.Check("ramchi!2024"); err == nil || !strings.Contains(err.Error(), "is too common") {
+		t.Fatalf("got %v, want the banned password refused", err)
+	}
+	if err := policy.Check("lowercaseonly"); err == nil || !strings.Contains(err.Error(), "must contain a symbol; must mix at least 3") {
+		t.Fatalf("got %v, want the classes refused", err)
+	}
+	if err := policy.Check("jane@example.com", "jane@example.com"); err == nil || !strings.Contains(err.Error(), "must not be your name or email address") {
+		t.Fatalf("got %v, want the user input refused", err)
+	}
+}
+
+func TestValidatePasswords(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email"`
+		Password string `json:"password" password:"Email"`
+	}
+
+	if err := ValidatePasswords(&signup{Email: "alice@example.com", Password: "correct horse battery staple"}); err != nil {
+		t.Fatalf("got %v for a strong password", err)
+	}
+	err := ValidatePasswords(&signup{Email: "alice@example.com", Password: "alice@example.com"})
+	var pe *PasswordError
+	if !errors.As(err, &pe) || !strings.Contains(err.Error(), "must not be your name or email address") {
+		t.Fatalf("got %v, want the email refused", err)
+	}
+	if err := ValidatePasswords(&struct{ Password string }{"x"}); err != nil {
+		t.Fatalf("got %v for an untagged field", err)
+	}
+}
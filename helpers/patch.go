@@ -0,0 +1,436 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSONPatchType is the media type of RFC 6902 JSON Patch documents.
+	JSONPatchType = "application/json-patch+json"
+	// MergePatchType is the media type of RFC 7386 JSON Merge Patch documents.
+	MergePatchType = "application/merge-patch+json"
+)
+
+// maxPatchBody bounds the patch documents read by ApplyPatch.
+const maxPatchBody = 1 << 20
+
+var (
+	// ErrInvalidPatch is returned for patches which are malformed, or which cannot
+	// be applied, such as operations on paths which do not exist.
+	ErrInvalidPatch = errors.New("helpers: invalid patch")
+	// ErrPathNotAllowed is returned for patches changing a path outside those
+	// allowed.
+	ErrPathNotAllowed = errors.New("helpers: path not allowed")
+	// ErrPatchTestFailed is returned for JSON Patch documents whose test operation
+	// failed, leaving the target unchanged.
+	ErrPatchTestFailed = errors.New("helpers: patch test failed")
+	// ErrUnsupportedPatch is returned by ApplyPatch for requests whose body is
+	// neither a JSON Patch nor a JSON Merge Patch document.
+	ErrUnsupportedPatch = errors.New("helpers: unsupported patch type")
+)
+
+// PatchOperation is an operation of an RFC 6902 JSON Patch document.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyPatch applies the body of a PATCH request to dst, a pointer, as
+// ApplyJSONPatch does for JSONPatchType and ApplyMergePatch for MergePatchType or
+// application/json. Other media types return ErrUnsupportedPatch, so that the
+// caller may respond 415 Unsupported Media Type.
+func ApplyPatch(r *http.Request, dst interface{}, allowed ...string) error {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != JSONPatchType && mt != MergePatchType && mt != "application/json" {
+		return fmt.Errorf("ApplyPatch: %q: %w", mt, ErrUnsupportedPatch)
+	}
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBody+1))
+	if err != nil {
+		return fmt.Errorf("ApplyPatch: failed reading body: %w", err)
+	}
+	if len(patch) > maxPatchBody {
+		return fmt.Errorf("ApplyPatch: body exceeds %d bytes: %w", maxPatchBody, ErrInvalidPatch)
+	}
+	if mt == JSONPatchType {
+		return ApplyJSONPatch(dst, patch, allowed...)
+	}
+	return ApplyMergePatch(dst, patch, allowed...)
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch document to dst, changing only the
+// allowed paths, if any. On error, dst is unchanged.
+func ApplyJSONPatch(dst interface{}, patch []byte, allowed ...string) error {
+	var ops []PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("ApplyJSONPatch: %v: %w", err, ErrInvalidPatch)
+	}
+	for i, op := range ops {
+		paths := []string{op.Path}
+		switch op.Op {
+		case "test":
+			paths = nil
+		case "move":
+			paths = append(paths, op.From)
+		}
+		for _, path := range paths {
+			if err := allowPointer(path, allowed); err != nil {
+				return fmt.Errorf("ApplyJSONPatch: operation %d: %w", i, err)
+			}
+		}
+	}
+
+	doc, err := decodeTarget(dst)
+	if err != nil {
+		return fmt.Errorf("ApplyJSONPatch: %w", err)
+	}
+	for i, op := range ops {
+		if doc, err = applyOperation(doc, op); err != nil {
+			return fmt.Errorf("ApplyJSONPatch: operation %d: %w", i, err)
+		}
+	}
+	if err := encodeTarget(dst, doc); err != nil {
+		return fmt.Errorf("ApplyJSONPatch: %w", err)
+	}
+	return nil
+}
+
+// ApplyMergePatch applies an RFC 7386 JSON Merge Patch document to dst, changing
+// only the allowed paths, if any. On error, dst is unchanged.
+func ApplyMergePatch(dst interface{}, patch []byte, allowed ...string) error {
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return fmt.Errorf("ApplyMergePatch: %v: %w", err, ErrInvalidPatch)
+	}
+	doc, err := decodeTarget(dst)
+	if err != nil {
+		return fmt.Errorf("ApplyMergePatch: %w", err)
+	}
+	if len(allowed) > 0 {
+		for _, path := range mergePaths(doc, p, "") {
+			if !fieldAllowed(path, allowed) {
+				return fmt.Errorf("ApplyMergePatch: %q: %w", path, ErrPathNotAllowed)
+			}
+		}
+	}
+	if err := encodeTarget(dst, mergePatch(doc, p)); err != nil {
+		return fmt.Errorf("ApplyMergePatch: %w", err)
+	}
+	return nil
+}
+
+// mergePatch returns the target with the merge patch applied, as RFC 7386 defines.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+		} else {
+			t[name] = mergePatch(t[name], value)
+		}
+	}
+	return t
+}
+
+// mergePaths returns the sorted paths the merge patch replaces or removes in the
+// target, descending into the objects it merges.
+func mergePaths(target, patch interface{}, prefix string) []string {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return []string{prefix}
+	}
+	t, _ := target.(map[string]interface{})
+	var paths []string
+	for name, value := range p {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		_, merged := value.(map[string]interface{})
+		if _, isObject := t[name].(map[string]interface{}); merged && isObject {
+			paths = append(paths, mergePaths(t[name], value, path)...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// applyOperation returns the document with the JSON Patch operation applied.
+func applyOperation(doc interface{}, op PatchOperation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("%s lacks a value: %w", op.Op, ErrInvalidPatch)
+		}
+		if value, err = decodeJSON(op.Value); err != nil {
+			return nil, fmt.Errorf("%s value: %v: %w", op.Op, err, ErrInvalidPatch)
+		}
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = getPointer(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %q into itself: %w", op.From, ErrInvalidPatch)
+			}
+			if doc, err = removePointer(doc, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = copyJSON(value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return addPointer(doc, path, value)
+	case "remove":
+		return removePointer(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = removePointer(doc, path); err != nil {
+			return nil, err
+		}
+		return addPointer(doc, path, value)
+	case "test":
+		current, err := getPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%q: %w", op.Path, ErrPatchTestFailed)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q: %w", op.Op, ErrInvalidPatch)
+}
+
+// parsePointer returns the reference tokens of an RFC 6901 JSON Pointer, nil for
+// the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%q is not a JSON pointer: %w", pointer, ErrInvalidPatch)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// allowPointer returns an error if the allowed paths do not include the path of
+// the JSON pointer.
+func allowPointer(pointer string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	if path := strings.Join(tokens, "."); len(tokens) == 0 || !fieldAllowed(path, allowed) {
+		return fmt.Errorf("%q: %w", pointer, ErrPathNotAllowed)
+	}
+	return nil
+}
+
+func getPointer(doc interface{}, tokens []string) (interface{}, error) {
+	node := doc
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist: %w", token, ErrInvalidPatch)
+			}
+			node = child
+		case []interface{}:
+			i, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%q is not in an object or array: %w", token, ErrInvalidPatch)
+		}
+	}
+	return node, nil
+}
+
+func addPointer(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return updateParent(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[token] = value
+			return p, nil
+		case []interface{}:
+			if token == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, fmt.Errorf("%q is not in an object or array: %w", token, ErrInvalidPatch)
+	})
+}
+
+func removePointer(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document: %w", ErrInvalidPatch)
+	}
+	return updateParent(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[token]; !ok {
+				return nil, fmt.Errorf("member %q does not exist: %w", token, ErrInvalidPatch)
+			}
+			delete(p, token)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(p)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%q is not in an object or array: %w", token, ErrInvalidPatch)
+	})
+}
+
+// updateParent replaces the parent of the last token with the result of fn, so
+// that arrays which grow or shrink are stored back in their own parents.
+func updateParent(node interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(node, tokens[0])
+	}
+	child, err := getPointer(node, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := updateParent(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[tokens[0]] = updated
+	case []interface{}:
+		i, _ := strconv.Atoi(tokens[0])
+		n[i] = updated
+	}
+	return node, nil
+}
+
+// arrayIndex parses the array index of a token, which must be at most last.
+func arrayIndex(token string, last int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > last || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%q is not an index of the array: %w", token, ErrInvalidPatch)
+	}
+	return i, nil
+}
+
+// decodeTarget returns dst, a pointer, as the document encoding/json encodes.
+func decodeTarget(dst interface{}) (interface{}, error) {
+	if rv := reflect.ValueOf(dst); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, fmt.Errorf("%T is not a pointer", dst)
+	}
+	b, err := json.Marshal(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding target: %w", err)
+	}
+	return decodeJSON(b)
+}
+
+// encodeTarget decodes the patched document into a copy of dst whose fields
+// encoded in JSON are reset, so that members removed by the patch are reset while
+// fields hidden from JSON are kept, rejecting members dst has no field for.
+func encodeTarget(dst interface{}, doc interface{}) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed encoding patched document: %w", err)
+	}
+	target := reflect.ValueOf(dst).Elem()
+	patched := reflect.New(target.Type())
+	if target.Kind() == reflect.Struct {
+		patched.Elem().Set(target)
+		for i := 0; i < target.NumField(); i++ {
+			if _, _, ok := jsonField(target.Type().Field(i)); ok {
+				patched.Elem().Field(i).SetZero()
+			}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(patched.Interface()); err != nil {
+		return fmt.Errorf("patched document: %v: %w", err, ErrInvalidPatch)
+	}
+	target.Set(patched.Elem())
+	return nil
+}
+
+// decodeJSON decodes a JSON value, keeping the precision of numbers.
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return v, nil
+}
+
+// copyJSON returns a deep copy of a decoded JSON value.
+func copyJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b)
+}
@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type patchPost struct {
+	ID     int          `json:"-"`
+	Title  string       `json:"title"`
+	Tags   []string     `json:"tags"`
+	Author *patchAuthor `json:"author,omitempty"`
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	post := patchPost{ID: 7, Title: "a", Tags: []string{"x", "y"}, Author: &patchAuthor{Name: "b", Email: "c"}}
+	patch := `[
+		{"op": "test", "path": "/title", "value": "a"},
+		{"op": "replace", "path": "/title", "value": "b"},
+		{"op": "add", "path": "/tags/1", "value": "z"},
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "add", "path": "/tags/-", "value": "w"},
+		{"op": "remove", "path": "/author/email"}
+	]`
+	if err := ApplyJSONPatch(&post, []byte(patch), "title", "tags", "author.email"); err != nil {
+		t.Fatalf("ApplyJSONPatch: %v", err)
+	}
+	want := patchPost{ID: 7, Title: "b", Tags: []string{"z", "y", "w"}, Author: &patchAuthor{Name: "b"}}
+	if !reflect.DeepEqual(post, want) {
+		t.Fatalf("got %+v, want %+v", post, want)
+	}
+
+	for patch, want := range map[string]error{
+		`[{"op": "replace", "path": "/author/name", "value": "c"}]`:  ErrPathNotAllowed,
+		`[{"op": "move", "from": "/author/name", "path": "/title"}]`: ErrPathNotAllowed,
+		`[{"op": "test", "path": "/title", "value": "a"}]`:           ErrPatchTestFailed,
+		`[{"op": "remove", "path": "/tags/9"}]`:                      ErrInvalidPatch,
+		`[{"op": "add", "path": "/unknown", "value": 1}]`:            ErrPathNotAllowed,
+		`[{"op": "frobnicate", "path": "/title"}]`:                   ErrInvalidPatch,
+	} {
+		if err := ApplyJSONPatch(&post, []byte(patch), "title", "tags"); !errors.Is(err, want) {
+			t.Fatalf("ApplyJSONPatch(%s) = %v, want %v", patch, err, want)
+		}
+	}
+	if err := ApplyJSONPatch(&post, []byte(`[{"op": "add", "path": "/unknown", "value": 1}]`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Fatalf("added an unknown member: %v", err)
+	}
+	if !reflect.DeepEqual(post, want) {
+		t.Fatalf("failed patches changed the target: %+v", post)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	post := patchPost{ID: 7, Title: "a", Tags: []string{"x"}, Author: &patchAuthor{Name: "b", Email: "c"}}
+	if err := ApplyMergePatch(&post, []byte(`{"title": "b", "author": {"email": null}}`), "title", "author.email"); err != nil {
+		t.Fatalf("ApplyMergePatch: %v", err)
+	}
+	want := patchPost{ID: 7, Title: "b", Tags: []string{"x"}, Author: &patchAuthor{Name: "b"}}
+	if !reflect.DeepEqual(post, want) {
+		t.Fatalf("got %+v, want %+v", post, want)
+	}
+	if err := ApplyMergePatch(&post, []byte(`{"author": {"name": "c"}}`), "title", "author.email"); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("changed a path not allowed: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPatch, "/posts/7", strings.NewReader(`{"tags": null}`))
+	r.Header.Set("Content-Type", MergePatchType)
+	if err := ApplyPatch(r, &post); err != nil || post.Tags != nil {
+		t.Fatalf("ApplyPatch = %v, tags %v", err, post.Tags)
+	}
+	r = httptest.NewRequest(http.MethodPatch, "/posts/7", strings.NewReader(`title=c`))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := ApplyPatch(r, &post); !errors.Is(err, ErrUnsupportedPatch) {
+		t.Fatalf("ApplyPatch of a form = %v, want ErrUnsupportedPatch", err)
+	}
+}
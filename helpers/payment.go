@@ -0,0 +1,212 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// The helpers of this file validate and mask card numbers (PANs) and IBANs. They
+// never retain what they are given, and neither should their callers: PANs must not
+// be logged or stored unless the system is PCI DSS compliant, and should be handed
+// to a payment provider and replaced by its token. Log MaskPAN, never the PAN; the
+// errors of ParsePAN never include it.
+
+// ErrInvalidPAN is returned by ParsePAN for numbers which are not card numbers.
+var ErrInvalidPAN = errors.New("helpers: invalid card number")
+
+// ErrInvalidIBAN is returned by ParseIBAN for numbers which are not IBANs.
+var ErrInvalidIBAN = errors.New("helpers: invalid iban")
+
+// The card brands detected by CardBrand.
+const (
+	BrandVisa       = "visa"
+	BrandMastercard = "mastercard"
+	BrandAmex       = "amex"
+	BrandDiscover   = "discover"
+	BrandDiners     = "diners"
+	BrandJCB        = "jcb"
+	BrandUnionPay   = "unionpay"
+	BrandMaestro    = "maestro"
+	BrandUnknown    = "unknown"
+)
+
+type cardRange struct {
+	brand   string
+	low     int
+	high    int
+	digits  int
+	lengths []int
+}
+
+// cardRanges are the issuer identification number ranges of the brands, matched in
+// order against the leading digits of a card number.
+var cardRanges = []cardRange{
+	{BrandAmex, 34, 34, 2, []int{15}},
+	{BrandAmex, 37, 37, 2, []int{15}},
+	{BrandDiners, 300, 305, 3, []int{14, 16, 17, 18, 19}},
+	{BrandDiners, 36, 36, 2, []int{14, 16, 17, 18, 19}},
+	{BrandDiners, 38, 39, 2, []int{14, 16, 17, 18, 19}},
+	{BrandDiscover, 6011, 6011, 4, []int{16, 17, 18, 19}},
+	{BrandDiscover, 644, 649, 3, []int{16, 17, 18, 19}},
+	{BrandDiscover, 65, 65, 2, []int{16, 17, 18, 19}},
+	{BrandJCB, 3528, 3589, 4, []int{16, 17, 18, 19}},
+	{BrandMastercard, 2221, 2720, 4, []int{16}},
+	{BrandMastercard, 51, 55, 2, []int{16}},
+	{BrandUnionPay, 62, 62, 2, []int{16, 17, 18, 19}},
+	{BrandMaestro, 50, 50, 2, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandMaestro, 56, 58, 2, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandMaestro, 67, 69, 2, []int{12, 13, 14, 15, 16, 17, 18, 19}},
+	{BrandVisa, 4, 4, 1, []int{13, 16, 19}},
+}
+
+// Luhn returns whether the digits pass the Luhn checksum of card numbers.
+func Luhn(digits string) bool {
+	if digits == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// CardBrand returns the brand of the card number, by its leading digits and length,
+// or BrandUnknown.
+func CardBrand(pan string) string {
+	digits := panDigits(pan)
+	for _, r := range cardRanges {
+		if len(digits) < r.digits {
+			continue
+		}
+		prefix := 0
+		for _, d := range digits[:r.digits] {
+			prefix = prefix*10 + int(d-'0')
+		}
+		if prefix < r.low || prefix > r.high {
+			continue
+		}
+		for _, n := range r.lengths {
+			if len(digits) == n {
+				return r.brand
+			}
+		}
+	}
+	return BrandUnknown
+}
+
+// ParsePAN returns the digits of the card number, ignoring spaces and hyphens, and
+// its brand, checking its length and Luhn checksum. Numbers of unknown brands are
+// accepted if they are 12 to 19 digits long.
+func ParsePAN(pan string) (string, string, error) {
+	digits := panDigits(pan)
+	if len(digits) != len(strings.NewReplacer(" ", "", "-", "").Replace(pan)) {
+		return "", "", fmt.Errorf("ParsePAN: %w: not a number", ErrInvalidPAN)
+	}
+	if len(digits) < 12 || len(digits) > 19 {
+		return "", "", fmt.Errorf("ParsePAN: %w: %d digits", ErrInvalidPAN, len(digits))
+	}
+	if !Luhn(digits) {
+		return "", "", fmt.Errorf("ParsePAN: %w: failed checksum", ErrInvalidPAN)
+	}
+	return digits, CardBrand(digits), nil
+}
+
+// MaskPAN masks the card number but for its last four digits, which PCI DSS allows
+// displaying, such as "************1111", so that it is safe to log.
+func MaskPAN(pan string) string {
+	digits := panDigits(pan)
+	if len(digits) < 12 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+}
+
+func panDigits(pan string) string {
+	var b strings.Builder
+	for _, r := range pan {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ibanLengths are the lengths of the IBANs of each country.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+	"BH": 22, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18, "FO": 18, "FR": 27,
+	"GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+	"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24,
+	"ME": 22, "MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24,
+	"PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24, "SC": 31,
+	"SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28, "TL": 23, "TN": 24,
+	"TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// ParseIBAN returns the IBAN in its electronic format, uppercased without spaces,
+// checking its country's length and its ISO 13616 mod-97 checksum.
+func ParseIBAN(iban string) (string, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(iban), " ", ""))
+	if len(s) < 5 {
+		return "", fmt.Errorf("ParseIBAN: %w: too short", ErrInvalidIBAN)
+	}
+	if n, ok := ibanLengths[s[:2]]; !ok || len(s) != n {
+		return "", fmt.Errorf("ParseIBAN: %w: invalid country or length", ErrInvalidIBAN)
+	}
+
+	// The country and check digits are moved to the end, and letters replaced by
+	// 10 to 35, giving a number whose remainder by 97 is 1.
+	var b strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&b, "%d", r-'A'+10)
+		default:
+			return "", fmt.Errorf("ParseIBAN: %w: invalid character", ErrInvalidIBAN)
+		}
+	}
+	n, _ := new(big.Int).SetString(b.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return "", fmt.Errorf("ParseIBAN: %w: failed checksum", ErrInvalidIBAN)
+	}
+	return s, nil
+}
+
+// FormatIBAN returns the IBAN in its print format, in groups of four.
+func FormatIBAN(iban string) string {
+	s := strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	var groups []string
+	for len(s) > 4 {
+		groups = append(groups, s[:4])
+		s = s[4:]
+	}
+	return strings.Join(append(groups, s), " ")
+}
+
+// MaskIBAN masks the IBAN but for its country and check digits and last four
+// characters, such as "GB82**************5432".
+func MaskIBAN(iban string) string {
+	s := strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	if len(s) < 12 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:4] + strings.Repeat("*", len(s)-8) + s[len(s)-4:]
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePAN(t *testing.T) {
+	for pan, brand := range map[string]string{
+		"4111 1111 1111 1111": BrandVisa,
+		"5555-5555-5555-4444": BrandMastercard,
+		"2223003122003222":    BrandMastercard,
+		"378282246310005":     BrandAmex,
+		"6011111111111117":    BrandDiscover,
+		"3530111333300000":    BrandJCB,
+		"30569309025904":      BrandDiners,
+	} {
+		digits, got, err := ParsePAN(pan)
+		if err != nil || got != brand {
+			t.Fatalf("ParsePAN(%q) = %q, %v, want %q", pan, got, err, brand)
+		}
+		if masked := MaskPAN(pan); masked[len(masked)-4:] != digits[len(digits)-4:] || len(masked) != len(digits) {
+			t.Fatalf("MaskPAN(%q) = %q", pan, masked)
+		}
+	}
+	for _, pan := range []string{"4111 1111 1111 1112", "4111", "4111-1111-1111-111a"} {
+		if _, _, err := ParsePAN(pan); !errors.Is(err, ErrInvalidPAN) {
+			t.Fatalf("ParsePAN(%q) = %v, want ErrInvalidPAN", pan, err)
+		}
+	}
+	if got := MaskPAN("4111 1111 1111 1111"); got != "************1111" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestParseIBAN(t *testing.T) {
+	for _, iban := range []string{"GB82 WEST 1234 5698 7654 32", "de89370400440532013000", "NO9386011117947"} {
+		if _, err := ParseIBAN(iban); err != nil {
+			t.Fatalf("ParseIBAN(%q): %v", iban, err)
+		}
+	}
+	for _, iban := range []string{"GB82 WEST 1234 5698 7654 33", "GB82 WEST 1234 5698 7654", "XX82WEST12345698765432", "GB82-WEST"} {
+		if _, err := ParseIBAN(iban); !errors.Is(err, ErrInvalidIBAN) {
+			t.Fatalf("ParseIBAN(%q) = %v, want ErrInvalidIBAN", iban, err)
+		}
+	}
+	if got := FormatIBAN("GB82WEST12345698765432"); got != "GB82 WEST 1234 5698 7654 32" {
+		t.Fatalf("got %q", got)
+	}
+	if got := MaskIBAN("GB82 WEST 1234 5698 7654 32"); got != "GB82**************5432" {
+		t.Fatalf("got %q", got)
+	}
+}
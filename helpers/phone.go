@@ -0,0 +1,177 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPhone is returned by ParsePhone for numbers which are not valid phone
+// numbers.
+var ErrInvalidPhone = errors.New("helpers: invalid phone number")
+
+// Phone is a phone number split into its country calling code and its national
+// significant number, such as 44 and 2079460958.
+type Phone struct {
+	CountryCode int
+	Number      string
+}
+
+// E164 returns the number in the E.164 format, such as +442079460958.
+func (p Phone) E164() string {
+	return "+" + strconv.Itoa(p.CountryCode) + p.Number
+}
+
+// String returns the number in the E.164 format, with its country code spaced, such
+// as +44 2079460958.
+func (p Phone) String() string {
+	return "+" + strconv.Itoa(p.CountryCode) + " " + p.Number
+}
+
+type phoneRegion struct {
+	code  int
+	trunk string
+}
+
+// phoneRegions maps regions, as ISO 3166-1 alpha-2 codes, to their country calling
+// code and the trunk prefix dialled before national numbers.
+var phoneRegions = map[string]phoneRegion{
+	"AE": {971, "0"}, "AR": {54, "0"}, "AT": {43, "0"}, "AU": {61, "0"}, "BE": {32, "0"},
+	"BR": {55, "0"}, "CA": {1, "1"}, "CH": {41, "0"}, "CL": {56, ""}, "CN": {86, "0"},
+	"CO": {57, ""}, "CZ": {420, ""}, "DE": {49, "0"}, "DK": {45, ""}, "EG": {20, "0"},
+	"ES": {34, ""}, "FI": {358, "0"}, "FR": {33, "0"}, "GB": {44, "0"}, "GR": {30, ""},
+	"HK": {852, ""}, "ID": {62, "0"}, "IE": {353, "0"}, "IL": {972, "0"}, "IN": {91, "0"},
+	"IT": {39, ""}, "JP": {81, "0"}, "KE": {254, "0"}, "KR": {82, "0"}, "MX": {52, ""},
+	"MY": {60, "0"}, "NG": {234, "0"}, "NL": {31, "0"}, "NO": {47, ""}, "NZ": {64, "0"},
+	"PH": {63, "0"}, "PK": {92, "0"}, "PL": {48, ""}, "PT": {351, ""}, "RU": {7, "8"},
+	"SA": {966, "0"}, "SE": {46, "0"}, "SG": {65, ""}, "TH": {66, "0"}, "TR": {90, "0"},
+	"TW": {886, "0"}, "UA": {380, "0"}, "US": {1, "1"}, "VN": {84, "0"}, "ZA": {27, "0"},
+}
+
+// twoDigitCodes are the country calling codes of two digits. Codes beginning with 1
+// or 7 have one digit, and others three, as calling codes are prefix-free.
+var twoDigitCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true, "36": true,
+	"39": true, "40": true, "41": true, "43": true, "44": true, "45": true, "46": true, "47": true,
+	"48": true, "49": true, "51": true, "52": true, "53": true, "54": true, "55": true, "56": true,
+	"57": true, "58": true, "60": true, "61": true, "62": true, "63": true, "64": true, "65": true,
+	"66": true, "81": true, "82": true, "84": true, "86": true, "90": true, "91": true, "92": true,
+	"93": true, "94": true, "95": true, "98": true,
+}
+
+// phoneLengths bounds the lengths of national significant numbers of the country
+// calling codes whose plans are well known; others are bounded by E.164 alone.
+var phoneLengths = map[int][2]int{
+	1: {10, 10}, 7: {10, 10}, 33: {9, 9}, 34: {9, 9}, 39: {6, 11}, 44: {9, 10},
+	49: {6, 13}, 61: {9, 9}, 81: {9, 10}, 86: {10, 11}, 91: {10, 10},
+}
+
+// ParsePhone parses a phone number in international format, such as
+// "+44 20 7946 0958" or "0044 20 7946 0958", or in the national format of the
+// region, such as "020 7946 0958" for "GB". Spaces, dots, hyphens and parentheses
+// are ignored. The region may be empty for numbers in international format.
+func ParsePhone(number string, region string) (Phone, error) {
+	digits, international, err := phoneDigits(number)
+	if err != nil {
+		return Phone{}, err
+	}
+
+	var p Phone
+	if international {
+		if len(digits) < 4 {
+			return Phone{}, fmt.Errorf("ParsePhone: %w: %q is too short", ErrInvalidPhone, number)
+		}
+		code := digits[:3]
+		switch {
+		case digits[0] == '1' || digits[0] == '7':
+			code = digits[:1]
+		case twoDigitCodes[digits[:2]]:
+			code = digits[:2]
+		case digits[0] == '0':
+			return Phone{}, fmt.Errorf("ParsePhone: %w: %q has no country code", ErrInvalidPhone, number)
+		}
+		p.CountryCode, _ = strconv.Atoi(code)
+		p.Number = digits[len(code):]
+	} else {
+		r, ok := phoneRegions[strings.ToUpper(region)]
+		if !ok {
+			return Phone{}, fmt.Errorf("ParsePhone: %w: %q is not international, and region %q is unknown", ErrInvalidPhone, number, region)
+		}
+		p.CountryCode = r.code
+		p.Number = digits
+		if r.trunk != "" && strings.HasPrefix(digits, r.trunk) {
+			p.Number = digits[len(r.trunk):]
+		}
+	}
+
+	if !validPhone(p) {
+		return Phone{}, fmt.Errorf("ParsePhone: %w: %q has an invalid length", ErrInvalidPhone, number)
+	}
+	return p, nil
+}
+
+// NormalizePhone returns the number, in international format or the national format
+// of the region, in the E.164 format.
+func NormalizePhone(number string, region string) (string, error) {
+	p, err := ParsePhone(number, region)
+	if err != nil {
+		return "", fmt.Errorf("NormalizePhone: %w", err)
+	}
+	return p.E164(), nil
+}
+
+// ValidE164 returns whether s is a phone number in the E.164 format, such as
+// +442079460958, without spacing.
+func ValidE164(s string) bool {
+	if !strings.HasPrefix(s, "+") || strings.ContainsAny(s, " .-()") {
+		return false
+	}
+	p, err := ParsePhone(s, "")
+	return err == nil && p.E164() == s
+}
+
+// phoneDigits returns the digits of the number, and whether it was in international
+// format, with a leading + or 00.
+func phoneDigits(number string) (string, bool, error) {
+	s := strings.TrimSpace(number)
+	international := strings.HasPrefix(s, "+")
+	if international {
+		s = s[1:]
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '.' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", false, fmt.Errorf("phoneDigits: %w: %q contains %q", ErrInvalidPhone, number, r)
+		}
+	}
+	digits := b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits, international = digits[2:], true
+	}
+	if digits == "" {
+		return "", false, fmt.Errorf("phoneDigits: %w: %q has no digits", ErrInvalidPhone, number)
+	}
+	return digits, international, nil
+}
+
+// validPhone returns whether the number fits E.164, of at most 15 digits, and the
+// numbering plan of its country code where known.
+func validPhone(p Phone) bool {
+	n := len(p.Number)
+	if n < 4 || len(strconv.Itoa(p.CountryCode))+n > 15 || p.Number[0] == '0' && p.CountryCode != 39 {
+		return false
+	}
+	if bounds, ok := phoneLengths[p.CountryCode]; ok && (n < bounds[0] || n > bounds[1]) {
+		return false
+	}
+	// North American area codes and exchanges do not begin with 0 or 1.
+	if p.CountryCode == 1 && (p.Number[0] < '2' || p.Number[3] < '2') {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhone(t *testing.T) {
+	for _, tc := range []struct {
+		number, region, want string
+	}{
+		{"+44 20 7946 0958", "", "+442079460958"},
+		{"0044 (20) 7946-0958", "", "+442079460958"},
+		{"020 7946 0958", "gb", "+442079460958"},
+		{"(415) 555-2671", "US", "+14155552671"},
+		{"1 415 555 2671", "US", "+14155552671"},
+		{"06 12 34 56 78", "FR", "+33612345678"},
+		{"06 1234 5678", "IT", "+390612345678"},
+		{"+353 1 234 5678", "", "+35312345678"},
+		{"8 912 345-67-89", "RU", "+79123456789"},
+	} {
+		got, err := NormalizePhone(tc.number, tc.region)
+		if err != nil || got != tc.want {
+			t.Fatalf("NormalizePhone(%q, %q) = %q, %v, want %q", tc.number, tc.region, got, err, tc.want)
+		}
+	}
+
+	for _, tc := range []struct{ number, region string }{
+		{"+1 015 555 2671", ""},
+		{"+44 20 7946", ""},
+		{"020 7946 0958", ""},
+		{"+44 20 7946 095x", ""},
+		{"+0 123 4567", ""},
+		{"+12", ""},
+	} {
+		if _, err := ParsePhone(tc.number, tc.region); !errors.Is(err, ErrInvalidPhone) {
+			t.Fatalf("ParsePhone(%q, %q) = %v, want ErrInvalidPhone", tc.number, tc.region, err)
+		}
+	}
+
+	p, _ := ParsePhone("+442079460958", "")
+	if p.CountryCode != 44 || p.Number != "2079460958" || p.String() != "+44 2079460958" {
+		t.Fatalf("got %+v", p)
+	}
+	if !ValidE164("+442079460958") || ValidE164("+44 2079460958") || ValidE164("02079460958") {
+		t.Fatal("ValidE164 misjudged")
+	}
+}
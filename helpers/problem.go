@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemDetails is a problem details response of RFC 9457.
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// Problem writes a problem details response with the status and detail, typed
+// about:blank so that the title is the status text. In envelope mode it is written
+// as the error member of an Envelope instead.
+func Problem(w http.ResponseWriter, r *http.Request, code int, detail string) {
+	if EnvelopeFromContext(r.Context()) != nil {
+		EnvelopeJSONError(w, r, &EnvelopeError{Status: code, Message: detail})
+		return
+	}
+	body, err := json.Marshal(ProblemDetails{Type: "about:blank", Title: http.StatusText(code), Status: code, Detail: detail, Instance: r.URL.Path})
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
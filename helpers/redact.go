@@ -0,0 +1,194 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	// RedactMask replaces a value with asterisks, keeping the last four characters of long values.
+	RedactMask = "mask"
+	// RedactHash replaces a value with a truncated SHA-256 digest, so that it can still be correlated.
+	RedactHash = "hash"
+	// RedactDrop removes a value entirely.
+	RedactDrop = "drop"
+)
+
+// DefaultRedactFields maps the keys redacted by SanitizeJSON, compared case-insensitively, to how they are redacted.
+var DefaultRedactFields = map[string]string{
+	"password":      RedactDrop,
+	"secret":        RedactDrop,
+	"token":         RedactMask,
+	"access_token":  RedactMask,
+	"refresh_token": RedactMask,
+	"apikey":        RedactMask,
+	"api_key":       RedactMask,
+	"authorization": RedactMask,
+	"email":         RedactHash,
+}
+
+// DefaultRedactHeaders lists the headers masked by SanitizeHeaders.
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Mask replaces s with asterisks, keeping its last four characters when it is at least twelve long.
+func Mask(s string) string {
+	r := []rune(s)
+	if len(r) < 12 {
+		return "****"
+	}
+	return strings.Repeat("*", len(r)-4) + string(r[len(r)-4:])
+}
+
+// Hash replaces s with a truncated SHA-256 digest.
+func Hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Redact returns v converted to its JSON representation, as maps, slices and
+// scalars, with struct fields tagged redact:"mask|hash|drop" redacted.
+// It is intended for logging values which may contain personal data.
+func Redact(v interface{}) interface{} {
+	return redactValue(reflect.ValueOf(v))
+}
+
+func redactValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if _, ok := v.Interface().(json.Marshaler); ok {
+			return v.Interface()
+		}
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, omitempty, ok := jsonField(f)
+			if !ok || (omitempty && v.Field(i).IsZero()) {
+				continue
+			}
+			switch f.Tag.Get("redact") {
+			case RedactDrop:
+				continue
+			case RedactMask:
+				out[name] = Mask(fmt.Sprint(redactValue(v.Field(i))))
+			case RedactHash:
+				out[name] = Hash(fmt.Sprint(redactValue(v.Field(i))))
+			default:
+				out[name] = redactValue(v.Field(i))
+			}
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redactValue(iter.Value())
+		}
+		return out
+	case reflect.Invalid:
+		return nil
+	}
+	return v.Interface()
+}
+
+// jsonField returns the name a struct field is encoded under, whether it is
+// omitted when empty, and whether it is encoded at all.
+func jsonField(f reflect.StructField) (string, bool, bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, strings.Contains(opts, "omitempty"), true
+}
+
+// SanitizeJSON redacts the members of a JSON document whose keys appear in
+// fields, at any depth. Documents which cannot be parsed are replaced entirely,
+// as they cannot be shown to be safe. A nil fields uses DefaultRedactFields.
+func SanitizeJSON(body []byte, fields map[string]string) []byte {
+	if fields == nil {
+		fields = DefaultRedactFields
+	}
+	lower := make(map[string]string, len(fields))
+	for k, mode := range fields {
+		lower[strings.ToLower(k)] = mode
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return []byte(`"[unparseable body redacted]"`)
+	}
+	out, err := json.Marshal(sanitize(doc, lower))
+	if err != nil {
+		return []byte(`"[unparseable body redacted]"`)
+	}
+	return out
+}
+
+func sanitize(v interface{}, fields map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			switch fields[strings.ToLower(k)] {
+			case RedactDrop:
+				delete(v, k)
+			case RedactMask:
+				v[k] = Mask(fmt.Sprint(child))
+			case RedactHash:
+				v[k] = Hash(fmt.Sprint(child))
+			default:
+				v[k] = sanitize(child, fields)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = sanitize(child, fields)
+		}
+	}
+	return v
+}
+
+// SanitizeHeaders returns a copy of h with the DefaultRedactHeaders masked.
+func SanitizeHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range DefaultRedactHeaders {
+		if values := out.Values(name); len(values) > 0 {
+			masked := make([]string, len(values))
+			for i, v := range values {
+				masked[i] = Mask(v)
+			}
+			out[http.CanonicalHeaderKey(name)] = masked
+		}
+	}
+	return out
+}
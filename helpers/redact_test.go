@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	type account struct {
+		ID       int    `json:"id"`
+		Email    string `json:"email" redact:"hash"`
+		Card     string `json:"card" redact:"mask"`
+		Password string `json:"password" redact:"drop"`
+	}
+
+	out, err := json.Marshal(Redact(&account{ID: 1, Email: "a@example.com", Card: "4111111111111111", Password: "hunter2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"card":"************1111","email":"` + Hash("a@example.com") + `","id":1}`; string(out) != expected {
+		t.Fatalf("unexpected redaction: %s", out)
+	}
+}
+
+func TestSanitizeJSON(t *testing.T) {
+	out := SanitizeJSON([]byte(`{"user":{"name":"a","Password":"hunter2"},"token":"abc"}`), nil)
+	if string(out) != `{"token":"****","user":{"name":"a"}}` {
+		t.Fatalf("unexpected sanitization: %s", out)
+	}
+
+	if out := SanitizeJSON([]byte(`password=hunter2`), nil); string(out) != `"[unparseable body redacted]"` {
+		t.Fatalf("unexpected sanitization of non-json body: %s", out)
+	}
+}
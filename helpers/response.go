@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSON writes v as a JSON response with the status code.
+// In envelope mode, v is written as the data member of an Envelope.
+func JSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	if state := EnvelopeFromContext(r.Context()); state != nil {
+		state.Written = true
+		v = Envelope{Data: v, Meta: state.Meta}
+	}
+	writeJSON(w, code, v)
+}
+
+// JSONError writes an error response with the status code and message.
+// In envelope mode, it is written as the error member of an Envelope.
+func JSONError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	EnvelopeJSONError(w, r, &EnvelopeError{Status: code, Message: message})
+}
+
+// EnvelopeJSONError writes e as an error response. In envelope mode it is written
+// as the error member of an Envelope, otherwise as {"error": message}.
+func EnvelopeJSONError(w http.ResponseWriter, r *http.Request, e *EnvelopeError) {
+	if state := EnvelopeFromContext(r.Context()); state != nil {
+		state.Written = true
+		writeJSON(w, e.Status, Envelope{Error: e, Meta: state.Meta})
+		return
+	}
+	writeJSON(w, e.Status, map[string]string{"error": e.Message})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+// RetryPolicy configures the retrying of an operation.
+type RetryPolicy struct {
+	// Name identifies the operation in metrics.
+	Name string
+	// Attempts is the maximum number of attempts, including the first.
+	Attempts int
+	// Base and Max bound the backoff, which doubles with each retry and is
+	// jittered uniformly up to the doubled value.
+	Base time.Duration
+	Max  time.Duration
+	// Retryable reports whether an error may succeed when retried. When nil,
+	// errors marked with Retryable and network timeouts are retried.
+	Retryable func(error) bool
+	// Registry, when set, such as to the Registry of the server, counts the
+	// attempts, retries and exhausted operations by policy name.
+	Registry *metrics.Registry
+}
+
+// DefaultRetryPolicy makes three attempts, backing off from 50ms up to 1s.
+var DefaultRetryPolicy = RetryPolicy{Name: "default", Attempts: 3, Base: 50 * time.Millisecond, Max: time.Second}
+
+type retryableError struct {
+	err error
+}
+
+func (r retryableError) Error() string {
+	return r.err.Error()
+}
+
+func (r retryableError) Unwrap() error {
+	return r.err
+}
+
+// Retryable marks the error as transient, to be retried by Retry.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return retryableError{err}
+}
+
+// IsRetryable reports whether the error is marked with Retryable or is a network timeout.
+func IsRetryable(err error) bool {
+	var r retryableError
+	if errors.As(err, &r) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Retry calls the operation until it succeeds, returns an error that is not
+// retryable, exhausts its attempts, or the context is done. It is meant for
+// idempotent operations within handlers, such as calls to flaky upstreams.
+func Retry(ctx context.Context, policy RetryPolicy, op func(context.Context) error) error {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	count := func(outcome string) {
+		if policy.Registry != nil {
+			policy.Registry.Counter("ramchi_retries_total", "Attempts of retried operations, by policy and outcome.", "policy", "outcome").Inc(policy.Name, outcome)
+		}
+	}
+
+	backoff := policy.Base
+	for attempt := 1; ; attempt++ {
+		count("attempt")
+		err := op(ctx)
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if attempt == policy.Attempts {
+			count("exhausted")
+			return fmt.Errorf("Retry: failed after %d attempts: %w", attempt, err)
+		}
+
+		wait := backoff
+		if wait > 0 {
+			wait = time.Duration(rand.Int63n(int64(wait)) + 1)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("Retry: %w", errors.Join(ctx.Err(), err))
+		}
+		count("retry")
+
+		backoff *= 2
+		if policy.Max > 0 && backoff > policy.Max {
+			backoff = policy.Max
+		}
+	}
+}
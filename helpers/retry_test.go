@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+func TestRetry(t *testing.T) {
+	reg := metrics.NewRegistry()
+	policy := RetryPolicy{Name: "test", Attempts: 3, Base: time.Millisecond, Max: 2 * time.Millisecond, Registry: reg}
+	transient := errors.New("upstream unavailable")
+
+	calls := 0
+	err := Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return Retryable(transient)
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on third attempt, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return Retryable(transient)
+	})
+	if !errors.Is(err, transient) || calls != 3 {
+		t.Fatalf("expected attempts to be exhausted, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	permanent := errors.New("not found")
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Fatalf("expected permanent error to be returned at once, got %v after %d", err, calls)
+	}
+
+	for _, want := range []string{
+		`ramchi_retries_total{policy="test",outcome="attempt"} 7`,
+		`ramchi_retries_total{policy="test",outcome="retry"} 4`,
+		`ramchi_retries_total{policy="test",outcome="exhausted"} 1`,
+	} {
+		if text := reg.Text(); !strings.Contains(text, want) {
+			t.Fatalf("metrics missing %s:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"context"
+	"net/http"
+)
+
+// RouteInfo describes the route which matched a request, so that middlewares, such
+// as rate limits, audit logs and metrics, may key policies by the logical route.
+type RouteInfo struct {
+	// Prefix is the prefix of the router owning the route, set by router.WithRouterPrefix.
+	Prefix string
+	// Name is the name of the route, set by router.WithName.
+	Name string
+	// Method is the method the route responds to.
+	Method string
+	// Pattern is the path of the route, including the prefix, such as /api/users/{id}.
+	Pattern string
+}
+
+type routeInfoKey struct{}
+
+// WithRouteInfo returns a context holding the RouteInfo of the route yet to be
+// matched, so that middlewares outside the router may read it once served. The
+// server registers it on every route.
+func WithRouteInfo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeInfoKey{}, &RouteInfo{})
+}
+
+// SetRouteInfo records the route which matched the request, in the RouteInfo held
+// by its context, or in a new one.
+func SetRouteInfo(r *http.Request, info RouteInfo) *http.Request {
+	if held, ok := r.Context().Value(routeInfoKey{}).(*RouteInfo); ok {
+		*held = info
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeInfoKey{}, &info))
+}
+
+// RouteInfoFromContext returns the route which matched the request, and whether one
+// has. Router and route middlewares, and handlers, may read it at any time, whereas
+// middlewares registered with the server must read it after calling the next handler,
+// as with RoutePattern.
+func RouteInfoFromContext(ctx context.Context) (RouteInfo, bool) {
+	info, ok := ctx.Value(routeInfoKey{}).(*RouteInfo)
+	if !ok || info.Pattern == "" {
+		return RouteInfo{}, false
+	}
+	return *info, true
+}
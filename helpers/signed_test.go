@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	key := []byte("secret")
+
+	signed, err := SignURL(key, "/downloads/report.pdf?user=42", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(signed)
+	claims, err := VerifySignedURL(key, u)
+	if err != nil || claims.Get("user") != "42" {
+		t.Fatalf("expected valid signature, got %v %v", claims, err)
+	}
+
+	tampered, _ := url.Parse(signed)
+	q := tampered.Query()
+	q.Set("user", "43")
+	tampered.RawQuery = q.Encode()
+	if _, err := VerifySignedURL(key, tampered); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("expected tampered url to be rejected, got %v", err)
+	}
+
+	expired, _ := SignURL(key, "/downloads/report.pdf", -time.Minute)
+	u, _ = url.Parse(expired)
+	if _, err := VerifySignedURL(key, u); !errors.Is(err, ErrSignatureExpired) {
+		t.Fatalf("expected expired url to be rejected, got %v", err)
+	}
+}
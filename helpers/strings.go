@@ -0,0 +1,121 @@
+package helpers
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ellipsis ends strings shortened by Truncate and TruncateWidth.
+const Ellipsis = "…"
+
+// Truncate shortens s to at most n runes, ending it with Ellipsis when shortened,
+// so that multibyte characters are never split.
+func Truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	var b strings.Builder
+	count := 0
+	for _, r := range s {
+		if count == n-1 {
+			break
+		}
+		b.WriteRune(r)
+		count++
+	}
+	b.WriteString(Ellipsis)
+	return b.String()
+}
+
+// RuneWidth returns the columns a rune occupies in a terminal or monospaced font:
+// zero for combining marks and control characters, two for East Asian wide and
+// fullwidth characters and emoji, and one otherwise.
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0, unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Cf, r), unicode.IsControl(r):
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// Width returns the columns s occupies, as the sum of the RuneWidth of its runes.
+func Width(s string) int {
+	width := 0
+	for _, r := range s {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// TruncateWidth shortens s to at most width columns, ending it with Ellipsis when
+// shortened, such as to align CJK text in fixed-width output.
+func TruncateWidth(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+	limit := width - Width(Ellipsis)
+	if limit < 0 {
+		return ""
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := RuneWidth(r)
+		if used+w > limit {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	b.WriteString(Ellipsis)
+	return b.String()
+}
+
+// Transliterate replaces Latin letters with diacritics by their ASCII spelling, such
+// as é with e and ß with ss, and drops combining marks. Other runes are kept.
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+		} else if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Slugify returns s as a URL slug: transliterated, lowercased, with runs of other
+// characters replaced by single hyphens. Letters and digits of other scripts, such
+// as Cyrillic or CJK, are kept, so that non-Latin titles do not yield empty slugs.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(Transliterate(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		hyphen = true
+	}
+	return b.String()
+}
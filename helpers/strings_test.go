@@ -0,0 +1,47 @@
+package helpers
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"héllo", 5, "héllo"},
+		{"héllo wörld", 5, "héll…"},
+		{"日本語のテキスト", 4, "日本語…"},
+		{"abc", 0, ""},
+	} {
+		if got := Truncate(tc.s, tc.n); got != tc.want {
+			t.Fatalf("Truncate(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if got := Width("abc日本"); got != 7 {
+		t.Fatalf("got %d, want 7", got)
+	}
+	if got := Width("é"); got != 1 {
+		t.Fatalf("got %d for a combining mark, want 1", got)
+	}
+	if got := TruncateWidth("日本語のテキスト", 7); got != "日本語…" {
+		t.Fatalf("got %q, want 日本語…", got)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for s, want := range map[string]string{
+		"Crème Brûlée: A Recipe!": "creme-brulee-a-recipe",
+		"Straße in Łódź":          "strasse-in-lodz",
+		"Tiếng Việt":              "tieng-viet",
+		"été":                   "ete",
+		"Привет, мир":             "привет-мир",
+		"  --  ":                  "",
+	} {
+		if got := Slugify(s); got != want {
+			t.Fatalf("Slugify(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
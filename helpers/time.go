@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTime is wrapped by the errors of the time parsing helpers, whose
+// messages are fit to be shown to the clients of an API.
+var ErrInvalidTime = errors.New("helpers: invalid time")
+
+// ParseDate parses a date of the form 2006-01-02, at midnight UTC.
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date%s, expected YYYY-MM-DD: %w", s, timeReason(err), ErrInvalidTime)
+	}
+	return t, nil
+}
+
+// ParseRFC3339 parses a timestamp of the form 2006-01-02T15:04:05Z07:00, with
+// optional fractional seconds, keeping its offset.
+func ParseRFC3339(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a timestamp%s, expected RFC 3339 such as 2006-01-02T15:04:05Z: %w", s, timeReason(err), ErrInvalidTime)
+	}
+	return t, nil
+}
+
+// localLayouts are the layouts ParseTime accepts for times without an offset.
+var localLayouts = []string{time.DateOnly, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// ParseTime parses an RFC 3339 timestamp, or a date or date and time without an
+// offset, such as 2006-01-02 or 2006-01-02 15:04, which is taken to be in loc. A
+// nil loc is UTC.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time, expected RFC 3339 such as 2006-01-02T15:04:05Z, or a date such as 2006-01-02: %w", s, ErrInvalidTime)
+}
+
+var locations sync.Map
+
+// InLocation returns t in the IANA time zone of the name, such as Europe/London.
+// Zones are loaded once, from the system or the embedded database if the binary
+// imports time/tzdata.
+func InLocation(t time.Time, name string) (time.Time, error) {
+	if loc, ok := locations.Load(name); ok {
+		return t.In(loc.(*time.Location)), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" || name == "Local" {
+		return time.Time{}, fmt.Errorf("%q is not a time zone, expected an IANA name such as Europe/London: %w", name, ErrInvalidTime)
+	}
+	locations.Store(name, loc)
+	return t.In(loc), nil
+}
+
+var durationDays = regexp.MustCompile(`^(?:(\d+)w)?(?:(\d+)d)?(.*)$`)
+
+// ParseDuration parses a duration such as 15m, 2h30m or 1d12h, with the units
+// of time.ParseDuration and d and w for days of 24 hours and weeks, or an integer
+// of seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	invalid := fmt.Errorf("%q is not a duration, expected such as 15m, 2h or 1d: %w", s, ErrInvalidTime)
+
+	m := durationDays.FindStringSubmatch(s)
+	if m == nil || s == "" {
+		return 0, invalid
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour} {
+		if m[i+1] != "" {
+			n, err := strconv.ParseInt(m[i+1], 10, 32)
+			if err != nil {
+				return 0, invalid
+			}
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[3] != "" {
+		rest, err := time.ParseDuration(m[3])
+		if err != nil || (d != 0 && rest < 0) {
+			return 0, invalid
+		}
+		d += rest
+	}
+	return d, nil
+}
+
+// timeReason returns the reason a value failed to parse, such as " (month out of
+// range)", or an empty string when the value is malformed.
+func timeReason(err error) string {
+	var pe *time.ParseError
+	if errors.As(err, &pe) && pe.Message != "" {
+		return " (" + strings.TrimPrefix(pe.Message, ": ") + ")"
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	if d, err := ParseDate("2024-02-29"); err != nil || !d.Equal(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ParseDate = %v, %v", d, err)
+	}
+	if _, err := ParseDate("2024-13-01"); !errors.Is(err, ErrInvalidTime) || err.Error() != `"2024-13-01" is not a date (month out of range), expected YYYY-MM-DD: helpers: invalid time` {
+		t.Fatalf("ParseDate error = %v", err)
+	}
+	if ts, err := ParseRFC3339("2024-02-01T10:00:00.5+02:00"); err != nil || !ts.Equal(time.Date(2024, 2, 1, 8, 0, 0, 5e8, time.UTC)) {
+		t.Fatalf("ParseRFC3339 = %v, %v", ts, err)
+	}
+	if _, err := ParseRFC3339("2024-02-01 10:00"); !errors.Is(err, ErrInvalidTime) {
+		t.Fatalf("ParseRFC3339 error = %v", err)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	for s, want := range map[string]time.Time{
+		"2024-02-01T10:00:00Z": time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC),
+		"2024-02-01":           time.Date(2024, 2, 1, 0, 0, 0, 0, ny),
+		"2024-02-01 10:30":     time.Date(2024, 2, 1, 10, 30, 0, 0, ny),
+	} {
+		if got, err := ParseTime(s, ny); err != nil || !got.Equal(want) {
+			t.Fatalf("ParseTime(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if got, err := InLocation(time.Date(2024, 2, 1, 15, 0, 0, 0, time.UTC), "America/New_York"); err != nil || got.Hour() != 10 {
+		t.Fatalf("InLocation = %v, %v", got, err)
+	}
+	if _, err := InLocation(time.Now(), "Mars/Olympus"); !errors.Is(err, ErrInvalidTime) {
+		t.Fatalf("InLocation error = %v", err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"15m":    15 * time.Minute,
+		"2h30m":  150 * time.Minute,
+		"1d12h":  36 * time.Hour,
+		"1w":     7 * 24 * time.Hour,
+		"90":     90 * time.Second,
+		"-5m":    -5 * time.Minute,
+		"1.5h":   90 * time.Minute,
+		" 2h ":   2 * time.Hour,
+		"2w3d1s": 17*24*time.Hour + time.Second,
+	} {
+		if got, err := ParseDuration(s); err != nil || got != want {
+			t.Fatalf("ParseDuration(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "15 minutes", "d", "1d-5m", "2x"} {
+		if _, err := ParseDuration(s); !errors.Is(err, ErrInvalidTime) {
+			t.Fatalf("ParseDuration(%q) = %v, want ErrInvalidTime", s, err)
+		}
+	}
+}
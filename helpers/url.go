@@ -0,0 +1,108 @@
+package helpers
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrInvalidURL is returned by NormalizeURL for URLs which are not absolute http
+// or https URLs.
+var ErrInvalidURL = errors.New("helpers: invalid url")
+
+// NormalizeURL returns the URL in a canonical form, so that URLs which address the
+// same resource compare equal: the scheme and host are lowercased, default ports
+// and the fragment are removed, dot segments are resolved, an empty path becomes
+// /, and query parameters are sorted by key.
+func NormalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	u.Path = cleanURLPath(u.Path)
+	u.RawPath = ""
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+	return u.String(), nil
+}
+
+// cleanURLPath resolves the dot segments of p, keeping its trailing slash.
+func cleanURLPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// IsSafeRedirect returns whether target is a path on the same origin, or an http
+// or https URL on one of allowedHosts, where "*." allows subdomains.
+func IsSafeRedirect(target string, allowedHosts []string) bool {
+	if target == "" || strings.ContainsRune(target, '\\') {
+		return false
+	}
+	for _, r := range target {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+
+	if strings.HasPrefix(target, "/") {
+		return !strings.HasPrefix(target, "//")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil || u.Opaque != "" {
+		return false
+	}
+	if scheme := strings.ToLower(u.Scheme); scheme != "http" && scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return false
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeRedirect returns target if it is a safe redirect for IsSafeRedirect, or
+// fallback otherwise.
+func SafeRedirect(target string, fallback string, allowedHosts []string) string {
+	if IsSafeRedirect(target, allowedHosts) {
+		return target
+	}
+	return fallback
+}
@@ -0,0 +1,50 @@
+package helpers
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"HTTP://Example.COM":                  "http://example.com/",
+		"https://example.com:443/a/./b/../c/": "https://example.com/a/c/",
+		"http://example.com:8080/x#frag":      "http://example.com:8080/x",
+		"https://example.com/?b=2&a=1&a=0":    "https://example.com/?a=1&a=0&b=2",
+		"http://[::1]:80/":                    "http://[::1]/",
+	} {
+		if got, err := NormalizeURL(raw); err != nil || got != want {
+			t.Fatalf("NormalizeURL(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"/relative", "ftp://example.com/", "javascript:alert(1)", "http://"} {
+		if _, err := NormalizeURL(raw); err != ErrInvalidURL {
+			t.Fatalf("NormalizeURL(%q) = %v, want ErrInvalidURL", raw, err)
+		}
+	}
+}
+
+func TestIsSafeRedirect(t *testing.T) {
+	allowed := []string{"example.com", "*.example.org"}
+	for target, want := range map[string]bool{
+		"/account":                          true,
+		"/search?q=//evil.example":          true,
+		"https://example.com/welcome":       true,
+		"https://EXAMPLE.com./welcome":      true,
+		"https://app.example.org/":          true,
+		"https://example.org/":              false,
+		"https://example.com.evil.example/": false,
+		"//evil.example":                    false,
+		"/\\evil.example":                   false,
+		"/\t/evil.example":                  false,
+		"https://user@example.com/":         false,
+		"javascript:alert(1)":               false,
+		"ftp://example.com/":                false,
+		"account":                           false,
+		"":                                  false,
+	} {
+		if got := IsSafeRedirect(target, allowed); got != want {
+			t.Fatalf("IsSafeRedirect(%q) = %v, want %v", target, got, want)
+		}
+	}
+	if got := SafeRedirect("//evil.example", "/", allowed); got != "/" {
+		t.Fatalf("SafeRedirect = %q, want the fallback", got)
+	}
+}
@@ -0,0 +1,95 @@
+package ramchi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// serveHTTP3 serves the handler of the main listener over HTTP/3 on the UDP port
+// of its address, advertised with Alt-Svc headers.
+func (s *Server) serveHTTP3() error {
+	conn, err := net.ListenPacket("udp", s.instance.Addr)
+	if err != nil {
+		return fmt.Errorf("serveHTTP3: failed binding %s: %w", s.instance.Addr, err)
+	}
+	srv := &http3.Server{
+		Addr:       s.instance.Addr,
+		Handler:    s.instance.Handler,
+		TLSConfig:  s.instance.TLSConfig,
+		QuicConfig: &quic.Config{Allow0RTT: s.cfg.TLSEarlyData},
+	}
+	if s.cfg.TLSEarlyData {
+		srv.Handler = rejectEarlyData(srv.Handler)
+		srv.ConnContext = func(ctx context.Context, c quic.Connection) context.Context {
+			if early, ok := c.(quic.EarlyConnection); ok {
+				return context.WithValue(ctx, handshakeKey{}, early.HandshakeComplete())
+			}
+			return ctx
+		}
+	}
+	s.http3, s.http3Conn = srv, conn
+
+	next := s.instance.Handler
+	s.instance.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.SetQuicHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+
+	s.log.Debug().Str("Listener", "http3").Str("Address", s.instance.Addr).Msg("Listener started")
+	go func() {
+		if err := srv.Serve(conn); err != http.ErrServerClosed {
+			s.log.Error().Str("Function", "serveHTTP3").Err(err).Msg("HTTP/3 listener failed")
+		}
+	}()
+	return nil
+}
+
+// closeHTTP3 closes the HTTP/3 listener, if any, aborting its requests, as quic-go
+// cannot yet drain them gracefully.
+func (s *Server) closeHTTP3() error {
+	if s.http3 == nil {
+		return nil
+	}
+	err := s.http3.Close()
+	s.http3Conn.Close()
+	if err != nil {
+		return fmt.Errorf("closeHTTP3: %w", err)
+	}
+	return nil
+}
+
+// handshakeKey carries the channel closed once the handshake of the connection of a
+// request completes, before which its requests were sent as replayable early data.
+type handshakeKey struct{}
+
+// rejectEarlyData answers requests sent in early data with 425 Too Early, unless
+// their method is safe, so that replaying them has no effect.
+func rejectEarlyData(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if done, ok := r.Context().Value(handshakeKey{}).(<-chan struct{}); ok && !safeMethod(r.Method) {
+			select {
+			case <-done:
+			default:
+				helpers.Error(w, r, http.StatusTooEarly)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// safeMethod returns whether requests of the method are read-only.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+// Package httpclient provides http.RoundTripper wrappers for outbound calls
+// made by services built with ramchi.
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgePolicy configures hedged requests.
+type HedgePolicy struct {
+	// Delay is how long to wait for a response before sending the hedge. When
+	// zero, the 95th percentile of recently observed latencies is used.
+	Delay time.Duration
+	// MinDelay bounds the observed delay from below, and is the delay used until
+	// enough latencies have been observed.
+	MinDelay time.Duration
+	// Budget is the fraction of requests which may be hedged, such as 0.05.
+	Budget float64
+	// Window is the number of recent latencies kept to estimate the percentile.
+	Window int
+}
+
+// DefaultHedgePolicy hedges at most 5% of requests, after the observed 95th
+// percentile latency and no sooner than 10ms.
+var DefaultHedgePolicy = HedgePolicy{MinDelay: 10 * time.Millisecond, Budget: 0.05, Window: 512}
+
+type hedger struct {
+	next   http.RoundTripper
+	policy HedgePolicy
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	cursor    int
+	tokens    float64
+}
+
+// Hedge wraps the transport to send a second attempt of idempotent requests not
+// answered within the policy's delay, returning whichever response arrives first.
+func Hedge(next http.RoundTripper, policy HedgePolicy) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if policy.Window < 1 {
+		policy.Window = DefaultHedgePolicy.Window
+	}
+	return &hedger{next: next, policy: policy}
+}
+
+type attempt struct {
+	res    *http.Response
+	err    error
+	cancel context.CancelFunc
+	index  int
+	hedge  bool
+}
+
+func (h *hedger) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		return h.next.RoundTrip(req)
+	}
+	h.deposit()
+
+	start := time.Now()
+	results := make(chan attempt, 2)
+	var cancels []context.CancelFunc
+	send := func(hedge bool) error {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		clone := req.Clone(ctx)
+		if hedge && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			clone.Body = body
+		}
+		go func() {
+			res, err := h.next.RoundTrip(clone)
+			results <- attempt{res: res, err: err, cancel: cancel, index: index, hedge: hedge}
+		}()
+		return nil
+	}
+
+	if err := send(false); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(h.delay())
+	defer timer.Stop()
+
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			if h.withdraw() && send(true) == nil {
+				pending++
+			}
+			continue
+		case a := <-results:
+			pending--
+			if a.err != nil && pending > 0 {
+				// Wait for the other attempt, which may yet succeed.
+				a.cancel()
+				continue
+			}
+			if a.err != nil {
+				a.cancel()
+				return nil, a.err
+			}
+			if !a.hedge {
+				h.observe(time.Since(start))
+			}
+			if pending > 0 {
+				for i, cancel := range cancels {
+					if i != a.index {
+						cancel()
+					}
+				}
+				go discard(results)
+			}
+			a.res.Body = &cancelBody{ReadCloser: a.res.Body, cancel: a.cancel}
+			return a.res, nil
+		}
+	}
+}
+
+// discard closes the response of the cancelled attempt, should it arrive.
+func discard(results <-chan attempt) {
+	a := <-results
+	if a.res != nil {
+		a.res.Body.Close()
+	}
+}
+
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "":
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// deposit credits the budget for a request, up to a burst of ten hedges.
+func (h *hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.policy.Budget
+	if h.tokens > 10 {
+		h.tokens = 10
+	}
+}
+
+// withdraw reports whether the budget allows a hedge, spending it if so.
+func (h *hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < h.policy.Window {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.cursor] = d
+	h.cursor = (h.cursor + 1) % h.policy.Window
+}
+
+// delay returns the configured delay, or the observed 95th percentile latency.
+func (h *hedger) delay() time.Duration {
+	if h.policy.Delay > 0 {
+		return h.policy.Delay
+	}
+
+	h.mu.Lock()
+	if len(h.latencies) < 20 {
+		h.mu.Unlock()
+		return h.policy.MinDelay
+	}
+	sorted := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	p95 := sorted[len(sorted)*95/100]
+	if p95 < h.policy.MinDelay {
+		return h.policy.MinDelay
+	}
+	return p95
+}
+
+// cancelBody releases the context of the winning attempt once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
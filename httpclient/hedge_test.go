@@ -0,0 +1,49 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedge(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write([]byte("slow"))
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: Hedge(nil, HedgePolicy{Delay: 20 * time.Millisecond, Budget: 1})}
+	start := time.Now()
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(body) != "fast" || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected hedge to answer first, got %q after %s", body, time.Since(start))
+	}
+
+	res, err = client.Post(ts.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected POST not to be hedged, got %d calls", n)
+	}
+}
@@ -0,0 +1,104 @@
+package ramchi
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Etwodev/ramchi/listener"
+	"github.com/Etwodev/ramchi/router"
+)
+
+// listen returns the listener inherited from a previous process or passed by
+// systemd socket activation, or binds a new one.
+func (s *Server) listen() (net.Listener, error) {
+	for _, inherit := range []func() (net.Listener, bool, error){inheritedListener, s.systemdListener} {
+		ln, ok, err := inherit()
+		if err != nil {
+			return nil, fmt.Errorf("listen: %w", err)
+		}
+		if ok {
+			s.log.Debug().Str("Function", "listen").Str("Address", ln.Addr().String()).Msg("Using inherited listener")
+			return ln, nil
+		}
+	}
+
+	ln, err := net.Listen("tcp", s.instance.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: failed binding listener: %w", err)
+	}
+	return ln, nil
+}
+
+// wrap applies the configured listener wrappers. Connections are throttled by the
+// address they arrive from, so trusted proxies are exempt from the throttle.
+func (s *Server) wrap(ln net.Listener) (net.Listener, error) {
+	var trusted []*net.IPNet
+	if s.cfg.EnableProxyProtocol {
+		var err error
+		if trusted, err = listener.ParseCIDRs(s.cfg.ProxyProtocolTrusted); err != nil {
+			return nil, fmt.Errorf("wrap: %w", err)
+		}
+	}
+
+	if s.cfg.ConnectionRate > 0 || s.cfg.MaxConnectionsPerIP > 0 {
+		ln = listener.RateLimit(ln, listener.LimitPolicy{
+			Rate:          s.cfg.ConnectionRate,
+			Burst:         s.cfg.ConnectionBurst,
+			MaxConcurrent: s.cfg.MaxConnectionsPerIP,
+			Exempt:        trusted,
+			Rejected: func(ip string) {
+				s.log.Debug().Str("Function", "Accept").Str("IP", ip).Msg("Connection throttled")
+			},
+		})
+	}
+
+	if s.cfg.EnableProxyProtocol {
+		ln = listener.ProxyProtocol(ln, listener.ProxyPolicy{
+			Trusted: trusted,
+			Timeout: time.Duration(s.cfg.ProxyProtocolTimeout) * time.Second,
+		})
+	}
+	return ln, nil
+}
+
+// serveListeners serves the routers bound to each configured listener on its own
+// address, with the TLS configuration of the main listener. Routers bound to an
+// unconfigured listener are served nowhere.
+func (s *Server) serveListeners() error {
+	addrs := s.listenerAddresses()
+	for _, rt := range s.routers {
+		if name := router.Listener(rt); name != "" {
+			if _, ok := addrs[name]; !ok {
+				s.log.Warn().Str("Function", "serveListeners").Str("Listener", name).Msg("Router bound to unconfigured listener")
+			}
+		}
+	}
+
+	for name, addr := range addrs {
+		handler, err := s.handlerFor(name)
+		if err != nil {
+			return fmt.Errorf("serveListeners: %w", err)
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("serveListeners: failed binding listener %s: %w", name, err)
+		}
+		srv := &http.Server{Addr: addr, Handler: handler, TLSConfig: s.instance.TLSConfig}
+		s.configure(srv)
+		if srv.TLSConfig != nil {
+			ln = tls.NewListener(ln, srv.TLSConfig)
+		}
+		s.listeners = append(s.listeners, srv)
+
+		s.log.Debug().Str("Listener", name).Str("Address", addr).Msg("Listener started")
+		go func(name string) {
+			if err := srv.Serve(ln); err != http.ErrServerClosed {
+				s.log.Error().Str("Function", "serveListeners").Str("Listener", name).Err(err).Msg("Listener failed")
+			}
+		}(name)
+	}
+	return nil
+}
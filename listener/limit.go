@@ -0,0 +1,142 @@
+package listener
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// LimitPolicy configures the throttling of new connections per source IP.
+type LimitPolicy struct {
+	// Rate is the number of new connections per second allowed from an address,
+	// and Burst the number allowed at once. A zero Rate disables the throttle.
+	Rate  float64
+	Burst int
+	// MaxConcurrent is the number of open connections allowed from an address,
+	// or zero for no limit.
+	MaxConcurrent int
+	// Exempt are the networks whose connections are never throttled, such as
+	// proxies multiplexing many clients.
+	Exempt []*net.IPNet
+	// Rejected is called with the address of each connection closed by the throttle.
+	Rejected func(ip string)
+}
+
+type limitListener struct {
+	net.Listener
+	policy  LimitPolicy
+	mu      sync.Mutex
+	clients map[string]*client
+	accepts int
+}
+
+// client is the token bucket and open connection count of an address.
+type client struct {
+	tokens float64
+	last   time.Time
+	open   int
+}
+
+// RateLimit wraps the listener to close connections from addresses exceeding the
+// policy as soon as they are accepted, before a TLS handshake or request is read.
+// This is distinct from request rate limiting, and blunts floods of connections
+// from misbehaving clients.
+func RateLimit(ln net.Listener, policy LimitPolicy) net.Listener {
+	return &limitListener{Listener: ln, policy: policy, clients: make(map[string]*client)}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip, ok := l.address(conn.RemoteAddr())
+		if !ok {
+			return conn, nil
+		}
+		if l.admit(ip) {
+			return &limitConn{Conn: conn, release: func() { l.release(ip) }}, nil
+		}
+		conn.Close()
+		if l.policy.Rejected != nil {
+			l.policy.Rejected(ip)
+		}
+	}
+}
+
+// address returns the IP of the connection, and false when it is exempt.
+func (l *limitListener) address(addr net.Addr) (string, bool) {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return "", false
+	}
+	for _, n := range l.policy.Exempt {
+		if n.Contains(tcp.IP) {
+			return "", false
+		}
+	}
+	return tcp.IP.String(), true
+}
+
+func (l *limitListener) admit(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.accepts++
+	if l.accepts%1024 == 0 {
+		l.sweep(now)
+	}
+
+	cl, ok := l.clients[ip]
+	if !ok {
+		cl = &client{tokens: float64(l.policy.Burst), last: now}
+		l.clients[ip] = cl
+	}
+	if l.policy.MaxConcurrent > 0 && cl.open >= l.policy.MaxConcurrent {
+		return false
+	}
+	if l.policy.Rate > 0 {
+		cl.tokens += now.Sub(cl.last).Seconds() * l.policy.Rate
+		if cl.tokens > float64(l.policy.Burst) {
+			cl.tokens = float64(l.policy.Burst)
+		}
+		cl.last = now
+		if cl.tokens < 1 {
+			return false
+		}
+		cl.tokens--
+	}
+	cl.open++
+	return true
+}
+
+func (l *limitListener) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cl, ok := l.clients[ip]; ok {
+		cl.open--
+	}
+}
+
+// sweep forgets addresses with no open connections whose buckets have refilled.
+func (l *limitListener) sweep(now time.Time) {
+	for ip, cl := range l.clients {
+		refilled := l.policy.Rate <= 0 || cl.tokens+now.Sub(cl.last).Seconds()*l.policy.Rate >= float64(l.policy.Burst)
+		if cl.open == 0 && refilled {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,34 @@
+package listener
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	l := RateLimit(nil, LimitPolicy{Rate: 0.001, Burst: 2, MaxConcurrent: 1}).(*limitListener)
+
+	if !l.admit("203.0.113.7") {
+		t.Fatal("expected first connection to be admitted")
+	}
+	if l.admit("203.0.113.7") {
+		t.Fatal("expected concurrent connection to be rejected")
+	}
+	l.release("203.0.113.7")
+	if !l.admit("203.0.113.7") {
+		t.Fatal("expected connection within burst to be admitted")
+	}
+	l.release("203.0.113.7")
+	if l.admit("203.0.113.7") {
+		t.Fatal("expected connection beyond burst to be rejected")
+	}
+	if !l.admit("203.0.113.8") {
+		t.Fatal("expected other address to be admitted")
+	}
+
+	exempt, _ := ParseCIDRs([]string{"10.0.0.0/8"})
+	l.policy.Exempt = exempt
+	if _, ok := l.address(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}); ok {
+		t.Fatal("expected exempt address to bypass the throttle")
+	}
+}
@@ -0,0 +1,206 @@
+// Package listener provides net.Listener wrappers applied by the server
+// before connections reach HTTP.
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProxyHeader is returned when reading from a connection whose PROXY protocol header is malformed.
+var ErrProxyHeader = errors.New("malformed proxy protocol header")
+
+// proxySignature begins every PROXY protocol version 2 header.
+var proxySignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ProxyPolicy configures the parsing of PROXY protocol headers.
+type ProxyPolicy struct {
+	// Trusted are the networks of the proxies which send headers. Connections from
+	// other addresses are served without parsing one, so that clients cannot spoof
+	// their address; when empty, no connection is trusted.
+	Trusted []*net.IPNet
+	// Timeout bounds the time taken to read the header.
+	Timeout time.Duration
+}
+
+type proxyListener struct {
+	net.Listener
+	policy ProxyPolicy
+}
+
+// ProxyProtocol wraps the listener to parse PROXY protocol (version 1 or 2) headers,
+// as sent by HAProxy or a network load balancer in TCP mode, so that the connection's
+// RemoteAddr, and so the request's RemoteAddr, is that of the client.
+func ProxyProtocol(ln net.Listener, policy ProxyPolicy) net.Listener {
+	return &proxyListener{Listener: ln, policy: policy}
+}
+
+// ParseCIDRs parses networks in CIDR notation, accepting bare IP addresses as single hosts.
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("ParseCIDRs: failed parsing network: %w", err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Accept waits for the next connection, deferring the reading of its header to
+// the connection's first use so that a slow client cannot stall the listener.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &proxyConn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.policy.Timeout}, nil
+}
+
+func (l *proxyListener) trusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range l.policy.Trusted {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+type proxyConn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	once    sync.Once
+	remote  net.Addr
+	local   net.Addr
+	err     error
+}
+
+func (p *proxyConn) Read(b []byte) (int, error) {
+	p.once.Do(p.readHeader)
+	if p.err != nil {
+		return 0, p.err
+	}
+	return p.reader.Read(b)
+}
+
+func (p *proxyConn) RemoteAddr() net.Addr {
+	p.once.Do(p.readHeader)
+	if p.remote != nil {
+		return p.remote
+	}
+	return p.Conn.RemoteAddr()
+}
+
+func (p *proxyConn) LocalAddr() net.Addr {
+	p.once.Do(p.readHeader)
+	if p.local != nil {
+		return p.local
+	}
+	return p.Conn.LocalAddr()
+}
+
+func (p *proxyConn) readHeader() {
+	if p.timeout > 0 {
+		p.Conn.SetReadDeadline(time.Now().Add(p.timeout))
+		defer p.Conn.SetReadDeadline(time.Time{})
+	}
+
+	prefix, err := p.reader.Peek(len(proxySignature))
+	if err != nil {
+		p.err = fmt.Errorf("readHeader: failed reading header: %w", err)
+		return
+	}
+	if bytes.Equal(prefix, proxySignature) {
+		p.err = p.readV2()
+	} else {
+		p.err = p.readV1()
+	}
+}
+
+// readV1 parses a header of the form "PROXY TCP4 <src> <dst> <sport> <dport>\r\n".
+func (p *proxyConn) readV1() error {
+	line, err := p.reader.ReadSlice('\n')
+	if err != nil || len(line) > 107 || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return fmt.Errorf("readV1: %w", ErrProxyHeader)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return fmt.Errorf("readV1: %w", ErrProxyHeader)
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("readV1: %w", ErrProxyHeader)
+	}
+
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	sport, serr := strconv.ParseUint(fields[4], 10, 16)
+	dport, derr := strconv.ParseUint(fields[5], 10, 16)
+	if src == nil || dst == nil || serr != nil || derr != nil {
+		return fmt.Errorf("readV1: %w", ErrProxyHeader)
+	}
+	p.remote = &net.TCPAddr{IP: src, Port: int(sport)}
+	p.local = &net.TCPAddr{IP: dst, Port: int(dport)}
+	return nil
+}
+
+// readV2 parses the binary header, ignoring any TLVs.
+func (p *proxyConn) readV2() error {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(p.reader, header); err != nil {
+		return fmt.Errorf("readV2: failed reading header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return fmt.Errorf("readV2: unsupported version: %w", ErrProxyHeader)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(p.reader, body); err != nil {
+		return fmt.Errorf("readV2: failed reading addresses: %w", err)
+	}
+
+	// LOCAL commands are health checks from the proxy itself, which keep the real addresses.
+	if header[12]&0x0f == 0 {
+		return nil
+	}
+	switch header[13] >> 4 {
+	case 1:
+		if len(body) < 12 {
+			return fmt.Errorf("readV2: %w", ErrProxyHeader)
+		}
+		p.remote = &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}
+		p.local = &net.TCPAddr{IP: net.IP(body[4:8]), Port: int(binary.BigEndian.Uint16(body[10:12]))}
+	case 2:
+		if len(body) < 36 {
+			return fmt.Errorf("readV2: %w", ErrProxyHeader)
+		}
+		p.remote = &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}
+		p.local = &net.TCPAddr{IP: net.IP(body[16:32]), Port: int(binary.BigEndian.Uint16(body[34:36]))}
+	}
+	return nil
+}
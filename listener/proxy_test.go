@@ -0,0 +1,70 @@
+package listener
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func proxied(t *testing.T, header []byte) (*proxyConn, string) {
+	client, server := net.Pipe()
+	go func() {
+		client.Write(append(header, "GET / HTTP/1.1\r\n"...))
+		client.Close()
+	}()
+	conn := &proxyConn{Conn: server, reader: bufio.NewReader(server), timeout: time.Second}
+	body, err := io.ReadAll(conn)
+	if err != nil && !errors.Is(err, ErrProxyHeader) {
+		t.Fatal(err)
+	}
+	return conn, string(body)
+}
+
+func TestProxyProtocol(t *testing.T) {
+	conn, body := proxied(t, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n"))
+	if conn.RemoteAddr().String() != "203.0.113.7:51234" || body != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("v1: unexpected remote %s with body %q", conn.RemoteAddr(), body)
+	}
+
+	v2 := append([]byte{}, proxySignature...)
+	v2 = append(v2, 0x21, 0x11, 0, 12, 203, 0, 113, 8, 10, 0, 0, 1)
+	v2 = binary.BigEndian.AppendUint16(v2, 40000)
+	v2 = binary.BigEndian.AppendUint16(v2, 443)
+	conn, body = proxied(t, v2)
+	if conn.RemoteAddr().String() != "203.0.113.8:40000" || body != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("v2: unexpected remote %s with body %q", conn.RemoteAddr(), body)
+	}
+
+	conn, _ = proxied(t, []byte("GARBAGE HEADER\r\n"))
+	if !errors.Is(conn.err, ErrProxyHeader) {
+		t.Fatalf("expected malformed header to be rejected, got %v", conn.err)
+	}
+}
+
+func TestProxyProtocolTrust(t *testing.T) {
+	proxy, err := ParseCIDRs([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		trusted []*net.IPNet
+		addr    string
+		want    bool
+	}{
+		{nil, "10.0.0.1:443", false},
+		{proxy, "10.1.2.3:443", true},
+		{proxy, "192.0.2.1:443", true},
+		{proxy, "192.0.2.2:443", false},
+		{proxy, "203.0.113.7:443", false},
+	} {
+		l := &proxyListener{policy: ProxyPolicy{Trusted: tc.trusted}}
+		addr, _ := net.ResolveTCPAddr("tcp", tc.addr)
+		if got := l.trusted(addr); got != tc.want {
+			t.Errorf("trusted(%s) with %d networks = %v, want %v", tc.addr, len(tc.trusted), got, tc.want)
+		}
+	}
+}
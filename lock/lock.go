@@ -0,0 +1,120 @@
+// Package lock provides locks coordinating work across goroutines or replicas,
+// which expire unless renewed, so that a crashed holder cannot hold one forever.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "lock").Logger()
+
+// ErrNotAcquired is returned when a lock is held by another holder.
+var ErrNotAcquired = errors.New("lock not acquired")
+
+// ErrLost is returned when a lock expired, or was taken by another holder, before
+// it was refreshed or released.
+var ErrLost = errors.New("lock lost")
+
+// Locker acquires locks by key.
+type Locker interface {
+	// TryAcquire acquires the lock for the ttl, or returns ErrNotAcquired at once
+	// when it is held. Other errors, such as of the store being unreachable, must
+	// not wrap ErrNotAcquired, so that Acquire gives up rather than waits.
+	TryAcquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a held lock.
+type Lock interface {
+	Key() string
+	// Refresh extends the lock to expire after the ttl, or returns ErrLost.
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release releases the lock, returning ErrLost if it was no longer held.
+	Release(ctx context.Context) error
+}
+
+// Acquire acquires the lock, retrying with jittered backoff while it is held by
+// another holder, until the context is done. Other errors are returned at once.
+func Acquire(ctx context.Context, locker Locker, key string, ttl time.Duration) (Lock, error) {
+	backoff := 10 * time.Millisecond
+	for {
+		l, err := locker.TryAcquire(ctx, key, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return l, err
+		}
+
+		select {
+		case <-time.After(backoff/2 + time.Duration(mrand.Int63n(int64(backoff)))):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Acquire: %w", errors.Join(ctx.Err(), ErrNotAcquired))
+		}
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Hold acquires the lock and calls fn while holding it, refreshing it every third of
+// the ttl. Should the lock be lost, the context passed to fn is cancelled, and Hold
+// returns ErrLost once fn returns. The lock is released afterwards.
+func Hold(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	l, err := Acquire(ctx, locker, key, ttl)
+	if err != nil {
+		return fmt.Errorf("Hold: %w", err)
+	}
+
+	held, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := l.Refresh(held, ttl); err != nil {
+					if held.Err() != nil {
+						return
+					}
+					log.Warn().Str("Function", "Hold").Str("Key", key).Err(err).Msg("Failed refreshing lock")
+					close(lost)
+					cancel()
+					return
+				}
+			case <-held.Done():
+				return
+			}
+		}
+	}()
+
+	err = fn(held)
+	cancel()
+
+	select {
+	case <-lost:
+		return fmt.Errorf("Hold: %s: %w", key, ErrLost)
+	default:
+	}
+	// The holder's context may be done, but the lock should still be released.
+	if rerr := l.Release(context.WithoutCancel(ctx)); rerr != nil && err == nil {
+		err = fmt.Errorf("Hold: %w", rerr)
+	}
+	return err
+}
+
+// token returns a random value identifying a holder.
+func token() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("token: failed reading random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
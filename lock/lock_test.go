@@ -0,0 +1,78 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMemory(t *testing.T) {
+	locker := NewMemory()
+	ctx := context.Background()
+
+	l, err := locker.TryAcquire(ctx, "job", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	if _, err := locker.TryAcquire(ctx, "job", time.Second); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryAcquire: got %v, want ErrNotAcquired", err)
+	}
+	if err := l.Refresh(ctx, 50*time.Millisecond); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	other, err := locker.TryAcquire(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire after expiry: %v", err)
+	}
+	if err := l.Release(ctx); !errors.Is(err, ErrLost) {
+		t.Fatalf("Release of expired lock: got %v, want ErrLost", err)
+	}
+	if err := other.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+}
+
+func TestHold(t *testing.T) {
+	locker := NewMemory()
+	ctx := context.Background()
+
+	err := Hold(ctx, locker, "job", 30*time.Millisecond, func(ctx context.Context) error {
+		// Outlive the ttl to exercise renewal.
+		time.Sleep(80 * time.Millisecond)
+		if _, err := locker.TryAcquire(ctx, "job", time.Second); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("TryAcquire while held: got %v, want ErrNotAcquired", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+
+	wait, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	l, err := Acquire(wait, locker, "job", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after Hold: %v", err)
+	}
+	l.Release(ctx)
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	locker := NewRedis(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := Acquire(ctx, locker, "job", time.Second)
+	if err == nil || errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("got %v, want a transport error other than ErrNotAcquired", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Acquire waited %v for an unreachable store", elapsed)
+	}
+}
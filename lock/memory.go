@@ -0,0 +1,74 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type memoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemory initializes a Locker local to the process.
+func NewMemory() Locker {
+	return &memoryLocker{locks: make(map[string]memoryEntry)}
+}
+
+func (m *memoryLocker) TryAcquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	t, err := token()
+	if err != nil {
+		return nil, fmt.Errorf("TryAcquire: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if e, ok := m.locks[key]; ok && now.Before(e.expires) {
+		return nil, fmt.Errorf("TryAcquire: %s: %w", key, ErrNotAcquired)
+	}
+	m.locks[key] = memoryEntry{token: t, expires: now.Add(ttl)}
+	return &memoryLock{locker: m, key: key, token: t}, nil
+}
+
+type memoryLock struct {
+	locker *memoryLocker
+	key    string
+	token  string
+}
+
+func (l *memoryLock) Key() string {
+	return l.key
+}
+
+func (l *memoryLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	now := time.Now()
+	if e, ok := l.locker.locks[l.key]; !ok || e.token != l.token || !now.Before(e.expires) {
+		return fmt.Errorf("Refresh: %s: %w", l.key, ErrLost)
+	}
+	l.locker.locks[l.key] = memoryEntry{token: l.token, expires: now.Add(ttl)}
+	return nil
+}
+
+func (l *memoryLock) Release(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	e, ok := l.locker.locks[l.key]
+	if !ok || e.token != l.token {
+		return fmt.Errorf("Release: %s: %w", l.key, ErrLost)
+	}
+	delete(l.locker.locks, l.key)
+	if !time.Now().Before(e.expires) {
+		return fmt.Errorf("Release: %s: %w", l.key, ErrLost)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	releaseScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`)
+	refreshScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`)
+)
+
+// driftFactor bounds the clock drift between the instances, as a fraction of the ttl.
+const driftFactor = 0.01
+
+type redisLocker struct {
+	clients []redis.Cmdable
+	prefix  string
+}
+
+// NewRedis initializes a Locker over independent Redis instances using the Redlock
+// algorithm: a lock is held when a majority of instances grant it within its ttl.
+// A single instance gives a lock which is lost should that instance fail over.
+// Keys are prefixed with "lock:".
+func NewRedis(clients ...redis.Cmdable) Locker {
+	return &redisLocker{clients: clients, prefix: "lock:"}
+}
+
+func (r *redisLocker) quorum() int {
+	return len(r.clients)/2 + 1
+}
+
+func (r *redisLocker) TryAcquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	t, err := token()
+	if err != nil {
+		return nil, fmt.Errorf("TryAcquire: %w", err)
+	}
+	l := &redisLock{locker: r, key: key, token: t}
+
+	start := time.Now()
+	granted, failed, failure := 0, 0, error(nil)
+	for _, cl := range r.clients {
+		ok, err := cl.SetNX(ctx, r.prefix+key, t, ttl).Result()
+		if err != nil {
+			failed++
+			failure = err
+		}
+		if ok {
+			granted++
+		}
+	}
+
+	validity := ttl - time.Since(start) - time.Duration(float64(ttl)*driftFactor) - 2*time.Millisecond
+	if granted >= r.quorum() && validity > 0 {
+		return l, nil
+	}
+	// Release the minority which granted the lock, so others need not wait for it to expire.
+	_ = l.Release(context.WithoutCancel(ctx))
+	// Without a quorum of reachable instances, the lock cannot be held by anyone, and
+	// waiting for it would only wait for the instances to recover.
+	if len(r.clients)-failed < r.quorum() {
+		return nil, fmt.Errorf("TryAcquire: failed reaching a quorum: %w", failure)
+	}
+	return nil, fmt.Errorf("TryAcquire: %s: %w", key, ErrNotAcquired)
+}
+
+type redisLock struct {
+	locker *redisLocker
+	key    string
+	token  string
+}
+
+func (l *redisLock) Key() string {
+	return l.key
+}
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if l.run(ctx, refreshScript, ttl.Milliseconds()) < l.locker.quorum() {
+		return fmt.Errorf("Refresh: %s: %w", l.key, ErrLost)
+	}
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	if l.run(ctx, releaseScript) < l.locker.quorum() {
+		return fmt.Errorf("Release: %s: %w", l.key, ErrLost)
+	}
+	return nil
+}
+
+// run runs the script on every instance, returning the number on which it succeeded.
+func (l *redisLock) run(ctx context.Context, script *redis.Script, args ...interface{}) int {
+	succeeded := 0
+	for _, cl := range l.locker.clients {
+		n, err := script.Run(ctx, cl, []string{l.locker.prefix + l.key}, append([]interface{}{l.token}, args...)...).Int64()
+		if err == nil && n > 0 {
+			succeeded++
+		}
+	}
+	return succeeded
+}
@@ -0,0 +1,39 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type fileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile returns a Sink appending entries to the file as JSON lines, creating it
+// if it does not exist.
+func NewFile(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("NewFile: failed opening file: %w", err)
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (f *fileSink) Send(ctx context.Context, entries [][]byte) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.Write(entry)
+		buf.WriteByte('\n')
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("Send: failed writing entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+type httpSink struct {
+	url         string
+	contentType string
+	header      http.Header
+	client      *http.Client
+	encode      func(entries [][]byte) ([]byte, error)
+}
+
+// NewHTTP returns a Sink posting entries to a bulk endpoint as newline delimited
+// JSON, such as a log collector, with the header, such as for authorization.
+func NewHTTP(url string, header http.Header) Sink {
+	return &httpSink{
+		url:         url,
+		contentType: "application/x-ndjson",
+		header:      header,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		encode: func(entries [][]byte) ([]byte, error) {
+			var buf bytes.Buffer
+			for _, entry := range entries {
+				buf.Write(entry)
+				buf.WriteByte('\n')
+			}
+			return buf.Bytes(), nil
+		},
+	}
+}
+
+// NewKafkaREST returns a Sink producing entries to the Kafka topic through the
+// Confluent REST Proxy at the endpoint, such as "http://kafka-rest:8082", as JSON
+// records of its v2 API. It does not speak the Kafka protocol, so brokers without a
+// REST Proxy in front of them cannot be used.
+func NewKafkaREST(endpoint string, topic string, header http.Header) Sink {
+	return &httpSink{
+		url:         strings.TrimRight(endpoint, "/") + "/topics/" + topic,
+		contentType: "application/vnd.kafka.json.v2+json",
+		header:      header,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		encode: func(entries [][]byte) ([]byte, error) {
+			type record struct {
+				Value json.RawMessage `json:"value"`
+			}
+			records := make([]record, len(entries))
+			for i, entry := range entries {
+				records[i] = record{entry}
+			}
+			return json.Marshal(map[string]interface{}{"records": records})
+		},
+	}
+}
+
+func (h *httpSink) Send(ctx context.Context, entries [][]byte) error {
+	body, err := h.encode(entries)
+	if err != nil {
+		return fmt.Errorf("Send: failed encoding entries: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Send: failed creating request: %w", err)
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", h.contentType)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return helpers.Retryable(fmt.Errorf("Send: failed posting entries: %w", err))
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return helpers.Retryable(fmt.Errorf("Send: endpoint responded %s", resp.Status))
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("Send: endpoint responded %s", resp.Status)
+	}
+	return nil
+}
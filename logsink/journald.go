@@ -0,0 +1,109 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// JournalSocket is the socket of systemd-journald's native protocol.
+const JournalSocket = "/run/systemd/journal/socket"
+
+// Journald is a zerolog.LevelWriter sending each entry to systemd-journald, with the
+// priority of its level, its message as MESSAGE, and its other fields as journal
+// fields, such as ROUTE and REQUESTID.
+type Journald struct {
+	mu         sync.Mutex
+	conn       net.Conn
+	identifier string
+}
+
+// NewJournald returns a Journald sending with the syslog identifier, such as the
+// name of the service.
+func NewJournald(identifier string) (*Journald, error) {
+	conn, err := net.Dial("unixgram", JournalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("NewJournald: failed connecting to journald: %w", err)
+	}
+	return &Journald{conn: conn, identifier: identifier}, nil
+}
+
+// Write sends the entry with the informational priority.
+func (j *Journald) Write(p []byte) (int, error) {
+	return j.WriteLevel(zerolog.InfoLevel, p)
+}
+
+// WriteLevel sends the entry with the priority of the level.
+func (j *Journald) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	priority, ok := severities[level]
+	if !ok {
+		priority = 6
+	}
+
+	var buf bytes.Buffer
+	writeField(&buf, "PRIORITY", fmt.Sprint(priority))
+	writeField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	var fields map[string]interface{}
+	if err := json.Unmarshal(p, &fields); err != nil {
+		writeField(&buf, "MESSAGE", string(bytes.TrimRight(p, "\n")))
+	} else {
+		message, _ := fields[zerolog.MessageFieldName].(string)
+		writeField(&buf, "MESSAGE", message)
+		for k, v := range fields {
+			if k == zerolog.MessageFieldName || k == zerolog.LevelFieldName {
+				continue
+			}
+			if name := fieldName(k); name != "" {
+				writeField(&buf, name, fmt.Sprint(v))
+			}
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.conn.Write(buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("WriteLevel: failed sending entry: %w", err)
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to journald.
+func (j *Journald) Close() error {
+	return j.conn.Close()
+}
+
+// writeField writes a field of the native protocol, length-prefixing values which
+// span lines.
+func writeField(buf *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// fieldName returns the journal field name of a log field, which may only contain
+// uppercase letters, digits and underscores, and not begin with an underscore, or an
+// empty string when there is none.
+func fieldName(k string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return -1
+	}, k)
+	return strings.TrimLeft(name, "_0123456789")
+}
@@ -0,0 +1,201 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBufferFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	sink, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := NewBuffer(sink, WithBatchSize(2), WithFlushInterval(time.Hour))
+	logger := zerolog.New(buf)
+	for _, route := range []string{"/a", "/b", "/c"} {
+		logger.Info().Str("Route", route).Msg("Request served")
+	}
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"Route":"/c"`) {
+		t.Fatalf("got %q, want three entries", b)
+	}
+	if _, err := buf.Write([]byte("{}")); err != ErrClosed {
+		t.Fatalf("write after close: got %v, want ErrClosed", err)
+	}
+}
+
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s blockingSink) Send(ctx context.Context, entries [][]byte) error {
+	<-s.release
+	return nil
+}
+
+func TestBufferDropsWhenFull(t *testing.T) {
+	sink := blockingSink{release: make(chan struct{})}
+	buf := NewBuffer(sink, WithBatchSize(1), WithQueueSize(1))
+	for i := 0; i < 10; i++ {
+		buf.Write([]byte(`{}`))
+	}
+	close(sink.release)
+	buf.Close()
+	if buf.Dropped() == 0 {
+		t.Fatal("no entries dropped from a full queue")
+	}
+}
+
+func TestKafkaREST(t *testing.T) {
+	var mu sync.Mutex
+	var got struct {
+		Records []struct {
+			Value map[string]string `json:"value"`
+		} `json:"records"`
+	}
+	var path, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &got)
+	}))
+	defer srv.Close()
+
+	if err := NewKafkaREST(srv.URL, "access", nil).Send(context.Background(), [][]byte{[]byte(`{"Route":"/a"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/topics/access" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Fatalf("posted %s as %s", path, contentType)
+	}
+	if len(got.Records) != 1 || got.Records[0].Value["Route"] != "/a" {
+		t.Fatalf("got records %+v", got.Records)
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewSyslog("udp", conn.LocalAddr().String(), FacilityLocal0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	logger := zerolog.New(w)
+	logger.Warn().Msg("disk low")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<132>1 ") || !strings.Contains(msg, " test ") || !strings.HasSuffix(msg, `"message":"disk low"}`) {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestJournald(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	client, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Journald{conn: client, identifier: "test"}
+	defer w.Close()
+	logger := zerolog.New(w)
+	logger.Error().Str("Route", "/users").Str("Stack", "a\nb").Msg("failed")
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := string(buf[:n])
+	for _, field := range []string{"PRIORITY=3\n", "SYSLOG_IDENTIFIER=test\n", "MESSAGE=failed\n", "ROUTE=/users\n", "STACK\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"} {
+		if !strings.Contains(entry, field) {
+			t.Fatalf("entry %q lacks %q", entry, field)
+		}
+	}
+	if strings.Contains(entry, "LEVEL=") {
+		t.Fatalf("entry %q has the level", entry)
+	}
+}
+
+type captureSink struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (s *captureSink) Send(ctx context.Context, entries [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		s.entries = append(s.entries, string(e))
+	}
+	return nil
+}
+
+func TestBufferRedaction(t *testing.T) {
+	sink := &captureSink{}
+	buf := NewBuffer(sink, WithRedaction(nil))
+	logger := zerolog.New(buf)
+	logger.Info().Str("Action", "login").Str("password", "hunter2").Str("token", "0123456789abcdef").Str("email", "a@example.com").Msg("Audit")
+	buf.Write([]byte("password=hunter2\n"))
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(sink.entries))
+	}
+	for _, e := range sink.entries {
+		for _, secret := range []string{"hunter2", "0123456789ab", "a@example.com"} {
+			if strings.Contains(e, secret) {
+				t.Fatalf("entry %s reached the sink with %q", e, secret)
+			}
+		}
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(sink.entries[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["Action"] != "login" || entry["token"] != "************cdef" {
+		t.Fatalf("got %v, want the other fields kept and the token masked", entry)
+	}
+}
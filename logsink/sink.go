@@ -0,0 +1,197 @@
+// Package logsink forwards log entries, such as access and audit logs, to files,
+// Kafka through a REST proxy or HTTP bulk endpoints rather than stdout, batching
+// them in the background.
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+// log reports failures of the sinks themselves, to stderr, so that a logger writing
+// to a failing sink does not log into it.
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "logsink").Logger()
+
+// ErrClosed is returned by writes to a closed Buffer.
+var ErrClosed = errors.New("logsink: buffer closed")
+
+// Sink receives batches of log entries, each a JSON document. Transient failures
+// should be marked with helpers.Retryable, to be retried.
+type Sink interface {
+	Send(ctx context.Context, entries [][]byte) error
+}
+
+// Buffer is an io.Writer batching the entries written to it, such as by a zerolog
+// logger, and sending them to a sink in the background.
+type Buffer struct {
+	sink     Sink
+	size     int
+	interval time.Duration
+	timeout  time.Duration
+	block    bool
+	redact   map[string]string
+	policy   helpers.RetryPolicy
+
+	mu      sync.RWMutex
+	closed  bool
+	entries chan []byte
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// Option configures a Buffer.
+type Option func(b *Buffer)
+
+// WithBatchSize sends batches of at most n entries, 100 by default.
+func WithBatchSize(n int) Option {
+	return func(b *Buffer) {
+		b.size = n
+	}
+}
+
+// WithFlushInterval sends a partial batch once the interval has elapsed, a second by default.
+func WithFlushInterval(d time.Duration) Option {
+	return func(b *Buffer) {
+		b.interval = d
+	}
+}
+
+// WithQueueSize bounds the entries waiting to be sent, 10000 by default.
+func WithQueueSize(n int) Option {
+	return func(b *Buffer) {
+		b.entries = make(chan []byte, n)
+	}
+}
+
+// WithBackpressure blocks writers while the queue is full, rather than dropping
+// their entries, for logs which must not be lost, such as audit logs.
+func WithBackpressure() Option {
+	return func(b *Buffer) {
+		b.block = true
+	}
+}
+
+// WithRedaction redacts the members of each entry whose keys appear in fields, as
+// helpers.SanitizeJSON, before it is queued, so that personal data logged by access
+// or audit logs never reaches the sink. A nil fields uses helpers.DefaultRedactFields.
+func WithRedaction(fields map[string]string) Option {
+	return func(b *Buffer) {
+		if fields == nil {
+			fields = helpers.DefaultRedactFields
+		}
+		b.redact = fields
+	}
+}
+
+// NewBuffer returns a Buffer sending to the sink, and starts sending.
+func NewBuffer(sink Sink, opts ...Option) *Buffer {
+	b := &Buffer{
+		sink:     sink,
+		size:     100,
+		interval: time.Second,
+		timeout:  10 * time.Second,
+		policy:   helpers.RetryPolicy{Name: "logsink", Attempts: 3, Base: 100 * time.Millisecond, Max: 2 * time.Second},
+		entries:  make(chan []byte, 10000),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	go b.run()
+	return b
+}
+
+// Write queues the entry, dropping it when the queue is full unless the buffer
+// applies backpressure. The entry is copied, as loggers reuse their buffers, and
+// redacted when the buffer redacts.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+
+	entry := append([]byte(nil), bytes.TrimRight(p, "\n")...)
+	if b.redact != nil {
+		entry = helpers.SanitizeJSON(entry, b.redact)
+	}
+	if b.block {
+		b.entries <- entry
+		return len(p), nil
+	}
+	select {
+	case b.entries <- entry:
+	default:
+		b.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many entries have been dropped, as the queue was full or the
+// sink failed.
+func (b *Buffer) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Close sends the queued entries and stops the buffer.
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.entries)
+	}
+	b.mu.Unlock()
+	<-b.done
+	return nil
+}
+
+func (b *Buffer) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, b.size)
+	for {
+		select {
+		case entry, ok := <-b.entries:
+			if !ok {
+				b.send(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) < b.size {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		b.send(batch)
+		batch = make([][]byte, 0, b.size)
+	}
+}
+
+// send sends the batch, retrying transient failures, and drops it should it fail.
+func (b *Buffer) send(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	if err := helpers.Retry(ctx, b.policy, func(ctx context.Context) error {
+		return b.sink.Send(ctx, batch)
+	}); err != nil {
+		b.dropped.Add(int64(len(batch)))
+		log.Error().Str("Function", "send").Int("Entries", len(batch)).Err(err).Msg("Failed sending log entries")
+	}
+}
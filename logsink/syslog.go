@@ -0,0 +1,113 @@
+package logsink
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Syslog facilities, of RFC 5424.
+const (
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+)
+
+// severities maps log levels to syslog severities.
+var severities = map[zerolog.Level]int{
+	zerolog.PanicLevel: 0,
+	zerolog.FatalLevel: 2,
+	zerolog.ErrorLevel: 3,
+	zerolog.WarnLevel:  4,
+	zerolog.InfoLevel:  6,
+	zerolog.DebugLevel: 7,
+	zerolog.TraceLevel: 7,
+}
+
+// Syslog is a zerolog.LevelWriter sending each entry as an RFC 5424 message, with the
+// severity of its level, to a syslog daemon.
+type Syslog struct {
+	mu       sync.Mutex
+	network  string
+	addr     string
+	conn     net.Conn
+	facility int
+	hostname string
+	app      string
+}
+
+// NewSyslog returns a Syslog sending as the app to the daemon at the address, over
+// udp, tcp or unixgram, or to the local daemon when the network is empty. Messages
+// sent over tcp are framed by octet counting, of RFC 6587.
+func NewSyslog(network string, addr string, facility int, app string) (*Syslog, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+	s := &Syslog{network: network, addr: addr, facility: facility, hostname: hostname, app: app}
+	if err := s.dial(); err != nil {
+		return nil, fmt.Errorf("NewSyslog: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Syslog) dial() error {
+	if s.network != "" {
+		conn, err := net.Dial(s.network, s.addr)
+		if err != nil {
+			return fmt.Errorf("dial: failed connecting to syslog: %w", err)
+		}
+		s.conn = conn
+		return nil
+	}
+	for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+		if conn, err := net.Dial("unixgram", path); err == nil {
+			s.conn = conn
+			return nil
+		}
+	}
+	return fmt.Errorf("dial: no local syslog daemon")
+}
+
+// Write sends the entry with the informational severity.
+func (s *Syslog) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.InfoLevel, p)
+}
+
+// WriteLevel sends the entry with the severity of the level, reconnecting once
+// should the connection have failed.
+func (s *Syslog) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	severity, ok := severities[level]
+	if !ok {
+		severity = 6
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", s.facility*8+severity, time.Now().Format(time.RFC3339Nano), s.hostname, s.app, os.Getpid(), bytes.TrimRight(p, "\n"))
+	if s.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.conn.Close()
+		if err := s.dial(); err != nil {
+			return 0, fmt.Errorf("WriteLevel: %w", err)
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			return 0, fmt.Errorf("WriteLevel: failed sending message: %w", err)
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the daemon.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.Close()
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// OverflowLabel is the label value of values beyond the limit of a LabelLimit.
+const OverflowLabel = "other"
+
+// LabelLimit bounds the cardinality of a label taking values from clients, such as
+// tenants or API keys, so that they cannot grow the series of a metric without end.
+type LabelLimit struct {
+	mu     sync.Mutex
+	max    int
+	hash   bool
+	values map[string]string
+}
+
+// NewLabelLimit returns a LabelLimit admitting the first max distinct values, later
+// values being recorded as OverflowLabel. When hash is set, values are recorded by
+// their hash, so that credentials such as API keys are not exposed.
+func NewLabelLimit(max int, hash bool) *LabelLimit {
+	return &LabelLimit{max: max, hash: hash, values: make(map[string]string)}
+}
+
+// Value returns the label value recorded for v.
+func (l *LabelLimit) Value(v string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if value, ok := l.values[v]; ok {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return OverflowLabel
+	}
+	value := v
+	if l.hash {
+		sum := sha256.Sum256([]byte(v))
+		value = "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	l.values[v] = value
+	return value
+}
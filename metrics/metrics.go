@@ -0,0 +1,430 @@
+// Package metrics records counters, gauges and histograms, and exposes them in the
+// Prometheus text format, so that servers may be scraped without a client library.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default buckets of request durations, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry of servers not configured with their own.
+var Default = NewRegistry()
+
+// Registry holds metrics by name, to be exposed together.
+type Registry struct {
+	mu        sync.Mutex
+	metrics   map[string]metric
+	exporters atomic.Value
+}
+
+type metric interface {
+	kind() string
+	help() string
+	write(b *strings.Builder, name string)
+	gather(f *Family)
+}
+
+// Family is a snapshot of a metric and its series, for exporters pushing the
+// metrics of a registry.
+type Family struct {
+	Name    string
+	Help    string
+	Kind    string
+	Labels  []string
+	Buckets []float64
+	Series  []Series
+}
+
+// Series is a snapshot of the series of the label values. Histograms have the
+// cumulative count of observations in each bucket, their count and their sum; other
+// metrics have a value.
+type Series struct {
+	Values []string
+	Value  float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// Exporter receives each change to the metrics of a registry, such as to push them
+// to a StatsD agent rather than be scraped. Methods are called with the names and
+// values of the labels of the series, and must not block.
+type Exporter interface {
+	Count(name string, delta float64, labels []string, values []string)
+	Gauge(name string, value float64, labels []string, values []string)
+	Observe(name string, value float64, labels []string, values []string)
+}
+
+// Export registers an exporter receiving each later change to the metrics.
+func (r *Registry) Export(e Exporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exporters, _ := r.exporters.Load().([]Exporter)
+	r.exporters.Store(append(append([]Exporter(nil), exporters...), e))
+}
+
+func (r *Registry) exportersOf() []Exporter {
+	exporters, _ := r.exporters.Load().([]Exporter)
+	return exporters
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register returns the metric registered under the name, registering the one
+// created by create if there is none, so that metrics may be declared wherever
+// they are used. Registering a name as another kind or with other labels panics.
+func (r *Registry) register(name string, kind string, labels []string, create func() metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.kind() != kind || strings.Join(labelsOf(m), ",") != strings.Join(labels, ",") {
+			panic(fmt.Sprintf("metrics: %s registered as another %s", name, m.kind()))
+		}
+		return m
+	}
+	m := create()
+	r.metrics[name] = m
+	return m
+}
+
+// Counter returns the counter registered under the name, registering it if necessary.
+func (r *Registry) Counter(name string, help string, labels ...string) *Counter {
+	return r.register(name, "counter", labels, func() metric {
+		return &Counter{vec: newVec(r, name, help, labels)}
+	}).(*Counter)
+}
+
+// Gauge returns the gauge registered under the name, registering it if necessary.
+func (r *Registry) Gauge(name string, help string, labels ...string) *Gauge {
+	return r.register(name, "gauge", labels, func() metric {
+		return &Gauge{vec: newVec(r, name, help, labels)}
+	}).(*Gauge)
+}
+
+// Histogram returns the histogram registered under the name, registering it with
+// the upper bounds of its buckets if necessary.
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return r.register(name, "histogram", labels, func() metric {
+		b := append([]float64(nil), buckets...)
+		sort.Float64s(b)
+		return &Histogram{vec: newVec(r, name, help, labels), buckets: b, series: make(map[string]*histogramSeries)}
+	}).(*Histogram)
+}
+
+// Text returns the metrics in the Prometheus text format, ordered by name.
+func (r *Registry) Text() string {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make(map[string]metric, len(r.metrics))
+	for name, m := range r.metrics {
+		metrics[name] = m
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := metrics[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(m.help()), name, m.kind())
+		m.write(&b, name)
+	}
+	return b.String()
+}
+
+// Gather returns a snapshot of the metrics, ordered by name.
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	families := make([]Family, 0, len(r.metrics))
+	for name, m := range r.metrics {
+		families = append(families, Family{Name: name, Help: m.help(), Kind: m.kind(), Labels: labelsOf(m)})
+	}
+	metrics := make(map[string]metric, len(r.metrics))
+	for name, m := range r.metrics {
+		metrics[name] = m
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	for i := range families {
+		metrics[families[i].Name].gather(&families[i])
+	}
+	return families
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(r.Text()))
+	})
+}
+
+// vec holds the values of a metric by its label values.
+type vec struct {
+	reg      *Registry
+	name     string
+	helpText string
+	labels   []string
+	mu       sync.RWMutex
+	values   map[string]*uint64
+	keys     map[string][]string
+}
+
+func newVec(reg *Registry, name string, help string, labels []string) vec {
+	return vec{reg: reg, name: name, helpText: help, labels: labels, values: make(map[string]*uint64), keys: make(map[string][]string)}
+}
+
+func (v *vec) help() string {
+	return v.helpText
+}
+
+// value returns the float64 bits of the series of the label values.
+func (v *vec) value(values []string) *uint64 {
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	bits, ok := v.values[key]
+	v.mu.RUnlock()
+	if ok {
+		return bits
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if bits, ok := v.values[key]; ok {
+		return bits
+	}
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values, want %d", len(values), len(v.labels)))
+	}
+	bits = new(uint64)
+	v.values[key] = bits
+	v.keys[key] = append([]string(nil), values...)
+	return bits
+}
+
+func (v *vec) write(b *strings.Builder, name string) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.keys) {
+		fmt.Fprintf(b, "%s%s %s\n", name, labelString(v.labels, v.keys[key], ""), formatFloat(math.Float64frombits(atomic.LoadUint64(v.values[key]))))
+	}
+}
+
+func (v *vec) gather(f *Family) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.keys) {
+		f.Series = append(f.Series, Series{Values: v.keys[key], Value: math.Float64frombits(atomic.LoadUint64(v.values[key]))})
+	}
+}
+
+func add(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Counter is a metric which only increases, such as a count of requests.
+type Counter struct {
+	vec
+}
+
+func (c *Counter) kind() string {
+	return "counter"
+}
+
+// Inc adds one to the series of the label values.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds the delta, which must not be negative, to the series of the label values.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter decreased")
+	}
+	add(c.value(values), delta)
+	for _, e := range c.reg.exportersOf() {
+		e.Count(c.name, delta, c.labels, values)
+	}
+}
+
+// Gauge is a metric which may increase and decrease, such as requests in flight.
+type Gauge struct {
+	vec
+}
+
+func (g *Gauge) kind() string {
+	return "gauge"
+}
+
+// Set sets the series of the label values.
+func (g *Gauge) Set(v float64, values ...string) {
+	atomic.StoreUint64(g.value(values), math.Float64bits(v))
+	for _, e := range g.reg.exportersOf() {
+		e.Gauge(g.name, v, g.labels, values)
+	}
+}
+
+// Add adds the delta, which may be negative, to the series of the label values.
+func (g *Gauge) Add(delta float64, values ...string) {
+	bits := g.value(values)
+	add(bits, delta)
+	if exporters := g.reg.exportersOf(); len(exporters) > 0 {
+		v := math.Float64frombits(atomic.LoadUint64(bits))
+		for _, e := range exporters {
+			e.Gauge(g.name, v, g.labels, values)
+		}
+	}
+}
+
+// Histogram counts observations, such as request durations, in buckets.
+type Histogram struct {
+	vec
+	buckets []float64
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) kind() string {
+	return "histogram"
+}
+
+// Observe records the value in the series of the label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	h.mu.RLock()
+	s, ok := h.series[key]
+	h.mu.RUnlock()
+	if !ok {
+		h.mu.Lock()
+		if s, ok = h.series[key]; !ok {
+			if len(values) != len(h.labels) {
+				h.mu.Unlock()
+				panic(fmt.Sprintf("metrics: got %d label values, want %d", len(values), len(h.labels)))
+			}
+			s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+			h.series[key] = s
+			h.keys[key] = append([]string(nil), values...)
+		}
+		h.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+	s.mu.Unlock()
+
+	for _, e := range h.reg.exportersOf() {
+		e.Observe(h.name, v, h.labels, values)
+	}
+}
+
+func (h *Histogram) write(b *strings.Builder, name string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, key := range sortedKeys(h.keys) {
+		s := h.series[key]
+		values := h.keys[key]
+		s.mu.Lock()
+		for i, bound := range h.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, labelString(h.labels, values, formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, labelString(h.labels, values, "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", name, labelString(h.labels, values, ""), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", name, labelString(h.labels, values, ""), s.count)
+		s.mu.Unlock()
+	}
+}
+
+func (h *Histogram) gather(f *Family) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	f.Buckets = h.buckets
+	for _, key := range sortedKeys(h.keys) {
+		s := h.series[key]
+		s.mu.Lock()
+		f.Series = append(f.Series, Series{Values: h.keys[key], Counts: append([]uint64(nil), s.counts...), Count: s.count, Sum: s.sum})
+		s.mu.Unlock()
+	}
+}
+
+func labelsOf(m metric) []string {
+	switch m := m.(type) {
+	case *Counter:
+		return m.labels
+	case *Gauge:
+		return m.labels
+	case *Histogram:
+		return m.labels
+	}
+	return nil
+}
+
+func sortedKeys(keys map[string][]string) []string {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// labelString formats the labels of a series, with the le label of a bucket.
+func labelString(labels []string, values []string, le string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestText(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.Counter("requests_total", "Requests served.", "route", "status")
+	requests.Inc("/users/{id}", "200")
+	requests.Add(2, "/users/{id}", "200")
+	requests.Inc(`/say/"hi"`, "500")
+	reg.Gauge("in_flight", "Requests being served.").Set(3)
+	durations := reg.Histogram("duration_seconds", "Time taken.", []float64{0.5, 0.1}, "route")
+	durations.Observe(0.05, "/a")
+	durations.Observe(0.3, "/a")
+
+	want := `# HELP duration_seconds Time taken.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 1
+duration_seconds_bucket{route="/a",le="0.5"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 2
+duration_seconds_sum{route="/a"} 0.35
+duration_seconds_count{route="/a"} 2
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight 3
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/say/\"hi\"",status="500"} 1
+requests_total{route="/users/{id}",status="200"} 3
+`
+	if got := reg.Text(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Metrics are declared wherever they are used, so registering again returns them.
+	if reg.Counter("requests_total", "Requests served.", "route", "status") != requests {
+		t.Fatal("registering a counter again returned another")
+	}
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(string), "requests_total") {
+			t.Fatalf("got %v, want a panic registering a counter as a gauge", v)
+		}
+	}()
+	reg.Gauge("requests_total", "Requests served.", "route", "status")
+}
+
+func TestLabelLimit(t *testing.T) {
+	limit := NewLabelLimit(2, false)
+	for _, v := range []string{"acme", "globex", "acme"} {
+		if got := limit.Value(v); got != v {
+			t.Fatalf("got %q, want %q", got, v)
+		}
+	}
+	if got := limit.Value("initech"); got != OverflowLabel {
+		t.Fatalf("got %q beyond the limit, want %q", got, OverflowLabel)
+	}
+
+	hashed := NewLabelLimit(1, true)
+	if got := hashed.Value("secret-key"); got == "secret-key" || !strings.HasPrefix(got, "sha256:") {
+		t.Fatalf("got %q, want a hash", got)
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	exporter, err := NewStatsD(conn.LocalAddr().String(), "app.", true, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewRegistry()
+	reg.Export(exporter)
+	reg.Counter("requests_total", "Requests served.", "route", "status").Add(2, "/users", "200")
+	reg.Gauge("in_flight", "Requests being served.").Add(3)
+	reg.Histogram("duration_seconds", "Time taken.", DefBuckets, "route").Observe(0.25, "/a,b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exporter.Run(ctx)
+
+	buf := make([]byte, maxPacket)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "app.requests_total:2|c|#route:/users,status:200\napp.in_flight:3|g\napp.duration_seconds:0.25|h|#route:/a_b"
+	if got := string(buf[:n]); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
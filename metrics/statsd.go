@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacket is the size of StatsD packets, within the MTU of most networks.
+const maxPacket = 1432
+
+// StatsD is an Exporter pushing metrics to a StatsD agent over UDP, counters as
+// counts, gauges as gauges and histogram observations as histograms. Lines are
+// batched into packets, flushed when full or at each interval of Run.
+type StatsD struct {
+	mu        sync.Mutex
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	interval  time.Duration
+	buf       bytes.Buffer
+}
+
+// NewStatsD returns a StatsD pushing to the agent at the address, naming metrics
+// with the prefix. When dogstatsd is set, labels are sent as DogStatsD tags, as
+// Datadog agents expect; otherwise they are dropped.
+func NewStatsD(addr string, prefix string, dogstatsd bool, interval time.Duration) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("NewStatsD: failed dialing agent: %w", err)
+	}
+	return &StatsD{conn: conn, prefix: prefix, dogstatsd: dogstatsd, interval: interval}, nil
+}
+
+// Run flushes the batched lines at each interval until the context is done, then
+// flushes them a last time and closes the connection, so that it may be loaded as a
+// worker of a server.
+func (s *StatsD) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.flush()
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// Count sends the delta of a counter.
+func (s *StatsD) Count(name string, delta float64, labels []string, values []string) {
+	s.send(name, delta, "c", labels, values)
+}
+
+// Gauge sends the value of a gauge.
+func (s *StatsD) Gauge(name string, value float64, labels []string, values []string) {
+	s.send(name, value, "g", labels, values)
+}
+
+// Observe sends an observation of a histogram.
+func (s *StatsD) Observe(name string, value float64, labels []string, values []string) {
+	s.send(name, value, "h", labels, values)
+}
+
+func (s *StatsD) send(name string, value float64, kind string, labels []string, values []string) {
+	line := fmt.Sprintf("%s%s:%s|%s", s.prefix, name, formatFloat(value), kind)
+	if s.dogstatsd && len(labels) > 0 {
+		tags := make([]string, len(labels))
+		for i, label := range labels {
+			tags[i] = label + ":" + tagEscaper.Replace(values[i])
+		}
+		line += "|#" + strings.Join(tags, ",")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len() > 0 && s.buf.Len()+1+len(line) > maxPacket {
+		s.flush()
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line)
+}
+
+// flush sends the batched lines. Errors are dropped, as StatsD is best effort.
+func (s *StatsD) flush() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	_, _ = s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+}
+
+var tagEscaper = strings.NewReplacer(",", "_", "|", "_", "\n", "_")
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// UnmatchedRoute is the route logged for requests which matched no route.
+const UnmatchedRoute = "unmatched"
+
+// AccessLog returns a handler wrapper logging each request once it has been served,
+// with the pattern of the matched route.
+func AccessLog(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if !helpers.Observed(r) {
+				return
+			}
+
+			route := helpers.RoutePattern(r)
+			if route == "" {
+				route = UnmatchedRoute
+			}
+			status := ww.Status()
+			gone := helpers.ClientGone(r.Context())
+			switch {
+			case gone:
+				status = helpers.StatusClientClosedRequest
+			case status == 0:
+				status = http.StatusOK
+			}
+
+			event := logger.Info()
+			if status >= http.StatusInternalServerError {
+				event = logger.Error()
+			}
+			event.Str("Method", r.Method).
+				Str("Route", route).
+				Str("Path", r.URL.Path).
+				Int("Status", status).
+				Bool("ClientGone", gone).
+				Int("Bytes", ww.BytesWritten()).
+				Dur("Duration", time.Since(start)).
+				Str("RemoteAddr", r.RemoteAddr).
+				Str("RemoteIP", remoteIP(r)).
+				Str("RequestID", helpers.RequestID(r)).
+				Msg("Request served")
+		})
+	}
+}
+
+// NewLoggingMiddleware returns AccessLog as a middleware, for handlers composed
+// outside a server; servers register it themselves with the accessLog config.
+func NewLoggingMiddleware(logger zerolog.Logger, opts ...MiddlewareWrapper) Middleware {
+	return NewMiddleware(AccessLog(logger), "accessLog", true, false, opts...)
+}
+
+// remoteIP returns the IP address of the client, without its port.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewLoggingMiddleware(zerolog.New(&buf)).Method()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.RemoteAddr = "192.0.2.1:5123"
+	req.Header.Set("X-Request-Id", "abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log %q: %v", buf.String(), err)
+	}
+	for field, want := range map[string]interface{}{
+		"Method":    "POST",
+		"Path":      "/items",
+		"Status":    float64(http.StatusCreated),
+		"Bytes":     float64(len("created")),
+		"RemoteIP":  "192.0.2.1",
+		"RequestID": "abc",
+	} {
+		if entry[field] != want {
+			t.Errorf("%s: got %v, want %v", field, entry[field], want)
+		}
+	}
+	if _, ok := entry["Duration"]; !ok {
+		t.Error("Duration not logged")
+	}
+}
+
+func TestAccessLogRoute(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(AccessLog(zerolog.New(&buf)))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for path, want := range map[string]string{
+		"/users/1": "/users/{id}",
+		"/users/2": "/users/{id}",
+		"/missing": UnmatchedRoute,
+	} {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		var entry map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: log %q: %v", path, buf.String(), err)
+		}
+		if entry["Route"] != want || entry["Path"] != path {
+			t.Errorf("%s: got route %v and path %v, want %s and %s", path, entry["Route"], entry["Path"], want, path)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/cache"
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// cachedResponse is a response stored by Cache, with the values of the request
+// headers named by its Vary header.
+type cachedResponse struct {
+	Status int               `json:"status"`
+	Header http.Header       `json:"header"`
+	Body   []byte            `json:"body"`
+	Vary   map[string]string `json:"vary"`
+}
+
+// uncacheable carries a response which may not be cached, and whether it may be
+// shared with the other requests which waited for it.
+type uncacheable struct {
+	response cachedResponse
+	shared   bool
+}
+
+func (uncacheable) Error() string {
+	return "response not cacheable"
+}
+
+// Cache returns a handler wrapper caching successful responses to GET requests for
+// the ttl. Requests with credentials and private responses are never cached.
+func Cache(ttl time.Duration) func(http.Handler) http.Handler {
+	responses := helpers.NewCacheAside(cache.NewMemory(10000))
+	var varyMu sync.RWMutex
+	vary := make(map[string][]string)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			base := r.Method + " " + r.Host + r.URL.RequestURI()
+			varyMu.RLock()
+			names := vary[base]
+			varyMu.RUnlock()
+			key := base
+			for _, name := range names {
+				key += "\n" + name + ": " + r.Header.Get(name)
+			}
+
+			led := false
+			resp, err := helpers.Cached(r.Context(), responses, key, ttl, func(ctx context.Context) (cachedResponse, error) {
+				led = true
+				rec := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+				next.ServeHTTP(rec, r.WithContext(ctx))
+				resp := cachedResponse{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+				if private(resp.Header) {
+					return resp, uncacheable{response: resp}
+				}
+				if resp.Status != http.StatusOK {
+					return resp, uncacheable{response: resp, shared: true}
+				}
+				if names := varyNames(resp.Header); len(names) > 0 {
+					resp.Vary = make(map[string]string, len(names))
+					for _, name := range names {
+						resp.Vary[name] = r.Header.Get(name)
+					}
+					varyMu.Lock()
+					vary[base] = names
+					varyMu.Unlock()
+				}
+				return resp, nil
+			})
+			var u uncacheable
+			switch {
+			case errors.As(err, &u) && (led || u.shared):
+				resp = u.response
+			case errors.As(err, &u):
+				next.ServeHTTP(w, r)
+				return
+			case err != nil:
+				helpers.Error(w, r, http.StatusServiceUnavailable)
+				return
+			}
+			for name, value := range resp.Vary {
+				if r.Header.Get(name) != value {
+					// Cached for another variant before its Vary header was known.
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(resp.Status)
+			_, _ = bytes.NewReader(resp.Body).WriteTo(w)
+		})
+	}
+}
+
+// private returns whether the response headers mark it personal to its client, or
+// make it vary on every request.
+func private(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return true
+	}
+	for _, directive := range strings.Split(strings.Join(h.Values("Cache-Control"), ","), ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "private") || strings.EqualFold(name, "no-store") {
+			return true
+		}
+	}
+	for _, name := range varyNames(h) {
+		if name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// varyNames returns the sorted, canonical header names of the Vary header.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, value := range h.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// responseRecorder records the response of the handler, to be cached.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		rr.wroteHeader = true
+		rr.status = code
+	}
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	return rr.body.Write(b)
+}
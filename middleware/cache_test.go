@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	h := Cache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("missing") != "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("report"))
+	}))
+	serve := func(path string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := serve("/cache-test/report", false)
+		if rec.Code != http.StatusOK || rec.Body.String() != "report" || rec.Header().Get("Content-Type") != "text/plain" {
+			t.Fatalf("got %d %q %q, want the report", rec.Code, rec.Body.String(), rec.Header().Get("Content-Type"))
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want 1", calls)
+	}
+
+	// Unsuccessful responses and requests bearing credentials are not cached.
+	serve("/cache-test/report?missing=1", false)
+	if rec := serve("/cache-test/report?missing=1", false); rec.Code != http.StatusNotFound {
+		t.Fatalf("got %d, want 404", rec.Code)
+	}
+	serve("/cache-test/report", true)
+	if calls != 4 {
+		t.Fatalf("handler called %d times, want 4", calls)
+	}
+}
+
+func TestCacheKeysAndPrivacy(t *testing.T) {
+	calls := 0
+	h := Cache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		w.Write([]byte(r.Host + " " + r.Header.Get("Accept-Language")))
+	}))
+	serve := func(host, path, lang string) string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Body.String() + rec.Header().Get("Set-Cookie")
+	}
+
+	if a, b := serve("a.example", "/page", ""), serve("b.example", "/page", ""); a == b {
+		t.Fatalf("hosts share a cache entry: %q", a)
+	}
+	serve("a.example", "/cookie", "")
+	serve("a.example", "/private", "")
+	before := calls
+	serve("a.example", "/cookie", "")
+	serve("a.example", "/private", "")
+	if calls != before+2 {
+		t.Fatal("cached a response setting a cookie or marked private")
+	}
+
+	if got := serve("a.example", "/vary", "en"); got != "a.example en" {
+		t.Fatalf("got %q", got)
+	}
+	if got := serve("a.example", "/vary", "fr"); got != "a.example fr" {
+		t.Fatalf("got %q, want the French variant", got)
+	}
+	before = calls
+	serve("a.example", "/vary", "fr")
+	serve("a.example", "/vary", "en")
+	if got := serve("a.example", "/vary", "en"); got != "a.example en" || calls > before+1 {
+		t.Fatalf("got %q after %d calls, want cached variants", got, calls-before)
+	}
+}
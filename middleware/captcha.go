@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// CaptchaHeader carries the CAPTCHA token of requests which are not form submissions.
+const CaptchaHeader = "X-Captcha-Token"
+
+// Captcha returns a handler wrapper refusing requests, with 403 Forbidden, unless they
+// carry a CAPTCHA token accepted by the provider, either in the X-Captcha-Token
+// header or in the provider's form field. Verification errors respond 503.
+func Captcha(provider helpers.CaptchaVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(CaptchaHeader)
+			if token == "" {
+				token = r.FormValue(provider.Field())
+			}
+
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			if err := helpers.VerifyCaptcha(r.Context(), provider, token, ip); err != nil {
+				if errors.Is(err, helpers.ErrCaptchaFailed) {
+					helpers.Error(w, r, http.StatusForbidden)
+				} else {
+					helpers.Error(w, r, http.StatusServiceUnavailable)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
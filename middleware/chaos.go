@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// ChaosPolicy configures the faults injected by Chaos.
+type ChaosPolicy struct {
+	// Percent is the percentage of requests affected.
+	Percent float64
+	// Latency is added before affected requests are handled, chosen uniformly
+	// up to MaxLatency when it is greater.
+	Latency    time.Duration
+	MaxLatency time.Duration
+	// ErrorPercent is the percentage of affected requests answered with Status,
+	// 503 Service Unavailable by default, instead of being handled.
+	ErrorPercent float64
+	Status       int
+	// ResetPercent is the percentage of affected requests whose connection is
+	// reset instead of being answered.
+	ResetPercent float64
+}
+
+// Chaos returns a handler wrapper injecting latency, errors and connection resets
+// into a percentage of requests, to test the retry behaviour of clients. It has
+// no effect under the prod profile.
+func Chaos(policy ChaosPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Without a configuration the profile is unknown, so it is taken to be prod.
+			if cfg := c.FromContext(r.Context()); cfg == nil || cfg.Profile == c.ProfileProd || !chance(policy.Percent) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			delay := policy.Latency
+			if policy.MaxLatency > delay {
+				delay += time.Duration(rand.Int63n(int64(policy.MaxLatency - delay)))
+			}
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			switch {
+			case chance(policy.ResetPercent):
+				reset(w)
+			case chance(policy.ErrorPercent):
+				code := policy.Status
+				if code == 0 {
+					code = http.StatusServiceUnavailable
+				}
+				helpers.Error(w, r, code)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func chance(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// reset closes the connection abruptly, with a TCP reset where possible.
+func reset(w http.ResponseWriter) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	c "github.com/Etwodev/ramchi/config"
+)
+
+func TestChaos(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(h http.Handler, profile string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if profile != "" {
+			req = req.WithContext(c.WithContext(req.Context(), &c.Config{Profile: profile}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	failing := Chaos(ChaosPolicy{Percent: 100, ErrorPercent: 100, Status: http.StatusBadGateway})(ok)
+	if rec := serve(failing, c.ProfileDev); rec.Code != http.StatusBadGateway {
+		t.Fatalf("dev: got %d, want the injected 502", rec.Code)
+	}
+	for _, profile := range []string{c.ProfileProd, ""} {
+		if rec := serve(failing, profile); rec.Code != http.StatusOK {
+			t.Fatalf("profile %q: got %d, want the request left alone", profile, rec.Code)
+		}
+	}
+	if rec := serve(Chaos(ChaosPolicy{Percent: 0, ErrorPercent: 100})(ok), c.ProfileDev); rec.Code != http.StatusOK {
+		t.Fatalf("no requests affected: got %d, want 200", rec.Code)
+	}
+	if rec := serve(Chaos(ChaosPolicy{Percent: 100, ErrorPercent: 100})(ok), c.ProfileDev); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("no status: got %d, want 503", rec.Code)
+	}
+
+	start := time.Now()
+	slow := Chaos(ChaosPolicy{Percent: 100, Latency: 20 * time.Millisecond, MaxLatency: 40 * time.Millisecond})(ok)
+	if rec := serve(slow, c.ProfileDev); rec.Code != http.StatusOK {
+		t.Fatalf("latency: got %d, want the request handled after the delay", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("handled after %v, want the injected delay of 20ms to 40ms", elapsed)
+	}
+}
+
+func TestChaosReset(t *testing.T) {
+	reset := Chaos(ChaosPolicy{Percent: 100, ResetPercent: 100})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reset.ServeHTTP(w, r.WithContext(c.WithContext(r.Context(), &c.Config{Profile: c.ProfileDev})))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("got %d, want the connection reset", resp.StatusCode)
+	}
+}
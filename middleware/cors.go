@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy describes which cross-origin requests are permitted.
+type CORSPolicy struct {
+	// AllowedOrigins lists the permitted origins, "*" permits any origin.
+	AllowedOrigins []string
+	// AllowedMethods lists the methods permitted in preflight requests.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers permitted in preflight requests.
+	// When empty, the requested headers are reflected back.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers readable by the client.
+	ExposedHeaders []string
+	// AllowCredentials permits cookies and authorization headers.
+	AllowCredentials bool
+	// MaxAge is the number of seconds a preflight response may be cached.
+	MaxAge int
+}
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodHead}
+
+// AllowsOrigin returns whether the policy permits the origin.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the policy permits credentialed requests from any
+// origin, which would let any site act on behalf of the user.
+func (p CORSPolicy) Validate() error {
+	if p.AllowCredentials && p.wildcard() {
+		return errors.New("CORSPolicy: cannot permit any origin with AllowCredentials")
+	}
+	return nil
+}
+
+func (p CORSPolicy) wildcard() bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS returns a handler wrapper that applies the policy to cross-origin requests.
+// Preflight requests are answered directly and never reach the wrapped handler.
+// It panics if the policy is invalid.
+func CORS(policy CORSPolicy) func(http.Handler) http.Handler {
+	if err := policy.Validate(); err != nil {
+		panic(err)
+	}
+	methods := policy.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if policy.AllowsOrigin(origin) {
+				if policy.wildcard() && !policy.AllowCredentials {
+					h.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					h.Set("Access-Control-Allow-Origin", origin)
+				}
+				if policy.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+
+				if preflight {
+					h.Add("Vary", "Access-Control-Request-Method")
+					h.Add("Vary", "Access-Control-Request-Headers")
+					h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+					if len(policy.AllowedHeaders) > 0 {
+						h.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+					} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+						h.Set("Access-Control-Allow-Headers", requested)
+					}
+					if policy.MaxAge > 0 {
+						h.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+					}
+				} else if len(policy.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
+
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSCredentials(t *testing.T) {
+	if err := (CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Fatal("validated credentials permitted from any origin")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("CORS accepted credentials permitted from any origin")
+			}
+		}()
+		CORS(CORSPolicy{AllowedOrigins: []string{"https://a.example", "*"}, AllowCredentials: true})
+	}()
+
+	h := CORS(CORSPolicy{AllowedOrigins: []string{"https://a.example"}, AllowCredentials: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for origin, allowed := range map[string]string{"https://a.example": "https://a.example", "https://b.example": ""} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != allowed {
+			t.Fatalf("%s: got Access-Control-Allow-Origin %q, want %q", origin, got, allowed)
+		}
+		if allowed != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatalf("%s: credentials not permitted", origin)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+// Disconnects returns a handler wrapper counting, in reg, the requests whose client
+// disconnected before the handler returned, by route. The server registers it on
+// every route, with its own registry.
+func Disconnects(reg *metrics.Registry) func(http.Handler) http.Handler {
+	disconnects := reg.Counter("ramchi_disconnects_total", "Requests whose client disconnected before they were served, by route.", "route")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			if !helpers.ClientGone(r.Context()) || !helpers.Observed(r) {
+				return
+			}
+			route := helpers.RoutePattern(r)
+			if route == "" {
+				route = UnmatchedRoute
+			}
+			disconnects.Inc(route)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+func TestDisconnects(t *testing.T) {
+	reg := metrics.NewRegistry()
+	serve := func(ctx context.Context, cancel context.CancelFunc) {
+		Disconnects(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The client disconnects while the request is being served.
+			cancel()
+			http.Error(w, "query cancelled", http.StatusInternalServerError)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(ctx))
+	}
+
+	serve(context.WithCancel(context.Background()))
+	serve(context.Background(), func() {})
+	if text := reg.Text(); !strings.Contains(text, `ramchi_disconnects_total{route="unmatched"} 1`) {
+		t.Fatalf("got metrics %s, want one disconnect counted", text)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+type envelopeWriter struct {
+	http.ResponseWriter
+	state       *helpers.EnvelopeState
+	code        int
+	buffer      bytes.Buffer
+	wroteHeader bool
+	passthrough bool
+}
+
+func (e *envelopeWriter) WriteHeader(code int) {
+	if e.wroteHeader {
+		return
+	}
+	e.wroteHeader = true
+	e.code = code
+
+	mt, _, _ := mime.ParseMediaType(e.Header().Get("Content-Type"))
+	if e.state.Written || mt != "application/json" {
+		e.passthrough = true
+		e.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (e *envelopeWriter) Write(b []byte) (int, error) {
+	if !e.wroteHeader {
+		if e.Header().Get("Content-Type") == "" {
+			e.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.passthrough {
+		return e.ResponseWriter.Write(b)
+	}
+	return e.buffer.Write(b)
+}
+
+// Flush sends buffered data to the client, which is only possible for
+// responses which are not being enveloped.
+func (e *envelopeWriter) Flush() {
+	if f, ok := e.ResponseWriter.(http.Flusher); ok && e.passthrough {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (e *envelopeWriter) Unwrap() http.ResponseWriter {
+	return e.ResponseWriter
+}
+
+// finish envelopes a buffered JSON response written without the helpers.
+func (e *envelopeWriter) finish() {
+	if !e.wroteHeader || e.passthrough {
+		return
+	}
+
+	env := helpers.Envelope{Meta: e.state.Meta}
+	if e.code >= http.StatusBadRequest {
+		env.Error = &helpers.EnvelopeError{Status: e.code, Message: http.StatusText(e.code)}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(e.buffer.Bytes(), &body) == nil && body.Error != "" {
+			env.Error.Message = body.Error
+		}
+	} else if e.buffer.Len() > 0 {
+		env.Data = json.RawMessage(e.buffer.Bytes())
+	}
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		e.ResponseWriter.WriteHeader(e.code)
+		_, _ = e.ResponseWriter.Write(e.buffer.Bytes())
+		return
+	}
+	e.Header().Del("Content-Length")
+	e.ResponseWriter.WriteHeader(e.code)
+	_, _ = e.ResponseWriter.Write(body)
+}
+
+// Envelope enables envelope mode for the helpers, and wraps JSON responses written
+// without them in an Envelope. Other content types are passed through untouched.
+func Envelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, state := helpers.WithEnvelope(r.Context())
+		ew := &envelopeWriter{ResponseWriter: w, state: state}
+		next.ServeHTTP(ew, r.WithContext(ctx))
+		ew.finish()
+	})
+}
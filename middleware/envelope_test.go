@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+func TestEnvelope(t *testing.T) {
+	raw := Envelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	raw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); body != `{"data":{"id":1},"error":null,"meta":{}}` {
+		t.Fatalf("unexpected raw envelope: %s", body)
+	}
+
+	paged := Envelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		helpers.Paginate(w, r, helpers.Pagination{Page: 1, PerPage: 10, Total: 25})
+		helpers.JSON(w, r, http.StatusOK, []int{1})
+	}))
+
+	rec = httptest.NewRecorder()
+	paged.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); body != `{"data":[1],"error":null,"meta":{"pagination":{"page":1,"perPage":10,"total":25,"totalPages":3}}}` {
+		t.Fatalf("unexpected helper envelope: %s", body)
+	}
+
+	failed := Envelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		helpers.JSONError(w, r, http.StatusTeapot, "no coffee")
+	}))
+
+	rec = httptest.NewRecorder()
+	failed.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); rec.Code != http.StatusTeapot || body != `{"data":null,"error":{"status":418,"message":"no coffee"},"meta":{}}` {
+		t.Fatalf("unexpected error envelope: %d %s", rec.Code, body)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// Fields returns a handler wrapper allowing requests to select only the fields, or
+// the fields nested within them, for sparse fieldsets written by helpers.JSONFields.
+// Requests for any other field respond 400 Bad Request, naming the field.
+func Fields(allowed ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(helpers.WithAllowedFields(r.Context(), allowed))
+			if f, ok := helpers.DisallowedField(r); ok {
+				helpers.JSONError(w, r, http.StatusBadRequest, fmt.Sprintf("field %q may not be requested", f))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import "net/http"
+
+// HeaderPolicy sets default response headers and controls the Server header.
+type HeaderPolicy struct {
+	// Defaults are set on responses which have not set them, such as Cache-Control
+	// or X-API-Version.
+	Defaults map[string]string
+	// Server replaces the Server header, such as one copied from an upstream by a proxy.
+	Server string
+	// HideServer removes the Server header, taking precedence over Server.
+	HideServer bool
+}
+
+// Headers applies the policy as the response status is written, so that headers set
+// by the handler take precedence over the defaults. Policies of nested routers are
+// applied innermost first, so the defaults of a router win over the server's.
+func Headers(policy HeaderPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&headerWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}
+}
+
+type headerWriter struct {
+	http.ResponseWriter
+	policy      HeaderPolicy
+	wroteHeader bool
+}
+
+func (hw *headerWriter) apply() {
+	if hw.wroteHeader {
+		return
+	}
+	hw.wroteHeader = true
+
+	h := hw.Header()
+	for k, v := range hw.policy.Defaults {
+		if h.Get(k) == "" {
+			h.Set(k, v)
+		}
+	}
+	switch {
+	case hw.policy.HideServer:
+		h.Del("Server")
+	case hw.policy.Server != "":
+		h.Set("Server", hw.policy.Server)
+	}
+}
+
+func (hw *headerWriter) WriteHeader(code int) {
+	hw.apply()
+	hw.ResponseWriter.WriteHeader(code)
+}
+
+func (hw *headerWriter) Write(b []byte) (int, error) {
+	hw.apply()
+	return hw.ResponseWriter.Write(b)
+}
+
+func (hw *headerWriter) Flush() {
+	hw.apply()
+	if f, ok := hw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (hw *headerWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	server := Headers(HeaderPolicy{Defaults: map[string]string{"Cache-Control": "no-store", "X-API-Version": "1"}, HideServer: true})
+	api := Headers(HeaderPolicy{Defaults: map[string]string{"X-API-Version": "2"}})
+
+	h := server(api(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "upstream/1.0")
+		if r.URL.Path == "/cached" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.Write([]byte("ok"))
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("X-API-Version") != "2" || w.Header().Get("Server") != "" {
+		t.Errorf("unexpected headers: %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cached", nil))
+	if w.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("handler header overridden: %v", w.Header())
+	}
+}
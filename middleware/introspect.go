@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+// IntrospectionPolicy configures the validation of opaque bearer tokens.
+type IntrospectionPolicy struct {
+	// Endpoint is the introspection endpoint of the authorization server, which
+	// is called with the client credentials using HTTP Basic authentication.
+	Endpoint     string
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+	// Scopes are the scopes every token must have been granted.
+	Scopes []string
+	// CacheTTL is how long an active token is trusted without introspecting it
+	// again, never beyond its expiry. NegativeTTL is the same for inactive tokens.
+	CacheTTL    time.Duration
+	NegativeTTL time.Duration
+	// StaleTTL is how long past its CacheTTL a cached active token is still trusted
+	// when the endpoint fails. When zero, requests fail with 503 Service Unavailable.
+	StaleTTL time.Duration
+	// MaxEntries bounds the cache.
+	MaxEntries int
+}
+
+type introspection struct {
+	policy  IntrospectionPolicy
+	mu      sync.Mutex
+	cache   map[[32]byte]introspected
+	flights map[[32]byte]*introspectFlight
+}
+
+// introspectFlight is an introspection in progress, shared by the concurrent
+// requests bearing its token.
+type introspectFlight struct {
+	done chan struct{}
+	info *helpers.TokenInfo
+	err  error
+}
+
+type introspected struct {
+	info    *helpers.TokenInfo
+	fresh   time.Time
+	expires time.Time
+}
+
+// Introspect returns a handler wrapper accepting only requests bearing a token the
+// authorization server reports active (RFC 7662), with the required scopes. The
+// token is available via helpers.Token. Missing or inactive tokens respond 401
+// Unauthorized and tokens lacking a scope 403 Forbidden.
+func Introspect(policy IntrospectionPolicy) func(http.Handler) http.Handler {
+	if policy.Client == nil {
+		policy.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if policy.MaxEntries <= 0 {
+		policy.MaxEntries = 10000
+	}
+	in := &introspection{policy: policy, cache: make(map[[32]byte]introspected), flights: make(map[[32]byte]*introspectFlight)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := helpers.BearerToken(r)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				helpers.Error(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			info, err := in.lookup(r.Context(), token)
+			if err != nil {
+				zerolog.Ctx(r.Context()).Warn().Str("Function", "Introspect").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed introspecting token")
+				helpers.Error(w, r, http.StatusServiceUnavailable)
+				return
+			}
+			if !info.Active || (info.Exp != 0 && time.Now().Unix() >= info.Exp) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				helpers.Error(w, r, http.StatusUnauthorized)
+				return
+			}
+			for _, scope := range policy.Scopes {
+				if !info.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, strings.Join(policy.Scopes, " ")))
+					helpers.Error(w, r, http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(helpers.WithTokenInfo(r.Context(), info)))
+		})
+	}
+}
+
+// lookup returns the cached introspection of the token, introspecting it when the
+// cache is not fresh, and falling back to a stale active entry when that fails.
+// Concurrent misses of a token share one introspection, so that a burst of
+// requests bearing a new token does not flood the authorization server.
+func (in *introspection) lookup(ctx context.Context, token string) (*helpers.TokenInfo, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	in.mu.Lock()
+	cached, ok := in.cache[key]
+	in.mu.Unlock()
+	if ok && now.Before(cached.fresh) {
+		return cached.info, nil
+	}
+
+	info, err := in.introspectOnce(ctx, key, token)
+	if err != nil {
+		if ok && cached.info.Active && now.Before(cached.expires) {
+			return cached.info, nil
+		}
+		return nil, err
+	}
+
+	ttl, stale := in.policy.NegativeTTL, time.Duration(0)
+	if info.Active {
+		ttl, stale = in.policy.CacheTTL, in.policy.StaleTTL
+	}
+	if ttl > 0 {
+		entry := introspected{info: info, fresh: now.Add(ttl), expires: now.Add(ttl + stale)}
+		if info.Exp != 0 {
+			exp := time.Unix(info.Exp, 0)
+			if entry.fresh.After(exp) {
+				entry.fresh = exp
+			}
+			if entry.expires.After(exp) {
+				entry.expires = exp
+			}
+		}
+		in.store(key, entry, now)
+	}
+	return info, nil
+}
+
+// introspectOnce introspects the token, joining an introspection of it already in
+// progress. The introspection runs detached from the context of the request
+// starting it, so that it cancelling does not fail the others waiting on it.
+func (in *introspection) introspectOnce(ctx context.Context, key [32]byte, token string) (*helpers.TokenInfo, error) {
+	in.mu.Lock()
+	f, ok := in.flights[key]
+	if !ok {
+		f = &introspectFlight{done: make(chan struct{})}
+		in.flights[key] = f
+		go func() {
+			f.info, f.err = in.introspect(context.WithoutCancel(ctx), token)
+			in.mu.Lock()
+			delete(in.flights, key)
+			in.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	in.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.info, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (in *introspection) store(key [32]byte, entry introspected, now time.Time) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.cache) >= in.policy.MaxEntries {
+		for k, e := range in.cache {
+			if !now.Before(e.expires) {
+				delete(in.cache, k)
+			}
+		}
+		// Evict arbitrary entries should none have expired.
+		for k := range in.cache {
+			if len(in.cache) < in.policy.MaxEntries {
+				break
+			}
+			delete(in.cache, k)
+		}
+	}
+	in.cache[key] = entry
+}
+
+func (in *introspection) introspect(ctx context.Context, token string) (*helpers.TokenInfo, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.policy.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("introspect: failed creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.policy.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(in.policy.ClientID), url.QueryEscape(in.policy.ClientSecret))
+	}
+
+	res, err := in.policy.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspect: failed sending request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspect: endpoint returned %s", res.Status)
+	}
+
+	var info helpers.TokenInfo
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&info); err != nil {
+		return nil, fmt.Errorf("introspect: failed decoding response: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+func TestIntrospect(t *testing.T) {
+	var calls atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if id, secret, _ := r.BasicAuth(); id != "api" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PostFormValue("token") {
+		case "active":
+			w.Write([]byte(`{"active": true, "scope": "read write", "sub": "alice"}`))
+		case "narrow":
+			w.Write([]byte(`{"active": true, "scope": "write"}`))
+		case "failing":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"active": false}`))
+		}
+	}))
+	defer idp.Close()
+
+	h := Introspect(IntrospectionPolicy{Endpoint: idp.URL, ClientID: "api", ClientSecret: "secret", Scopes: []string{"read"}, CacheTTL: time.Minute, NegativeTTL: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(helpers.Token(r).Sub))
+	}))
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{"active", http.StatusOK},
+		{"active", http.StatusOK},
+		{"revoked", http.StatusUnauthorized},
+		{"narrow", http.StatusForbidden},
+		{"failing", http.StatusServiceUnavailable},
+		{"", http.StatusUnauthorized},
+	} {
+		if rec := serve(tc.token); rec.Code != tc.want {
+			t.Fatalf("token %q: got %d, want %d", tc.token, rec.Code, tc.want)
+		}
+	}
+	if rec := serve("active"); rec.Body.String() != "alice" {
+		t.Fatalf("got subject %q, want the token in the request context", rec.Body.String())
+	}
+	if n := calls.Load(); n != 4 {
+		t.Fatalf("introspected %d times, want each token once", n)
+	}
+}
+
+func TestIntrospectSharesMisses(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(`{"active": true}`))
+	}))
+	defer idp.Close()
+
+	h := Introspect(IntrospectionPolicy{Endpoint: idp.URL, CacheTTL: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer new")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("got %d, want 200", code)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("introspected %d times, want the misses to share one", n)
+	}
+}
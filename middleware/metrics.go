@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// SizeBuckets are the buckets of response sizes, in bytes.
+var SizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Metrics returns a handler wrapper recording, in the registry, the count of
+// requests by router prefix, route, method and status, histograms of their
+// durations and response sizes, and the requests in flight. Requests to routes
+// marked noisy or internal are recorded as sampled by helpers.Observed.
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.Counter("ramchi_http_requests_total", "Requests served, by route and status.", "prefix", "route", "method", "status")
+	durations := reg.Histogram("ramchi_http_request_duration_seconds", "Time taken serving requests, by route.", metrics.DefBuckets, "prefix", "route", "method")
+	sizes := reg.Histogram("ramchi_http_response_size_bytes", "Sizes of response bodies, by route.", SizeBuckets, "prefix", "route", "method")
+	inFlight := reg.Gauge("ramchi_http_requests_in_flight", "Requests being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			start := time.Now()
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if !helpers.Observed(r) {
+				return
+			}
+
+			info, _ := helpers.RouteInfoFromContext(r.Context())
+			route := routeLabel(r)
+			status := ww.Status()
+			switch {
+			case helpers.ClientGone(r.Context()):
+				status = helpers.StatusClientClosedRequest
+			case status == 0:
+				status = http.StatusOK
+			}
+
+			requests.Inc(info.Prefix, route, r.Method, strconv.Itoa(status))
+			durations.Observe(time.Since(start).Seconds(), info.Prefix, route, r.Method)
+			sizes.Observe(float64(ww.BytesWritten()), info.Prefix, route, r.Method)
+		})
+	}
+}
+
+// TenantMetrics returns a handler wrapper recording requests in the registry by
+// their tenant, bounded by limit.
+func TenantMetrics(reg *metrics.Registry, tenant func(r *http.Request) string, limit *metrics.LabelLimit) func(http.Handler) http.Handler {
+	requests := reg.Counter("ramchi_http_tenant_requests_total", "Requests served, by tenant and status.", "tenant", "status")
+	durations := reg.Histogram("ramchi_http_tenant_request_duration_seconds", "Time taken serving requests, by tenant and route.", metrics.DefBuckets, "tenant", "route", "method")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if !helpers.Observed(r) {
+				return
+			}
+
+			name := "none"
+			if t := tenant(r); t != "" {
+				name = limit.Value(t)
+			}
+			route := routeLabel(r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			requests.Inc(name, strconv.Itoa(status))
+			durations.Observe(time.Since(start).Seconds(), name, route, r.Method)
+		})
+	}
+}
+
+// routeLabel returns the route pattern of the request, or UnmatchedRoute.
+func routeLabel(r *http.Request) string {
+	if info, _ := helpers.RouteInfoFromContext(r.Context()); info.Pattern != "" {
+		return info.Pattern
+	}
+	if route := helpers.RoutePattern(r); route != "" {
+		return route
+	}
+	return UnmatchedRoute
+}
+
+// NewMetricsMiddleware returns Metrics as a middleware recording in metrics.Default.
+func NewMetricsMiddleware(opts ...MiddlewareWrapper) Middleware {
+	return NewMiddleware(Metrics(metrics.Default), "metrics", true, false, opts...)
+}
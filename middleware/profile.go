@@ -0,0 +1,49 @@
+package middleware
+
+type profileMiddleware struct {
+	Middleware
+	profiles []string
+}
+
+// Unwrap returns the middleware that was wrapped.
+func (p profileMiddleware) Unwrap() Middleware {
+	return p.Middleware
+}
+
+// WithProfiles registers the middleware only under the given config profiles, such
+// as config.ProfileDev. Middlewares without profiles are registered under every profile.
+func WithProfiles(profiles ...string) MiddlewareWrapper {
+	return func(m Middleware) Middleware {
+		return profileMiddleware{m, profiles}
+	}
+}
+
+// Profiles returns the profiles the middleware is registered under, or nil for
+// every profile. Wrappers are traversed through their Unwrap() Middleware method.
+func Profiles(m Middleware) []string {
+	for m != nil {
+		if p, ok := m.(profileMiddleware); ok {
+			return p.profiles
+		}
+		u, ok := m.(interface{ Unwrap() Middleware })
+		if !ok {
+			return nil
+		}
+		m = u.Unwrap()
+	}
+	return nil
+}
+
+// InProfile returns whether the middleware is registered under the profile.
+func InProfile(m Middleware, profile string) bool {
+	profiles := Profiles(m)
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+// Recovery returns a handler wrapper recovering from panicking handlers, logging the
+// panic with its stack trace, and responding 500 Internal Server Error with the body
+// as JSON, or as a problem when the body is empty. Panics with http.ErrAbortHandler
+// are propagated, so that the connection is aborted as intended.
+func Recovery(logger zerolog.Logger, body []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				route := helpers.RoutePattern(r)
+				if route == "" {
+					route = UnmatchedRoute
+				}
+				logger.Error().
+					Str("Function", "Recovery").
+					Str("Method", r.Method).
+					Str("Route", route).
+					Str("RequestID", helpers.RequestID(r)).
+					Interface("Panic", v).
+					Str("Stack", string(debug.Stack())).
+					Msg("Handler panicked")
+
+				// A response already begun cannot be replaced.
+				if rw.wroteHeader {
+					return
+				}
+				if len(body) == 0 {
+					helpers.Problem(w, r, http.StatusInternalServerError, "")
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write(body)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// NewRecoveryMiddleware returns Recovery as a middleware, responding with a problem.
+func NewRecoveryMiddleware(logger zerolog.Logger, opts ...MiddlewareWrapper) Middleware {
+	return NewMiddleware(Recovery(logger, nil), "recovery", true, false, opts...)
+}
+
+// recoveryWriter records whether the response has begun.
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *recoveryWriter) WriteHeader(code int) {
+	rw.wroteHeader = true
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recoveryWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recoveryWriter) Flush() {
+	rw.wroteHeader = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (rw *recoveryWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
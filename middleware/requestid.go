@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestID identifies each request by its X-Request-Id header, or a random ID,
+// stored in the context and echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(helpers.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(helpers.RequestIDHeader, id)
+		}
+		w.Header().Set(helpers.RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), chimw.RequestIDKey, id)
+		logger := helpers.Logger(r).With().Str("RequestID", id).Logger()
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(ctx)))
+	})
+}
+
+// NewRequestIDMiddleware returns RequestID as a middleware.
+func NewRequestIDMiddleware(opts ...MiddlewareWrapper) Middleware {
+	return NewMiddleware(RequestID, "requestId", true, false, opts...)
+}
+
+// validRequestID reports whether a propagated ID is safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
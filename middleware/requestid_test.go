@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = helpers.RequestID(r)
+		helpers.Logger(r).Info().Msg("handled")
+	}))
+	serve := func(header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(helpers.RequestIDHeader, header)
+		}
+		logger := zerolog.New(&buf)
+		req = req.WithContext(logger.WithContext(req.Context()))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("upstream-42")
+	if seen != "upstream-42" || rec.Header().Get(helpers.RequestIDHeader) != "upstream-42" {
+		t.Fatalf("got %q and header %q, want the propagated ID", seen, rec.Header().Get(helpers.RequestIDHeader))
+	}
+	if !strings.Contains(buf.String(), `"RequestID":"upstream-42"`) {
+		t.Fatalf("log %q lacks the request ID", buf.String())
+	}
+
+	for _, header := range []string{"", "bad id\n"} {
+		rec := serve(header)
+		if len(seen) != 32 || rec.Header().Get(helpers.RequestIDHeader) != seen {
+			t.Fatalf("header %q: got %q and header %q, want a generated ID", header, seen, rec.Header().Get(helpers.RequestIDHeader))
+		}
+	}
+}
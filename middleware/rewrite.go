@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule rewrites the paths of requests before they are routed, such as to
+// keep serving legacy paths.
+type RewriteRule struct {
+	// From is a path prefix, matching whole segments, or, when Regex is set, a
+	// regular expression, which should be anchored to match the whole path.
+	From string
+	// To replaces the prefix or, when Regex is set, the match, with $1 and ${name}
+	// expanding to its submatches.
+	To    string
+	Regex bool
+	// Status redirects the client with the status, such as 301 Moved Permanently,
+	// instead of rewriting the path internally.
+	Status int
+}
+
+type compiledRule struct {
+	RewriteRule
+	re *regexp.Regexp
+}
+
+// Rewrite applies the first rule matching the path of each request, preserving its
+// query. It should be registered on the server, so that it applies before routing.
+func Rewrite(rules []RewriteRule) (func(http.Handler) http.Handler, error) {
+	compiled := make([]compiledRule, len(rules))
+	for i, rule := range rules {
+		compiled[i].RewriteRule = rule
+		if rule.Regex {
+			re, err := regexp.Compile(rule.From)
+			if err != nil {
+				return nil, fmt.Errorf("Rewrite: failed compiling rule %q: %w", rule.From, err)
+			}
+			compiled[i].re = re
+		}
+		if rule.Status != 0 && (rule.Status < 300 || rule.Status > 399) {
+			return nil, fmt.Errorf("Rewrite: rule %q has non-redirect status %d", rule.From, rule.Status)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range compiled {
+				path, ok := rule.apply(r.URL.Path)
+				if !ok {
+					continue
+				}
+				if rule.Status != 0 {
+					if r.URL.RawQuery != "" {
+						path += "?" + r.URL.RawQuery
+					}
+					http.Redirect(w, r, path, rule.Status)
+					return
+				}
+				r.URL.Path = path
+				r.URL.RawPath = ""
+				break
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func (rule compiledRule) apply(path string) (string, bool) {
+	if rule.re != nil {
+		if !rule.re.MatchString(path) {
+			return "", false
+		}
+		return rule.re.ReplaceAllString(path, rule.To), true
+	}
+	rest, ok := strings.CutPrefix(path, rule.From)
+	// Prefixes match whole segments, so that /v1/old does not match /v1/older.
+	if !ok || (rest != "" && !strings.HasSuffix(rule.From, "/") && !strings.HasPrefix(rest, "/")) {
+		return "", false
+	}
+	return rule.To + rest, true
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	rewrite, err := Rewrite([]RewriteRule{
+		{From: "/v1/old", To: "/v2/new"},
+		{From: `^/users/(\d+)/profile$`, To: "/profiles/$1", Regex: true, Status: http.StatusMovedPermanently},
+	})
+	if err != nil {
+		t.Fatalf("Rewrite: %v", err)
+	}
+	h := rewrite(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/old/items/3", nil))
+	if w.Body.String() != "/v2/new/items/3" {
+		t.Errorf("prefix rewrite: got %q", w.Body.String())
+	}
+
+	for path, want := range map[string]string{
+		"/v1/old":         "/v2/new",
+		"/v1/old/":        "/v2/new/",
+		"/v1/older/items": "/v1/older/items",
+		"/v1/old-items/3": "/v1/old-items/3",
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != want {
+			t.Errorf("prefix rewrite of %s: got %q, want %q", path, w.Body.String(), want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42/profile?tab=posts", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/profiles/42?tab=posts" {
+		t.Errorf("regex redirect: got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if w.Body.String() != "/users/42" {
+		t.Errorf("unmatched: got %q", w.Body.String())
+	}
+
+	if _, err := Rewrite([]RewriteRule{{From: "(", Regex: true}}); err == nil {
+		t.Error("invalid expression: want error")
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// Window is a period of time during which a route is available.
+type Window interface {
+	// Contains reports whether the time is within the window.
+	Contains(t time.Time) bool
+	// Ended reports whether the window will never contain a time after t.
+	Ended(t time.Time) bool
+}
+
+type between struct {
+	start time.Time
+	end   time.Time
+}
+
+// Between returns the window from start until end. A zero start or end leaves
+// the window unbounded on that side.
+func Between(start time.Time, end time.Time) Window {
+	return between{start: start, end: end}
+}
+
+// From returns the window beginning at start.
+func From(start time.Time) Window {
+	return between{start: start}
+}
+
+// Until returns the window ending at end, such as the end of a promotion.
+func Until(end time.Time) Window {
+	return between{end: end}
+}
+
+func (b between) Contains(t time.Time) bool {
+	return (b.start.IsZero() || !t.Before(b.start)) && (b.end.IsZero() || t.Before(b.end))
+}
+
+func (b between) Ended(t time.Time) bool {
+	return !b.end.IsZero() && !t.Before(b.end)
+}
+
+type weekly struct {
+	days  map[time.Weekday]bool
+	start time.Duration
+	end   time.Duration
+	loc   *time.Location
+}
+
+// Daily returns the window recurring every day between the clock times, given
+// as offsets from midnight in the location. The window spans midnight when end
+// is before start, such as for a change window from 22:00 until 02:00.
+func Daily(start time.Duration, end time.Duration, loc *time.Location) Window {
+	return Weekly(nil, start, end, loc)
+}
+
+// Weekly returns the window recurring on the days between the clock times, given
+// as offsets from midnight in the location. A window spanning midnight belongs
+// to the day on which it starts. No days means every day.
+func Weekly(days []time.Weekday, start time.Duration, end time.Duration, loc *time.Location) Window {
+	w := weekly{start: start, end: end, loc: loc}
+	if len(days) > 0 {
+		w.days = make(map[time.Weekday]bool)
+		for _, d := range days {
+			w.days[d] = true
+		}
+	}
+	if w.loc == nil {
+		w.loc = time.UTC
+	}
+	return w
+}
+
+func (w weekly) Contains(t time.Time) bool {
+	t = t.In(w.loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.loc)
+	clock := t.Sub(midnight)
+
+	if w.start <= w.end {
+		return w.on(t.Weekday()) && clock >= w.start && clock < w.end
+	}
+	// The window spans midnight, so it may have started the day before.
+	return (w.on(t.Weekday()) && clock >= w.start) || (w.on(midnight.AddDate(0, 0, -1).Weekday()) && clock < w.end)
+}
+
+func (w weekly) Ended(t time.Time) bool {
+	return false
+}
+
+func (w weekly) on(d time.Weekday) bool {
+	return w.days == nil || w.days[d]
+}
+
+// Schedule returns a handler wrapper making a route available only within the
+// windows. Outside them it responds 403 Forbidden, or 410 Gone once every window
+// has ended.
+func Schedule(windows ...Window) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			now := time.Now()
+			ended := true
+			for _, window := range windows {
+				if window.Contains(now) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				ended = ended && window.Ended(now)
+			}
+			if ended && len(windows) > 0 {
+				helpers.Error(w, r, http.StatusGone)
+				return
+			}
+			helpers.Error(w, r, http.StatusForbidden)
+		})
+	}
+}
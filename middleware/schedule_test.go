@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleWindows(t *testing.T) {
+	saturday := time.Date(2024, 6, 1, 23, 30, 0, 0, time.UTC)
+
+	change := Weekly([]time.Weekday{time.Saturday}, 22*time.Hour, 2*time.Hour, time.UTC)
+	if !change.Contains(saturday) || !change.Contains(saturday.Add(2*time.Hour)) {
+		t.Fatal("expected change window to span midnight from saturday")
+	}
+	if change.Contains(saturday.Add(3*time.Hour)) || change.Contains(saturday.Add(-2*time.Hour)) {
+		t.Fatal("expected change window to be closed outside its hours")
+	}
+
+	if !Until(saturday).Ended(saturday) || Until(saturday).Contains(saturday) {
+		t.Fatal("expected window to end at its end time")
+	}
+	if Between(saturday, time.Time{}).Contains(saturday.Add(-time.Second)) {
+		t.Fatal("expected window to open at its start time")
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// RequireScopes returns a handler wrapper accepting only requests whose bearer token,
+// introspected by Introspect, was granted every scope. Requests without a token
+// respond 401 Unauthorized and tokens lacking a scope 403 Forbidden.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := helpers.Token(r)
+			if info == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				helpers.Error(w, r, http.StatusUnauthorized)
+				return
+			}
+			for _, scope := range scopes {
+				if !info.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, strings.Join(scopes, " ")))
+					helpers.Error(w, r, http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// SignedURL returns a handler wrapper accepting only requests made through a URL
+// signed with key by helpers.SignURL. Expired URLs respond 410 Gone, and any
+// other invalid URL 403 Forbidden. The claims are available via helpers.SignedClaims.
+func SignedURL(key []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := helpers.VerifySignedURL(key, r.URL)
+			if errors.Is(err, helpers.ErrSignatureExpired) {
+				helpers.Error(w, r, http.StatusGone)
+				return
+			}
+			if err != nil {
+				helpers.Error(w, r, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(helpers.WithSignedClaims(r.Context(), claims)))
+		})
+	}
+}
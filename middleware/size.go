@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrResponseTooLarge is returned by writes beyond the limit of MaxResponseSize.
+var ErrResponseTooLarge = errors.New("middleware: response exceeds the size limit")
+
+// MaxResponseSize returns a handler wrapper truncating responses over the limit of
+// bytes, or replacing them with a 500 when nothing has been written.
+func MaxResponseSize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sw, ok := r.Context().Value(sizeLimitKey{}).(*sizeLimitWriter); ok {
+				sw.limit = limit
+				next.ServeHTTP(w, r)
+				return
+			}
+			sw := &sizeLimitWriter{ResponseWriter: w, limit: limit}
+			sw.r = r.WithContext(context.WithValue(r.Context(), sizeLimitKey{}, sw))
+			next.ServeHTTP(sw, sw.r)
+		})
+	}
+}
+
+type sizeLimitKey struct{}
+
+type sizeLimitWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	limit       int64
+	written     int64
+	wroteHeader bool
+	exceeded    bool
+}
+
+// exceed replaces the response with an error if nothing has been sent, and logs the
+// route the first time the limit is exceeded.
+func (sw *sizeLimitWriter) exceed(size int64) error {
+	if sw.exceeded {
+		return ErrResponseTooLarge
+	}
+	sw.exceeded = true
+	if !sw.wroteHeader {
+		sw.wroteHeader = true
+		sw.Header().Del("Content-Length")
+		helpers.Error(sw.ResponseWriter, sw.r, http.StatusInternalServerError)
+	}
+
+	route := helpers.RoutePattern(sw.r)
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	zerolog.Ctx(sw.r.Context()).Error().
+		Str("Function", "MaxResponseSize").
+		Str("Method", sw.r.Method).
+		Str("Route", route).
+		Int64("Size", size).
+		Int64("Limit", sw.limit).
+		Str("RequestID", helpers.RequestID(sw.r)).
+		Msg("Response exceeded the size limit")
+	return ErrResponseTooLarge
+}
+
+func (sw *sizeLimitWriter) WriteHeader(code int) {
+	if sw.wroteHeader {
+		return
+	}
+	if size, err := strconv.ParseInt(sw.Header().Get("Content-Length"), 10, 64); err == nil && size > sw.limit {
+		sw.exceed(size)
+		return
+	}
+	sw.wroteHeader = true
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *sizeLimitWriter) Write(b []byte) (int, error) {
+	if sw.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+	if !sw.wroteHeader {
+		if int64(len(b)) > sw.limit {
+			return 0, sw.exceed(int64(len(b)))
+		}
+		sw.WriteHeader(http.StatusOK)
+		if sw.exceeded {
+			return 0, ErrResponseTooLarge
+		}
+	}
+
+	if remaining := sw.limit - sw.written; int64(len(b)) > remaining {
+		n, err := sw.ResponseWriter.Write(b[:remaining])
+		sw.written += int64(n)
+		if err != nil {
+			return n, err
+		}
+		return n, sw.exceed(sw.written + int64(len(b)) - int64(n))
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.written += int64(n)
+	return n, err
+}
+
+func (sw *sizeLimitWriter) Flush() {
+	sw.wroteHeader = true
+	_ = http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+func (sw *sizeLimitWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
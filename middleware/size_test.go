@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/config"
+)
+
+func TestMaxResponseSize(t *testing.T) {
+	config.Use(&config.Config{})
+	defer config.Use(nil)
+
+	var writeErr error
+	rows := MaxResponseSize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5 && writeErr == nil; i++ {
+			_, writeErr = w.Write([]byte("row\n"))
+		}
+	}))
+	rec := httptest.NewRecorder()
+	rows.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rows", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "row\nrow\nro" {
+		t.Fatalf("got %d %q, want the body truncated", rec.Code, rec.Body.String())
+	}
+	if !errors.Is(writeErr, ErrResponseTooLarge) {
+		t.Fatalf("got %v, want ErrResponseTooLarge", writeErr)
+	}
+
+	large := MaxResponseSize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "20")
+		w.Write([]byte(strings.Repeat("x", 20)))
+	}))
+	rec = httptest.NewRecorder()
+	large.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/large", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "x") {
+		t.Fatalf("got %d %q, want a 500", rec.Code, rec.Body.String())
+	}
+
+	nested := MaxResponseSize(10)(MaxResponseSize(100)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, writeErr = w.Write([]byte(strings.Repeat("x", 50)))
+	})))
+	rec = httptest.NewRecorder()
+	nested.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nested", nil))
+	if writeErr != nil || rec.Body.Len() != 50 {
+		t.Fatalf("got %d bytes and %v, want the inner limit", rec.Body.Len(), writeErr)
+	}
+}
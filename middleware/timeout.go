@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+// Timeout returns a handler wrapper cancelling the request's context once the
+// duration has elapsed, responding 503 if nothing has been written.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+			r = r.WithContext(ctx)
+
+			start := time.Now()
+			tw := &timeoutWriter{ResponseWriter: w, r: r, cancel: cancel, start: start, deadline: start.Add(d)}
+			tw.timer = time.AfterFunc(d, tw.expire)
+			next.ServeHTTP(tw, r)
+			tw.finish()
+		})
+	}
+}
+
+// timeoutWriter serializes the handler's writes with the timeout response, which is
+// written from the timer, so that the two are never interleaved.
+type timeoutWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	cancel context.CancelCauseFunc
+	timer  *time.Timer
+	start  time.Time
+
+	mu          sync.Mutex
+	deadline    time.Time
+	wroteHeader bool
+	timedOut    bool
+	finished    bool
+	logged      bool
+}
+
+func (tw *timeoutWriter) expire() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	// The deadline may have been extended after the timer fired.
+	if tw.finished || tw.deadline.IsZero() || time.Now().Before(tw.deadline) {
+		return
+	}
+	tw.timedOut = true
+	tw.cancel(context.DeadlineExceeded)
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		helpers.Problem(tw.ResponseWriter, tw.r, http.StatusServiceUnavailable, "The request timed out.")
+	}
+}
+
+// finish stops the timer once the handler has returned, so that nothing is written
+// by it afterwards.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	tw.finished = true
+	tw.mu.Unlock()
+	tw.timer.Stop()
+}
+
+// late logs the first write after expiry, which the handler should have abandoned.
+func (tw *timeoutWriter) late() error {
+	if !tw.logged {
+		tw.logged = true
+		route := helpers.RoutePattern(tw.r)
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		zerolog.Ctx(tw.r.Context()).Warn().
+			Str("Function", "Timeout").
+			Str("Method", tw.r.Method).
+			Str("Route", route).
+			Dur("Elapsed", time.Since(tw.start)).
+			Str("RequestID", helpers.RequestID(tw.r)).
+			Msg("Handler wrote after timeout")
+	}
+	return http.ErrHandlerTimeout
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		tw.late()
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, tw.late()
+	}
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return tw.late()
+	}
+	tw.wroteHeader = true
+	return http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+func (tw *timeoutWriter) Flush() {
+	_ = tw.FlushError()
+}
+
+// SetWriteDeadline moves the timeout to the deadline, or removes it for the zero
+// time, and sets the write deadline of the connection where supported.
+func (tw *timeoutWriter) SetWriteDeadline(deadline time.Time) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return tw.late()
+	}
+	tw.deadline = deadline
+	if deadline.IsZero() {
+		tw.timer.Stop()
+	} else {
+		tw.timer.Reset(time.Until(deadline))
+	}
+	if err := http.NewResponseController(tw.ResponseWriter).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
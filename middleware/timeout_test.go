@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+func TestTimeout(t *testing.T) {
+	var writeErr error
+	var gone bool
+	slow := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		gone = helpers.ClientGone(r.Context())
+		_, writeErr = w.Write([]byte("too late"))
+	}))
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("got %d %q, want a 503 problem", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !errors.Is(writeErr, http.ErrHandlerTimeout) {
+		t.Fatalf("write after timeout: got %v, want http.ErrHandlerTimeout", writeErr)
+	}
+	if gone {
+		t.Fatal("a timed out request was reported as a client disconnect")
+	}
+
+	// A handler extending its deadline is allowed to finish.
+	extended := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := helpers.ExtendDeadline(w, time.Second); err != nil {
+			t.Errorf("ExtendDeadline: %v", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if err := r.Context().Err(); err != nil {
+			t.Errorf("context cancelled despite the extended deadline: %v", err)
+		}
+		w.Write([]byte("done"))
+	}))
+	rec = httptest.NewRecorder()
+	extended.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "done" {
+		t.Fatalf("got %d %q, want 200 done", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,103 @@
+package ramchi
+
+import (
+	"net/http"
+	"strings"
+
+	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/middleware"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// NormalizeRedirect redirects requests to the normalized path with 308 Permanent Redirect.
+	NormalizeRedirect = c.NormalizeRedirect
+	// NormalizeRewrite routes requests to the normalized path without informing the client.
+	NormalizeRewrite = c.NormalizeRewrite
+)
+
+// normalizeMiddleware retries requests which match no route against the normalized
+// form of their path, and either redirects or rewrites them when that form matches.
+func (s *Server) normalizeMiddleware(m *chi.Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.Match(chi.NewRouteContext(), r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			normalized := s.normalizePath(r.URL.Path)
+			if s.cfg.CaseInsensitivePaths && !m.Match(chi.NewRouteContext(), r.Method, normalized) {
+				rctx := chi.NewRouteContext()
+				if m.Match(rctx, r.Method, strings.ToLower(normalized)) {
+					normalized = routeCase(normalized, rctx.RoutePattern())
+				}
+			}
+			if normalized == r.URL.Path || !m.Match(chi.NewRouteContext(), r.Method, normalized) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if s.cfg.PathNormalization == NormalizeRedirect {
+				target := normalized
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
+				}
+				http.Redirect(w, r, target, http.StatusPermanentRedirect)
+				return
+			}
+
+			r.URL.Path = normalized
+			r.URL.RawPath = ""
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// normalizePath applies the configured path normalizations.
+func (s *Server) normalizePath(path string) string {
+	if s.cfg.CleanPath {
+		var b strings.Builder
+		for i := 0; i < len(path); i++ {
+			if path[i] == '/' && i > 0 && path[i-1] == '/' {
+				continue
+			}
+			b.WriteByte(path[i])
+		}
+		path = b.String()
+	}
+	if s.cfg.TrailingSlash {
+		for len(path) > 1 && strings.HasSuffix(path, "/") {
+			path = strings.TrimSuffix(path, "/")
+		}
+	}
+	return path
+}
+
+// routeCase returns the path with the case of the static segments of the route
+// pattern it matched, case-insensitively, keeping the segments matched by URL
+// parameters and wildcards as the client sent them.
+func routeCase(path string, pattern string) string {
+	segments := strings.Split(path, "/")
+	for i, p := range strings.Split(pattern, "/") {
+		if i >= len(segments) || p == "*" {
+			break
+		}
+		if !strings.ContainsAny(p, "{}") {
+			segments[i] = p
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// compileRewriteRules converts the configured rewrite rules to the middleware,
+// returning an error for a rule which does not compile. The rules are applied
+// before normalization so that legacy paths are matched as clients sent them.
+func compileRewriteRules(rules []c.RewriteRule) (func(http.Handler) http.Handler, error) {
+	converted := make([]middleware.RewriteRule, len(rules))
+	for i, rule := range rules {
+		converted[i] = middleware.RewriteRule{From: rule.From, To: rule.To, Regex: rule.Regex, Status: rule.Status}
+	}
+	return middleware.Rewrite(converted)
+}
@@ -0,0 +1,125 @@
+package ramchi
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspRetryInterval is how long the stapler waits after failing to fetch a response.
+const ocspRetryInterval = 5 * time.Minute
+
+var ocspClient = &http.Client{Timeout: 10 * time.Second}
+
+// staple keeps an OCSP response stapled to the served certificate until done is
+// closed, refreshing it halfway through its validity so that clients need not
+// query the responder themselves.
+func (cc *certificateChecker) staple(done <-chan struct{}) {
+	for {
+		wait := cc.refreshStaple()
+		if wait > certificateCheckInterval {
+			// Wake at least as often as the checker, to staple reloaded certificates promptly.
+			wait = certificateCheckInterval
+		}
+		select {
+		case <-time.After(wait):
+		case <-done:
+			return
+		}
+	}
+}
+
+// refreshStaple fetches a response when the staple is missing or due for refresh,
+// returning how long to wait before the next attempt.
+func (cc *certificateChecker) refreshStaple() time.Duration {
+	cc.mu.RLock()
+	cert, refresh, expiry := cc.cert, cc.stapleRefresh, cc.stapleExpiry
+	cc.mu.RUnlock()
+
+	now := time.Now()
+	if cert.OCSPStaple != nil && now.Before(refresh) {
+		return refresh.Sub(now)
+	}
+
+	raw, resp, err := fetchOCSP(cert)
+	if err != nil {
+		cc.log.Warn().Str("Function", "refreshStaple").Str("Subject", cert.Leaf.Subject.String()).Err(err).Msg("Failed fetching OCSP response")
+		if cert.OCSPStaple != nil && now.After(expiry) {
+			cc.setStaple(cert, nil, time.Time{}, time.Time{})
+		}
+		return ocspRetryInterval
+	}
+	if resp.Status != ocsp.Good {
+		cc.log.Error().Str("Function", "refreshStaple").Str("Subject", cert.Leaf.Subject.String()).Int("Status", resp.Status).Msg("Certificate is not in good standing, not stapling")
+		cc.setStaple(cert, nil, time.Time{}, time.Time{})
+		return certificateCheckInterval
+	}
+
+	next := resp.NextUpdate
+	if next.IsZero() {
+		next = now.Add(24 * time.Hour)
+	}
+	refresh = resp.ThisUpdate.Add(next.Sub(resp.ThisUpdate) / 2)
+	if refresh.Before(now.Add(time.Minute)) {
+		refresh = now.Add(time.Minute)
+	}
+	cc.setStaple(cert, raw, refresh, next)
+	cc.log.Debug().Str("Function", "refreshStaple").Str("Subject", cert.Leaf.Subject.String()).Time("NextUpdate", next).Msg("Stapled OCSP response")
+	return refresh.Sub(now)
+}
+
+// setStaple replaces the staple of the certificate, unless it has since been reloaded.
+func (cc *certificateChecker) setStaple(cert *tls.Certificate, raw []byte, refresh time.Time, expiry time.Time) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.cert != cert {
+		return
+	}
+	stapled := *cert
+	stapled.OCSPStaple = raw
+	cc.cert, cc.stapleRefresh, cc.stapleExpiry = &stapled, refresh, expiry
+}
+
+// fetchOCSP requests the status of the certificate from its issuer's responder.
+func fetchOCSP(cert *tls.Certificate) ([]byte, *ocsp.Response, error) {
+	if len(cert.Leaf.OCSPServer) == 0 {
+		return nil, nil, errors.New("fetchOCSP: certificate names no OCSP responder")
+	}
+	if len(cert.Certificate) < 2 {
+		return nil, nil, errors.New("fetchOCSP: certificate file does not include the issuer")
+	}
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetchOCSP: failed parsing issuer: %w", err)
+	}
+
+	req, err := ocsp.CreateRequest(cert.Leaf, issuer, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetchOCSP: failed creating request: %w", err)
+	}
+	res, err := ocspClient.Post(cert.Leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetchOCSP: failed sending request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetchOCSP: responder returned %s", res.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetchOCSP: failed reading response: %w", err)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, cert.Leaf, issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetchOCSP: failed parsing response: %w", err)
+	}
+	return raw, resp, nil
+}
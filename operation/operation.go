@@ -0,0 +1,214 @@
+// Package operation implements asynchronous requests: a handler answers 202
+// Accepted with the location of an operation, whose status and result clients
+// poll while it runs in the background.
+package operation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "operation").Logger()
+
+// Status is the state of an operation.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Operation is the progress of a long-running request.
+type Operation struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	// Progress is the fraction completed, from 0 to 1.
+	Progress float64         `json:"progress"`
+	Message  string          `json:"message,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+}
+
+// Done reports whether the operation succeeded or failed.
+func (op *Operation) Done() bool {
+	return op.Status == StatusSucceeded || op.Status == StatusFailed
+}
+
+// Operations runs operations and reports their status.
+type Operations struct {
+	Store Store
+	// Prefix is the path of the status route, followed by the operation ID.
+	Prefix string
+	// TTL is how long operations are kept after their last update.
+	TTL time.Duration
+	// RetryAfter is suggested to clients polling pending operations.
+	RetryAfter time.Duration
+}
+
+// New initializes operations reported under "/operations", kept for a day and
+// polled every second.
+func New(store Store) *Operations {
+	return &Operations{Store: store, Prefix: "/operations", TTL: 24 * time.Hour, RetryAfter: time.Second}
+}
+
+// Func is the work of an operation, returning its result, which is marshalled as
+// JSON. It may report progress through the tracker.
+type Func func(ctx context.Context, t *Tracker) (interface{}, error)
+
+// Tracker updates the progress of a running operation.
+type Tracker struct {
+	o  *Operations
+	op *Operation
+}
+
+// ID returns the ID of the operation.
+func (t *Tracker) ID() string {
+	return t.op.ID
+}
+
+// Progress records the fraction completed, and a message describing the current stage.
+func (t *Tracker) Progress(ctx context.Context, fraction float64, message string) error {
+	t.op.Progress, t.op.Message = fraction, message
+	return t.o.save(ctx, t.op)
+}
+
+// Create stores a pending operation, for work run elsewhere, such as by a queue
+// worker, which reports its progress with Update.
+func (o *Operations) Create(ctx context.Context) (*Operation, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("Create: failed generating ID: %w", err)
+	}
+	now := time.Now().UTC()
+	op := &Operation{ID: hex.EncodeToString(b), Status: StatusPending, Created: now, Updated: now}
+	if err := o.Store.Save(ctx, op, o.TTL); err != nil {
+		return nil, fmt.Errorf("Create: failed saving operation: %w", err)
+	}
+	return op, nil
+}
+
+// Update stores the operation, as changed by the work running it.
+func (o *Operations) Update(ctx context.Context, op *Operation) error {
+	if err := o.save(ctx, op); err != nil {
+		return fmt.Errorf("Update: %w", err)
+	}
+	return nil
+}
+
+// Get returns the operation.
+func (o *Operations) Get(ctx context.Context, id string) (*Operation, error) {
+	op, err := o.Store.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Get: %w", err)
+	}
+	return op, nil
+}
+
+// Start runs the work in the background of the process, and answers the request
+// with 202 Accepted, the operation, and its location. The work outlives the
+// request, but not the process: durable work should be enqueued instead, with
+// the operation created by Create and answered with Accept.
+func (o *Operations) Start(w http.ResponseWriter, r *http.Request, fn Func) {
+	op, err := o.Create(r.Context())
+	if err != nil {
+		log.Error().Str("Function", "Start").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed creating operation")
+		helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed creating operation")
+		return
+	}
+
+	running := *op
+	go o.run(context.WithoutCancel(r.Context()), &running, fn)
+	o.Accept(w, r, op)
+}
+
+// Accept answers the request with 202 Accepted, the operation, and its location.
+func (o *Operations) Accept(w http.ResponseWriter, r *http.Request, op *Operation) {
+	w.Header().Set("Location", o.Prefix+"/"+op.ID)
+	w.Header().Set("Retry-After", o.retryAfter())
+	helpers.JSON(w, r, http.StatusAccepted, op)
+}
+
+// retryAfter returns RetryAfter in whole seconds, and at least one.
+func (o *Operations) retryAfter() string {
+	seconds := int(o.RetryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+func (o *Operations) run(ctx context.Context, op *Operation, fn Func) {
+	op.Status = StatusRunning
+	if err := o.save(ctx, op); err != nil {
+		log.Error().Str("Function", "run").Str("ID", op.ID).Err(err).Msg("Failed saving operation")
+	}
+
+	result, err := o.call(ctx, op, fn)
+	if err != nil {
+		op.Status, op.Error = StatusFailed, err.Error()
+	} else if b, merr := json.Marshal(result); merr != nil {
+		op.Status, op.Error = StatusFailed, "failed marshalling result"
+		log.Error().Str("Function", "run").Str("ID", op.ID).Err(merr).Msg("Failed marshalling result")
+	} else {
+		op.Status, op.Progress, op.Result = StatusSucceeded, 1, b
+	}
+	if err := o.save(ctx, op); err != nil {
+		log.Error().Str("Function", "run").Str("ID", op.ID).Err(err).Msg("Failed saving operation")
+	}
+}
+
+func (o *Operations) call(ctx context.Context, op *Operation, fn Func) (result interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Error().Str("Function", "call").Str("ID", op.ID).Interface("Panic", v).Msg("Operation panicked")
+			err = errors.New("operation panicked")
+		}
+	}()
+	return fn(ctx, &Tracker{o: o, op: op})
+}
+
+func (o *Operations) save(ctx context.Context, op *Operation) error {
+	op.Updated = time.Now().UTC()
+	if err := o.Store.Save(ctx, op, o.TTL); err != nil {
+		return fmt.Errorf("save: failed saving operation: %w", err)
+	}
+	return nil
+}
+
+// Route returns the status route, answering with the operation, and Retry-After
+// while it is not done.
+func (o *Operations) Route(opts ...router.RouteWrapper) router.Route {
+	return router.NewGetRoute(o.Prefix+"/{id}", true, false, func(w http.ResponseWriter, r *http.Request) {
+		op, err := o.Store.Load(r.Context(), helpers.URLParam(r, "id"))
+		if errors.Is(err, ErrNotFound) {
+			helpers.JSONError(w, r, http.StatusNotFound, "operation not found")
+			return
+		}
+		if err != nil {
+			log.Error().Str("Function", "Route").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed loading operation")
+			helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed loading operation")
+			return
+		}
+		if !op.Done() {
+			w.Header().Set("Retry-After", o.retryAfter())
+		}
+		helpers.JSON(w, r, http.StatusOK, op)
+	}, opts...)
+}
@@ -0,0 +1,70 @@
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestStart(t *testing.T) {
+	ops := New(NewMemoryStore())
+	release := make(chan struct{})
+
+	m := chi.NewRouter()
+	m.Post("/reports", func(w http.ResponseWriter, r *http.Request) {
+		ops.Start(w, r, func(ctx context.Context, t *Tracker) (interface{}, error) {
+			t.Progress(ctx, 0.5, "rendering")
+			<-release
+			return map[string]string{"url": "/reports/1.pdf"}, nil
+		})
+	})
+	status := ops.Route()
+	m.Method(status.Method(), status.Path(), status.Handler())
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reports", nil))
+	if w.Code != http.StatusAccepted || w.Header().Get("Location") == "" {
+		t.Fatalf("Start: got %d with location %q", w.Code, w.Header().Get("Location"))
+	}
+	location := w.Header().Get("Location")
+
+	poll := func() *Operation {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("poll: got %d", w.Code)
+		}
+		var op Operation
+		json.Unmarshal(w.Body.Bytes(), &op)
+		return &op
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for op := poll(); op.Message != "rendering"; op = poll() {
+		if time.Now().After(deadline) {
+			t.Fatalf("operation did not report progress: %+v", op)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	for op := poll(); !op.Done(); op = poll() {
+		if time.Now().After(deadline) {
+			t.Fatalf("operation did not complete: %+v", op)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if op := poll(); op.Status != StatusSucceeded || string(op.Result) != `{"url":"/reports/1.pdf"}` {
+		t.Fatalf("unexpected operation: %+v", op)
+	}
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/operations/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown operation: got %d", w.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when an operation does not exist or has expired.
+var ErrNotFound = errors.New("operation not found")
+
+// Store persists operations, so that their status may be polled from any replica.
+type Store interface {
+	// Save creates or replaces the operation, expiring it once the ttl elapses.
+	Save(ctx context.Context, op *Operation, ttl time.Duration) error
+	Load(ctx context.Context, id string) (*Operation, error)
+}
+
+type entry struct {
+	op      Operation
+	expires time.Time
+}
+
+type memoryStore struct {
+	mu         sync.Mutex
+	operations map[string]entry
+	saves      int
+}
+
+// sweepEvery is the number of saves between sweeps of expired operations.
+const sweepEvery = 256
+
+// NewMemoryStore initializes a Store local to the process.
+func NewMemoryStore() Store {
+	return &memoryStore{operations: make(map[string]entry)}
+}
+
+func (m *memoryStore) Save(ctx context.Context, op *Operation, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.saves++
+	if m.saves%sweepEvery == 0 {
+		for id, e := range m.operations {
+			if now.After(e.expires) {
+				delete(m.operations, id)
+			}
+		}
+	}
+	m.operations[op.ID] = entry{op: *op, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *memoryStore) Load(ctx context.Context, id string) (*Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.operations[id]
+	if !ok || time.Now().After(e.expires) {
+		delete(m.operations, id)
+		return nil, ErrNotFound
+	}
+	op := e.op
+	return &op, nil
+}
@@ -0,0 +1,75 @@
+package ramchi
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/metrics"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures a server created by New.
+type Option func(s *Server)
+
+// WithConfig configures the server from cfg, rather than from the config file, so
+// that the server may be embedded without one on disk. The configuration is scoped to
+// the server, and carried by the context of its requests for the helpers.
+func WithConfig(cfg *c.Config) Option {
+	return func(s *Server) {
+		s.cfg = cfg
+	}
+}
+
+// WithLogger replaces the console logger the server logs to.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(s *Server) {
+		s.log = logger
+		s.logSet = true
+	}
+}
+
+// WithAccessLogger logs requests to logger rather than the server's logger, such as
+// to a logsink.Buffer, so that access logs may bypass stdout. Buffers created with
+// logsink.WithRedaction redact personal data before it reaches their sink.
+func WithAccessLogger(logger zerolog.Logger) Option {
+	return func(s *Server) {
+		s.accessLog = &logger
+	}
+}
+
+// WithRegistry records the metrics of the server in reg, such as metrics.Default,
+// rather than in a registry of its own, so that servers may share one.
+func WithRegistry(reg *metrics.Registry) Option {
+	return func(s *Server) {
+		s.registry = reg
+	}
+}
+
+// WithHTTPServer serves with srv, so that its timeouts, limits and hooks apply. Its
+// address and handler are set from the config and routers unless already set.
+func WithHTTPServer(srv *http.Server) Option {
+	return func(s *Server) {
+		s.httpServer = srv
+	}
+}
+
+// WithBaseContext serves every request with a context derived from ctx, so that
+// application-wide values, such as build information or a dependency container,
+// reach each handler through r.Context(), and cancelling ctx cancels them.
+func WithBaseContext(ctx context.Context) Option {
+	return func(s *Server) {
+		s.baseCtx = ctx
+	}
+}
+
+// WithSignals replaces the signals which begin a graceful shutdown, overriding the
+// shutdownSignals config. Without any, the server is shut down only by Shutdown or the
+// context of StartContext, such as when another server of the process owns them.
+func WithSignals(signals ...os.Signal) Option {
+	return func(s *Server) {
+		s.signals = append([]os.Signal{}, signals...)
+	}
+}
@@ -0,0 +1,102 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Etwodev/ramchi/logsink"
+
+	"github.com/rs/zerolog"
+)
+
+// severities maps log levels to OTLP severity numbers.
+var severities = map[string]int{
+	zerolog.LevelTraceValue: 1,
+	zerolog.LevelDebugValue: 5,
+	zerolog.LevelInfoValue:  9,
+	zerolog.LevelWarnValue:  13,
+	zerolog.LevelErrorValue: 17,
+	zerolog.LevelFatalValue: 21,
+	zerolog.LevelPanicValue: 24,
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber,omitempty"`
+	SeverityText         string     `json:"severityText,omitempty"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+}
+
+type logSink struct {
+	client   *client
+	resource resource
+}
+
+// NewLogSink returns a logsink.Sink exporting log entries to the collector at the
+// endpoint, with the resource attributes and header.
+func NewLogSink(endpoint string, attrs map[string]string, header http.Header) logsink.Sink {
+	return &logSink{client: newClient(endpoint, "/v1/logs", header), resource: resource{Attributes: stringAttributes(attrs)}}
+}
+
+func (l *logSink) Send(ctx context.Context, entries [][]byte) error {
+	now := nanos(time.Now())
+	records := make([]logRecord, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, toRecord(entry, now))
+	}
+
+	request := map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": l.resource,
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      scope{Name: ScopeName},
+				"logRecords": records,
+			}},
+		}},
+	}
+	if err := l.client.post(ctx, request); err != nil {
+		return fmt.Errorf("Send: %w", err)
+	}
+	return nil
+}
+
+// toRecord returns the log record of a zerolog entry, or of a line of text should it
+// not be JSON.
+func toRecord(entry []byte, observed string) logRecord {
+	record := logRecord{TimeUnixNano: observed, ObservedTimeUnixNano: observed}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		record.Body = value(string(entry))
+		return record
+	}
+
+	if level, ok := fields[zerolog.LevelFieldName].(string); ok {
+		record.SeverityNumber = severities[level]
+		record.SeverityText = level
+	}
+	if ts, ok := fields[zerolog.TimestampFieldName].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			record.TimeUnixNano = nanos(t)
+		}
+	}
+	message, _ := fields[zerolog.MessageFieldName].(string)
+	record.Body = value(message)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != zerolog.LevelFieldName && k != zerolog.TimestampFieldName && k != zerolog.MessageFieldName {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		record.Attributes = append(record.Attributes, keyValue{Key: k, Value: value(fields[k])})
+	}
+	return record
+}
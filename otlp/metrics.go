@@ -0,0 +1,151 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type histogramDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
+
+// aggregationCumulative is the temporality of sums and histograms, which are
+// recorded since the exporter started.
+const aggregationCumulative = 2
+
+// MetricExporter pushes the metrics of a registry to an OpenTelemetry collector at
+// each interval of Run.
+type MetricExporter struct {
+	client   *client
+	reg      *metrics.Registry
+	resource resource
+	interval time.Duration
+	start    time.Time
+}
+
+// NewMetricExporter returns a MetricExporter pushing the metrics of the registry to
+// the collector at the endpoint, such as "http://collector:4318", with the resource
+// attributes, such as service.name, and the header, such as for authorization.
+func NewMetricExporter(endpoint string, reg *metrics.Registry, attrs map[string]string, header http.Header, interval time.Duration) *MetricExporter {
+	return &MetricExporter{
+		client:   newClient(endpoint, "/v1/metrics", header),
+		reg:      reg,
+		resource: resource{Attributes: stringAttributes(attrs)},
+		interval: interval,
+		start:    time.Now(),
+	}
+}
+
+// Run pushes the metrics at each interval until the context is done, then pushes
+// them a last time, so that it may be loaded as a worker of a server. Failures are
+// logged and retried at the next interval.
+func (e *MetricExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil {
+				log.Warn().Str("Function", "Run").Err(err).Msg("Failed exporting metrics")
+			}
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := e.Export(final); err != nil {
+				log.Warn().Str("Function", "Run").Err(err).Msg("Failed exporting metrics")
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// Export pushes the metrics once.
+func (e *MetricExporter) Export(ctx context.Context) error {
+	start, now := nanos(e.start), nanos(time.Now())
+	var out []interface{}
+	for _, f := range e.reg.Gather() {
+		m := map[string]interface{}{"name": f.Name, "description": f.Help}
+		switch f.Kind {
+		case "counter", "gauge":
+			points := make([]numberDataPoint, len(f.Series))
+			for i, s := range f.Series {
+				points[i] = numberDataPoint{Attributes: labelAttributes(f.Labels, s.Values), StartTimeUnixNano: start, TimeUnixNano: now, AsDouble: s.Value}
+			}
+			if f.Kind == "counter" {
+				m["sum"] = map[string]interface{}{"dataPoints": points, "aggregationTemporality": aggregationCumulative, "isMonotonic": true}
+			} else {
+				m["gauge"] = map[string]interface{}{"dataPoints": points}
+			}
+		case "histogram":
+			points := make([]histogramDataPoint, len(f.Series))
+			for i, s := range f.Series {
+				points[i] = histogramDataPoint{
+					Attributes:        labelAttributes(f.Labels, s.Values),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      now,
+					Count:             strconv.FormatUint(s.Count, 10),
+					Sum:               s.Sum,
+					BucketCounts:      bucketCounts(s.Counts, s.Count),
+					ExplicitBounds:    f.Buckets,
+				}
+			}
+			m["histogram"] = map[string]interface{}{"dataPoints": points, "aggregationTemporality": aggregationCumulative}
+		default:
+			continue
+		}
+		out = append(out, m)
+	}
+
+	request := map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": e.resource,
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   scope{Name: ScopeName},
+				"metrics": out,
+			}},
+		}},
+	}
+	if err := e.client.post(ctx, request); err != nil {
+		return fmt.Errorf("Export: %w", err)
+	}
+	return nil
+}
+
+func labelAttributes(labels []string, values []string) []keyValue {
+	kvs := make([]keyValue, len(labels))
+	for i, label := range labels {
+		kvs[i] = keyValue{Key: label, Value: value(values[i])}
+	}
+	return kvs
+}
+
+// bucketCounts returns the counts of each bucket, and of the overflow bucket, from
+// the cumulative counts of a histogram, as JSON-encoded fixed64s.
+func bucketCounts(cumulative []uint64, count uint64) []string {
+	counts := make([]string, len(cumulative)+1)
+	var prev uint64
+	for i, c := range cumulative {
+		counts[i] = strconv.FormatUint(c-prev, 10)
+		prev = c
+	}
+	counts[len(cumulative)] = strconv.FormatUint(count-prev, 10)
+	return counts
+}
@@ -0,0 +1,121 @@
+// Package otlp exports logs and metrics to an OpenTelemetry collector over OTLP/HTTP,
+// in its JSON encoding, so that servers may export them without an SDK.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "otlp").Logger()
+
+// ScopeName is the instrumentation scope of exported logs and metrics.
+const ScopeName = "github.com/Etwodev/ramchi"
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// value returns the attribute value of a decoded JSON value.
+func value(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	}
+	b, _ := json.Marshal(v)
+	s := string(b)
+	return anyValue{StringValue: &s}
+}
+
+// stringAttributes returns the attributes of the map, ordered by key.
+func stringAttributes(attrs map[string]string) []keyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]keyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, keyValue{Key: k, Value: value(attrs[k])})
+	}
+	return kvs
+}
+
+// nanos returns the time as a JSON-encoded fixed64 of nanoseconds since the epoch.
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+type client struct {
+	url    string
+	header http.Header
+	client *http.Client
+}
+
+func newClient(endpoint string, path string, header http.Header) *client {
+	return &client{url: strings.TrimRight(endpoint, "/") + path, header: header, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// post posts the request to the collector. Responses which the OTLP/HTTP
+// specification marks retryable are Retryable.
+func (c *client) post(ctx context.Context, request interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("post: failed encoding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post: failed creating request: %w", err)
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return helpers.Retryable(fmt.Errorf("post: failed exporting: %w", err))
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return helpers.Retryable(fmt.Errorf("post: collector responded %s", resp.Status))
+	}
+	return fmt.Errorf("post: collector responded %s", resp.Status)
+}
@@ -0,0 +1,76 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+func collector(t *testing.T, path string, got *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("got %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, got); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestLogSink(t *testing.T) {
+	var got map[string]interface{}
+	ts := collector(t, "/v1/logs", &got)
+	defer ts.Close()
+
+	sink := NewLogSink(ts.URL, map[string]string{"service.name": "users"}, http.Header{"Authorization": {"Bearer token"}})
+	entry := `{"level":"warn","time":"2024-01-02T03:04:05Z","Route":"/users","message":"slow"}`
+	if err := sink.Send(context.Background(), [][]byte{[]byte(entry)}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(got)
+	for _, want := range []string{
+		`"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"users"}}]}`,
+		`"severityNumber":13`,
+		`"timeUnixNano":"1704164645000000000"`,
+		`"body":{"stringValue":"slow"}`,
+		`"attributes":[{"key":"Route","value":{"stringValue":"/users"}}]`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("missing %s in %s", want, b)
+		}
+	}
+}
+
+func TestMetricExporter(t *testing.T) {
+	var got map[string]interface{}
+	ts := collector(t, "/v1/metrics", &got)
+	defer ts.Close()
+
+	reg := metrics.NewRegistry()
+	reg.Counter("requests_total", "Requests served.", "route").Add(2, "/users")
+	reg.Histogram("duration_seconds", "Time taken.", []float64{0.1, 0.5}).Observe(0.3)
+	reg.Histogram("duration_seconds", "Time taken.", []float64{0.1, 0.5}).Observe(1)
+
+	e := NewMetricExporter(ts.URL, reg, nil, http.Header{"Authorization": {"Bearer token"}}, 0)
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(got)
+	for _, want := range []string{
+		`"name":"requests_total","sum":{"aggregationTemporality":2,"dataPoints":[{"asDouble":2,"attributes":[{"key":"route","value":{"stringValue":"/users"}}]`,
+		`"bucketCounts":["0","1","1"],"count":"2","explicitBounds":[0.1,0.5]`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("missing %s in %s", want, b)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ramchi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	MethodOverrideHeader = "X-HTTP-Method-Override"
+	MethodOverrideField  = "_method"
+)
+
+// methodOverrideMiddleware reroutes POST requests carrying a method override to the
+// PUT, PATCH or DELETE route they name, provided the route permits it and the
+// request comes from a permitted origin.
+func (s *Server) methodOverrideMiddleware(m *chi.Mux, table *routeTable) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || !s.overrideOriginAllowed(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			permitted := func(method string) bool {
+				rctx := chi.NewRouteContext()
+				return m.Match(rctx, method, r.URL.Path) && (s.cfg.EnableMethodOverride || table.overrides[rctx.RoutePattern()+" "+method])
+			}
+
+			// The body is parsed for the form field only when a route could be overridden,
+			// so that it is left to the handler of other requests.
+			method := r.Header.Get(MethodOverrideHeader)
+			if method == "" && isForm(r) && (permitted(http.MethodPut) || permitted(http.MethodPatch) || permitted(http.MethodDelete)) {
+				method = r.PostFormValue(MethodOverrideField)
+			}
+			method = strings.ToUpper(method)
+			if method != http.MethodPut && method != http.MethodPatch && method != http.MethodDelete || !permitted(method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r.Method = method
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isForm(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
+
+// overrideOriginAllowed returns whether overrides are accepted from the origin of
+// the request. When no origins are configured, overrides are accepted only from
+// the same origin, or from clients which send none, as browsers always do on POST.
+func (s *Server) overrideOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	origins := s.cfg.MethodOverrideOrigins
+	if len(origins) == 0 {
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
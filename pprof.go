@@ -0,0 +1,30 @@
+package ramchi
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// pprofRouter returns the router serving the net/http/pprof handlers under the
+// prefix, such as "/debug/pprof". Its routes are internal, so that profiling is not
+// observed as traffic.
+func pprofRouter(prefix string) router.Router {
+	prefix = "/" + strings.Trim(prefix, "/")
+	routes := []router.Route{
+		router.NewGetRoute(prefix+"/", true, false, pprof.Index, router.WithInternal()),
+		router.NewGetRoute(prefix+"/cmdline", true, false, pprof.Cmdline, router.WithInternal()),
+		router.NewGetRoute(prefix+"/profile", true, false, pprof.Profile, router.WithInternal()),
+		router.NewGetRoute(prefix+"/symbol", true, false, pprof.Symbol, router.WithInternal()),
+		router.NewPostRoute(prefix+"/symbol", true, false, pprof.Symbol, router.WithInternal()),
+		router.NewGetRoute(prefix+"/trace", true, false, pprof.Trace, router.WithInternal()),
+		router.NewGetRoute(prefix+"/{profile}", true, false, func(w http.ResponseWriter, r *http.Request) {
+			pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+		}, router.WithInternal()),
+	}
+	return router.NewRouter(routes, true)
+}
@@ -0,0 +1,175 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "privacy").Logger()
+
+// ErrUnauthenticated is returned by an Authenticator when the request carries no valid identity.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Exporter returns the data an application holds about a subject.
+// The result is marshaled to JSON as one section of the export archive.
+type Exporter func(ctx context.Context, subject string) (interface{}, error)
+
+// Eraser deletes the data an application holds about a subject.
+type Eraser func(ctx context.Context, subject string) error
+
+// Authenticator returns the subject a request acts on behalf of.
+type Authenticator func(r *http.Request) (string, error)
+
+// Archive is the document returned by an export.
+type Archive struct {
+	Subject     string                 `json:"subject"`
+	GeneratedAt time.Time              `json:"generatedAt"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// Privacy holds the exporters and erasers registered by an application.
+type Privacy struct {
+	mu           sync.RWMutex
+	exporters    map[string]Exporter
+	erasers      map[string]Eraser
+	authenticate Authenticator
+	prefix       string
+}
+
+// New initializes a privacy registry whose endpoints act on the subject returned by authenticate.
+func New(authenticate Authenticator) *Privacy {
+	return &Privacy{
+		exporters:    make(map[string]Exporter),
+		erasers:      make(map[string]Eraser),
+		authenticate: authenticate,
+		prefix:       "/privacy",
+	}
+}
+
+// SetPrefix sets the path the endpoints are served under, /privacy by default.
+func (p *Privacy) SetPrefix(prefix string) {
+	p.prefix = prefix
+}
+
+// RegisterExporter registers the exporter for a named section of the archive.
+func (p *Privacy) RegisterExporter(name string, e Exporter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exporters[name] = e
+}
+
+// RegisterEraser registers a named eraser.
+func (p *Privacy) RegisterEraser(name string, e Eraser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.erasers[name] = e
+}
+
+// Export collects the data held about the subject from every exporter.
+func (p *Privacy) Export(ctx context.Context, subject string) (*Archive, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	archive := &Archive{Subject: subject, GeneratedAt: time.Now().UTC(), Data: make(map[string]interface{})}
+	for _, name := range sortedKeys(p.exporters) {
+		data, err := p.exporters[name](ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("Export: failed exporting %q: %w", name, err)
+		}
+		archive.Data[name] = data
+	}
+	return archive, nil
+}
+
+// Erase deletes the data held about the subject with every eraser. All erasers
+// are run even when some fail, and their errors are joined.
+func (p *Privacy) Erase(ctx context.Context, subject string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var errs []error
+	for _, name := range sortedKeys(p.erasers) {
+		if err := p.erasers[name](ctx, subject); err != nil {
+			errs = append(errs, fmt.Errorf("Erase: failed erasing %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Router returns the router serving GET {prefix}/export and DELETE {prefix}/data.
+func (p *Privacy) Router(opts ...router.RouterWrapper) router.Router {
+	return router.NewRouter([]router.Route{
+		router.NewGetRoute(p.prefix+"/export", true, false, p.exportHandler),
+		router.NewDeleteRoute(p.prefix+"/data", true, false, p.eraseHandler),
+	}, true, opts...)
+}
+
+func (p *Privacy) exportHandler(w http.ResponseWriter, r *http.Request) {
+	subject, ok := p.subject(w, r, "export")
+	if !ok {
+		return
+	}
+
+	archive, err := p.Export(r.Context(), subject)
+	if err != nil {
+		log.Error().Str("Function", "exportHandler").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Export failed")
+		helpers.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Str("Action", "export").Str("Subject", subject).Str("RequestID", helpers.RequestID(r)).Strs("Sections", sortedKeys(archive.Data)).Msg("Personal data exported")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.json"`, archive.GeneratedAt.Format("20060102T150405Z")))
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.JSON(w, r, http.StatusOK, archive)
+}
+
+func (p *Privacy) eraseHandler(w http.ResponseWriter, r *http.Request) {
+	subject, ok := p.subject(w, r, "erase")
+	if !ok {
+		return
+	}
+
+	if err := p.Erase(r.Context(), subject); err != nil {
+		log.Error().Str("Function", "eraseHandler").Str("Subject", subject).Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Erasure failed")
+		helpers.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Str("Action", "erase").Str("Subject", subject).Str("RequestID", helpers.RequestID(r)).Msg("Personal data erased")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// subject authenticates the request, writing the error response when it fails.
+func (p *Privacy) subject(w http.ResponseWriter, r *http.Request, action string) (string, bool) {
+	subject, err := p.authenticate(r)
+	if err != nil {
+		log.Warn().Str("Action", action).Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Privacy request refused")
+		if errors.Is(err, ErrUnauthenticated) {
+			helpers.Error(w, r, http.StatusUnauthorized)
+		} else {
+			helpers.Error(w, r, http.StatusForbidden)
+		}
+		return "", false
+	}
+	return subject, true
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrivacy(t *testing.T) {
+	orders := map[string][]string{"alice": {"order-1"}, "bob": {"order-2"}}
+	p := New(func(r *http.Request) (string, error) {
+		if user := r.Header.Get("X-User"); user != "" {
+			return user, nil
+		}
+		return "", ErrUnauthenticated
+	})
+	p.RegisterExporter("orders", func(ctx context.Context, subject string) (interface{}, error) {
+		return orders[subject], nil
+	})
+	p.RegisterEraser("orders", func(ctx context.Context, subject string) error {
+		delete(orders, subject)
+		return nil
+	})
+	p.RegisterEraser("analytics", func(ctx context.Context, subject string) error {
+		return errors.New("warehouse unavailable")
+	})
+
+	serve := func(h http.HandlerFunc, user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := serve(p.exportHandler, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous export: got %d, want 401", rec.Code)
+	}
+	rec := serve(p.exportHandler, "alice")
+	var archive struct {
+		Subject string              `json:"subject"`
+		Data    map[string][]string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &archive); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || archive.Subject != "alice" || len(archive.Data["orders"]) != 1 || archive.Data["orders"][0] != "order-1" {
+		t.Fatalf("got %d %s, want alice's orders alone", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(p.eraseHandler, "alice"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("erase with a failing eraser: got %d, want 500", rec.Code)
+	}
+	if _, ok := orders["alice"]; ok || len(orders["bob"]) != 1 {
+		t.Fatalf("got %v, want alice's orders erased despite the failing eraser, and bob's kept", orders)
+	}
+}
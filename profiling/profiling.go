@@ -0,0 +1,150 @@
+// Package profiling continuously profiles a server, pushing its CPU and heap
+// profiles to a Pyroscope-compatible ingest endpoint.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "profiling").Logger()
+
+// Profiler profiles the process for each interval of Run, pushing a CPU profile of
+// the interval and a heap profile at its end.
+type Profiler struct {
+	endpoint string
+	app      string
+	tags     map[string]string
+	header   http.Header
+	interval time.Duration
+	client   *http.Client
+}
+
+// New returns a Profiler pushing to the endpoint, such as "http://pyroscope:4040",
+// as the application, such as the name of the service, with the tags, such as its
+// version, and the header, such as for authorization.
+func New(endpoint string, app string, tags map[string]string, header http.Header, interval time.Duration) *Profiler {
+	return &Profiler{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		app:      app,
+		tags:     tags,
+		header:   header,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Run profiles until the context is done, so that it may be loaded as a worker of a
+// server. Failures are logged, and profiling continues with the next interval. The
+// CPU profile is skipped for an interval in which another is running, such as one
+// requested of the pprof handlers.
+func (p *Profiler) Run(ctx context.Context) {
+	for {
+		from := time.Now()
+		var cpu bytes.Buffer
+		profiling := pprof.StartCPUProfile(&cpu) == nil
+
+		select {
+		case <-time.After(p.interval):
+		case <-ctx.Done():
+		}
+		if profiling {
+			pprof.StopCPUProfile()
+		}
+		until := time.Now()
+
+		if profiling {
+			if err := p.push(ctx, "cpu", cpu.Bytes(), from, until); err != nil {
+				log.Warn().Str("Function", "Run").Str("Profile", "cpu").Err(err).Msg("Failed pushing profile")
+			}
+		}
+		var heap bytes.Buffer
+		if err := pprof.Lookup("heap").WriteTo(&heap, 0); err == nil {
+			if err := p.push(ctx, "heap", heap.Bytes(), from, until); err != nil {
+				log.Warn().Str("Function", "Run").Str("Profile", "heap").Err(err).Msg("Failed pushing profile")
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// push posts the profile to the ingest endpoint. A push running when the context
+// is done is given a moment to finish, so that the last interval is not lost.
+func (p *Profiler) push(ctx context.Context, kind string, profile []byte, from time.Time, until time.Time) error {
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return fmt.Errorf("push: failed creating form: %w", err)
+	}
+	if _, err := part.Write(profile); err != nil {
+		return fmt.Errorf("push: failed creating form: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("push: failed creating form: %w", err)
+	}
+
+	query := url.Values{
+		"name":       {p.name(kind)},
+		"from":       {strconv.FormatInt(from.Unix(), 10)},
+		"until":      {strconv.FormatInt(until.Unix(), 10)},
+		"format":     {"pprof"},
+		"spyName":    {"gospy"},
+		"sampleRate": {"100"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/ingest?"+query.Encode(), &body)
+	if err != nil {
+		return fmt.Errorf("push: failed creating request: %w", err)
+	}
+	for k, v := range p.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push: failed posting profile: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("push: endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+// name returns the series name of the profile, such as "users.cpu{version=1.2.0}".
+func (p *Profiler) name(kind string) string {
+	keys := make([]string, 0, len(p.tags))
+	for k := range p.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]string, len(keys))
+	for i, k := range keys {
+		tags[i] = k + "=" + p.tags[k]
+	}
+	return p.app + "." + kind + "{" + strings.Join(tags, ",") + "}"
+}
@@ -0,0 +1,48 @@
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProfiler(t *testing.T) {
+	var mu sync.Mutex
+	var names []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" || r.URL.Query().Get("format") != "pprof" {
+			t.Errorf("got %s", r.URL)
+		}
+		file, _, err := r.FormFile("profile")
+		if err != nil {
+			t.Error(err)
+		} else {
+			file.Close()
+		}
+		mu.Lock()
+		names = append(names, r.URL.Query().Get("name"))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	p := New(ts.URL, "users", map[string]string{"version": "1.2.0", "region": "eu"}, nil, 50*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	p.Run(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(names) < 2 {
+		t.Fatalf("got %d pushes, want at least 2", len(names))
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		seen[name] = true
+	}
+	if !seen["users.cpu{region=eu,version=1.2.0}"] || !seen["users.heap{region=eu,version=1.2.0}"] {
+		t.Fatalf("got %v", names)
+	}
+}
@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/router"
+)
+
+// Path is the conventional prefix of the admin routes.
+const Path = "/_queues"
+
+// maxPayload bounds the payloads enqueued over HTTP.
+const maxPayload = 1 << 20
+
+// Admin returns a router under the prefix reporting the depths of the queues, and
+// their dead-letter queues, for dashboards:
+//
+//	GET  {prefix}                              depths of every queue
+//	GET  {prefix}/{queue}/dead?n=              most recently buried jobs, 50 by default
+//	POST {prefix}/{queue}/dead/{id}/requeue    requeue a buried job
+//	POST {prefix}/{queue}/jobs?delay=          enqueue the JSON body as a job
+//
+// The routes expose and alter jobs, so they should be protected or served on an
+// internal listener.
+func Admin(backend Backend, prefix string, queues []string, opts ...router.RouterWrapper) router.Router {
+	known := make(map[string]bool, len(queues))
+	for _, q := range queues {
+		known[q] = true
+	}
+	queue := func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		name := helpers.URLParam(r, "queue")
+		if !known[name] {
+			helpers.JSONError(w, r, http.StatusNotFound, "unknown queue")
+			return "", false
+		}
+		return name, true
+	}
+
+	routes := []router.Route{
+		router.NewGetRoute(prefix, true, false, func(w http.ResponseWriter, r *http.Request) {
+			stats := make([]Stats, 0, len(queues))
+			for _, q := range queues {
+				s, err := backend.Stats(r.Context(), q)
+				if err != nil {
+					log.Error().Str("Function", "Admin").Str("Queue", q).Err(err).Msg("Failed reading stats")
+					helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed reading stats")
+					return
+				}
+				stats = append(stats, s)
+			}
+			helpers.JSON(w, r, http.StatusOK, stats)
+		}),
+		router.NewGetRoute(prefix+"/{queue}/dead", true, false, func(w http.ResponseWriter, r *http.Request) {
+			name, ok := queue(w, r)
+			if !ok {
+				return
+			}
+			n := 50
+			if v := r.URL.Query().Get("n"); v != "" {
+				parsed, err := strconv.Atoi(v)
+				if err != nil || parsed < 1 {
+					helpers.JSONError(w, r, http.StatusBadRequest, "n must be a positive integer")
+					return
+				}
+				n = parsed
+			}
+			jobs, err := backend.Dead(r.Context(), name, n)
+			if err != nil {
+				log.Error().Str("Function", "Admin").Str("Queue", name).Err(err).Msg("Failed listing dead jobs")
+				helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed listing dead jobs")
+				return
+			}
+			if jobs == nil {
+				jobs = []*Job{}
+			}
+			helpers.JSON(w, r, http.StatusOK, jobs)
+		}),
+		router.NewPostRoute(prefix+"/{queue}/dead/{id}/requeue", true, false, func(w http.ResponseWriter, r *http.Request) {
+			name, ok := queue(w, r)
+			if !ok {
+				return
+			}
+			err := backend.Requeue(r.Context(), name, helpers.URLParam(r, "id"))
+			if errors.Is(err, ErrNotFound) {
+				helpers.JSONError(w, r, http.StatusNotFound, "job not found")
+				return
+			}
+			if err != nil {
+				log.Error().Str("Function", "Admin").Str("Queue", name).Err(err).Msg("Failed requeuing job")
+				helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed requeuing job")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		router.NewPostRoute(prefix+"/{queue}/jobs", true, false, func(w http.ResponseWriter, r *http.Request) {
+			name, ok := queue(w, r)
+			if !ok {
+				return
+			}
+			var opts []Option
+			if v := r.URL.Query().Get("delay"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil || d < 0 {
+					helpers.JSONError(w, r, http.StatusBadRequest, "delay must be a non-negative duration")
+					return
+				}
+				opts = append(opts, Delay(d))
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+			if err != nil {
+				helpers.JSONError(w, r, http.StatusRequestEntityTooLarge, "payload too large")
+				return
+			}
+			if !json.Valid(body) {
+				helpers.JSONError(w, r, http.StatusBadRequest, "payload must be JSON")
+				return
+			}
+			job, err := New(backend, name).Enqueue(r.Context(), json.RawMessage(body), opts...)
+			if err != nil {
+				log.Error().Str("Function", "Admin").Str("Queue", name).Err(err).Msg("Failed enqueuing job")
+				helpers.JSONError(w, r, http.StatusServiceUnavailable, "failed enqueuing job")
+				return
+			}
+			helpers.JSON(w, r, http.StatusAccepted, job)
+		}),
+	}
+	return router.NewRouter(routes, true, opts...)
+}
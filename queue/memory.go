@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryQueue struct {
+	scheduled map[string]*Job
+	running   map[string]time.Time
+	dead      []*Job
+}
+
+type memory struct {
+	mu     sync.Mutex
+	queues map[string]*memoryQueue
+}
+
+// NewMemory initializes a Backend local to the process, whose jobs do not survive
+// a restart.
+func NewMemory() Backend {
+	return &memory{queues: make(map[string]*memoryQueue)}
+}
+
+func (m *memory) queue(name string) *memoryQueue {
+	q, ok := m.queues[name]
+	if !ok {
+		q = &memoryQueue{scheduled: make(map[string]*Job), running: make(map[string]time.Time)}
+		m.queues[name] = q
+	}
+	return q
+}
+
+func (m *memory) Enqueue(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := *job
+	m.queue(job.Queue).scheduled[job.ID] = &c
+	return nil
+}
+
+func (m *memory) Dequeue(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(queue)
+	now := time.Now()
+
+	var next *Job
+	for id, job := range q.scheduled {
+		if deadline, ok := q.running[id]; ok {
+			if now.Before(deadline) {
+				continue
+			}
+			delete(q.running, id)
+		}
+		if !job.RunAt.After(now) && (next == nil || job.RunAt.Before(next.RunAt)) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, ErrEmpty
+	}
+	q.running[next.ID] = now.Add(visibility)
+	c := *next
+	return &c, nil
+}
+
+func (m *memory) Ack(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(job.Queue)
+	if _, ok := q.scheduled[job.ID]; !ok {
+		return ErrNotFound
+	}
+	delete(q.scheduled, job.ID)
+	delete(q.running, job.ID)
+	return nil
+}
+
+func (m *memory) Retry(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(job.Queue)
+	c := *job
+	q.scheduled[job.ID] = &c
+	delete(q.running, job.ID)
+	return nil
+}
+
+func (m *memory) Bury(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(job.Queue)
+	delete(q.scheduled, job.ID)
+	delete(q.running, job.ID)
+	c := *job
+	q.dead = append(q.dead, &c)
+	return nil
+}
+
+func (m *memory) Stats(ctx context.Context, queue string) (Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(queue)
+	now := time.Now()
+	s := Stats{Queue: queue, Dead: int64(len(q.dead))}
+	for id, job := range q.scheduled {
+		switch deadline, ok := q.running[id]; {
+		case ok && now.Before(deadline):
+			s.Running++
+		case job.RunAt.After(now):
+			s.Delayed++
+		default:
+			s.Ready++
+		}
+	}
+	return s, nil
+}
+
+func (m *memory) Dead(ctx context.Context, queue string, n int) ([]*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(queue)
+	var jobs []*Job
+	for i := len(q.dead) - 1; i >= 0 && len(jobs) < n; i-- {
+		c := *q.dead[i]
+		jobs = append(jobs, &c)
+	}
+	return jobs, nil
+}
+
+func (m *memory) Requeue(ctx context.Context, queue string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(queue)
+	for i, job := range q.dead {
+		if job.ID == id {
+			q.dead = append(q.dead[:i], q.dead[i+1:]...)
+			job.Attempts, job.Error, job.RunAt = 0, "", time.Now()
+			q.scheduled[id] = job
+			return nil
+		}
+	}
+	return ErrNotFound
+}
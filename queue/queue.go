@@ -0,0 +1,149 @@
+// Package queue runs background jobs enqueued by handlers, with delays, retries
+// backing off, and a dead-letter queue for jobs exhausting their attempts.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "queue").Logger()
+
+// ErrEmpty is returned by Dequeue when no job is ready.
+var ErrEmpty = errors.New("queue is empty")
+
+// ErrNotFound is returned when a job does not exist.
+var ErrNotFound = errors.New("job not found")
+
+// Job is a unit of work on a queue.
+type Job struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	Created     time.Time       `json:"created"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// Decode unmarshals the payload of the job.
+func (j *Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("Decode: failed unmarshalling payload: %w", err)
+	}
+	return nil
+}
+
+// Stats are the depths of a queue.
+type Stats struct {
+	Queue   string `json:"queue"`
+	Ready   int64  `json:"ready"`
+	Delayed int64  `json:"delayed"`
+	Running int64  `json:"running"`
+	Dead    int64  `json:"dead"`
+}
+
+// Backend stores the jobs of queues.
+type Backend interface {
+	// Enqueue stores the job, to be ready from its RunAt.
+	Enqueue(ctx context.Context, job *Job) error
+	// Dequeue leases the earliest ready job of the queue until the visibility timeout
+	// elapses, after which it is ready again unless acknowledged, retried or buried.
+	// It returns ErrEmpty when no job is ready.
+	Dequeue(ctx context.Context, queue string, visibility time.Duration) (*Job, error)
+	// Ack removes the completed job.
+	Ack(ctx context.Context, job *Job) error
+	// Retry stores the job, updated with its attempt, to be ready from its RunAt.
+	Retry(ctx context.Context, job *Job) error
+	// Bury moves the job to the dead-letter queue.
+	Bury(ctx context.Context, job *Job) error
+	Stats(ctx context.Context, queue string) (Stats, error)
+	// Dead returns up to n jobs of the dead-letter queue, most recent first.
+	Dead(ctx context.Context, queue string, n int) ([]*Job, error)
+	// Requeue moves the job from the dead-letter queue back to the queue, ready
+	// at once with its attempts reset.
+	Requeue(ctx context.Context, queue string, id string) error
+}
+
+// Queue enqueues jobs on a named queue.
+type Queue struct {
+	Backend Backend
+	Name    string
+	// MaxAttempts bounds the attempts of jobs enqueued without the Attempts option.
+	MaxAttempts int
+}
+
+// New initializes a queue whose jobs are attempted up to 5 times.
+func New(backend Backend, name string) *Queue {
+	return &Queue{Backend: backend, Name: name, MaxAttempts: 5}
+}
+
+// Option configures an enqueued job.
+type Option func(j *Job)
+
+// Delay makes the job ready once the duration elapses.
+func Delay(d time.Duration) Option {
+	return func(j *Job) {
+		j.RunAt = time.Now().Add(d)
+	}
+}
+
+// At makes the job ready at the time.
+func At(t time.Time) Option {
+	return func(j *Job) {
+		j.RunAt = t
+	}
+}
+
+// Attempts bounds the attempts of the job before it is buried.
+func Attempts(n int) Option {
+	return func(j *Job) {
+		j.MaxAttempts = n
+	}
+}
+
+// ID sets the ID of the job, which is otherwise generated.
+func ID(id string) Option {
+	return func(j *Job) {
+		j.ID = id
+	}
+}
+
+// Enqueue enqueues the payload, marshalled as JSON, returning the job.
+func (q *Queue) Enqueue(ctx context.Context, payload interface{}, opts ...Option) (*Job, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Enqueue: failed marshalling payload: %w", err)
+	}
+	now := time.Now()
+	job := &Job{Queue: q.Name, Payload: b, MaxAttempts: q.MaxAttempts, RunAt: now, Created: now}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if job.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, fmt.Errorf("Enqueue: failed generating ID: %w", err)
+		}
+		job.ID = hex.EncodeToString(id)
+	}
+
+	if err := q.Backend.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("Enqueue: %w", err)
+	}
+	return job, nil
+}
+
+// Stats returns the depths of the queue.
+func (q *Queue) Stats(ctx context.Context) (Stats, error) {
+	return q.Backend.Stats(ctx, q.Name)
+}
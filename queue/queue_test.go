@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorker(t *testing.T) {
+	backend := NewMemory()
+	q := New(backend, "emails")
+	ctx := context.Background()
+
+	var done, flaky atomic.Int32
+	w := q.Worker(func(ctx context.Context, job *Job) error {
+		var to string
+		if err := job.Decode(&to); err != nil {
+			return err
+		}
+		switch to {
+		case "bounce":
+			return errors.New("mailbox unavailable")
+		case "flaky":
+			if flaky.Add(1) == 1 {
+				return errors.New("timeout")
+			}
+		}
+		done.Add(1)
+		return nil
+	}, 2)
+	w.Poll, w.Base, w.Max = time.Millisecond, time.Millisecond, time.Millisecond
+
+	q.Enqueue(ctx, "ok")
+	q.Enqueue(ctx, "flaky")
+	q.Enqueue(ctx, "bounce", Attempts(2), ID("bounce"))
+	q.Enqueue(ctx, "later", Delay(time.Hour))
+
+	run, cancel := context.WithCancel(ctx)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	w.Run(run)
+
+	if done.Load() != 2 {
+		t.Fatalf("completed %d jobs, want 2", done.Load())
+	}
+	stats, _ := q.Stats(ctx)
+	if stats != (Stats{Queue: "emails", Delayed: 1, Dead: 1}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	dead, _ := backend.Dead(ctx, "emails", 10)
+	if len(dead) != 1 || dead[0].Attempts != 2 || dead[0].Error != "mailbox unavailable" {
+		t.Fatalf("unexpected dead jobs: %+v", dead)
+	}
+
+	if err := backend.Requeue(ctx, "emails", "bounce"); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if stats, _ := q.Stats(ctx); stats.Ready != 1 || stats.Dead != 0 {
+		t.Fatalf("unexpected stats after requeue: %+v", stats)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	backend := NewMemory()
+	ctx := context.Background()
+	New(backend, "q").Enqueue(ctx, 1)
+
+	job, err := backend.Dequeue(ctx, "q", 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if _, err := backend.Dequeue(ctx, "q", time.Second); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("Dequeue of leased job: got %v, want ErrEmpty", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	again, err := backend.Dequeue(ctx, "q", time.Second)
+	if err != nil || again.ID != job.ID {
+		t.Fatalf("Dequeue after lease expired: got %v, %v", again, err)
+	}
+}
@@ -0,0 +1,187 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dequeueScript makes jobs whose lease expired ready again, then leases the
+// earliest ready job, atomically so that replicas never lease the same job.
+var dequeueScript = redis.NewScript(`
+local expired = redis.call("zrangebyscore", KEYS[2], "-inf", ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call("zrem", KEYS[2], id)
+	redis.call("zadd", KEYS[1], ARGV[1], id)
+end
+local ids = redis.call("zrangebyscore", KEYS[1], "-inf", ARGV[1], "limit", 0, 1)
+if #ids == 0 then return false end
+redis.call("zrem", KEYS[1], ids[1])
+redis.call("zadd", KEYS[2], ARGV[2], ids[1])
+return redis.call("hget", KEYS[3], ids[1])`)
+
+type redisBackend struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedis initializes a Backend storing jobs in Redis, with keys prefixed by
+// "queue:" and the queue name. Ready and delayed jobs are kept in a sorted set
+// by RunAt, leased jobs in a sorted set by lease expiry, and buried jobs in a list.
+func NewRedis(client redis.Cmdable) Backend {
+	return &redisBackend{client: client, prefix: "queue:"}
+}
+
+func (r *redisBackend) key(queue string, kind string) string {
+	return r.prefix + queue + ":" + kind
+}
+
+func (r *redisBackend) Enqueue(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("Enqueue: failed marshalling job: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, r.key(job.Queue, "jobs"), job.ID, b)
+		p.ZAdd(ctx, r.key(job.Queue, "scheduled"), redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Enqueue: failed storing job: %w", err)
+	}
+	return nil
+}
+
+func (r *redisBackend) Dequeue(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	now := time.Now()
+	keys := []string{r.key(queue, "scheduled"), r.key(queue, "running"), r.key(queue, "jobs")}
+	b, err := dequeueScript.Run(ctx, r.client, keys, now.UnixMilli(), now.Add(visibility).UnixMilli()).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Dequeue: failed leasing job: %w", err)
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(b), &job); err != nil {
+		return nil, fmt.Errorf("Dequeue: failed unmarshalling job: %w", err)
+	}
+	return &job, nil
+}
+
+func (r *redisBackend) Ack(ctx context.Context, job *Job) error {
+	_, err := r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZRem(ctx, r.key(job.Queue, "running"), job.ID)
+		p.HDel(ctx, r.key(job.Queue, "jobs"), job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Ack: failed removing job: %w", err)
+	}
+	return nil
+}
+
+func (r *redisBackend) Retry(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("Retry: failed marshalling job: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZRem(ctx, r.key(job.Queue, "running"), job.ID)
+		p.HSet(ctx, r.key(job.Queue, "jobs"), job.ID, b)
+		p.ZAdd(ctx, r.key(job.Queue, "scheduled"), redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Retry: failed rescheduling job: %w", err)
+	}
+	return nil
+}
+
+func (r *redisBackend) Bury(ctx context.Context, job *Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("Bury: failed marshalling job: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.ZRem(ctx, r.key(job.Queue, "running"), job.ID)
+		p.HSet(ctx, r.key(job.Queue, "jobs"), job.ID, b)
+		p.LPush(ctx, r.key(job.Queue, "dead"), job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Bury: failed burying job: %w", err)
+	}
+	return nil
+}
+
+func (r *redisBackend) Stats(ctx context.Context, queue string) (Stats, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	var ready, delayed, running, dead *redis.IntCmd
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		ready = p.ZCount(ctx, r.key(queue, "scheduled"), "-inf", now)
+		delayed = p.ZCount(ctx, r.key(queue, "scheduled"), "("+now, "+inf")
+		running = p.ZCard(ctx, r.key(queue, "running"))
+		dead = p.LLen(ctx, r.key(queue, "dead"))
+		return nil
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("Stats: failed counting jobs: %w", err)
+	}
+	return Stats{Queue: queue, Ready: ready.Val(), Delayed: delayed.Val(), Running: running.Val(), Dead: dead.Val()}, nil
+}
+
+func (r *redisBackend) Dead(ctx context.Context, queue string, n int) ([]*Job, error) {
+	ids, err := r.client.LRange(ctx, r.key(queue, "dead"), 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Dead: failed listing jobs: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := r.client.HMGet(ctx, r.key(queue, "jobs"), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("Dead: failed reading jobs: %w", err)
+	}
+	var jobs []*Job
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(s), &job); err != nil {
+			return nil, fmt.Errorf("Dead: failed unmarshalling job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+func (r *redisBackend) Requeue(ctx context.Context, queue string, id string) error {
+	removed, err := r.client.LRem(ctx, r.key(queue, "dead"), 1, id).Result()
+	if err != nil {
+		return fmt.Errorf("Requeue: failed removing job: %w", err)
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	b, err := r.client.HGet(ctx, r.key(queue, "jobs"), id).Bytes()
+	if err != nil {
+		return fmt.Errorf("Requeue: failed reading job: %w", err)
+	}
+	var job Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		return fmt.Errorf("Requeue: failed unmarshalling job: %w", err)
+	}
+	job.Attempts, job.Error, job.RunAt = 0, "", time.Now()
+	if err := r.Retry(ctx, &job); err != nil {
+		return fmt.Errorf("Requeue: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Handler processes a job. Jobs for which it returns an error are retried, and
+// buried once they exhaust their attempts.
+type Handler func(ctx context.Context, job *Job) error
+
+// Worker processes the jobs of a queue with a pool of goroutines.
+type Worker struct {
+	Backend     Backend
+	Queue       string
+	Handler     Handler
+	Concurrency int
+	// Visibility is how long a job is leased to a goroutine, after which it is
+	// redelivered, such as when its replica crashed. Handlers exceeding it are
+	// cancelled.
+	Visibility time.Duration
+	// Poll is how long a goroutine waits when the queue is empty.
+	Poll time.Duration
+	// Base and Max bound the backoff between attempts, which doubles with each and
+	// is jittered uniformly up to the doubled value.
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Worker initializes a worker processing the jobs of the queue with the handler,
+// leasing jobs for 5 minutes, polling every second, and backing off from 1s up to 1h.
+func (q *Queue) Worker(handler Handler, concurrency int) *Worker {
+	return &Worker{
+		Backend:     q.Backend,
+		Queue:       q.Name,
+		Handler:     handler,
+		Concurrency: concurrency,
+		Visibility:  5 * time.Minute,
+		Poll:        time.Second,
+		Base:        time.Second,
+		Max:         time.Hour,
+	}
+}
+
+// Run processes jobs until the context is done, then waits for running handlers
+// to return, so that it may be tied to the lifecycle of the server.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.Backend.Dequeue(ctx, w.Queue, w.Visibility)
+		if err != nil {
+			if !errors.Is(err, ErrEmpty) && ctx.Err() == nil {
+				log.Error().Str("Function", "loop").Str("Queue", w.Queue).Err(err).Msg("Failed dequeuing job")
+			}
+			select {
+			case <-time.After(w.Poll):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		// Handlers finish the job being processed on shutdown, within its lease.
+		if err := w.process(context.WithoutCancel(ctx), job); err != nil {
+			log.Error().Str("Function", "loop").Str("Queue", w.Queue).Str("ID", job.ID).Err(err).Msg("Failed settling job")
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job *Job) error {
+	err := w.handle(ctx, job)
+	if err == nil {
+		return w.Backend.Ack(ctx, job)
+	}
+
+	job.Attempts++
+	job.Error = err.Error()
+	if job.Attempts >= job.MaxAttempts {
+		log.Error().Str("Function", "process").Str("Queue", w.Queue).Str("ID", job.ID).Int("Attempts", job.Attempts).Err(err).Msg("Job exhausted its attempts")
+		return w.Backend.Bury(ctx, job)
+	}
+	log.Warn().Str("Function", "process").Str("Queue", w.Queue).Str("ID", job.ID).Int("Attempts", job.Attempts).Err(err).Msg("Job failed")
+	job.RunAt = time.Now().Add(w.backoff(job.Attempts))
+	return w.Backend.Retry(ctx, job)
+}
+
+func (w *Worker) handle(ctx context.Context, job *Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, w.Visibility)
+	defer cancel()
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("handle: job panicked: %v", v)
+		}
+	}()
+	return w.Handler(ctx, job)
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.Base
+	for i := 1; i < attempts && d < w.Max; i++ {
+		d *= 2
+	}
+	if d > w.Max {
+		d = w.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
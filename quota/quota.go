@@ -0,0 +1,184 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/ratelimit"
+	"github.com/Etwodev/ramchi/router"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// Block refuses requests with 429 Too Many Requests once a quota is exhausted.
+	Block = "block"
+	// Bill serves requests beyond the quota, reporting each through OnOverage.
+	Bill = "bill"
+)
+
+// Plan is the allowance of an API key for each period. A zero limit is unlimited.
+type Plan struct {
+	Requests int64
+	Bytes    int64
+	Period   time.Duration
+}
+
+// Usage is the consumption of an API key in the current period.
+type Usage struct {
+	Key               string    `json:"key"`
+	Requests          int64     `json:"requests"`
+	RequestsLimit     int64     `json:"requestsLimit"`
+	Bytes             int64     `json:"bytes"`
+	BytesLimit        int64     `json:"bytesLimit"`
+	PeriodStart       time.Time `json:"periodStart"`
+	PeriodEnd         time.Time `json:"periodEnd"`
+	RequestsExhausted bool      `json:"requestsExhausted"`
+	BytesExhausted    bool      `json:"bytesExhausted"`
+}
+
+// Quota accounts the requests and response bytes of each API key against its plan.
+type Quota struct {
+	Store ratelimit.Store
+	// Plan returns the plan of an API key.
+	Plan func(key string) Plan
+	// Key returns the API key of a request, requests without one are not accounted.
+	Key func(r *http.Request) string
+	// Overage is either Block or Bill.
+	Overage string
+	// OnOverage is called for each request served beyond the quota under Bill.
+	OnOverage func(ctx context.Context, u Usage)
+}
+
+// New initializes a quota applying the same plan to every key read from the X-Api-Key header.
+func New(store ratelimit.Store, plan Plan) *Quota {
+	return &Quota{
+		Store:   store,
+		Plan:    func(string) Plan { return plan },
+		Key:     func(r *http.Request) string { return r.Header.Get("X-Api-Key") },
+		Overage: Block,
+	}
+}
+
+// Usage returns the consumption of the key in the current period.
+func (q *Quota) Usage(ctx context.Context, key string) (Usage, error) {
+	plan := q.Plan(key)
+	start, end := period(plan.Period)
+	u := Usage{Key: key, RequestsLimit: plan.Requests, BytesLimit: plan.Bytes, PeriodStart: start, PeriodEnd: end}
+
+	var err error
+	if u.Requests, _, err = q.Store.Get(ctx, q.counter(key, "requests", start)); err != nil {
+		return Usage{}, fmt.Errorf("Usage: failed reading requests: %w", err)
+	}
+	if u.Bytes, _, err = q.Store.Get(ctx, q.counter(key, "bytes", start)); err != nil {
+		return Usage{}, fmt.Errorf("Usage: failed reading bytes: %w", err)
+	}
+	u.RequestsExhausted = plan.Requests > 0 && u.Requests >= plan.Requests
+	u.BytesExhausted = plan.Bytes > 0 && u.Bytes >= plan.Bytes
+	return u, nil
+}
+
+// Handler accounts each request against the quota of its key, reporting the
+// remaining quota in the X-Quota-* response headers.
+func (q *Quota) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := q.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		plan := q.Plan(key)
+		start, end := period(plan.Period)
+		u := Usage{Key: key, RequestsLimit: plan.Requests, BytesLimit: plan.Bytes, PeriodStart: start, PeriodEnd: end}
+		requests := q.counter(key, "requests", start)
+		var err error
+		if u.Bytes, _, err = q.Store.Get(r.Context(), q.counter(key, "bytes", start)); err != nil {
+			helpers.Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		if u.Requests, _, err = q.Store.Increment(r.Context(), requests, 1, time.Until(end)); err != nil {
+			helpers.Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		// The request itself is not yet consumption, so that the headers and
+		// overage report the usage before it, as Usage does.
+		u.Requests--
+		u.RequestsExhausted = plan.Requests > 0 && u.Requests >= plan.Requests
+		u.BytesExhausted = plan.Bytes > 0 && u.Bytes >= plan.Bytes
+
+		exhausted := u.RequestsExhausted || u.BytesExhausted
+		setHeaders(w.Header(), u)
+		if exhausted && q.Overage != Bill {
+			_, _, _ = q.Store.Increment(r.Context(), requests, -1, time.Until(end))
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(time.Until(end).Seconds())+1, 10))
+			helpers.Error(w, r, http.StatusTooManyRequests)
+			return
+		}
+		if exhausted {
+			w.Header().Set("X-Quota-Overage", "true")
+			if q.OnOverage != nil {
+				q.OnOverage(r.Context(), u)
+			}
+		}
+
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		if n := int64(ww.BytesWritten()); n > 0 {
+			_, _, _ = q.Store.Increment(r.Context(), q.counter(key, "bytes", start), n, time.Until(end))
+		}
+	})
+}
+
+// Middleware returns the quota as a server middleware.
+func (q *Quota) Middleware(opts ...middleware.MiddlewareWrapper) middleware.Middleware {
+	return middleware.NewMiddleware(q.Handler, "quota", true, false, opts...)
+}
+
+// UsageRoute returns a route reporting the usage of the caller's key.
+func (q *Quota) UsageRoute(path string, opts ...router.RouteWrapper) router.Route {
+	return router.NewGetRoute(path, true, false, func(w http.ResponseWriter, r *http.Request) {
+		key := q.Key(r)
+		if key == "" {
+			helpers.Error(w, r, http.StatusUnauthorized)
+			return
+		}
+		u, err := q.Usage(r.Context(), key)
+		if err != nil {
+			helpers.Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		helpers.JSON(w, r, http.StatusOK, u)
+	}, opts...)
+}
+
+func (q *Quota) counter(key string, kind string, start time.Time) string {
+	return fmt.Sprintf("quota:%s:%s:%d", key, kind, start.Unix())
+}
+
+// period returns the bounds of the current period, aligned to multiples of its length.
+func period(length time.Duration) (time.Time, time.Time) {
+	if length <= 0 {
+		length = 24 * time.Hour
+	}
+	start := time.Now().UTC().Truncate(length)
+	return start, start.Add(length)
+}
+
+func setHeaders(h http.Header, u Usage) {
+	h.Set("X-Quota-Reset", strconv.FormatInt(u.PeriodEnd.Unix(), 10))
+	if u.RequestsLimit > 0 {
+		h.Set("X-Quota-Limit", strconv.FormatInt(u.RequestsLimit, 10))
+		h.Set("X-Quota-Remaining", strconv.FormatInt(max(u.RequestsLimit-u.Requests-1, 0), 10))
+	}
+	if u.BytesLimit > 0 {
+		h.Set("X-Quota-Bytes-Limit", strconv.FormatInt(u.BytesLimit, 10))
+		h.Set("X-Quota-Bytes-Remaining", strconv.FormatInt(max(u.BytesLimit-u.Bytes, 0), 10))
+	}
+}
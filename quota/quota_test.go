@@ -0,0 +1,72 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/ratelimit"
+)
+
+func TestQuotaConcurrent(t *testing.T) {
+	q := New(ratelimit.NewMemoryStore(), Plan{Requests: 10, Period: time.Hour})
+	h := q.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var served, refused atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Api-Key", "tenant")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			switch rec.Code {
+			case http.StatusOK:
+				served.Add(1)
+			case http.StatusTooManyRequests:
+				refused.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if served.Load() != 10 || refused.Load() != 40 {
+		t.Fatalf("served %d and refused %d, want 10 and 40", served.Load(), refused.Load())
+	}
+	u, err := q.Usage(context.Background(), "tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Requests != 10 || !u.RequestsExhausted {
+		t.Fatalf("got usage %+v, want the 10 requests served", u)
+	}
+}
+
+func TestQuotaBill(t *testing.T) {
+	q := New(ratelimit.NewMemoryStore(), Plan{Requests: 1, Period: time.Hour})
+	q.Overage = Bill
+	var billed []Usage
+	q.OnOverage = func(ctx context.Context, u Usage) {
+		billed = append(billed, u)
+	}
+	h := q.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, want := range []string{"", "true", "true"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", "tenant")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Quota-Overage") != want {
+			t.Fatalf("request %d: got %d with overage %q, want 200 with %q", i, rec.Code, rec.Header().Get("X-Quota-Overage"), want)
+		}
+	}
+	if len(billed) != 2 {
+		t.Fatalf("billed %d requests, want 2", len(billed))
+	}
+}
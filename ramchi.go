@@ -2,37 +2,171 @@ package ramchi
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/logsink"
+	"github.com/Etwodev/ramchi/metrics"
 	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/otlp"
+	"github.com/Etwodev/ramchi/profiling"
 	"github.com/Etwodev/ramchi/router"
+	"github.com/Etwodev/ramchi/scheduler"
+	"github.com/Etwodev/ramchi/spiffe"
+	"github.com/Etwodev/ramchi/watchdog"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rs/zerolog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
-var log zerolog.Logger
+// log is the logger of functions outside any server; servers log to their own.
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "ramchi").Logger()
 
+// Server serves routers with a configuration, logger and signal handling of its own,
+// so that a process may run several, such as a public API and an internal tools
+// server, and shut them down independently.
 type Server struct {
-	idle        chan struct{}
-	middlewares []middleware.Middleware
-	routers     []router.Router
-	instance    *http.Server
+	cfg             *c.Config
+	live            atomic.Pointer[c.Config]
+	path            string
+	log             zerolog.Logger
+	accessLog       *zerolog.Logger
+	level           *levelHook
+	logSet          bool
+	registry        *metrics.Registry
+	signals         []os.Signal
+	idle            chan struct{}
+	middlewares     []middleware.Middleware
+	routers         []router.Router
+	workers         []Worker
+	onStart         []func(ctx context.Context) error
+	onShutdown      []func(ctx context.Context) error
+	httpServerHooks []func(srv *http.Server)
+	listenerHooks   []func(ln net.Listener) net.Listener
+	instance        *http.Server
+	httpServer      *http.Server
+	baseCtx         context.Context
+	mux             http.Handler
+	muxErr          error
+	handlerOnce     sync.Once
+	handlers        []listenerHandler
+	handlersMu      sync.Mutex
+	onReload        []func(cfg *c.Config, restart []string)
+	listeners       []*http.Server
+	http3           *http3.Server
+	http3Conn       net.PacketConn
+	certs           *certificateChecker
+	svids           *spiffe.FileSource
+	stop            chan struct{}
+	stopOnce        sync.Once
+	idleOnce        sync.Once
+	stopCtx         context.Context
+	stopErr         error
+	serving         atomic.Bool
+	pressure        atomic.Bool
 }
 
-func New() *Server {
-	format := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}
-	log = zerolog.New(format).With().Timestamp().Str("Group", "ramchi").Logger()
+// New returns a server configured from ./ramchi.config.json, which is created with
+// the defaults if it does not exist, unless configured otherwise by the options.
+// WithConfig, such as with config.Defaults, configures it without touching disk.
+func New(opts ...Option) *Server {
+	s := &Server{log: log, registry: metrics.NewRegistry(), idle: make(chan struct{}), stop: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
 
-	err := c.New()
-	if err != nil {
-		log.Fatal().Str("Function", "New").Err(err).Msg("Unexpected error")
+	if s.cfg == nil {
+		if err := c.New(); err != nil {
+			s.log.Fatal().Str("Function", "New").Err(err).Msg("Unexpected error")
+		}
+		s.cfg = c.Current()
+		s.path = c.Path()
+	}
+	s.live.Store(s.cfg)
+
+	if !s.logSet {
+		logger, err := s.outputLogger()
+		if err != nil {
+			s.log.Warn().Str("Function", "New").Str("Output", s.cfg.LogOutput).Err(err).Msg("Logging to console")
+		} else {
+			s.log = logger
+		}
 	}
-	return &Server{}
+	s.level = newLevelHook()
+	s.log = s.log.Hook(s.level)
+	if err := s.level.set(s.cfg.LogLevel); err != nil {
+		s.log.Warn().Str("Function", "New").Err(err).Msg("Keeping log level")
+	}
+	return s
+}
+
+// outputLogger returns the logger of the configured output, so that bare-metal
+// deployments may log to syslog or journald, and others to an OpenTelemetry
+// collector, rather than stdout.
+func (s *Server) outputLogger() (zerolog.Logger, error) {
+	cfg := s.cfg
+	switch cfg.LogOutput {
+	case "", c.LogOutputConsole:
+		return log, nil
+	case c.LogOutputSyslog:
+		w, err := logsink.NewSyslog(cfg.SyslogNetwork, cfg.SyslogAddress, logsink.FacilityDaemon, cfg.ServiceName)
+		if err != nil {
+			return log, fmt.Errorf("outputLogger: %w", err)
+		}
+		return zerolog.New(w).With().Timestamp().Str("Group", "ramchi").Logger(), nil
+	case c.LogOutputJournald:
+		w, err := logsink.NewJournald(cfg.ServiceName)
+		if err != nil {
+			return log, fmt.Errorf("outputLogger: %w", err)
+		}
+		return zerolog.New(w).With().Str("Group", "ramchi").Logger(), nil
+	case c.LogOutputOTLP:
+		buf := logsink.NewBuffer(otlp.NewLogSink(cfg.OTLPEndpoint, otlpResource(cfg), otlpHeader(cfg)), logsink.WithRedaction(nil))
+		s.OnShutdown(func(ctx context.Context) error {
+			return buf.Close()
+		})
+		return zerolog.New(buf).With().Timestamp().Str("Group", "ramchi").Logger(), nil
+	}
+	return log, fmt.Errorf("outputLogger: unknown log output %q", cfg.LogOutput)
+}
+
+// otlpResource returns the resource attributes of exported logs and metrics.
+func otlpResource(cfg *c.Config) map[string]string {
+	attrs := map[string]string{"service.name": cfg.ServiceName}
+	if cfg.ServiceVersion != "" {
+		attrs["service.version"] = cfg.ServiceVersion
+	}
+	for k, v := range cfg.OTLPResource {
+		attrs[k] = v
+	}
+	return attrs
+}
+
+func otlpHeader(cfg *c.Config) http.Header {
+	header := make(http.Header, len(cfg.OTLPHeaders))
+	for k, v := range cfg.OTLPHeaders {
+		header.Set(k, v)
+	}
+	return header
+}
+
+// Registry returns the registry the server records its metrics in, and serves, so
+// that the application may register its own alongside them.
+func (s *Server) Registry() *metrics.Registry {
+	return s.registry
 }
 
 func (s *Server) LoadRouter(routers []router.Router) {
@@ -43,28 +177,443 @@ func (s *Server) LoadMiddleware(middlewares []middleware.Middleware) {
 	s.middlewares = append(s.middlewares, middlewares...)
 }
 
+// OnStart registers a hook run before the server listens, such as to open database
+// pools. Hooks run in the order registered, with the context of StartContext, and an
+// error from one aborts the start.
+func (s *Server) OnStart(fn func(ctx context.Context) error) {
+	s.onStart = append(s.onStart, fn)
+}
+
+// OnShutdown registers a hook run during graceful shutdown, once requests and
+// workers have drained, such as to close database pools or deregister from service
+// discovery. Hooks run in the reverse order registered, bounded by the shutdown
+// deadline, and run only once the server has started.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// ConfigureHTTPServer registers a function customizing the http.Server of each
+// listener before it serves, such as to set ConnContext, ErrorLog or TLSNextProto.
+// It is called once TLS has been configured.
+func (s *Server) ConfigureHTTPServer(fn func(srv *http.Server)) {
+	s.httpServerHooks = append(s.httpServerHooks, fn)
+}
+
+// configure applies the configured timeouts, unless srv sets its own, the base
+// context, h2c and the registered customizations to srv.
+func (s *Server) configure(srv *http.Server) {
+	if srv.ReadTimeout == 0 {
+		srv.ReadTimeout = s.cfg.ReadTimeout.Duration()
+	}
+	if srv.WriteTimeout == 0 {
+		srv.WriteTimeout = s.cfg.WriteTimeout.Duration()
+	}
+	if srv.IdleTimeout == 0 {
+		srv.IdleTimeout = s.cfg.IdleTimeout.Duration()
+	}
+	if s.cfg.EnableH2C && srv.TLSConfig == nil {
+		srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{IdleTimeout: srv.IdleTimeout})
+	}
+	if s.baseCtx != nil {
+		srv.BaseContext = func(net.Listener) context.Context {
+			return s.baseCtx
+		}
+	}
+	for _, fn := range s.httpServerHooks {
+		fn(srv)
+	}
+}
+
+// ConfigureListener registers a function wrapping the main listener once it is
+// bound, after the configured wrappers and before TLS, such as to instrument
+// connections.
+func (s *Server) ConfigureListener(fn func(ln net.Listener) net.Listener) {
+	s.listenerHooks = append(s.listenerHooks, fn)
+}
+
+// Worker runs in the background until its context is done, such as a scheduler
+// or a queue worker.
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// LoadWorker runs the worker while the server is serving. Shutdown cancels its
+// context once requests have drained, and waits for it to return.
+func (s *Server) LoadWorker(w Worker) {
+	s.workers = append(s.workers, w)
+}
+
+// LoadScheduler runs the scheduler's jobs while the server is serving.
+func (s *Server) LoadScheduler(sch *scheduler.Scheduler) {
+	s.LoadWorker(sch)
+}
+
+// Start serves until an interrupt, or Shutdown, shuts the server down. Errors starting
+// or serving are fatal, exiting the process; use StartE or StartContext to handle them.
 func (s *Server) Start() {
-	s.instance = &http.Server{Addr: fmt.Sprintf("%s:%s", c.Address(), c.Port()), Handler: s.handler()}
-	log.Debug().Str("Port", c.Port()).Str("Address", c.Address()).Bool("Experimental", c.Experimental()).Msg("Server started")
+	if err := s.StartE(); err != nil {
+		s.log.Fatal().Str("Function", "Start").Err(err).Msg("Unexpected error")
+	}
+}
+
+// StartE behaves like Start, but returns errors binding the listeners, loading TLS
+// certificates, or serving, so that the server may be supervised by the caller.
+func (s *Server) StartE() error {
+	return s.StartContext(context.Background())
+}
+
+// StartContext serves until the context is done, an interrupt, or Shutdown, shuts the
+// server down, returning once it has drained. Errors binding or serving are
+// returned, so that the caller controls the exit of the process, such as when run
+// in an errgroup.
+func (s *Server) StartContext(ctx context.Context) error {
+	if err := s.cfg.Validate(); err != nil {
+		s.closeIdle()
+		return fmt.Errorf("StartContext: %w", err)
+	}
+	s.instance = s.httpServer
+	if s.instance == nil {
+		s.instance = &http.Server{}
+	}
+	if s.instance.Addr == "" {
+		s.instance.Addr = fmt.Sprintf("%s:%s", s.cfg.Address, s.cfg.Port)
+	}
+
+	// Until serving begins, failures release what was started by closing idle.
+	fail := func(err error) error {
+		for _, l := range s.listeners {
+			l.Close()
+		}
+		s.closeHTTP3()
+		s.closeSVIDs()
+		s.closeIdle()
+		return fmt.Errorf("StartContext: %w", err)
+	}
+
+	if s.instance.Handler == nil {
+		h, err := s.handler()
+		if err != nil {
+			return fail(err)
+		}
+		s.instance.Handler = h
+	}
+
+	if s.cfg.MemoryLimitMB > 0 {
+		watchdog.SetMemoryLimit(int64(s.cfg.MemoryLimitMB) << 20)
+	}
+
+	if s.cfg.EnableTLS {
+		certs, err := newCertificateChecker(s.cfg.TLSCertFile, s.cfg.TLSKeyFile, s.cfg.TLSExpiryWarningDays)
+		if err != nil {
+			return fail(err)
+		}
+		certs.days = s.registry.Gauge("ramchi_tls_certificate_days_remaining", "Days until the served certificate expires.")
+		certs.log = s.log
+		s.certs = certs
+		tlsCfg, err := s.tlsConfig(certs.getCertificate)
+		if err != nil {
+			return fail(err)
+		}
+		s.instance.TLSConfig = tlsCfg
+		go certs.run(s.idle)
+		if s.cfg.EnableOCSPStapling {
+			go certs.staple(s.idle)
+		}
+	} else if s.cfg.EnableAutocert {
+		tlsCfg, err := s.autocert()
+		if err != nil {
+			return fail(err)
+		}
+		s.instance.TLSConfig = tlsCfg
+	} else if s.cfg.EnableSPIFFE {
+		src, err := spiffe.NewFileSource(s.cfg.SPIFFEDir, time.Minute)
+		if err != nil {
+			return fail(err)
+		}
+		s.svids = src
+		authorize := spiffe.AuthorizeAny()
+		if s.cfg.SPIFFETrustDomain != "" {
+			authorize = spiffe.AuthorizeMemberOf(s.cfg.SPIFFETrustDomain)
+		}
+		s.instance.TLSConfig = spiffe.ServerTLSConfig(src, authorize)
+	}
+	s.configure(s.instance)
+
+	signals := s.signals
+	if signals == nil {
+		var err error
+		if signals, err = parseSignals(s.cfg.ShutdownSignals); err != nil {
+			return fail(err)
+		}
+	}
+	for _, sig := range signals {
+		if upgradeSignal != nil && sig == upgradeSignal {
+			return fail(fmt.Errorf("%v triggers upgrades, and may not begin a shutdown", sig))
+		}
+	}
+
+	for _, fn := range s.onStart {
+		if err := fn(ctx); err != nil {
+			return fail(fmt.Errorf("failed running start hook: %w", err))
+		}
+	}
+
+	ln, err := s.listen()
+	if err != nil {
+		return fail(err)
+	}
+	if s.cfg.EnableUpgrade {
+		go s.watchUpgrade(ln)
+	}
+	wrapped, err := s.wrap(ln)
+	if err != nil {
+		ln.Close()
+		return fail(err)
+	}
+	ln = wrapped
+	for _, fn := range s.listenerHooks {
+		ln = fn(ln)
+	}
+	if err := s.serveListeners(); err != nil {
+		ln.Close()
+		return fail(err)
+	}
+	if s.cfg.EnableHTTP3 {
+		if err := s.serveHTTP3(); err != nil {
+			ln.Close()
+			return fail(err)
+		}
+	}
+	workers := s.workers
+	if s.cfg.EnableMetrics && !s.pullMetrics() {
+		exporter, err := s.metricsExporter()
+		if err != nil {
+			ln.Close()
+			return fail(err)
+		}
+		workers = append(workers[:len(workers):len(workers)], exporter)
+	}
+	if s.cfg.EnableProfiling {
+		workers = append(workers[:len(workers):len(workers)], s.profiler())
+	}
+	if s.cfg.WatchdogHeapMB > 0 || s.cfg.WatchdogGoroutines > 0 {
+		workers = append(workers[:len(workers):len(workers)], s.watchdog())
+	}
+	if s.cfg.WatchConfig && s.path != "" {
+		workers = append(workers[:len(workers):len(workers)], s.configWatcher())
+	}
+	jobs, cancelJobs := context.WithCancel(context.Background())
+	var running sync.WaitGroup
+	for _, w := range workers {
+		running.Add(1)
+		go func(w Worker) {
+			defer running.Done()
+			w.Run(jobs)
+		}(w)
+	}
+
+	s.serving.Store(true)
+	s.notifyReady()
+	s.runService()
+	s.log.Debug().Str("Port", s.cfg.Port).Str("Address", s.cfg.Address).Bool("Experimental", s.cfg.Experimental).Bool("TLS", s.instance.TLSConfig != nil).Msg("Server started")
 
-	s.idle = make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt)
-		<-sigint
-		if err := s.instance.Shutdown(context.Background()); err != nil {
-			log.Warn().Str("Function", "Shutdown").Err(err).Msg("Server shutdown failed!")
+		if len(signals) > 0 {
+			signal.Notify(sigint, signals...)
+		}
+		drain := context.Background()
+		select {
+		case <-sigint:
+		case <-ctx.Done():
+		case <-s.stop:
+			drain = s.stopCtx
+		}
+		signal.Stop(sigint)
+		if timeout := s.cfg.ShutdownTimeout.Duration(); timeout > 0 {
+			var cancel context.CancelFunc
+			drain, cancel = context.WithTimeout(drain, timeout)
+			defer cancel()
+		}
+		s.stopErr = s.drain(drain, cancelJobs, &running)
+		s.closeIdle()
+	}()
+
+	if s.instance.TLSConfig != nil {
+		ln = tls.NewListener(ln, s.instance.TLSConfig)
+	}
+	serveErr := s.instance.Serve(ln)
+	if serveErr != http.ErrServerClosed {
+		s.shutdown()
+	}
+	<-s.idle
+
+	s.log.Debug().Str("Port", s.cfg.Port).Str("Address", s.cfg.Address).Bool("Experimental", s.cfg.Experimental).Msg("Server stopped")
+	if serveErr != http.ErrServerClosed {
+		return fmt.Errorf("StartContext: failed serving: %w", serveErr)
+	}
+	if s.stopErr != nil {
+		return fmt.Errorf("StartContext: %w", s.stopErr)
+	}
+	return nil
+}
+
+// pullMetrics returns whether metrics are served for scraping rather than pushed.
+func (s *Server) pullMetrics() bool {
+	return s.cfg.MetricsExporter == "" || s.cfg.MetricsExporter == c.MetricsPrometheus
+}
+
+// metricsExporter returns the worker pushing the registry's metrics to the
+// configured exporter.
+func (s *Server) metricsExporter() (Worker, error) {
+	switch s.cfg.MetricsExporter {
+	case c.MetricsStatsD, c.MetricsDogStatsD:
+		exporter, err := metrics.NewStatsD(s.cfg.StatsDAddress, s.cfg.StatsDPrefix, s.cfg.MetricsExporter == c.MetricsDogStatsD, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("metricsExporter: %w", err)
+		}
+		s.registry.Export(exporter)
+		return exporter, nil
+	case c.MetricsOTLP:
+		return otlp.NewMetricExporter(s.cfg.OTLPEndpoint, s.registry, otlpResource(s.cfg), otlpHeader(s.cfg), 10*time.Second), nil
+	}
+	return nil, fmt.Errorf("metricsExporter: unknown metrics exporter %q", s.cfg.MetricsExporter)
+}
+
+// watchdog returns the worker guarding the heap and goroutines of the process,
+// failing readiness while it is under pressure.
+func (s *Server) watchdog() Worker {
+	interval := time.Duration(s.cfg.WatchdogInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return watchdog.New(uint64(s.cfg.WatchdogHeapMB)<<20, s.cfg.WatchdogGoroutines, interval, watchdog.WithPressure(s.pressure.Store))
+}
+
+// Ready returns whether the server is ready for traffic: it is serving, not
+// draining, and its process is not under pressure of the watchdog.
+func (s *Server) Ready() bool {
+	return s.serving.Load() && !s.pressure.Load()
+}
+
+// Readiness is a handler reporting whether the server is Ready, responding 503
+// Service Unavailable when it is not, so that load balancers shed its load.
+func (s *Server) Readiness(w http.ResponseWriter, r *http.Request) {
+	if !s.Ready() {
+		helpers.JSON(w, r, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+	helpers.JSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// profiler returns the worker continuously profiling the server.
+func (s *Server) profiler() Worker {
+	tags := map[string]string{}
+	if s.cfg.ServiceVersion != "" {
+		tags["version"] = s.cfg.ServiceVersion
+	}
+	for k, v := range s.cfg.ProfilingTags {
+		tags[k] = v
+	}
+	interval := time.Duration(s.cfg.ProfilingInterval) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return profiling.New(s.cfg.ProfilingEndpoint, s.cfg.ServiceName, tags, nil, interval)
+}
+
+// Shutdown gracefully shuts the server down, waiting for requests and workers to
+// drain until the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopWith(ctx)
+	select {
+	case <-s.idle:
+		if s.stopErr != nil {
+			return fmt.Errorf("Shutdown: %w", s.stopErr)
 		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Shutdown: %w", ctx.Err())
+	}
+}
+
+// Stop behaves like Shutdown.
+func (s *Server) Stop(ctx context.Context) error {
+	return s.Shutdown(ctx)
+}
+
+// shutdown begins a graceful shutdown of the server, as an interrupt does.
+func (s *Server) shutdown() {
+	s.stopWith(context.Background())
+}
+
+func (s *Server) stopWith(ctx context.Context) {
+	s.stopOnce.Do(func() {
+		s.stopCtx = ctx
+		close(s.stop)
+	})
+}
+
+// closeIdle marks the server idle, once, whether it drained or failed to start.
+func (s *Server) closeIdle() {
+	s.idleOnce.Do(func() {
 		close(s.idle)
+	})
+}
+
+// drain shuts the listeners down, waiting for their requests to complete, then
+// cancels the workers and waits for them to return, until the context is done.
+func (s *Server) drain(ctx context.Context, cancelWorkers context.CancelFunc, workers *sync.WaitGroup) error {
+	s.serving.Store(false)
+	var errs []error
+	if err := s.instance.Shutdown(ctx); err != nil {
+		s.log.Warn().Str("Function", "Shutdown").Err(err).Msg("Server shutdown failed!")
+		errs = append(errs, err)
+	}
+	for _, l := range s.listeners {
+		if err := l.Shutdown(ctx); err != nil {
+			s.log.Warn().Str("Function", "Shutdown").Str("Address", l.Addr).Err(err).Msg("Listener shutdown failed!")
+			errs = append(errs, err)
+		}
+	}
+	if err := s.closeHTTP3(); err != nil {
+		s.log.Warn().Str("Function", "Shutdown").Err(err).Msg("HTTP/3 listener shutdown failed!")
+		errs = append(errs, err)
+	}
+	s.closeSVIDs()
+
+	cancelWorkers()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
 	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.log.Warn().Str("Function", "Shutdown").Err(ctx.Err()).Msg("Workers did not return")
+		errs = append(errs, ctx.Err())
+	}
 
-	if err := s.instance.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatal().Str("Function", "ListenAndServe").Err(err).Msg("Unexpected error")
+	for i := len(s.onShutdown) - 1; i >= 0; i-- {
+		if err := s.onShutdown[i](ctx); err != nil {
+			s.log.Warn().Str("Function", "Shutdown").Err(err).Msg("Shutdown hook failed!")
+			errs = append(errs, err)
+		}
 	}
 
-	<-s.idle
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("drain: %w", err)
+	}
+	return nil
+}
 
-	log.Debug().Str("Port", c.Port()).Str("Address", c.Address()).Bool("Experimental", c.Experimental()).Msg("Server stopped")
+// closeSVIDs stops watching the SPIFFE directory for rotated SVIDs, once no further
+// handshakes need them.
+func (s *Server) closeSVIDs() {
+	if s.svids != nil {
+		s.svids.Close()
+	}
 }
 
 func Handle(w http.ResponseWriter, function string, err error, msg string, code int) {
@@ -74,28 +623,284 @@ func Handle(w http.ResponseWriter, function string, err error, msg string, code
 	}
 }
 
-func (s *Server) handler() *chi.Mux {
+// HandleRequest behaves like Handle, but logs to the logger of the server handling
+// the request, with the request ID, and writes the response through helpers.Error,
+// so that it carries the request ID when configured.
+func HandleRequest(w http.ResponseWriter, r *http.Request, function string, err error, msg string, code int) {
+	if err != nil {
+		zerolog.Ctx(r.Context()).Error().Str("Function", function).Str("Status", http.StatusText(code)).Str("RequestID", helpers.RequestID(r)).Err(err).Msg(msg)
+		helpers.Error(w, r, code)
+	}
+}
+
+// Handler returns the composed handler of the server, to be mounted in another mux
+// or tested with httptest.
+func (s *Server) Handler() http.Handler {
+	h, _ := s.handler()
+	return h
+}
+
+// handler returns the handler of the server, and the error building it, if any.
+func (s *Server) handler() (http.Handler, error) {
+	s.handlerOnce.Do(func() {
+		s.mux, s.muxErr = s.handlerFor("")
+		if s.muxErr != nil {
+			s.log.Error().Str("Function", "Handler").Err(s.muxErr).Msg("Failed building handler")
+			s.mux = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				helpers.Error(w, r, http.StatusInternalServerError)
+			})
+		}
+	})
+	return s.mux, s.muxErr
+}
+
+// ServeHTTP serves the request with the handler of the server.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Handler().ServeHTTP(w, r)
+}
+
+// handlerFor returns the handler of the named listener, serving the routers bound
+// to it, or the routers not bound to any listener for the main listener. It is
+// rebuilt from the live configuration when the configuration is reloaded.
+func (s *Server) handlerFor(listener string) (http.Handler, error) {
+	m, err := s.buildMux(listener, s.live.Load())
+	if err != nil {
+		return nil, fmt.Errorf("handlerFor: %w", err)
+	}
+	h := &swapHandler{}
+	h.mux.Store(m)
+
+	s.handlersMu.Lock()
+	s.handlers = append(s.handlers, listenerHandler{name: listener, handler: h})
+	s.handlersMu.Unlock()
+	return h, nil
+}
+
+func (s *Server) buildMux(listener string, cfg *c.Config) (*chi.Mux, error) {
 	m := chi.NewMux()
-	s.initMux(m)
-	return m
+	if err := s.initMux(m, listener, cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (s *Server) initMux(m *chi.Mux) {
-	for _, middleware := range s.middlewares {
-		if middleware.Status() && (middleware.Experimental() == c.Experimental() || !middleware.Experimental()) {
-			log.Debug().Str("Name", middleware.Name()).Bool("Experimental", middleware.Experimental()).Bool("Status", middleware.Status()).Msg("Registering middleware")
-			m.Use(middleware.Method())
+func (s *Server) initMux(m *chi.Mux, listener string, cfg *c.Config) error {
+	table := newRouteTable(s.log)
+	if cfg.ErrorRequestID || cfg.ResponseEnvelope {
+		m.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			helpers.Error(w, r, http.StatusNotFound)
+		})
+		m.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if m.Match(rctx, http.MethodOptions, r.URL.Path) {
+				w.Header().Set("Allow", table.allow(rctx.RoutePattern()))
+			}
+			helpers.Error(w, r, http.StatusMethodNotAllowed)
+		})
+	}
+
+	if cfg.EnableMetrics && listener == s.opsListener() && s.pullMetrics() {
+		path := cfg.MetricsPath
+		if path == "" {
+			path = "/metrics"
+		}
+		route := router.NewGetRoute(path, true, false, s.registry.Handler().ServeHTTP, router.WithInternal())
+		table.add(router.NewRouter([]router.Route{route}, true), route)
+	}
+
+	if cfg.EnablePprof && listener == s.pprofListener() {
+		prefix := cfg.PprofPrefix
+		if prefix == "" {
+			prefix = "/debug/pprof"
+		}
+		rt := pprofRouter(prefix)
+		for _, r := range rt.Routes() {
+			table.add(rt, r)
+		}
+	}
+
+	if listener == AdminListener && cfg.AdminAddress != "" {
+		rt := s.adminRouter()
+		for _, r := range rt.Routes() {
+			table.add(rt, r)
 		}
 	}
 
-	for _, router := range s.routers {
-		if router.Status() {
-			for _, r := range router.Routes() {
-				if r.Status() && (r.Experimental() == c.Experimental() || !r.Experimental()) {
-					log.Debug().Bool("Experimental", r.Experimental()).Bool("Status", r.Status()).Str("Method", r.Method()).Str("Path", r.Path()).Msg("Registering route")
-					m.Method(r.Method(), r.Path(), r.Handler())
+	for _, rt := range s.routers {
+		if rt.Status() && router.Listener(rt) == listener {
+			for _, r := range rt.Routes() {
+				if r.Status() && (r.Experimental() == cfg.Experimental || !r.Experimental()) && router.InProfile(rt, r, cfg.Profile) {
+					table.add(rt, r)
 				}
 			}
 		}
 	}
+
+	m.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := s.log.WithContext(c.WithContext(r.Context(), cfg))
+			next.ServeHTTP(w, r.WithContext(helpers.WithRouteInfo(helpers.WithObservation(ctx))))
+		})
+	})
+	m.Use(middleware.Disconnects(s.registry))
+
+	if cfg.EnableMetrics {
+		s.log.Debug().Str("Name", "metrics").Msg("Registering middleware")
+		m.Use(middleware.Metrics(s.registry))
+		if header := cfg.MetricsTenantHeader; header != "" {
+			max := cfg.MetricsTenantLimit
+			if max <= 0 {
+				max = 100
+			}
+			tenant := func(r *http.Request) string { return r.Header.Get(header) }
+			m.Use(middleware.TenantMetrics(s.registry, tenant, metrics.NewLabelLimit(max, !cfg.MetricsTenantRaw)))
+		}
+	}
+
+	if cfg.EnableRequestLogging {
+		s.log.Debug().Str("Name", "accessLog").Msg("Registering middleware")
+		logger := s.log
+		if s.accessLog != nil {
+			logger = s.accessLog.Hook(s.level)
+		}
+		m.Use(middleware.AccessLog(logger))
+	}
+
+	if !cfg.DisableRecovery {
+		s.log.Debug().Str("Name", "recovery").Msg("Registering middleware")
+		m.Use(middleware.Recovery(s.log, []byte(cfg.RecoveryBody)))
+	}
+
+	if cfg.MaxResponseBytes > 0 {
+		s.log.Debug().Str("Name", "maxResponseSize").Msg("Registering middleware")
+		m.Use(middleware.MaxResponseSize(cfg.MaxResponseBytes))
+	}
+
+	if len(table.names) > 0 {
+		m.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(helpers.WithRoutes(r.Context(), table.names)))
+			})
+		})
+	}
+
+	if rules := cfg.RewriteRules; len(rules) > 0 {
+		rewrite, err := compileRewriteRules(rules)
+		if err != nil {
+			return fmt.Errorf("initMux: %w", err)
+		}
+		s.log.Debug().Str("Name", "rewrite").Int("Rules", len(rules)).Msg("Registering middleware")
+		m.Use(rewrite)
+	}
+
+	if mode := cfg.PathNormalization; mode == NormalizeRedirect || mode == NormalizeRewrite {
+		s.log.Debug().Str("Name", "normalize").Str("Mode", mode).Bool("TrailingSlash", cfg.TrailingSlash).Bool("CleanPath", cfg.CleanPath).Bool("CaseInsensitive", cfg.CaseInsensitivePaths).Msg("Registering middleware")
+		m.Use(s.normalizeMiddleware(m))
+	}
+
+	if cfg.EnableMethodOverride || len(table.overrides) > 0 {
+		s.log.Debug().Str("Name", "methodOverride").Bool("Global", cfg.EnableMethodOverride).Int("Routes", len(table.overrides)).Msg("Registering middleware")
+		m.Use(s.methodOverrideMiddleware(m, table))
+	}
+
+	if cfg.EnableCORS || len(table.cors) > 0 {
+		s.log.Debug().Str("Name", "cors").Bool("Global", cfg.EnableCORS).Int("Overrides", len(table.cors)).Msg("Registering middleware")
+		cors, err := s.corsMiddleware(m, table, cfg)
+		if err != nil {
+			return fmt.Errorf("initMux: %w", err)
+		}
+		m.Use(cors)
+	}
+
+	if len(cfg.DefaultHeaders) > 0 || cfg.ServerHeader != "" || cfg.HideServerHeader {
+		s.log.Debug().Str("Name", "headers").Int("Defaults", len(cfg.DefaultHeaders)).Str("Server", cfg.ServerHeader).Bool("HideServer", cfg.HideServerHeader).Msg("Registering middleware")
+		m.Use(middleware.Headers(middleware.HeaderPolicy{Defaults: cfg.DefaultHeaders, Server: cfg.ServerHeader, HideServer: cfg.HideServerHeader}))
+	}
+
+	if cfg.ResponseEnvelope {
+		s.log.Debug().Str("Name", "envelope").Msg("Registering middleware")
+		m.Use(middleware.Envelope)
+	}
+
+	for _, mw := range s.middlewares {
+		if mw.Status() && (mw.Experimental() == cfg.Experimental || !mw.Experimental()) && middleware.InProfile(mw, cfg.Profile) {
+			s.log.Debug().Str("Name", mw.Name()).Bool("Experimental", mw.Experimental()).Bool("Status", mw.Status()).Msg("Registering middleware")
+			m.Use(mw.Method())
+		}
+	}
+
+	// Registered last, so that it recovers from MustPath functions within the handler only.
+	m.Use(helpers.RecoverParams)
+
+	for i, r := range table.routes {
+		s.log.Debug().Bool("Experimental", r.Experimental()).Bool("Status", r.Status()).Str("Method", r.Method()).Str("Path", r.Path()).Msg("Registering route")
+		m.Method(r.Method(), r.Path(), withRouteInfo(table.infos[i], r.Handler()))
+	}
+
+	for _, path := range table.paths {
+		if !table.handles(path, http.MethodOptions) {
+			m.Method(http.MethodOptions, path, table.allowHandler(path))
+		}
+	}
+	return nil
+}
+
+// corsMiddleware applies the configured CORS policy, or the policy overriding it
+// for the matched path and method. Preflight requests are answered with the methods
+// both registered at the path and permitted by the policy, and with 404 when no
+// route matches. It returns an error for an invalid policy.
+func (s *Server) corsMiddleware(m *chi.Mux, table *routeTable, cfg *c.Config) (func(http.Handler) http.Handler, error) {
+	var global *middleware.CORSPolicy
+	if cfg.EnableCORS {
+		global = &middleware.CORSPolicy{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowedMethods:   cfg.CORSAllowedMethods,
+			AllowedHeaders:   cfg.CORSAllowedHeaders,
+			ExposedHeaders:   cfg.CORSExposedHeaders,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		}
+		if err := global.Validate(); err != nil {
+			return nil, fmt.Errorf("corsMiddleware: %w", err)
+		}
+	}
+	for route, policy := range table.cors {
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("corsMiddleware: route %s: %w", route, err)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := r.Method
+			preflight := method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				method = r.Header.Get("Access-Control-Request-Method")
+			}
+
+			rctx := chi.NewRouteContext()
+			matched := m.Match(rctx, http.MethodOptions, r.URL.Path)
+			policy, ok := table.cors[rctx.RoutePattern()+" "+method]
+			if !matched || !ok {
+				if global == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				policy = *global
+			}
+
+			if preflight {
+				if !matched {
+					m.NotFoundHandler().ServeHTTP(w, r)
+					return
+				}
+				policy.AllowedMethods = table.permitted(rctx.RoutePattern(), policy.AllowedMethods)
+				if len(policy.AllowedMethods) == 0 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			middleware.CORS(policy)(next).ServeHTTP(w, r)
+		})
+	}, nil
 }
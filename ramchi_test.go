@@ -1,14 +1,31 @@
 package ramchi
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/ratelimit"
 	"github.com/Etwodev/ramchi/router"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"golang.org/x/net/http2"
 )
 
 func testRequest(t *testing.T, ts *httptest.Server, method, path string, body io.Reader) (*http.Response, string) {
@@ -69,15 +86,1137 @@ func TestBasicServer(t *testing.T) {
 
 	ts.LoadRouter(testRouters())
 
-	instance := httptest.NewServer(ts.handler())
+	instance := httptest.NewServer(ts.Handler())
 	defer instance.Close()
 
 	if _, body := testRequest(t, instance, http.MethodGet, "/ping", nil); body != `{"success":"ping"}` {
 		t.Fatalf(body)
 	}
 
-	
 	if _, body := testRequest(t, instance, http.MethodGet, "/error", nil); body != "I'm a teapot\u000a" {
 		t.Fatalf(body)
 	}
 }
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	for _, mode := range []string{NormalizeRewrite, NormalizeRedirect} {
+		ts := New(WithConfig(&config.Config{PathNormalization: mode, CaseInsensitivePaths: true}), WithSignals())
+		ts.LoadRouter([]router.Router{
+			router.NewRouter([]router.Route{
+				router.NewGetRoute("/users/{id}/files/*", true, false, func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(chi.URLParam(r, "id") + " " + chi.URLParam(r, "*")))
+				}),
+			}, true),
+		})
+
+		rec := httptest.NewRecorder()
+		ts.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/USERS/AbC/Files/Report.PDF", nil))
+		switch mode {
+		case NormalizeRewrite:
+			if rec.Code != http.StatusOK || rec.Body.String() != "AbC Report.PDF" {
+				t.Fatalf("rewrite: got %d %q, want the parameters as sent", rec.Code, rec.Body.String())
+			}
+		case NormalizeRedirect:
+			if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != "/users/AbC/files/Report.PDF" {
+				t.Fatalf("redirect: got %d to %q, want the static segments folded", rec.Code, rec.Header().Get("Location"))
+			}
+		}
+	}
+}
+
+func TestCORSOverride(t *testing.T) {
+	ts := New()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	widget := middleware.CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}}
+	admin := middleware.CORSPolicy{AllowedOrigins: []string{"https://admin.example"}, AllowedMethods: []string{http.MethodPost, http.MethodDelete}}
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/widget", true, false, ok, router.WithCORS(widget)),
+			router.NewPostRoute("/widget", true, false, ok, router.WithCORS(admin)),
+			router.NewGetRoute("/private", true, false, ok),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	req, _ := http.NewRequest(http.MethodOptions, instance.URL+"/widget", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "*" || resp.Header.Get("Access-Control-Allow-Methods") != "GET" {
+		t.Fatalf("unexpected preflight response: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// The POST route at the same path keeps its own policy.
+	for origin, allowed := range map[string]string{"https://admin.example": "https://admin.example", "https://example.com": ""} {
+		req, _ = http.NewRequest(http.MethodOptions, instance.URL+"/widget", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Header.Get("Access-Control-Allow-Origin") != allowed {
+			t.Fatalf("%s: unexpected preflight response: %v", origin, resp.Header)
+		}
+		if allowed != "" && resp.Header.Get("Access-Control-Allow-Methods") != "POST" {
+			t.Fatalf("got methods %q, want only the registered POST", resp.Header.Get("Access-Control-Allow-Methods"))
+		}
+	}
+
+	req, _ = http.NewRequest(http.MethodOptions, instance.URL+"/unknown", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected preflight response for unknown path: %d", resp.StatusCode)
+	}
+
+	if resp, _ := testRequest(t, instance, http.MethodOptions, "/private", nil); resp.Header.Get("Allow") != "GET, OPTIONS" {
+		t.Fatalf("unexpected Allow header: %q", resp.Header.Get("Allow"))
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, instance.URL+"/private", nil)
+	req.Header.Set("Origin", "https://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("unexpected CORS header on private route: %v", resp.Header)
+	}
+
+	credentialed := New()
+	credentialed.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/widget", true, false, ok, router.WithCORS(middleware.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true})),
+		}, true),
+	})
+	if _, err := credentialed.handler(); err == nil {
+		t.Fatal("built a handler permitting credentials from any origin")
+	}
+}
+
+func TestMethodOverride(t *testing.T) {
+	ts := New()
+
+	method := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := w.Write([]byte(r.Method)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewPutRoute("/item", true, false, method, router.WithMethodOverride()),
+			router.NewDeleteRoute("/item", true, false, method),
+			router.NewPostRoute("/upload", true, false, func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(w, r.Body)
+			}),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	// The body of a path without overridable routes is left to its handler.
+	req, _ := http.NewRequest(http.MethodPost, instance.URL+"/upload", strings.NewReader("_method=put&name=a"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "_method=put&name=a" {
+		t.Fatalf("got %d %q, want the body unread by the override", resp.StatusCode, body)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, instance.URL+"/item", strings.NewReader("_method=put"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != http.MethodPut {
+		t.Fatalf("expected override to PUT, got %d %s", resp.StatusCode, body)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, instance.URL+"/item", nil)
+	req.Header.Set(MethodOverrideHeader, http.MethodDelete)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected DELETE override to be refused, got %d", resp.StatusCode)
+	}
+
+	// Without configured origins, a cross-origin override is left a POST, which the
+	// route does not accept.
+	for origin, want := range map[string]int{instance.URL: http.StatusOK, "https://attacker.example": http.StatusMethodNotAllowed} {
+		req, _ = http.NewRequest(http.MethodPost, instance.URL+"/item", nil)
+		req.Header.Set(MethodOverrideHeader, http.MethodPut)
+		req.Header.Set("Origin", origin)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("override from %s: expected %d, got %d", origin, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestErrorRequestID(t *testing.T) {
+	ts := New(WithConfig(&config.Config{ErrorRequestID: true}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/fail", true, false, func(w http.ResponseWriter, r *http.Request) {
+				HandleRequest(w, r, "fail", errors.New("database unavailable"), "Handler failed", http.StatusInternalServerError)
+			}),
+		}, true),
+	})
+
+	const trace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	for _, tc := range []struct {
+		method, path string
+		code         int
+	}{
+		{http.MethodGet, "/fail", http.StatusInternalServerError},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+		{http.MethodPost, "/fail", http.StatusMethodNotAllowed},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("X-Request-Id", "req-1")
+		req.Header.Set("traceparent", "00-"+trace+"-00f067aa0ba902b7-01")
+		rec := httptest.NewRecorder()
+		ts.ServeHTTP(rec, req)
+
+		var body helpers.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: got %q: %v", tc.method, tc.path, rec.Body.String(), err)
+		}
+		if rec.Code != tc.code || body.Status != tc.code || body.RequestID != "req-1" || body.TraceID != trace {
+			t.Errorf("%s %s: got %d %+v, want %d with the request and trace IDs", tc.method, tc.path, rec.Code, body, tc.code)
+		}
+		if rec.Header().Get("X-Request-Id") != "req-1" || rec.Header().Get("X-Trace-Id") != trace {
+			t.Errorf("%s %s: got headers %v", tc.method, tc.path, rec.Header())
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	ts := New()
+
+	user := func(w http.ResponseWriter, r *http.Request) {
+		self, err := helpers.LinkFor(r, "user", "id", helpers.URLParam(r, "id"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		helpers.JSON(w, r, http.StatusOK, helpers.WithLinks(map[string]string{"id": helpers.URLParam(r, "id")}, helpers.Links{"self": self}))
+	}
+
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/users/{id:[0-9]+}", true, false, user, router.WithName("user")),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	if _, body := testRequest(t, instance, http.MethodGet, "/users/42", nil); body != `{"_links":{"self":{"href":"/users/42"}},"id":"42"}` {
+		t.Fatalf(body)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	ts := New()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	header := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Debug", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ts.LoadMiddleware([]middleware.Middleware{
+		middleware.NewMiddleware(header, "debug", true, false, middleware.WithProfiles(config.ProfileDev)),
+	})
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/debug", true, false, ok),
+			router.NewGetRoute("/live", true, false, ok, router.WithProfiles(config.ProfileStaging, config.ProfileProd)),
+		}, true, router.WithRouterProfiles(config.ProfileDev)),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	// The default profile is prod.
+	if resp, _ := testRequest(t, instance, http.MethodGet, "/debug", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected dev route to be unmounted, got %d", resp.StatusCode)
+	}
+	resp, _ := testRequest(t, instance, http.MethodGet, "/live", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected prod route to be mounted, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-Debug") != "" {
+		t.Fatal("expected dev middleware to be unregistered")
+	}
+}
+
+func TestRouteWrappers(t *testing.T) {
+	ts := New()
+
+	var calls atomic.Int32
+	count := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fmt.Sprint(calls.Add(1))))
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}
+
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/cached", true, false, count, router.WithCache(time.Minute)),
+			router.NewGetRoute("/limited", true, false, ok, router.WithRateLimit(ratelimit.NewMemoryStore(), 1, time.Minute)),
+			router.NewGetRoute("/slow", true, false, slow, router.WithTimeout(20*time.Millisecond)),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	for i := 0; i < 2; i++ {
+		if resp, body := testRequest(t, instance, http.MethodGet, "/cached", nil); resp.StatusCode != http.StatusOK || body != "1" {
+			t.Fatalf("request %d: got %d %q, want the first response cached", i+1, resp.StatusCode, body)
+		}
+	}
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if resp, _ := testRequest(t, instance, http.MethodGet, "/limited", nil); resp.StatusCode != want {
+			t.Fatalf("request %d: got %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+	if resp, _ := testRequest(t, instance, http.MethodGet, "/slow", nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, want the request timed out", resp.StatusCode)
+	}
+}
+
+func TestListenerBinding(t *testing.T) {
+	ts := New()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{router.NewGetRoute("/api", true, false, ok)}, true),
+		router.NewRouter([]router.Route{router.NewGetRoute("/internal", true, false, ok)}, true, router.WithRouterListener("admin")),
+	})
+
+	public := httptest.NewServer(ts.Handler())
+	defer public.Close()
+	adminHandler, err := ts.handlerFor("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := httptest.NewServer(adminHandler)
+	defer admin.Close()
+
+	for _, tc := range []struct {
+		server *httptest.Server
+		path   string
+		code   int
+	}{
+		{public, "/api", http.StatusOK},
+		{public, "/internal", http.StatusNotFound},
+		{admin, "/internal", http.StatusOK},
+		{admin, "/api", http.StatusNotFound},
+	} {
+		if resp, _ := testRequest(t, tc.server, http.MethodGet, tc.path, nil); resp.StatusCode != tc.code {
+			t.Errorf("%s on %s: got %d, want %d", tc.path, tc.server.URL, resp.StatusCode, tc.code)
+		}
+	}
+}
+
+func TestRedirectRoute(t *testing.T) {
+	ts := New()
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewRedirectRoute("/docs", "https://docs.example.com/", http.StatusFound),
+			router.NewRedirectRoute("/items/{id}", "/v2/items/{id}", http.StatusMovedPermanently),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	for path, want := range map[string]string{
+		"/docs":             "https://docs.example.com/",
+		"/items/7?expand=1": "/v2/items/7?expand=1",
+	} {
+		resp, err := client.Get(instance.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if location := resp.Header.Get("Location"); location != want {
+			t.Errorf("%s: redirected to %q, want %q", path, location, want)
+		}
+	}
+}
+
+type createItem struct {
+	List   string        `path:"list"`
+	Name   string        `json:"name"`
+	Count  int           `json:"count" query:"count"`
+	Since  time.Time     `json:"since" query:"since"`
+	Within time.Duration `json:"within" query:"within"`
+}
+
+func (c *createItem) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestAdapt(t *testing.T) {
+	ts := New()
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewPostRoute("/lists/{list}/items", true, false, Adapt(func(ctx context.Context, req createItem) (createItem, error) {
+				if req.List == "archived" {
+					return createItem{}, Errorf(http.StatusConflict, "list %s is archived", req.List)
+				}
+				return req, nil
+			})),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+
+	for _, tc := range []struct {
+		path, body string
+		code       int
+		want       string
+	}{
+		{"/lists/todo/items?count=3&since=2024-02-01&within=2h", `{"name":"milk"}`, http.StatusOK, `{"List":"todo","name":"milk","count":3,"since":"2024-02-01T00:00:00Z","within":7200000000000}`},
+		{"/lists/todo/items", `{"count":1}`, http.StatusUnprocessableEntity, `"detail":"name is required"`},
+		{"/lists/todo/items?count=x", `{"name":"milk"}`, http.StatusBadRequest, `"detail":"query parameter count: must be an integer"`},
+		{"/lists/todo/items?since=2024-13-01", `{"name":"milk"}`, http.StatusBadRequest, `"detail":"query parameter since: must be a time`},
+		{"/lists/archived/items", `{"name":"milk"}`, http.StatusConflict, `"detail":"list archived is archived"`},
+	} {
+		resp, body := testRequest(t, instance, http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if resp.StatusCode != tc.code || !strings.Contains(body, tc.want) {
+			t.Errorf("%s %s: got %d %s", tc.path, tc.body, resp.StatusCode, body)
+		}
+	}
+
+	resp, err := http.Post(instance.URL+"/lists/todo/items?count=2", "application/x-www-form-urlencoded", strings.NewReader("name=eggs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"name":"eggs","count":2`) {
+		t.Errorf("form: got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestAdaptPasswords(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email"`
+		Password string `json:"password" password:"Email"`
+	}
+	ts := New()
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewPostRoute("/signup", true, false, Adapt(func(ctx context.Context, req signup) (Empty, error) {
+				return Empty{}, nil
+			})),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+	resp, body := testRequest(t, instance, http.MethodPost, "/signup", strings.NewReader(`{"email":"a@example.com","password":"password"}`))
+	if resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(body, "is too common") {
+		t.Fatalf("got %d %s, want the common password refused", resp.StatusCode, body)
+	}
+	if resp, body := testRequest(t, instance, http.MethodPost, "/signup", strings.NewReader(`{"email":"a@example.com","password":"correct horse battery staple"}`)); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("got %d %s, want the strong password accepted", resp.StatusCode, body)
+	}
+}
+
+func TestInternalRoutes(t *testing.T) {
+	ts := New()
+
+	var observed []string
+	ts.LoadMiddleware([]middleware.Middleware{
+		middleware.NewMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r)
+				if helpers.Observed(r) {
+					observed = append(observed, r.URL.Path)
+				}
+			})
+		}, "observer", true, false),
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/healthz", true, false, ok),
+			router.NewGetRoute("/ready", true, false, ok, router.WithNoisy(1)),
+		}, true, router.WithRouterInternal()),
+		router.NewRouter([]router.Route{router.NewGetRoute("/api", true, false, ok)}, true),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+	for _, path := range []string{"/healthz", "/ready", "/api"} {
+		testRequest(t, instance, http.MethodGet, path, nil)
+	}
+	if strings.Join(observed, " ") != "/ready /api" {
+		t.Fatalf("observed %v", observed)
+	}
+}
+
+func TestRouteInfo(t *testing.T) {
+	var seen RouteInfo
+	audit := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = RouteInfoFromContext(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ts := New()
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/users/{id}", true, false, func(w http.ResponseWriter, r *http.Request) {}, router.WithName("user")),
+		}, true, router.WithRouterPrefix("/api/"), router.WithRouterMiddleware(audit)),
+	})
+
+	instance := httptest.NewServer(ts.Handler())
+	defer instance.Close()
+	if resp, _ := testRequest(t, instance, http.MethodGet, "/api/users/7", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d, want 200", resp.StatusCode)
+	}
+	want := RouteInfo{Prefix: "/api", Name: "user", Method: http.MethodGet, Pattern: "/api/users/{id}"}
+	if seen != want {
+		t.Fatalf("got %+v, want %+v", seen, want)
+	}
+}
+
+func TestServerHandler(t *testing.T) {
+	ts := New()
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/status", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}),
+		}, true),
+	})
+
+	outer := chi.NewMux()
+	outer.Mount("/app", ts)
+	instance := httptest.NewServer(outer)
+	defer instance.Close()
+	if resp, body := testRequest(t, instance, http.MethodGet, "/app/status", nil); resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Fatalf("got %d %q, want 200 ok", resp.StatusCode, body)
+	}
+
+	// A handler which cannot be built fails its requests instead of the process.
+	broken := New(WithConfig(&config.Config{RewriteRules: []config.RewriteRule{{From: "(", Regex: true}}}))
+	if _, err := broken.handler(); err == nil {
+		t.Fatal("built a handler with a rewrite rule which does not compile")
+	}
+	rec := httptest.NewRecorder()
+	broken.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", rec.Code)
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	ts := New(WithSignals())
+	ts.OnStart(hook("open pool", nil))
+	ts.OnShutdown(hook("close pool", nil))
+	ts.OnShutdown(hook("deregister", nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ts.StartContext(ctx); err != nil {
+		t.Fatalf("StartContext: %v", err)
+	}
+	if got := strings.Join(calls, ", "); got != "open pool, deregister, close pool" {
+		t.Fatalf("hooks ran as %q", got)
+	}
+
+	calls = nil
+	ts = New(WithSignals())
+	ts.OnStart(hook("open pool", errors.New("unreachable")))
+	ts.OnShutdown(hook("close pool", nil))
+	if err := ts.StartContext(context.Background()); err == nil {
+		t.Fatal("StartContext with a failing start hook: want error")
+	}
+	if got := strings.Join(calls, ", "); got != "open pool" {
+		t.Fatalf("hooks ran as %q", got)
+	}
+}
+
+func TestShutdownSignals(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7001", ShutdownSignals: []string{"SIGBOGUS"}}))
+	if err := ts.StartContext(context.Background()); err == nil || !strings.Contains(err.Error(), "SIGBOGUS") {
+		t.Fatalf("StartContext with an unsupported signal: got %v", err)
+	}
+
+	signals, err := parseSignals(nil)
+	if err != nil || len(signals) != 2 {
+		t.Fatalf("got %v %v, want the default signals", signals, err)
+	}
+	if signals, err := parseSignals([]string{}); err != nil || len(signals) != 0 {
+		t.Fatalf("got %v %v, want no signals", signals, err)
+	}
+}
+
+// startAndGet starts the server, returns the body of the URL once it is served,
+// and stops the server.
+func startAndGet(t *testing.T, ts *Server, url string) string {
+	t.Helper()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get(url); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+type connKey struct{}
+
+type countingListener struct {
+	net.Listener
+	accepted *atomic.Int64
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+func TestConfigureHTTPServer(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/conn", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Context().Value(connKey{}).(string)))
+			}),
+		}, true),
+	})
+	ts.ConfigureHTTPServer(func(srv *http.Server) {
+		srv.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, "tagged")
+		}
+	})
+	var accepted atomic.Int64
+	ts.ConfigureListener(func(ln net.Listener) net.Listener {
+		return countingListener{ln, &accepted}
+	})
+
+	body := startAndGet(t, ts, "http://127.0.0.1:7002/conn")
+	if body != "tagged" || accepted.Load() == 0 {
+		t.Fatalf("got %q after %d connections, want the tagged connection counted", body, accepted.Load())
+	}
+}
+
+type buildKey struct{}
+
+func TestBaseContext(t *testing.T) {
+	base := context.WithValue(context.Background(), buildKey{}, "v1.2.3")
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals(), WithBaseContext(base))
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/version", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Context().Value(buildKey{}).(string)))
+			}),
+		}, true),
+	})
+	if body := startAndGet(t, ts, "http://127.0.0.1:7002/version"); body != "v1.2.3" {
+		t.Fatalf("got %q, want the build of the base context", body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	ts := New(WithConfig(&config.Config{EnableMetrics: true, MetricsPath: "/metrics"}), WithRegistry(reg))
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/users/{id}", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("user"))
+			}),
+		}, true, router.WithRouterPrefix("/api")),
+	})
+
+	instance := httptest.NewServer(ts)
+	defer instance.Close()
+	testRequest(t, instance, http.MethodGet, "/api/users/1", nil)
+	testRequest(t, instance, http.MethodGet, "/api/users/2", nil)
+	testRequest(t, instance, http.MethodGet, "/metrics", nil)
+	resp, body := testRequest(t, instance, http.MethodGet, "/metrics", nil)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("got %q, want the text format", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, `ramchi_http_requests_total{prefix="/api",route="/api/users/{id}",method="GET",status="200"} 2`) {
+		t.Fatalf("requests not counted by route:\n%s", body)
+	}
+	if strings.Contains(body, `route="/metrics"`) {
+		t.Fatalf("scrapes counted:\n%s", body)
+	}
+}
+
+func TestTenantMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	ts := New(WithConfig(&config.Config{EnableMetrics: true, MetricsTenantHeader: "X-Api-Key", MetricsTenantLimit: 1, MetricsTenantRaw: true}), WithRegistry(reg))
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/users", true, false, func(w http.ResponseWriter, r *http.Request) {}),
+		}, true),
+	})
+
+	instance := httptest.NewServer(ts)
+	defer instance.Close()
+	for _, key := range []string{"acme", "acme", "globex", ""} {
+		req, _ := http.NewRequest(http.MethodGet, instance.URL+"/users", nil)
+		req.Header.Set("X-Api-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	text := reg.Text()
+	for _, want := range []string{
+		`ramchi_http_tenant_requests_total{tenant="acme",status="200"} 2`,
+		`ramchi_http_tenant_requests_total{tenant="other",status="200"} 1`,
+		`ramchi_http_tenant_requests_total{tenant="none",status="200"} 1`,
+		`ramchi_http_tenant_request_duration_seconds_count{tenant="acme",route="/users",method="GET"} 2`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("missing %s:\n%s", want, text)
+		}
+	}
+}
+
+func TestPprof(t *testing.T) {
+	ts := New(WithConfig(&config.Config{EnablePprof: true, PprofPublic: true, PprofPrefix: "/internal/pprof"}))
+	instance := httptest.NewServer(ts)
+	defer instance.Close()
+
+	resp, body := testRequest(t, instance, http.MethodGet, "/internal/pprof/", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "goroutine") {
+		t.Fatalf("got %d, want the index", resp.StatusCode)
+	}
+	resp, body = testRequest(t, instance, http.MethodGet, "/internal/pprof/goroutine?debug=1", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "goroutine profile") {
+		t.Fatalf("got %d, want the goroutine profile", resp.StatusCode)
+	}
+
+	admin := New(WithConfig(&config.Config{EnablePprof: true, AdminAddress: "127.0.0.1:7003", PprofListener: "admin"}))
+	instance = httptest.NewServer(admin)
+	defer instance.Close()
+	if resp, _ := testRequest(t, instance, http.MethodGet, "/debug/pprof/", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("got %d on the main listener, want 404", resp.StatusCode)
+	}
+}
+
+func TestAdminListener(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002", AdminAddress: "127.0.0.1:7003", EnableMetrics: true, EnablePprof: true, OTLPHeaders: map[string]string{"Authorization": "Bearer secret"}}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/ready", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ready"))
+			}),
+		}, true, router.WithRouterListener(AdminListener)),
+	})
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+	for i := 0; i < 50; i++ {
+		if resp, err := http.Get("http://127.0.0.1:7002/"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	get := func(url string) (int, string) {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	for _, path := range []string{"/metrics", "/debug/pprof/", "/healthz", "/readyz", "/config", "/ready"} {
+		if code, _ := get("http://127.0.0.1:7002" + path); code != http.StatusNotFound {
+			t.Fatalf("got %d for %s on the public listener, want 404", code, path)
+		}
+		if code, _ := get("http://127.0.0.1:7003" + path); code != http.StatusOK {
+			t.Fatalf("got %d for %s on the admin listener, want 200", code, path)
+		}
+	}
+	if _, body := get("http://127.0.0.1:7003/config"); !strings.Contains(body, `"adminAddress":"127.0.0.1:7003"`) || strings.Contains(body, "secret") {
+		t.Fatalf("got config %s", body)
+	}
+}
+
+func TestStop(t *testing.T) {
+	ts := New()
+
+	stopped := make(chan struct{})
+	go func() {
+		ts.Start()
+		close(stopped)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ts.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ts.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("StartE: %v", err)
+	}
+
+	// A server which failed to start may be started again.
+	invalid := New(WithConfig(&config.Config{Port: "invalid"}), WithSignals())
+	for i := 0; i < 2; i++ {
+		if err := invalid.StartE(); err == nil {
+			t.Fatal("started with an invalid port")
+		}
+	}
+	if err := invalid.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown after a failed start: %v", err)
+	}
+}
+
+func TestStartContext(t *testing.T) {
+	ts := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartContext(ctx)
+	}()
+	cancel()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("StartContext: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartContext did not return once its context was cancelled")
+	}
+
+	// The address is already bound, so the listen error is returned, not fatal.
+	ln, err := net.Listen("tcp", ":7000")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+	if err := New().StartE(); err == nil {
+		t.Fatal("StartE on a bound address: want error")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	config.Use(nil)
+	defer config.Use(nil)
+
+	srv := &http.Server{ReadHeaderTimeout: time.Second}
+	ts := New(
+		WithConfig(&config.Config{Address: "127.0.0.1", Port: "7001", Profile: config.ProfileDev}),
+		WithHTTPServer(srv),
+		WithLogger(zerolog.Nop()),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ts.StartContext(ctx); err != nil {
+		t.Fatalf("StartContext: %v", err)
+	}
+	if srv.Addr != "127.0.0.1:7001" || srv.Handler == nil {
+		t.Fatalf("got address %q and handler %v, want the configured address and the router", srv.Addr, srv.Handler)
+	}
+	if config.Current() != nil {
+		t.Fatal("WithConfig replaced the package configuration")
+	}
+}
+
+func TestIndependentServers(t *testing.T) {
+	routers := []router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/debug", true, false, func(w http.ResponseWriter, r *http.Request) {}),
+		}, true, router.WithRouterProfiles(config.ProfileDev)),
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/login", true, false, func(w http.ResponseWriter, r *http.Request) {
+				helpers.SetCookie(w, r, "session", "id", 0)
+			}),
+		}, true),
+	}
+
+	// The servers share the process, but not their configuration.
+	tools := New(WithConfig(&config.Config{Profile: config.ProfileDev, ErrorRequestID: true, CookieProfile: helpers.CookieLaxDev}), WithSignals())
+	tools.LoadRouter(routers)
+	api := New(WithConfig(&config.Config{Profile: config.ProfileProd, CookieDomain: "api.example"}), WithSignals())
+	api.LoadRouter(routers)
+
+	if tools.Registry() == api.Registry() || api.Registry() == metrics.Default {
+		t.Error("servers share a metrics registry")
+	}
+	for _, tc := range []struct {
+		server *Server
+		secure bool
+		domain string
+	}{{tools, false, ""}, {api, true, "api.example"}} {
+		rec := httptest.NewRecorder()
+		tc.server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Secure != tc.secure || cookies[0].Domain != tc.domain {
+			t.Errorf("got cookies %v, want secure %v for domain %q", cookies, tc.secure, tc.domain)
+		}
+	}
+
+	for _, tc := range []struct {
+		server *Server
+		want   int
+	}{{tools, http.StatusOK}, {api, http.StatusNotFound}} {
+		rec := httptest.NewRecorder()
+		tc.server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug", nil))
+		if rec.Code != tc.want {
+			t.Errorf("got %d, want %d", rec.Code, tc.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	tools.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("got %q, want the JSON error of the tools server", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestReload(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Port: "7000", EnableCORS: true, CORSAllowedOrigins: []string{"https://a.example"}, CORSAllowedMethods: []string{"GET"}}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/beta", true, true, func(w http.ResponseWriter, r *http.Request) {}),
+		}, true),
+	})
+	var hooked []string
+	ts.OnConfigReload(func(cfg *config.Config, restart []string) {
+		hooked = restart
+	})
+	instance := httptest.NewServer(ts)
+	defer instance.Close()
+
+	origin := func() string {
+		req, _ := http.NewRequest("GET", instance.URL+"/beta", nil)
+		req.Header.Set("Origin", "https://b.example")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("Access-Control-Allow-Origin")
+	}
+	if resp, _ := testRequest(t, instance, "GET", "/beta", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("got %d for an experimental route, want 404", resp.StatusCode)
+	}
+
+	restart, err := ts.Reload(&config.Config{Port: "7001", Experimental: true, EnableCORS: true, CORSAllowedOrigins: []string{"https://b.example"}, CORSAllowedMethods: []string{"GET"}})
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(restart) != 1 || restart[0] != "port" || len(hooked) != 1 {
+		t.Fatalf("got restart %v and hooked %v, want [port]", restart, hooked)
+	}
+	if resp, _ := testRequest(t, instance, "GET", "/beta", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d for an experimental route after reload, want 200", resp.StatusCode)
+	}
+	if got := origin(); got != "https://b.example" {
+		t.Fatalf("got allowed origin %q after reload, want https://b.example", got)
+	}
+
+	if _, err := ts.Reload(&config.Config{Port: "x"}); err == nil {
+		t.Fatal("Reload accepted an invalid config")
+	}
+	if resp, _ := testRequest(t, instance, "GET", "/beta", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d after an invalid reload, want the config kept", resp.StatusCode)
+	}
+}
+
+func TestReloadLogLevel(t *testing.T) {
+	var logs, other bytes.Buffer
+	ts := New(WithConfig(&config.Config{Port: "7000", LogLevel: "info"}), WithLogger(zerolog.New(&logs)), WithSignals())
+	bystander := zerolog.New(&other)
+
+	if _, err := ts.Reload(&config.Config{Port: "7000", LogLevel: "error"}); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	ts.log.Warn().Msg("dropped")
+	ts.log.Error().Msg("kept")
+	bystander.Info().Msg("untouched")
+	if strings.Contains(logs.String(), "dropped") || !strings.Contains(logs.String(), "kept") {
+		t.Fatalf("got logs %q, want only the error", logs.String())
+	}
+	if !strings.Contains(other.String(), "untouched") || zerolog.GlobalLevel() != zerolog.TraceLevel {
+		t.Fatalf("the reload changed the level of other loggers")
+	}
+}
+
+func TestH2C(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002", EnableH2C: true}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/proto", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}),
+		}, true),
+	})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, network, addr)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://127.0.0.1:7002/proto"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "HTTP/2.0" {
+		t.Fatalf("got %q, want HTTP/2.0 over cleartext", body)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		var buf bytes.Buffer
+		ts := New(WithConfig(&config.Config{EnableRequestLogging: enabled}), WithAccessLogger(zerolog.New(&buf)))
+		ts.LoadRouter([]router.Router{
+			router.NewRouter([]router.Route{
+				router.NewGetRoute("/users/{id}", true, false, func(w http.ResponseWriter, r *http.Request) {}),
+			}, true),
+		})
+		ts.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		if logged := strings.Contains(buf.String(), "/users/{id}"); logged != enabled {
+			t.Fatalf("enableRequestLogging %v: got log %q", enabled, buf.String())
+		}
+	}
+}
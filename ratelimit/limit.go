@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// Limit returns a handler wrapper allowing each client the limit of requests to a
+// route per window.
+func Limit(store Store, limit int64, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			count, reset, err := store.Increment(r.Context(), "limit|"+helpers.RoutePattern(r)+"|"+ip, 1, window)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remaining := limit - count
+			if remaining < 0 {
+				remaining = 0
+			}
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.FormatInt(limit, 10))
+			h.Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if count > limit {
+				h.Set("Retry-After", strconv.FormatInt(int64(time.Until(reset).Seconds())+1, 10))
+				helpers.Error(w, r, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
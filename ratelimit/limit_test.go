@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/config"
+)
+
+func TestLimit(t *testing.T) {
+	config.Use(&config.Config{})
+	defer config.Use(nil)
+
+	h := Limit(NewMemoryStore(), 2, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/search", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if rec := serve("192.0.2.1:1234"); rec.Code != want {
+			t.Fatalf("request %d: got %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	rec := serve("192.0.2.2:1234")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("another client: got %d with %s remaining, want 200 with 1", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
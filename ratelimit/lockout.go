@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoginThrottle protects authentication endpoints from brute force attempts by
+// counting failures per identifier, and locking the identifier out for
+// exponentially longer periods once the threshold is exceeded.
+type LoginThrottle struct {
+	Store Store
+	// Threshold is the number of failures tolerated before the first lockout.
+	Threshold int
+	// Lockout is the length of the first lockout, doubled by each further failure.
+	Lockout time.Duration
+	// MaxLockout caps the length of a lockout.
+	MaxLockout time.Duration
+	// Window is how long failures are remembered after the first one, as the store
+	// counts them in fixed windows: failures are forgotten together once it elapses,
+	// however recent the last one.
+	Window time.Duration
+	// CaptchaAfter is the number of failures after which a CAPTCHA should be
+	// demanded, zero disables escalation.
+	CaptchaAfter int
+	// OnEscalate is called when an identifier reaches CaptchaAfter failures.
+	OnEscalate func(ctx context.Context, key string, s LoginStatus)
+}
+
+// LoginStatus describes the throttling state of an identifier.
+type LoginStatus struct {
+	Failures int64
+	// Locked reports whether attempts must be refused until RetryAfter.
+	Locked     bool
+	RetryAfter time.Time
+	// CaptchaRequired reports whether attempts must be accompanied by a solved CAPTCHA.
+	CaptchaRequired bool
+}
+
+// NewLoginThrottle initializes a throttle locking identifiers out after five
+// failures, starting at one minute and capped at one day.
+func NewLoginThrottle(store Store) *LoginThrottle {
+	return &LoginThrottle{
+		Store:        store,
+		Threshold:    5,
+		Lockout:      time.Minute,
+		MaxLockout:   24 * time.Hour,
+		Window:       24 * time.Hour,
+		CaptchaAfter: 3,
+	}
+}
+
+// LoginKey returns the identifier of a login attempt, combining the client IP with the username.
+func LoginKey(r *http.Request, username string) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ip + "|" + strings.ToLower(username)
+}
+
+// Check returns the status of the identifier, to be consulted before verifying credentials.
+func (t *LoginThrottle) Check(ctx context.Context, key string) (LoginStatus, error) {
+	failures, _, err := t.Store.Get(ctx, "login:fail:"+key)
+	if err != nil {
+		return LoginStatus{}, fmt.Errorf("Check: failed reading failures: %w", err)
+	}
+	_, until, err := t.Store.Get(ctx, "login:lock:"+key)
+	if err != nil {
+		return LoginStatus{}, fmt.Errorf("Check: failed reading lockout: %w", err)
+	}
+	return t.status(failures, until), nil
+}
+
+// Failure records a failed attempt, locking the identifier out once the threshold is exceeded.
+func (t *LoginThrottle) Failure(ctx context.Context, key string) (LoginStatus, error) {
+	failures, _, err := t.Store.Increment(ctx, "login:fail:"+key, 1, t.Window)
+	if err != nil {
+		return LoginStatus{}, fmt.Errorf("Failure: failed counting failure: %w", err)
+	}
+
+	var until time.Time
+	if excess := failures - int64(t.Threshold); excess > 0 {
+		lockout := t.Lockout
+		for i := int64(1); i < excess && lockout < t.MaxLockout; i++ {
+			lockout *= 2
+		}
+		if t.MaxLockout > 0 && lockout > t.MaxLockout {
+			lockout = t.MaxLockout
+		}
+		if err := t.Store.Reset(ctx, "login:lock:"+key); err != nil {
+			return LoginStatus{}, fmt.Errorf("Failure: failed resetting lockout: %w", err)
+		}
+		if _, until, err = t.Store.Increment(ctx, "login:lock:"+key, 1, lockout); err != nil {
+			return LoginStatus{}, fmt.Errorf("Failure: failed locking out: %w", err)
+		}
+	}
+
+	s := t.status(failures, until)
+	if t.OnEscalate != nil && t.CaptchaAfter > 0 && failures == int64(t.CaptchaAfter) {
+		t.OnEscalate(ctx, key, s)
+	}
+	return s, nil
+}
+
+// Success clears the failures of the identifier after a successful attempt.
+func (t *LoginThrottle) Success(ctx context.Context, key string) error {
+	if err := t.Store.Reset(ctx, "login:fail:"+key); err != nil {
+		return fmt.Errorf("Success: failed resetting failures: %w", err)
+	}
+	if err := t.Store.Reset(ctx, "login:lock:"+key); err != nil {
+		return fmt.Errorf("Success: failed resetting lockout: %w", err)
+	}
+	return nil
+}
+
+func (t *LoginThrottle) status(failures int64, until time.Time) LoginStatus {
+	return LoginStatus{
+		Failures:        failures,
+		Locked:          time.Now().Before(until),
+		RetryAfter:      until,
+		CaptchaRequired: t.CaptchaAfter > 0 && failures >= int64(t.CaptchaAfter),
+	}
+}
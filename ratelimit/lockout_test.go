@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewLoginThrottle(NewMemoryStore())
+	throttle.Threshold = 2
+	throttle.CaptchaAfter = 1
+
+	escalated := 0
+	throttle.OnEscalate = func(ctx context.Context, key string, s LoginStatus) {
+		escalated++
+	}
+
+	var s LoginStatus
+	var err error
+	for i := 0; i < 2; i++ {
+		if s, err = throttle.Failure(ctx, "ip|user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Locked || !s.CaptchaRequired || escalated != 1 {
+		t.Fatalf("unexpected status below threshold: %+v, escalated %d", s, escalated)
+	}
+
+	if _, err = throttle.Failure(ctx, "ip|user"); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = throttle.Failure(ctx, "ip|user"); err != nil {
+		t.Fatal(err)
+	}
+	if lockout := time.Until(s.RetryAfter); !s.Locked || lockout <= time.Minute || lockout > 2*time.Minute {
+		t.Fatalf("expected doubled lockout, got %+v", s)
+	}
+
+	if err := throttle.Success(ctx, "ip|user"); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = throttle.Check(ctx, "ip|user"); err != nil || s.Locked || s.Failures != 0 {
+		t.Fatalf("expected cleared status, got %+v %v", s, err)
+	}
+}
+
+func TestLoginThrottleWindow(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewLoginThrottle(NewMemoryStore())
+	throttle.Window = 100 * time.Millisecond
+
+	throttle.Failure(ctx, "ip|user")
+	time.Sleep(60 * time.Millisecond)
+	if s, err := throttle.Failure(ctx, "ip|user"); err != nil || s.Failures != 2 {
+		t.Fatalf("got %+v %v, want both failures counted", s, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if s, err := throttle.Check(ctx, "ip|user"); err != nil || s.Failures != 0 {
+		t.Fatalf("got %+v %v, want the failures forgotten a window after the first", s, err)
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of increments between sweeps of expired counters.
+const sweepEvery = 1024
+
+type counter struct {
+	count int64
+	reset time.Time
+}
+
+type memoryStore struct {
+	mu       sync.Mutex
+	counters map[string]counter
+	writes   int
+}
+
+// NewMemoryStore initializes a Store local to the process.
+func NewMemoryStore() Store {
+	return &memoryStore{counters: make(map[string]counter)}
+}
+
+// Increment adds n to the counter for key.
+func (m *memoryStore) Increment(ctx context.Context, key string, n int64, window time.Duration) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.writes++
+	if m.writes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+
+	ct, ok := m.counters[key]
+	if !ok || !now.Before(ct.reset) {
+		ct = counter{reset: now.Add(window)}
+	}
+	ct.count += n
+	m.counters[key] = ct
+	return ct.count, ct.reset, nil
+}
+
+// Get returns the count for key.
+func (m *memoryStore) Get(ctx context.Context, key string) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ct, ok := m.counters[key]
+	if !ok || !time.Now().Before(ct.reset) {
+		return 0, time.Time{}, nil
+	}
+	return ct.count, ct.reset, nil
+}
+
+// Reset clears the counter for key.
+func (m *memoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *memoryStore) sweep(now time.Time) {
+	for key, ct := range m.counters {
+		if !now.Before(ct.reset) {
+			delete(m.counters, key)
+		}
+	}
+}
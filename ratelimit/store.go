@@ -0,0 +1,19 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store holds counters which reset at the end of a fixed window.
+type Store interface {
+	// Increment adds n to the counter for key, starting a new window of the given
+	// length when the key is unset or its window has elapsed. It returns the new
+	// count and the time at which the counter resets.
+	Increment(ctx context.Context, key string, n int64, window time.Duration) (int64, time.Time, error)
+	// Get returns the count for key and the time at which it resets,
+	// or zero values when the key is unset or its window has elapsed.
+	Get(ctx context.Context, key string) (int64, time.Time, error)
+	// Reset clears the counter for key.
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,189 @@
+package ramchi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	c "github.com/Etwodev/ramchi/config"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+)
+
+// reloadable are the settings, by their json name, which Reload applies at
+// runtime. Any other setting requires a restart.
+var reloadable = map[string]bool{
+	"logLevel":             true,
+	"experimental":         true,
+	"enableRequestLogging": true,
+	"enableCors":           true,
+	"corsAllowedOrigins":   true,
+	"corsAllowedMethods":   true,
+	"corsAllowedHeaders":   true,
+	"corsExposedHeaders":   true,
+	"corsAllowCredentials": true,
+	"corsMaxAge":           true,
+	"defaultHeaders":       true,
+	"serverHeader":         true,
+	"hideServerHeader":     true,
+	"rewriteRules":         true,
+	"maxResponseBytes":     true,
+}
+
+// swapHandler serves with a mux which is replaced when the configuration is
+// reloaded, so that requests in flight finish with the mux they began with.
+type swapHandler struct {
+	mux atomic.Pointer[chi.Mux]
+}
+
+func (h *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.Load().ServeHTTP(w, r)
+}
+
+type listenerHandler struct {
+	name    string
+	handler *swapHandler
+}
+
+// OnConfigReload registers a hook run after the configuration is reloaded, with
+// the configuration in use and the names of the changed settings which were not
+// applied, as they require a restart. The sections of the application are those
+// reloaded, for the hook to apply.
+func (s *Server) OnConfigReload(fn func(cfg *c.Config, restart []string)) {
+	s.onReload = append(s.onReload, fn)
+}
+
+// Reload applies the settings of cfg which are safe to change at runtime, such as
+// the log level, the CORS origins and the experimental flag, rebuilding the
+// handlers of every listener. It returns the names of the changed settings which
+// require a restart, and leaves the configuration untouched if cfg is invalid.
+func (s *Server) Reload(cfg *c.Config) ([]string, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("Reload: %w", err)
+	}
+
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+
+	current := s.live.Load()
+	next := *cfg
+	var restart []string
+	cur, upd, dst := reflect.ValueOf(current).Elem(), reflect.ValueOf(cfg).Elem(), reflect.ValueOf(&next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		f := cur.Type().Field(i)
+		if !f.IsExported() || sameSetting(cur.Field(i), upd.Field(i)) {
+			continue
+		}
+		if name := settingName(f); !reloadable[name] {
+			dst.Field(i).Set(cur.Field(i))
+			restart = append(restart, name)
+		}
+	}
+
+	muxes := make([]*chi.Mux, len(s.handlers))
+	for i, h := range s.handlers {
+		m, err := s.buildMux(h.name, &next)
+		if err != nil {
+			return nil, fmt.Errorf("Reload: %w", err)
+		}
+		muxes[i] = m
+	}
+	if err := s.level.set(next.LogLevel); err != nil {
+		return nil, fmt.Errorf("Reload: %w", err)
+	}
+	s.live.Store(&next)
+	for i, h := range s.handlers {
+		h.handler.mux.Store(muxes[i])
+	}
+
+	for _, fn := range s.onReload {
+		fn(&next, restart)
+	}
+	return restart, nil
+}
+
+// sameSetting returns whether two values of a setting are equal, an empty slice or
+// map being equal to a nil one.
+func sameSetting(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Slice, reflect.Map:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func settingName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// levelHook discards the events of a logger below a level which may be changed at
+// runtime, so that reloading the log level affects only the loggers of the server.
+type levelHook struct {
+	level atomic.Int32
+}
+
+func newLevelHook() *levelHook {
+	h := &levelHook{}
+	h.level.Store(int32(zerolog.TraceLevel))
+	return h
+}
+
+func (h *levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	min := zerolog.Level(h.level.Load())
+	if min == zerolog.Disabled || level != zerolog.NoLevel && level < min {
+		e.Discard()
+	}
+}
+
+// set sets the minimum level logged, leaving it as is when level is empty.
+func (h *levelHook) set(level string) error {
+	if level == "" {
+		return nil
+	}
+	l, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("set: failed parsing level: %w", err)
+	}
+	h.level.Store(int32(l))
+	return nil
+}
+
+// configWatcher returns the worker reloading the configuration file the server
+// was configured from whenever it changes.
+func (s *Server) configWatcher() Worker {
+	return &configWatcher{s: s}
+}
+
+type configWatcher struct {
+	s *Server
+}
+
+func (w *configWatcher) Run(ctx context.Context) {
+	s := w.s
+	err := c.Watch(ctx, s.path, func(cfg *c.Config, err error) {
+		if err == nil {
+			var restart []string
+			if restart, err = s.Reload(cfg); err == nil {
+				s.log.Info().Str("Path", s.path).Msg("Config reloaded")
+				if len(restart) > 0 {
+					s.log.Warn().Str("Path", s.path).Strs("Restart", restart).Msg("Changed settings require a restart")
+				}
+				return
+			}
+		}
+		s.log.Error().Str("Function", "configWatcher").Str("Path", s.path).Err(err).Msg("Keeping config")
+	})
+	if err != nil {
+		s.log.Error().Str("Function", "configWatcher").Str("Path", s.path).Err(err).Msg("Not watching config")
+	}
+}
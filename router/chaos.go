@@ -0,0 +1,13 @@
+package router
+
+import "github.com/Etwodev/ramchi/middleware"
+
+// WithChaos injects faults into a percentage of the route's requests, outside the prod profile.
+func WithChaos(policy middleware.ChaosPolicy) RouteWrapper {
+	return WithMiddleware(middleware.Chaos(policy))
+}
+
+// WithRouterChaos injects faults into a percentage of the requests of every route of the router.
+func WithRouterChaos(policy middleware.ChaosPolicy) RouterWrapper {
+	return WithRouterMiddleware(middleware.Chaos(policy))
+}
@@ -0,0 +1,22 @@
+package router
+
+import "github.com/Etwodev/ramchi/middleware"
+
+type corsKey struct{}
+
+// WithCORS overrides the server CORS policy for the route.
+func WithCORS(policy middleware.CORSPolicy) RouteWrapper {
+	return WithValue(corsKey{}, policy)
+}
+
+// WithRouterCORS overrides the server CORS policy for every route of the router.
+// A policy attached to an individual route takes precedence.
+func WithRouterCORS(policy middleware.CORSPolicy) RouterWrapper {
+	return WithRouterValue(corsKey{}, policy)
+}
+
+// CORS returns the policy overriding the server CORS policy for the route, if any.
+func CORS(rt Router, r Route) (middleware.CORSPolicy, bool) {
+	policy, ok := Lookup(rt, r, corsKey{}).(middleware.CORSPolicy)
+	return policy, ok
+}
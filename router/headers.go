@@ -0,0 +1,13 @@
+package router
+
+import "github.com/Etwodev/ramchi/middleware"
+
+// WithHeaders applies the header policy to the route's responses.
+func WithHeaders(policy middleware.HeaderPolicy) RouteWrapper {
+	return WithMiddleware(middleware.Headers(policy))
+}
+
+// WithRouterHeaders applies the header policy to the responses of every route of the router.
+func WithRouterHeaders(policy middleware.HeaderPolicy) RouterWrapper {
+	return WithRouterMiddleware(middleware.Headers(policy))
+}
@@ -0,0 +1,17 @@
+package router
+
+type listenerKey struct{}
+
+// WithRouterListener serves the routes of the router only on the named listener,
+// configured in config.Listeners, such as an admin port bound to a private
+// interface. Its routes are not mounted on the main listener.
+func WithRouterListener(name string) RouterWrapper {
+	return WithRouterValue(listenerKey{}, name)
+}
+
+// Listener returns the name of the listener serving the router, or an empty string
+// for the main listener.
+func Listener(rt Router) string {
+	name, _ := RouterValue(rt, listenerKey{}).(string)
+	return name
+}
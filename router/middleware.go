@@ -0,0 +1,52 @@
+package router
+
+import "net/http"
+
+type middlewareRouter struct {
+	Router
+	method func(http.Handler) http.Handler
+}
+
+type middlewareRoute struct {
+	Route
+	method func(http.Handler) http.Handler
+}
+
+// Unwrap returns the router that was wrapped.
+func (m middlewareRouter) Unwrap() Router {
+	return m.Router
+}
+
+// Routes returns the routes of the router, with the middleware applied to each.
+func (m middlewareRouter) Routes() []Route {
+	routes := m.Router.Routes()
+	wrapped := make([]Route, len(routes))
+	for i, r := range routes {
+		wrapped[i] = middlewareRoute{r, m.method}
+	}
+	return wrapped
+}
+
+// Unwrap returns the route that was wrapped.
+func (m middlewareRoute) Unwrap() Route {
+	return m.Route
+}
+
+// Handler returns the handler of the route, with the middleware applied.
+func (m middlewareRoute) Handler() http.HandlerFunc {
+	return m.method(m.Route.Handler()).ServeHTTP
+}
+
+// WithMiddleware applies a middleware to the route only.
+func WithMiddleware(method func(http.Handler) http.Handler) RouteWrapper {
+	return func(r Route) Route {
+		return middlewareRoute{r, method}
+	}
+}
+
+// WithRouterMiddleware applies a middleware to every route of the router.
+func WithRouterMiddleware(method func(http.Handler) http.Handler) RouterWrapper {
+	return func(r Router) Router {
+		return middlewareRouter{r, method}
+	}
+}
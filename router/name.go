@@ -0,0 +1,14 @@
+package router
+
+type nameKey struct{}
+
+// WithName names the route, so that its URL can be built from the name.
+func WithName(name string) RouteWrapper {
+	return WithValue(nameKey{}, name)
+}
+
+// Name returns the name of the route, or an empty string.
+func Name(r Route) string {
+	name, _ := Value(r, nameKey{}).(string)
+	return name
+}
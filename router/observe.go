@@ -0,0 +1,39 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// WithNoisy marks the route as noisy, so that access logging, metrics and tracing
+// record only the fraction of its requests, such as 0.01 for a health check.
+func WithNoisy(rate float64) RouteWrapper {
+	return WithMiddleware(sample(rate))
+}
+
+// WithRouterNoisy marks every route of the router as noisy. A rate set on a route
+// takes precedence.
+func WithRouterNoisy(rate float64) RouterWrapper {
+	return WithRouterMiddleware(sample(rate))
+}
+
+// WithInternal marks the route as internal, so that access logging, metrics and
+// tracing skip its requests, such as for metrics scrapes.
+func WithInternal() RouteWrapper {
+	return WithNoisy(0)
+}
+
+// WithRouterInternal marks every route of the router as internal.
+func WithRouterInternal() RouterWrapper {
+	return WithRouterNoisy(0)
+}
+
+func sample(rate float64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			helpers.SetSampleRate(r, rate)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
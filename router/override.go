@@ -0,0 +1,21 @@
+package router
+
+type methodOverrideKey struct{}
+
+// WithMethodOverride allows the route to be reached through X-HTTP-Method-Override
+// or a _method form field on a POST request.
+func WithMethodOverride() RouteWrapper {
+	return WithValue(methodOverrideKey{}, true)
+}
+
+// WithRouterMethodOverride allows every route of the router to be reached through
+// X-HTTP-Method-Override or a _method form field on a POST request.
+func WithRouterMethodOverride() RouterWrapper {
+	return WithRouterValue(methodOverrideKey{}, true)
+}
+
+// MethodOverride returns whether the route may be reached through a method override.
+func MethodOverride(rt Router, r Route) bool {
+	allowed, _ := Lookup(rt, r, methodOverrideKey{}).(bool)
+	return allowed
+}
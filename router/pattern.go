@@ -0,0 +1,27 @@
+package router
+
+import "strings"
+
+// Regular expressions constraining path parameters with Param.
+const (
+	PatternInt  = `[0-9]+`
+	PatternUUID = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+	PatternSlug = `[a-z0-9]+(?:-[a-z0-9]+)*`
+	PatternDate = `[0-9]{4}-[0-9]{2}-[0-9]{2}`
+)
+
+// Param returns a path segment matching the parameter only where the regular
+// expression matches, such as Param("id", PatternInt) for "{id:[0-9]+}". Requests
+// which do not match fall through to other routes, or 404 Not Found.
+func Param(name string, pattern string) string {
+	if pattern == "" {
+		return "{" + name + "}"
+	}
+	return "{" + name + ":" + pattern + "}"
+}
+
+// CatchAll returns a path matching the prefix and anything beneath it, such as
+// CatchAll("/files") for "/files/*". The remainder is read with helpers.Wildcard.
+func CatchAll(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/*"
+}
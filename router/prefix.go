@@ -0,0 +1,62 @@
+package router
+
+import "strings"
+
+type prefixKey struct{}
+
+type prefixRouter struct {
+	Router
+	prefix string
+}
+
+type prefixRoute struct {
+	Route
+	prefix string
+}
+
+// Unwrap returns the router that was wrapped.
+func (p prefixRouter) Unwrap() Router {
+	return p.Router
+}
+
+// Routes returns the routes of the router, with the prefix applied to each.
+func (p prefixRouter) Routes() []Route {
+	routes := p.Router.Routes()
+	prefixed := make([]Route, len(routes))
+	for i, r := range routes {
+		prefixed[i] = prefixRoute{r, p.prefix}
+	}
+	return prefixed
+}
+
+// Unwrap returns the route that was wrapped.
+func (p prefixRoute) Unwrap() Route {
+	return p.Route
+}
+
+// Path returns the path of the route beneath the prefix. The root path of the
+// router responds at the prefix itself.
+func (p prefixRoute) Path() string {
+	if p.Route.Path() == "/" {
+		return p.prefix
+	}
+	return p.prefix + p.Route.Path()
+}
+
+// WithRouterPrefix mounts every route of the router beneath the prefix, such as
+// /api/v1, so that the router may be written with paths relative to it.
+func WithRouterPrefix(prefix string) RouterWrapper {
+	prefix = strings.Trim(prefix, "/")
+	return func(r Router) Router {
+		if prefix == "" {
+			return r
+		}
+		return prefixRouter{WithRouterValue(prefixKey{}, "/"+prefix)(r), "/" + prefix}
+	}
+}
+
+// Prefix returns the prefix the router is mounted beneath, or an empty string.
+func Prefix(r Router) string {
+	prefix, _ := RouterValue(r, prefixKey{}).(string)
+	return prefix
+}
@@ -0,0 +1,39 @@
+package router
+
+type profilesKey struct{}
+
+// WithProfiles mounts the route only under the given config profiles, such as
+// config.ProfileDev. Routes without profiles are mounted under every profile.
+func WithProfiles(profiles ...string) RouteWrapper {
+	return WithValue(profilesKey{}, profiles)
+}
+
+// WithRouterProfiles mounts the routes of the router only under the given config
+// profiles. A profile set on a route takes precedence.
+func WithRouterProfiles(profiles ...string) RouterWrapper {
+	return WithRouterValue(profilesKey{}, profiles)
+}
+
+// Profiles returns the profiles the route is mounted under, falling back to those
+// of the router, or nil for every profile.
+func Profiles(rt Router, r Route) []string {
+	profiles, _ := Lookup(rt, r, profilesKey{}).([]string)
+	return profiles
+}
+
+// InProfile returns whether the route is mounted under the profile.
+func InProfile(rt Router, r Route, profile string) bool {
+	return inProfile(Profiles(rt, r), profile)
+}
+
+func inProfile(profiles []string, profile string) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NewRedirectRoute initializes a GET route redirecting to the target with the
+// status, such as http.StatusMovedPermanently. Parameters of the path, such as
+// {id}, are expanded in the target, and the query of the request is preserved
+// when the target has none.
+func NewRedirectRoute(path string, target string, status int, opts ...RouteWrapper) Route {
+	return NewRoute(http.MethodGet, path, true, false, RedirectHandler(target, status), opts...)
+}
+
+// RedirectHandler redirects to the target with the status, as NewRedirectRoute does,
+// for redirects needing another method or a route of their own.
+func RedirectHandler(target string, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		location := target
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			for i, key := range rctx.URLParams.Keys {
+				location = strings.ReplaceAll(location, "{"+key+"}", rctx.URLParams.Values[i])
+			}
+		}
+		if r.URL.RawQuery != "" && !strings.Contains(location, "?") {
+			location += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, location, status)
+	}
+}
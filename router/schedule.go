@@ -0,0 +1,14 @@
+package router
+
+import "github.com/Etwodev/ramchi/middleware"
+
+// WithSchedule makes the route available only within the windows, responding
+// 403 Forbidden outside them, or 410 Gone once every window has ended.
+func WithSchedule(windows ...middleware.Window) RouteWrapper {
+	return WithMiddleware(middleware.Schedule(windows...))
+}
+
+// WithRouterSchedule makes every route of the router available only within the windows.
+func WithRouterSchedule(windows ...middleware.Window) RouterWrapper {
+	return WithRouterMiddleware(middleware.Schedule(windows...))
+}
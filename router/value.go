@@ -0,0 +1,76 @@
+package router
+
+type valueRouter struct {
+	Router
+	key, value interface{}
+}
+
+type valueRoute struct {
+	Route
+	key, value interface{}
+}
+
+// Unwrap returns the router that was wrapped.
+func (v valueRouter) Unwrap() Router {
+	return v.Router
+}
+
+// Unwrap returns the route that was wrapped.
+func (v valueRoute) Unwrap() Route {
+	return v.Route
+}
+
+// WithRouterValue attaches a value to a router, in the manner of context.WithValue.
+func WithRouterValue(key, value interface{}) RouterWrapper {
+	return func(r Router) Router {
+		return valueRouter{r, key, value}
+	}
+}
+
+// WithValue attaches a value to a route, in the manner of context.WithValue.
+func WithValue(key, value interface{}) RouteWrapper {
+	return func(r Route) Route {
+		return valueRoute{r, key, value}
+	}
+}
+
+// RouterValue returns the value attached to the router for key, or nil.
+// Wrappers are traversed through their Unwrap() Router method.
+func RouterValue(r Router, key interface{}) interface{} {
+	for r != nil {
+		if v, ok := r.(valueRouter); ok && v.key == key {
+			return v.value
+		}
+		u, ok := r.(interface{ Unwrap() Router })
+		if !ok {
+			return nil
+		}
+		r = u.Unwrap()
+	}
+	return nil
+}
+
+// Value returns the value attached to the route for key, or nil.
+// Wrappers are traversed through their Unwrap() Route method.
+func Value(r Route, key interface{}) interface{} {
+	for r != nil {
+		if v, ok := r.(valueRoute); ok && v.key == key {
+			return v.value
+		}
+		u, ok := r.(interface{ Unwrap() Route })
+		if !ok {
+			return nil
+		}
+		r = u.Unwrap()
+	}
+	return nil
+}
+
+// Lookup returns the value for key attached to the route, falling back
+// to the value attached to the router that owns it.
+func Lookup(rt Router, r Route, key interface{}) interface{} {
+	if v := Value(r, key); v != nil {
+		return v
+	}
+	return RouterValue(rt, key)
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"time"
+
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/ratelimit"
+)
+
+// WithTimeout cancels the route's requests once the duration has elapsed, as
+// middleware.Timeout.
+func WithTimeout(d time.Duration) RouteWrapper {
+	return WithMiddleware(middleware.Timeout(d))
+}
+
+// WithRouterTimeout cancels the requests of every route of the router once the
+// duration has elapsed, as middleware.Timeout.
+func WithRouterTimeout(d time.Duration) RouterWrapper {
+	return WithRouterMiddleware(middleware.Timeout(d))
+}
+
+// WithScopes requires the route's bearer token to have been granted every scope, as
+// middleware.RequireScopes. The token must have been introspected by an outer
+// middleware, such as middleware.Introspect.
+func WithScopes(scopes ...string) RouteWrapper {
+	return WithMiddleware(middleware.RequireScopes(scopes...))
+}
+
+// WithRouterScopes requires the bearer token of requests to every route of the
+// router to have been granted every scope, as middleware.RequireScopes.
+func WithRouterScopes(scopes ...string) RouterWrapper {
+	return WithRouterMiddleware(middleware.RequireScopes(scopes...))
+}
+
+// WithFields allows requests to the route to select only the fields, for sparse
+// fieldsets written by helpers.JSONFields, as middleware.Fields.
+func WithFields(allowed ...string) RouteWrapper {
+	return WithMiddleware(middleware.Fields(allowed...))
+}
+
+// WithRouterFields allows requests to every route of the router to select only the
+// fields, as middleware.Fields.
+func WithRouterFields(allowed ...string) RouterWrapper {
+	return WithRouterMiddleware(middleware.Fields(allowed...))
+}
+
+// WithRateLimit allows each client the limit of requests to the route per window,
+// as ratelimit.Limit.
+func WithRateLimit(store ratelimit.Store, limit int64, window time.Duration) RouteWrapper {
+	return WithMiddleware(ratelimit.Limit(store, limit, window))
+}
+
+// WithRouterRateLimit allows each client the limit of requests to each route of the
+// router per window, as ratelimit.Limit.
+func WithRouterRateLimit(store ratelimit.Store, limit int64, window time.Duration) RouterWrapper {
+	return WithRouterMiddleware(ratelimit.Limit(store, limit, window))
+}
+
+// WithCache caches the route's successful responses for the ttl, as middleware.Cache.
+func WithCache(ttl time.Duration) RouteWrapper {
+	return WithMiddleware(middleware.Cache(ttl))
+}
+
+// WithRouterCache caches the successful responses of every route of the router for
+// the ttl, as middleware.Cache.
+func WithRouterCache(ttl time.Duration) RouterWrapper {
+	return WithRouterMiddleware(middleware.Cache(ttl))
+}
+
+// WithMaxResponseSize guards the route against responses over the limit of bytes, as
+// middleware.MaxResponseSize.
+func WithMaxResponseSize(limit int64) RouteWrapper {
+	return WithMiddleware(middleware.MaxResponseSize(limit))
+}
+
+// WithRouterMaxResponseSize guards every route of the router against responses over
+// the limit of bytes, as middleware.MaxResponseSize.
+func WithRouterMaxResponseSize(limit int64) RouterWrapper {
+	return WithRouterMiddleware(middleware.MaxResponseSize(limit))
+}
+
+type metadataKey struct {
+	key string
+}
+
+// WithMetadata attaches a string value to the route, such as an owning team or
+// audit category, to be read by Metadata.
+func WithMetadata(key, value string) RouteWrapper {
+	return WithValue(metadataKey{key}, value)
+}
+
+// WithRouterMetadata attaches a string value to the router, which its routes inherit
+// unless they attach their own.
+func WithRouterMetadata(key, value string) RouterWrapper {
+	return WithRouterValue(metadataKey{key}, value)
+}
+
+// Metadata returns the value attached to the route for key, falling back to the
+// value attached to the router that owns it, or an empty string.
+func Metadata(rt Router, r Route, key string) string {
+	value, _ := Lookup(rt, r, metadataKey{key}).(string)
+	return value
+}
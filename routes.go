@@ -0,0 +1,120 @@
+package ramchi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+// routeTable collects the enabled routes of a server before they are mounted,
+// so that path-level behaviour can be derived from the complete set.
+type routeTable struct {
+	routes    []router.Route
+	infos     []RouteInfo
+	paths     []string
+	methods   map[string][]string
+	cors      map[string]middleware.CORSPolicy
+	overrides map[string]bool
+	names     map[string]string
+	log       zerolog.Logger
+}
+
+func newRouteTable(logger zerolog.Logger) *routeTable {
+	return &routeTable{
+		log:       logger,
+		methods:   make(map[string][]string),
+		cors:      make(map[string]middleware.CORSPolicy),
+		overrides: make(map[string]bool),
+		names:     make(map[string]string),
+	}
+}
+
+// add records a route, along with any CORS policy overriding the server policy for
+// its path and method, whether it may be reached through a method override, and
+// its name.
+func (t *routeTable) add(rt router.Router, r router.Route) {
+	if _, ok := t.methods[r.Path()]; !ok {
+		t.paths = append(t.paths, r.Path())
+	}
+	t.routes = append(t.routes, r)
+	t.infos = append(t.infos, RouteInfo{Prefix: router.Prefix(rt), Name: router.Name(r), Method: r.Method(), Pattern: r.Path()})
+	t.methods[r.Path()] = append(t.methods[r.Path()], r.Method())
+
+	if policy, ok := router.CORS(rt, r); ok {
+		t.cors[r.Path()+" "+r.Method()] = policy
+	}
+	if router.MethodOverride(rt, r) {
+		t.overrides[r.Path()+" "+r.Method()] = true
+	}
+	if name := router.Name(r); name != "" {
+		if pattern, ok := t.names[name]; ok && pattern != r.Path() {
+			t.log.Warn().Str("Name", name).Str("Path", r.Path()).Str("Existing", pattern).Msg("Duplicate route name")
+		} else {
+			t.names[name] = r.Path()
+		}
+	}
+}
+
+// handles returns whether a route for the method is registered at the path.
+func (t *routeTable) handles(path string, method string) bool {
+	for _, m := range t.methods[path] {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// allow returns the value of the Allow header for the path.
+func (t *routeTable) allow(path string) string {
+	return strings.Join(append(append([]string{}, t.methods[path]...), http.MethodOptions), ", ")
+}
+
+// permitted returns the methods registered at the path which are listed in allowed,
+// or all of them when allowed is empty.
+func (t *routeTable) permitted(path string, allowed []string) []string {
+	if len(allowed) == 0 {
+		return t.methods[path]
+	}
+	var methods []string
+	for _, m := range t.methods[path] {
+		for _, a := range allowed {
+			if strings.EqualFold(a, m) {
+				methods = append(methods, m)
+				break
+			}
+		}
+	}
+	return methods
+}
+
+// allowHandler answers OPTIONS requests with the methods registered at the path.
+func (t *routeTable) allowHandler(path string) http.HandlerFunc {
+	allow := t.allow(path)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RouteInfo describes the route which matched a request, as helpers.RouteInfo.
+type RouteInfo = helpers.RouteInfo
+
+// withRouteInfo records the matched route before its router and route middlewares.
+func withRouteInfo(info RouteInfo, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, helpers.SetRouteInfo(r, info))
+	}
+}
+
+// RouteInfoFromContext returns the route which matched the request, and whether one
+// has, as helpers.RouteInfoFromContext.
+func RouteInfoFromContext(ctx context.Context) (RouteInfo, bool) {
+	return helpers.RouteInfoFromContext(ctx)
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Etwodev/ramchi/lock"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Elector elects a single leader among replicas.
+type Elector interface {
+	// Campaign blocks until leadership is acquired, or the context is done.
+	// Leadership is held until the returned context is done: it is resigned once
+	// the given context is done, or it was lost, such as to a partition.
+	Campaign(ctx context.Context) (context.Context, error)
+}
+
+// lease is held by one replica until it expires unrenewed.
+type lease interface {
+	acquire(ctx context.Context) (bool, error)
+	// renew returns false without error when the lease is held by another.
+	renew(ctx context.Context) (bool, error)
+	release(ctx context.Context) error
+}
+
+// campaign acquires the lease and renews it every third of the ttl. Should renewal
+// fail, leadership is kept until a third of the ttl before the lease would expire,
+// to step down before another replica may take over.
+func campaign(ctx context.Context, l lease, ttl time.Duration) (context.Context, error) {
+	for {
+		ok, err := l.acquire(ctx)
+		if err != nil {
+			log.Warn().Str("Function", "Campaign").Err(err).Msg("Failed acquiring lease")
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-time.After(ttl / 3):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("Campaign: %w", ctx.Err())
+		}
+	}
+
+	lead, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		expires := time.Now().Add(ttl)
+		for {
+			select {
+			case <-ctx.Done():
+				if err := l.release(context.WithoutCancel(ctx)); err != nil {
+					log.Warn().Str("Function", "Campaign").Err(err).Msg("Failed releasing lease")
+				}
+				return
+			case <-ticker.C:
+				renewed := time.Now()
+				ok, err := l.renew(ctx)
+				if ok {
+					expires = renewed.Add(ttl)
+					continue
+				}
+				if err == nil {
+					return
+				}
+				log.Warn().Str("Function", "Campaign").Err(err).Msg("Failed renewing lease")
+				if time.Until(expires) < ttl/3 {
+					return
+				}
+			}
+		}
+	}()
+	return lead, nil
+}
+
+type lockElector struct {
+	locker lock.Locker
+	key    string
+	ttl    time.Duration
+	held   lock.Lock
+}
+
+// NewLockElector initializes an Elector whose leader holds the lock for the key.
+func NewLockElector(locker lock.Locker, key string, ttl time.Duration) Elector {
+	return &lockElector{locker: locker, key: key, ttl: ttl}
+}
+
+// NewRedisElector initializes an Elector over a Redlock held across the instances.
+func NewRedisElector(key string, ttl time.Duration, clients ...redis.Cmdable) Elector {
+	return NewLockElector(lock.NewRedis(clients...), key, ttl)
+}
+
+func (e *lockElector) Campaign(ctx context.Context) (context.Context, error) {
+	return campaign(ctx, e, e.ttl)
+}
+
+func (e *lockElector) acquire(ctx context.Context) (bool, error) {
+	l, err := e.locker.TryAcquire(ctx, e.key, e.ttl)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("acquire: %w", err)
+	}
+	e.held = l
+	return true, nil
+}
+
+func (e *lockElector) renew(ctx context.Context) (bool, error) {
+	err := e.held.Refresh(ctx, e.ttl)
+	if errors.Is(err, lock.ErrLost) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (e *lockElector) release(ctx context.Context) error {
+	if err := e.held.Release(ctx); err != nil && !errors.Is(err, lock.ErrLost) {
+		return fmt.Errorf("release: %w", err)
+	}
+	return nil
+}
+
+// identity names this replica as a lease holder: its hostname, which is the pod
+// name under Kubernetes, with a random suffix distinguishing restarts.
+func identity() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "ramchi"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
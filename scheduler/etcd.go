@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type etcdElector struct {
+	endpoint string
+	key      string
+	identity string
+	ttl      time.Duration
+	client   *http.Client
+	id       string
+}
+
+// NewEtcdElector initializes an Elector whose leader holds the key in etcd, attached
+// to a lease of the ttl, through the gRPC gateway of the endpoint, such as
+// "http://127.0.0.1:2379". The ttl is rounded up to whole seconds.
+func NewEtcdElector(endpoint string, key string, ttl time.Duration) Elector {
+	return &etcdElector{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		key:      key,
+		identity: identity(),
+		ttl:      ttl,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (e *etcdElector) Campaign(ctx context.Context) (context.Context, error) {
+	return campaign(ctx, e, e.ttl)
+}
+
+func (e *etcdElector) acquire(ctx context.Context) (bool, error) {
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	seconds := int64((e.ttl + time.Second - 1) / time.Second)
+	if err := e.call(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": seconds}, &grant); err != nil {
+		return false, fmt.Errorf("acquire: %w", err)
+	}
+
+	// Put the key only when it does not exist, as it is deleted once its holder's lease expires.
+	txn := map[string]interface{}{
+		"compare": []map[string]interface{}{{"key": []byte(e.key), "result": "EQUAL", "target": "CREATE", "create_revision": "0"}},
+		"success": []map[string]interface{}{{"request_put": map[string]interface{}{"key": []byte(e.key), "value": []byte(e.identity), "lease": grant.ID}}},
+	}
+	var result struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := e.call(ctx, "/v3/kv/txn", txn, &result); err != nil {
+		e.revoke(ctx, grant.ID)
+		return false, fmt.Errorf("acquire: %w", err)
+	}
+	if !result.Succeeded {
+		e.revoke(ctx, grant.ID)
+		return false, nil
+	}
+	e.id = grant.ID
+	return true, nil
+}
+
+func (e *etcdElector) renew(ctx context.Context) (bool, error) {
+	var keepalive struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := e.call(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": e.id}, &keepalive); err != nil {
+		return false, fmt.Errorf("renew: %w", err)
+	}
+	// An expired lease is kept alive with no TTL.
+	ttl := keepalive.Result.TTL
+	return ttl != "" && ttl != "0", nil
+}
+
+func (e *etcdElector) release(ctx context.Context) error {
+	if err := e.revoke(ctx, e.id); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+	return nil
+}
+
+func (e *etcdElector) revoke(ctx context.Context, id string) error {
+	return e.call(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": id}, nil)
+}
+
+func (e *etcdElector) call(ctx context.Context, path string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("call: failed marshalling request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("call: failed creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("call: failed calling %s: %w", path, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("call: %s returned %s", path, res.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	// Streaming calls, such as keepalive, answer with a sequence of objects.
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("call: failed decoding response: %w", err)
+	}
+	return nil
+}
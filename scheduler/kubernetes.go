@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServiceAccountDir holds the credentials Kubernetes mounts into pods.
+var ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTime is the format of times in a Lease.
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+var errConflict = errors.New("lease was modified concurrently")
+
+type kubernetesLease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       string `json:"holderIdentity,omitempty"`
+		LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+		AcquireTime          string `json:"acquireTime,omitempty"`
+		RenewTime            string `json:"renewTime,omitempty"`
+		LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+	} `json:"spec"`
+}
+
+type kubernetesElector struct {
+	url      string
+	name     string
+	ns       string
+	identity string
+	ttl      time.Duration
+	client   *http.Client
+
+	held *kubernetesLease
+	// observed is when the lease was last seen to change. Expiry is judged from it,
+	// rather than from the renew time written by the holder, whose clock may differ.
+	observed        time.Time
+	observedVersion string
+}
+
+// NewKubernetesElector initializes an Elector whose leader holds the named
+// coordination.k8s.io Lease, using the credentials of the pod's service account.
+// The namespace defaults to the pod's. The ttl is rounded up to whole seconds.
+func NewKubernetesElector(namespace string, name string, ttl time.Duration) (Elector, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("NewKubernetesElector: not running in a cluster")
+	}
+	if namespace == "" {
+		b, err := os.ReadFile(filepath.Join(ServiceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("NewKubernetesElector: failed reading namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	ca, err := os.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("NewKubernetesElector: failed reading CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("NewKubernetesElector: no certificates in CA")
+	}
+
+	return &kubernetesElector{
+		url:      fmt.Sprintf("https://%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", net.JoinHostPort(host, port), namespace),
+		name:     name,
+		ns:       namespace,
+		identity: identity(),
+		ttl:      ttl,
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+func (e *kubernetesElector) Campaign(ctx context.Context) (context.Context, error) {
+	return campaign(ctx, e, e.ttl)
+}
+
+func (e *kubernetesElector) acquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	current, err := e.get(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire: %w", err)
+	}
+
+	var next kubernetesLease
+	method, url := http.MethodPost, e.url
+	if current != nil {
+		if current.Metadata.ResourceVersion != e.observedVersion {
+			e.observed, e.observedVersion = now, current.Metadata.ResourceVersion
+		}
+		duration := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+		if current.Spec.HolderIdentity != "" && now.Before(e.observed.Add(duration)) {
+			return false, nil
+		}
+		next = *current
+		next.Spec.LeaseTransitions++
+		method, url = http.MethodPut, e.url+"/"+e.name
+	} else {
+		next.APIVersion, next.Kind = "coordination.k8s.io/v1", "Lease"
+		next.Metadata.Name, next.Metadata.Namespace = e.name, e.ns
+	}
+	next.Spec.HolderIdentity = e.identity
+	next.Spec.LeaseDurationSeconds = int((e.ttl + time.Second - 1) / time.Second)
+	next.Spec.AcquireTime = now.UTC().Format(microTime)
+	next.Spec.RenewTime = next.Spec.AcquireTime
+
+	held, err := e.write(ctx, method, url, &next)
+	if errors.Is(err, errConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("acquire: %w", err)
+	}
+	e.held = held
+	return true, nil
+}
+
+func (e *kubernetesElector) renew(ctx context.Context) (bool, error) {
+	next := *e.held
+	next.Spec.RenewTime = time.Now().UTC().Format(microTime)
+	held, err := e.write(ctx, http.MethodPut, e.url+"/"+e.name, &next)
+	if errors.Is(err, errConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("renew: %w", err)
+	}
+	e.held = held
+	return true, nil
+}
+
+func (e *kubernetesElector) release(ctx context.Context) error {
+	next := *e.held
+	next.Spec.HolderIdentity = ""
+	next.Spec.LeaseDurationSeconds = 1
+	next.Spec.RenewTime = time.Now().UTC().Format(microTime)
+	if _, err := e.write(ctx, http.MethodPut, e.url+"/"+e.name, &next); err != nil && !errors.Is(err, errConflict) {
+		return fmt.Errorf("release: %w", err)
+	}
+	return nil
+}
+
+// get returns the lease, or nil if it does not exist.
+func (e *kubernetesElector) get(ctx context.Context) (*kubernetesLease, error) {
+	res, err := e.do(ctx, http.MethodGet, e.url+"/"+e.name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get: lease returned %s", res.Status)
+	}
+	var l kubernetesLease
+	if err := json.NewDecoder(res.Body).Decode(&l); err != nil {
+		return nil, fmt.Errorf("get: failed decoding lease: %w", err)
+	}
+	return &l, nil
+}
+
+// write creates or replaces the lease, returning errConflict when it was modified
+// since it was read, so that two replicas cannot both take it over.
+func (e *kubernetesElector) write(ctx context.Context, method string, url string, l *kubernetesLease) (*kubernetesLease, error) {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("write: failed marshalling lease: %w", err)
+	}
+	res, err := e.do(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusConflict {
+		return nil, errConflict
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("write: lease returned %s", res.Status)
+	}
+	var written kubernetesLease
+	if err := json.NewDecoder(res.Body).Decode(&written); err != nil {
+		return nil, fmt.Errorf("write: failed decoding lease: %w", err)
+	}
+	return &written, nil
+}
+
+func (e *kubernetesElector) do(ctx context.Context, method string, url string, body []byte) (*http.Response, error) {
+	// The token is read on each request, as the kubelet rotates it.
+	token, err := os.ReadFile(filepath.Join(ServiceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("do: failed reading token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("do: failed creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do: failed calling API server: %w", err)
+	}
+	return res, nil
+}
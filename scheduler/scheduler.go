@@ -0,0 +1,170 @@
+// Package scheduler runs jobs at intervals alongside the server. Jobs marked as
+// singleton run on one replica only: the leader, elected through an Elector.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "scheduler").Logger()
+
+// Job is run every interval, never overlapping with its previous run.
+type Job struct {
+	Name  string
+	Every time.Duration
+	// Timeout bounds each run, when non-zero.
+	Timeout time.Duration
+	// Singleton jobs run on the leader replica only. Their context is cancelled
+	// should leadership be lost mid-run.
+	Singleton bool
+	Run       func(ctx context.Context) error
+}
+
+// Scheduler runs jobs until its context is done.
+type Scheduler struct {
+	jobs    []Job
+	elector Elector
+
+	mu     sync.RWMutex
+	leader context.Context
+}
+
+// Option configures a Scheduler.
+type Option func(s *Scheduler)
+
+// WithElector elects the replica running singleton jobs. Without one, each replica
+// considers itself leader, which suits a single replica only.
+func WithElector(e Elector) Option {
+	return func(s *Scheduler) {
+		s.elector = e
+	}
+}
+
+// New initializes a Scheduler.
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds a job. Jobs added once the scheduler is running are not run.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Leader reports whether this replica currently runs singleton jobs.
+func (s *Scheduler) Leader() bool {
+	return s.leading() != nil
+}
+
+// Run runs the jobs, blocking until the context is done and running jobs have
+// returned. Leadership is resigned on return.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	if s.elector != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.elect(ctx)
+		}()
+	} else {
+		s.setLeader(ctx)
+	}
+
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+// elect campaigns for leadership until the context is done, campaigning again
+// whenever leadership is lost so that another replica's loss is failed over.
+func (s *Scheduler) elect(ctx context.Context) {
+	for {
+		lead, err := s.elector.Campaign(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn().Str("Function", "elect").Err(err).Msg("Failed campaigning for leadership")
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		log.Info().Str("Function", "elect").Msg("Acquired leadership")
+		s.setLeader(lead)
+		<-lead.Done()
+		s.setLeader(nil)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Str("Function", "elect").Msg("Lost leadership")
+	}
+}
+
+func (s *Scheduler) setLeader(ctx context.Context) {
+	s.mu.Lock()
+	s.leader = ctx
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) leading() context.Context {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.leader == nil || s.leader.Err() != nil {
+		return nil
+	}
+	return s.leader
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		run := ctx
+		if job.Singleton {
+			if run = s.leading(); run == nil {
+				continue
+			}
+		}
+		if err := s.run(run, job); err != nil {
+			log.Error().Str("Function", "loop").Str("Job", job.Name).Err(err).Msg("Job failed")
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) (err error) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("run: job panicked: %v", v)
+		}
+	}()
+	return job.Run(ctx)
+}
@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/lock"
+)
+
+func TestSingletonFailover(t *testing.T) {
+	locker := lock.NewMemory()
+	var runs [2]atomic.Int32
+
+	ctxs := make([]context.CancelFunc, 2)
+	scheds := make([]*Scheduler, 2)
+	for i := range scheds {
+		i := i
+		scheds[i] = New(WithElector(NewLockElector(locker, "jobs", 60*time.Millisecond)))
+		scheds[i].Add(Job{Name: "singleton", Every: 5 * time.Millisecond, Singleton: true, Run: func(ctx context.Context) error {
+			runs[i].Add(1)
+			return nil
+		}})
+		ctx, cancel := context.WithCancel(context.Background())
+		ctxs[i] = cancel
+		go scheds[i].Run(ctx)
+	}
+	defer ctxs[0]()
+	defer ctxs[1]()
+
+	time.Sleep(100 * time.Millisecond)
+	leader := 0
+	if scheds[1].Leader() {
+		leader = 1
+	}
+	if !scheds[leader].Leader() || scheds[1-leader].Leader() {
+		t.Fatalf("want exactly one leader, got %v and %v", scheds[0].Leader(), scheds[1].Leader())
+	}
+	if runs[1-leader].Load() != 0 {
+		t.Fatalf("follower ran singleton job %d times", runs[1-leader].Load())
+	}
+
+	// Stopping the leader resigns, so the follower takes over.
+	ctxs[leader]()
+	time.Sleep(100 * time.Millisecond)
+	if follower := 1 - leader; !scheds[follower].Leader() || runs[follower].Load() == 0 {
+		t.Fatalf("follower did not take over: leader %v, runs %d", scheds[follower].Leader(), runs[follower].Load())
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package ramchi
+
+// runService does nothing, as services are only supported on Windows.
+func (s *Server) runService() {}
@@ -0,0 +1,117 @@
+//go:build windows
+
+package ramchi
+
+import (
+	"sync"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// processService is the service of the process, which svc.Run connects to the
+// service control manager once, however many servers run in the process.
+var (
+	processService     = newService()
+	processServiceOnce sync.Once
+)
+
+// runService registers the server with the service control manager when it
+// was started as a Windows service, so that stopping the service or shutting
+// down the host shuts the server down gracefully.
+func (s *Server) runService() {
+	ok, err := svc.IsWindowsService()
+	if err != nil {
+		s.log.Warn().Str("Function", "runService").Err(err).Msg("Failed detecting service")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	processService.add(s)
+	processServiceOnce.Do(func() {
+		go func() {
+			if err := svc.Run(s.cfg.ServiceName, processService); err != nil {
+				s.log.Error().Str("Function", "runService").Str("Service", s.cfg.ServiceName).Err(err).Msg("Service failed")
+				processService.shutdown()
+			}
+		}()
+	})
+}
+
+// service maps service control requests to the lifecycle of the servers of the
+// process, reporting it stopped once they all have.
+type service struct {
+	mu       sync.Mutex
+	servers  []*Server
+	running  int
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newService() *service {
+	return &service{stopped: make(chan struct{})}
+}
+
+// add registers the server, until it stops.
+func (sv *service) add(s *Server) {
+	sv.mu.Lock()
+	sv.servers = append(sv.servers, s)
+	sv.running++
+	sv.mu.Unlock()
+
+	go func() {
+		<-s.idle
+		sv.mu.Lock()
+		defer sv.mu.Unlock()
+		if sv.running--; sv.running == 0 {
+			sv.stopOnce.Do(func() {
+				close(sv.stopped)
+			})
+		}
+	}()
+}
+
+// shutdown shuts every registered server down, returning once they have drained.
+func (sv *service) shutdown() {
+	sv.mu.Lock()
+	servers := append([]*Server{}, sv.servers...)
+	sv.mu.Unlock()
+
+	for _, s := range servers {
+		s.shutdown()
+	}
+	for _, s := range servers {
+		<-s.idle
+	}
+}
+
+// Execute reports the service running until it is asked to stop, then reports it
+// stopped once in-flight requests have completed.
+func (sv *service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+	status <- svc.Status{State: svc.StartPending}
+	status <- svc.Status{State: svc.Running, Accepts: accepted}
+
+	for {
+		select {
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				sv.mu.Lock()
+				for _, s := range sv.servers {
+					s.log.Info().Str("Function", "Execute").Uint32("Command", uint32(req.Cmd)).Msg("Service stop requested")
+				}
+				sv.mu.Unlock()
+				status <- svc.Status{State: svc.StopPending}
+				sv.shutdown()
+				return false, 0
+			}
+		case <-sv.stopped:
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package ramchi
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/config"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func TestServiceStop(t *testing.T) {
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", "127.0.0.1:7002"); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	other := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7003"}), WithSignals())
+	otherErrs := make(chan error, 1)
+	go func() {
+		otherErrs <- other.StartE()
+	}()
+
+	// Both servers of the process share the one service.
+	sv := newService()
+	sv.add(ts)
+	sv.add(other)
+	requests := make(chan svc.ChangeRequest)
+	status := make(chan svc.Status, 10)
+	go sv.Execute(nil, requests, status)
+	requests <- svc.ChangeRequest{Cmd: svc.Stop}
+
+	for _, errs := range []chan error{errs, otherErrs} {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("StartE: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("server not shut down by the service stop request")
+		}
+	}
+	var last svc.Status
+	for len(status) > 0 {
+		last = <-status
+	}
+	if last.State != svc.StopPending {
+		t.Fatalf("got state %v, want StopPending", last.State)
+	}
+}
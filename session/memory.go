@@ -0,0 +1,74 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	values  map[string]string
+	expires time.Time
+}
+
+// sweepEvery is the number of saves between sweeps of expired sessions.
+const sweepEvery = 256
+
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]entry
+	saves    int
+}
+
+// NewMemoryStore initializes a Store local to the process.
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: make(map[string]entry)}
+}
+
+// Load returns the values of the session.
+func (m *memoryStore) Load(ctx context.Context, id string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.sessions[id]
+	if !ok || time.Now().After(e.expires) {
+		delete(m.sessions, id)
+		return nil, ErrNotFound
+	}
+	return copyValues(e.values), nil
+}
+
+// Save persists the values of the session.
+func (m *memoryStore) Save(ctx context.Context, id string, values map[string]string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.saves++
+	if m.saves%sweepEvery == 0 {
+		for k, e := range m.sessions {
+			if now.After(e.expires) {
+				delete(m.sessions, k)
+			}
+		}
+	}
+	m.sessions[id] = entry{values: copyValues(values), expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes the session.
+func (m *memoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func copyValues(values map[string]string) map[string]string {
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		out[k] = v
+	}
+	return out
+}
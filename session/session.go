@@ -0,0 +1,131 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// ErrNotFound is returned by a Store when no session has the ID.
+var ErrNotFound = errors.New("session not found")
+
+// Store persists session values server-side, keyed by session ID.
+type Store interface {
+	// Load returns the values of the session, or ErrNotFound.
+	Load(ctx context.Context, id string) (map[string]string, error)
+	// Save persists the values of the session for ttl.
+	Save(ctx context.Context, id string, values map[string]string, ttl time.Duration) error
+	// Delete removes the session.
+	Delete(ctx context.Context, id string) error
+}
+
+// Session holds the values of a single client's session.
+type Session struct {
+	id     string
+	values map[string]string
+}
+
+// ID returns the session ID, which is empty until the session is first saved.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get returns the value for key.
+func (s *Session) Get(key string) (string, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set sets the value for key.
+func (s *Session) Set(key string, value string) {
+	s.values[key] = value
+}
+
+// Delete removes the value for key.
+func (s *Session) Delete(key string) {
+	delete(s.values, key)
+}
+
+// Manager loads and saves sessions, identified by a cookie carrying the
+// session ID. The cookie follows the configured cookie profile.
+type Manager struct {
+	Store      Store
+	CookieName string
+	TTL        time.Duration
+}
+
+// NewManager initializes a manager with sessions lasting a day.
+func NewManager(store Store) *Manager {
+	return &Manager{Store: store, CookieName: "ramchi_session", TTL: 24 * time.Hour}
+}
+
+// Load returns the session of the request, or a new empty session.
+func (m *Manager) Load(r *http.Request) (*Session, error) {
+	cookie, err := r.Cookie(m.CookieName)
+	if err != nil || cookie.Value == "" {
+		return &Session{values: make(map[string]string)}, nil
+	}
+
+	values, err := m.Store.Load(r.Context(), cookie.Value)
+	if errors.Is(err, ErrNotFound) {
+		return &Session{values: make(map[string]string)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Load: failed loading session: %w", err)
+	}
+	return &Session{id: cookie.Value, values: values}, nil
+}
+
+// Save persists the session, setting the session cookie if it is new.
+func (m *Manager) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	if s.id == "" {
+		id, err := newID()
+		if err != nil {
+			return fmt.Errorf("Save: %w", err)
+		}
+		s.id = id
+	}
+	if err := m.Store.Save(r.Context(), s.id, s.values, m.TTL); err != nil {
+		return fmt.Errorf("Save: failed saving session: %w", err)
+	}
+	helpers.SetCookie(w, r, m.CookieName, s.id, int(m.TTL.Seconds()))
+	return nil
+}
+
+// Renew moves the session to a new ID, to be called when the privileges of the
+// session change, such as on login, to prevent session fixation.
+func (m *Manager) Renew(w http.ResponseWriter, r *http.Request, s *Session) error {
+	if s.id != "" {
+		if err := m.Store.Delete(r.Context(), s.id); err != nil {
+			return fmt.Errorf("Renew: failed deleting session: %w", err)
+		}
+		s.id = ""
+	}
+	return m.Save(w, r, s)
+}
+
+// Destroy removes the session and clears the session cookie.
+func (m *Manager) Destroy(w http.ResponseWriter, r *http.Request, s *Session) error {
+	if s.id != "" {
+		if err := m.Store.Delete(r.Context(), s.id); err != nil {
+			return fmt.Errorf("Destroy: failed deleting session: %w", err)
+		}
+	}
+	s.id, s.values = "", make(map[string]string)
+	helpers.ClearCookie(w, r, m.CookieName)
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("newID: failed reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
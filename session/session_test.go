@@ -0,0 +1,101 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	m := NewManager(NewMemoryStore())
+	send := func(cookies []*http.Cookie) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+
+	s, err := m.Load(send(nil))
+	if err != nil || s.ID() != "" {
+		t.Fatalf("got session %q, %v, want a new session", s.ID(), err)
+	}
+	s.Set("user", "alice")
+	rec := httptest.NewRecorder()
+	if err := m.Save(rec, send(nil), s); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "ramchi_session" || cookies[0].Value != s.ID() {
+		t.Fatalf("got cookies %v, want the session ID in ramchi_session", cookies)
+	}
+
+	loaded, err := m.Load(send(cookies))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := loaded.Get("user"); !ok || v != "alice" || loaded.ID() != s.ID() {
+		t.Fatalf("got %q, %v in session %q, want alice in %q", v, ok, loaded.ID(), s.ID())
+	}
+
+	old := loaded.ID()
+	rec = httptest.NewRecorder()
+	if err := m.Renew(rec, send(cookies), loaded); err != nil {
+		t.Fatal(err)
+	}
+	renewed := rec.Result().Cookies()
+	if loaded.ID() == old || len(renewed) != 1 || renewed[0].Value != loaded.ID() {
+		t.Fatalf("got ID %q and cookies %v, want a new ID replacing %q", loaded.ID(), renewed, old)
+	}
+	if s, _ := m.Load(send(cookies)); s.ID() != "" {
+		t.Fatal("old session ID still loads after renewing")
+	}
+	if s, _ := m.Load(send(renewed)); s.ID() != loaded.ID() {
+		t.Fatalf("got session %q, want the renewed %q", s.ID(), loaded.ID())
+	}
+
+	rec = httptest.NewRecorder()
+	if err := m.Destroy(rec, send(renewed), loaded); err != nil {
+		t.Fatal(err)
+	}
+	if cleared := rec.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("got cookies %v, want the session cookie cleared", cleared)
+	}
+	if _, ok := loaded.Get("user"); ok || loaded.ID() != "" {
+		t.Fatal("session keeps its values after being destroyed")
+	}
+	if s, _ := m.Load(send(renewed)); s.ID() != "" {
+		t.Fatal("destroyed session still loads")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	values := map[string]string{"k": "v"}
+	if err := store.Save(ctx, "a", values, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	values["k"] = "changed"
+	got, err := store.Load(ctx, "a")
+	if err != nil || got["k"] != "v" {
+		t.Fatalf("got %v, %v, want the values as saved", got, err)
+	}
+
+	if err := store.Save(ctx, "expired", values, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound for an expired session", err)
+	}
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound for a deleted session", err)
+	}
+}
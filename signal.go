@@ -0,0 +1,27 @@
+package ramchi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultSignals are the signals which begin a graceful shutdown when none are configured.
+var defaultSignals = []string{"SIGINT", "SIGTERM"}
+
+// parseSignals returns the named signals, or the defaults for nil, so that an empty
+// list disables signal handling.
+func parseSignals(names []string) ([]os.Signal, error) {
+	if names == nil {
+		names = defaultSignals
+	}
+	signals := make([]os.Signal, 0, len(names))
+	for _, name := range names {
+		sig, ok := signalNames[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("parseSignals: unsupported signal %q", name)
+		}
+		signals = append(signals, sig)
+	}
+	return signals, nil
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalNames are the signals which may be configured to begin a graceful shutdown.
+// SIGUSR2 is not among them, as it triggers upgrades.
+var signalNames = map[string]os.Signal{
+	"SIGINT":  os.Interrupt,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+}
@@ -0,0 +1,54 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/config"
+)
+
+func TestShutdownOnSignal(t *testing.T) {
+	// Keep the signal from terminating the test process, should it arrive before
+	// the server subscribes to it.
+	guard := make(chan os.Signal, 10)
+	signal.Notify(guard, syscall.SIGUSR1)
+	defer signal.Stop(guard)
+
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals(syscall.SIGUSR1))
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("StartE: %v", err)
+			}
+			return
+		case <-deadline:
+			ts.Stop(context.Background())
+			t.Fatal("server not shut down by the configured signal")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestUpgradeSignalReserved(t *testing.T) {
+	if _, err := parseSignals([]string{"SIGUSR2"}); err == nil {
+		t.Fatal("parsed SIGUSR2 as a shutdown signal")
+	}
+	ts := New(WithConfig(&config.Config{Address: "127.0.0.1", Port: "7002"}), WithSignals(syscall.SIGTERM, syscall.SIGUSR2))
+	if err := ts.StartE(); err == nil {
+		t.Fatal("started with SIGUSR2 beginning a shutdown")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package ramchi
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalNames are the signals which may be configured to begin a graceful shutdown.
+// The runtime delivers Ctrl+C and Ctrl+Break as os.Interrupt, and closing the
+// console, logging off and shutting down as syscall.SIGTERM.
+var signalNames = map[string]os.Signal{
+	"SIGINT":  os.Interrupt,
+	"SIGTERM": syscall.SIGTERM,
+}
@@ -0,0 +1,127 @@
+package spiffe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The file names written by spiffe-helper.
+const (
+	SVIDFile   = "svid.pem"
+	KeyFile    = "svid_key.pem"
+	BundleFile = "svid_bundle.pem"
+)
+
+// FileSource is a Source reading the SVID and bundle from a directory, as written
+// by spiffe-helper from the SPIFFE Workload API socket, and reloading them as they
+// are rotated.
+type FileSource struct {
+	dir      string
+	mu       sync.RWMutex
+	svid     *tls.Certificate
+	bundle   *x509.CertPool
+	modified time.Time
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewFileSource reads the SVID and bundle from the directory, checking it for
+// rotated files each interval until closed.
+func NewFileSource(dir string, interval time.Duration) (*FileSource, error) {
+	fs := &FileSource{dir: dir, done: make(chan struct{})}
+	if err := fs.load(); err != nil {
+		return nil, fmt.Errorf("NewFileSource: %w", err)
+	}
+	go fs.watch(interval)
+	return fs, nil
+}
+
+// SVID returns the current X.509 SVID.
+func (fs *FileSource) SVID() (*tls.Certificate, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.svid, nil
+}
+
+// Bundle returns the current trust bundle.
+func (fs *FileSource) Bundle() (*x509.CertPool, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.bundle, nil
+}
+
+// Close stops checking for rotated files.
+func (fs *FileSource) Close() error {
+	fs.once.Do(func() { close(fs.done) })
+	return nil
+}
+
+func (fs *FileSource) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := fs.load(); err != nil {
+				log.Error().Str("Function", "watch").Str("Directory", fs.dir).Err(err).Msg("Failed reloading SVID")
+			}
+		case <-fs.done:
+			return
+		}
+	}
+}
+
+// load reads the files when any has been modified since they were last read.
+func (fs *FileSource) load() error {
+	var modified time.Time
+	for _, name := range []string{SVIDFile, KeyFile, BundleFile} {
+		info, err := os.Stat(filepath.Join(fs.dir, name))
+		if err != nil {
+			return fmt.Errorf("load: failed reading file info: %w", err)
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	fs.mu.RLock()
+	current := fs.svid != nil && modified.Equal(fs.modified)
+	fs.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	svid, err := tls.LoadX509KeyPair(filepath.Join(fs.dir, SVIDFile), filepath.Join(fs.dir, KeyFile))
+	if err != nil {
+		return fmt.Errorf("load: failed loading SVID: %w", err)
+	}
+	if svid.Leaf == nil {
+		if svid.Leaf, err = x509.ParseCertificate(svid.Certificate[0]); err != nil {
+			return fmt.Errorf("load: failed parsing SVID: %w", err)
+		}
+	}
+	id, err := IDFromCertificate(svid.Leaf)
+	if err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+
+	pem, err := os.ReadFile(filepath.Join(fs.dir, BundleFile))
+	if err != nil {
+		return fmt.Errorf("load: failed reading bundle: %w", err)
+	}
+	bundle := x509.NewCertPool()
+	if !bundle.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("load: bundle contains no certificates")
+	}
+
+	fs.mu.Lock()
+	fs.svid, fs.bundle, fs.modified = &svid, bundle, modified
+	fs.mu.Unlock()
+	log.Debug().Str("Function", "load").Str("ID", id.String()).Time("NotAfter", svid.Leaf.NotAfter).Msg("Loaded SVID")
+	return nil
+}
@@ -0,0 +1,100 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issue writes an SVID for the ID, signed by a new CA, to a directory as spiffe-helper would.
+func issue(t *testing.T, id string) string {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	uri, _ := url.Parse(id)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	dir := t.TempDir()
+	write := func(name string, kind string, der []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(SVIDFile, "CERTIFICATE", leafDER)
+	write(KeyFile, "EC PRIVATE KEY", keyDER)
+	write(BundleFile, "CERTIFICATE", caDER)
+	return dir
+}
+
+func TestMutualTLS(t *testing.T) {
+	src, err := NewFileSource(issue(t, "spiffe://example.org/api"), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", ServerTLSConfig(src, AuthorizeMemberOf("example.org")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, PeerID(r).String())
+	}))
+	defer ln.Close()
+	addr := "https://" + ln.Addr().(*net.TCPAddr).String()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: ClientTLSConfig(src, AuthorizeID("spiffe://example.org/api"))}}
+	res, err := client.Get(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "spiffe://example.org/api" {
+		t.Fatalf("expected peer ID to be exposed, got %q", body)
+	}
+
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: ClientTLSConfig(src, AuthorizeID("spiffe://example.org/other"))}}
+	if _, err := client.Get(addr); err == nil {
+		t.Fatal("expected unauthorized server to be refused")
+	}
+}
@@ -0,0 +1,208 @@
+// Package sqltx runs each request in a database transaction, committed or rolled
+// back according to the outcome of the request.
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "sqltx").Logger()
+
+type txKey struct{}
+
+type skipKey struct{}
+
+// Transactor opens a transaction for each request.
+type Transactor struct {
+	DB      *sql.DB
+	Options *sql.TxOptions
+	// Commit reports whether a request answered with the status should commit.
+	Commit func(status int) bool
+	// Registry, when set, such as to the Registry of the server, counts the
+	// transactions committed, rolled back, and failing to commit, as
+	// ramchi_transactions_total.
+	Registry *metrics.Registry
+}
+
+// New initializes a transactor committing requests answered with a 2xx status,
+// and rolling back the rest, as redirects and informational responses do not
+// report that the request succeeded.
+func New(db *sql.DB) *Transactor {
+	return &Transactor{
+		DB:     db,
+		Commit: func(status int) bool { return status >= 200 && status < 300 },
+	}
+}
+
+func (t *Transactor) count(outcome string) {
+	if t.Registry != nil {
+		t.Registry.Counter("ramchi_transactions_total", "Transactions of requests, by outcome.", "outcome").Inc(outcome)
+	}
+}
+
+// Tx returns the transaction of the request, or nil.
+func Tx(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// Handler serves each request within a transaction, available via Tx. The outcome
+// is decided when the response status is written, so that a failed commit can
+// still be reported with 500 Internal Server Error. Panics roll back.
+func (t *Transactor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tx, err := t.DB.BeginTx(r.Context(), t.Options)
+		if err != nil {
+			log.Error().Str("Function", "Handler").Str("RequestID", helpers.RequestID(r)).Err(err).Msg("Failed beginning transaction")
+			helpers.Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+
+		tw := &txWriter{ResponseWriter: w, t: t, tx: tx, r: r}
+		defer func() {
+			if p := recover(); p != nil {
+				if !tw.decided {
+					tw.rollback()
+				}
+				panic(p)
+			}
+			if !tw.decided {
+				tw.WriteHeader(http.StatusOK)
+			}
+		}()
+		next.ServeHTTP(tw, r.WithContext(context.WithValue(r.Context(), txKey{}, tx)))
+	})
+}
+
+// Middleware returns the transactor as a server middleware, serving every request
+// within a transaction. It runs before routing, so routes cannot opt out with
+// WithoutTransaction; use WithRouterTransaction for routers with routes which must
+// not hold a transaction.
+func (t *Transactor) Middleware(opts ...middleware.MiddlewareWrapper) middleware.Middleware {
+	return middleware.NewMiddleware(t.Handler, "sqltx", true, false, opts...)
+}
+
+// WithTransaction serves the route within a transaction.
+func WithTransaction(t *Transactor) router.RouteWrapper {
+	return router.WithMiddleware(t.Handler)
+}
+
+// WithRouterTransaction serves every route of the router within a transaction,
+// except those wrapped with WithoutTransaction.
+func WithRouterTransaction(t *Transactor) router.RouterWrapper {
+	return func(rt router.Router) router.Router {
+		return txRouter{rt, t}
+	}
+}
+
+// WithoutTransaction opts the route out of WithRouterTransaction. It has no effect
+// on Middleware.
+func WithoutTransaction() router.RouteWrapper {
+	return router.WithValue(skipKey{}, true)
+}
+
+type txRouter struct {
+	router.Router
+	t *Transactor
+}
+
+// Unwrap returns the router that was wrapped.
+func (t txRouter) Unwrap() router.Router {
+	return t.Router
+}
+
+// Routes returns the routes of the router, within transactions unless opted out.
+func (t txRouter) Routes() []router.Route {
+	routes := t.Router.Routes()
+	wrapped := make([]router.Route, len(routes))
+	for i, r := range routes {
+		if skip, _ := router.Value(r, skipKey{}).(bool); skip {
+			wrapped[i] = r
+			continue
+		}
+		wrapped[i] = WithTransaction(t.t)(r)
+	}
+	return wrapped
+}
+
+// txWriter ends the transaction before the response status is written.
+type txWriter struct {
+	http.ResponseWriter
+	t       *Transactor
+	tx      *sql.Tx
+	r       *http.Request
+	decided bool
+}
+
+func (tw *txWriter) WriteHeader(code int) {
+	if tw.decided {
+		return
+	}
+	tw.decided = true
+
+	if !tw.t.Commit(code) {
+		tw.rollback()
+		tw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if err := tw.tx.Commit(); err != nil {
+		tw.t.count("commitFailed")
+		log.Error().Str("Function", "WriteHeader").Str("RequestID", helpers.RequestID(tw.r)).Err(err).Msg("Failed committing transaction")
+		helpers.Error(tw.ResponseWriter, tw.r, http.StatusInternalServerError)
+		// The handler's body belongs to the response it intended, so discard it.
+		tw.ResponseWriter = discardWriter{tw.ResponseWriter}
+		return
+	}
+	tw.t.count("committed")
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *txWriter) Write(b []byte) (int, error) {
+	if !tw.decided {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *txWriter) rollback() {
+	tw.decided = true
+	if err := tw.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Error().Str("Function", "rollback").Str("RequestID", helpers.RequestID(tw.r)).Err(err).Msg("Failed rolling back transaction")
+	}
+	tw.t.count("rolledBack")
+}
+
+// Flush decides the outcome, as the status is written, then flushes.
+func (tw *txWriter) Flush() {
+	if !tw.decided {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (tw *txWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+type discardWriter struct {
+	http.ResponseWriter
+}
+
+func (discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardWriter) WriteHeader(int) {}
@@ -0,0 +1,84 @@
+package sqltx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Etwodev/ramchi/metrics"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTransactor(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		expect  func(mock sqlmock.Sqlmock)
+		want    int
+		outcome string
+	}{
+		{"commit", http.StatusCreated, func(mock sqlmock.Sqlmock) { mock.ExpectCommit() }, http.StatusCreated, "committed"},
+		{"rollback", http.StatusConflict, func(mock sqlmock.Sqlmock) { mock.ExpectRollback() }, http.StatusConflict, "rolledBack"},
+		{"redirect", http.StatusSeeOther, func(mock sqlmock.Sqlmock) { mock.ExpectRollback() }, http.StatusSeeOther, "rolledBack"},
+		{"commit failure", http.StatusOK, func(mock sqlmock.Sqlmock) { mock.ExpectCommit().WillReturnError(errors.New("serialization failure")) }, http.StatusInternalServerError, "commitFailed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			tc.expect(mock)
+
+			tr := New(db)
+			tr.Registry = metrics.NewRegistry()
+			rec := httptest.NewRecorder()
+			tr.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if Tx(r.Context()) == nil {
+					t.Error("no transaction in the request context")
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte("body"))
+			})).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			if rec.Code != tc.want {
+				t.Fatalf("got %d, want %d", rec.Code, tc.want)
+			}
+			if tc.want != tc.status && strings.Contains(rec.Body.String(), "body") {
+				t.Fatalf("got the handler's body %q with a failed commit", rec.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if want := `ramchi_transactions_total{outcome="` + tc.outcome + `"} 1`; !strings.Contains(tr.Registry.Text(), want) {
+				t.Fatalf("metrics lack %s:\n%s", want, tr.Registry.Text())
+			}
+		})
+	}
+}
+
+func TestTransactorPanic(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("got panic %v, want it repanicked", p)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	New(db).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+}
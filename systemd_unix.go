@@ -0,0 +1,42 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// systemdListenFDsStart is the first descriptor passed by systemd socket activation.
+const systemdListenFDsStart = 3
+
+// systemdListener returns the listener passed by systemd socket activation
+// (sd_listen_fds(3)), so that a unit may bind privileged ports on the server's
+// behalf and start it on demand. When several sockets are passed, the first is used.
+func (s *Server) systemdListener() (net.Listener, bool, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, false, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, false, nil
+	}
+	names := os.Getenv("LISTEN_FDNAMES")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(systemdListenFDsStart), "systemd")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("systemdListener: failed creating listener: %w", err)
+	}
+	if n > 1 {
+		s.log.Warn().Str("Function", "systemdListener").Int("Sockets", n).Str("Names", names).Msg("Several sockets passed, using the first")
+	}
+	return ln, true, nil
+}
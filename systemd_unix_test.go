@@ -0,0 +1,55 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestSystemdListener(t *testing.T) {
+	// The activated process checks the socket passed as descriptor 3.
+	if want := os.Getenv("RAMCHI_TEST_SYSTEMD_ADDR"); want != "" {
+		ln, ok, err := (&Server{}).systemdListener()
+		if err != nil || !ok {
+			fmt.Printf("got %v and %v, want the activated socket", ok, err)
+			os.Exit(1)
+		}
+		if ln.Addr().String() != want {
+			fmt.Printf("got %s, want %s", ln.Addr(), want)
+			os.Exit(1)
+		}
+		if _, set := os.LookupEnv("LISTEN_FDS"); set {
+			fmt.Print("LISTEN_FDS left in the environment of later processes")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if _, ok, err := (&Server{}).systemdListener(); ok || err != nil {
+		t.Fatalf("got %v and %v without socket activation", ok, err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// As systemd does, the socket is passed to the process whose PID is LISTEN_PID,
+	// which exec keeps.
+	cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ LISTEN_FDS=1 exec "$0" -test.run='^TestSystemdListener$'`, os.Args[0])
+	cmd.Env = append(os.Environ(), "RAMCHI_TEST_SYSTEMD_ADDR="+ln.Addr().String())
+	cmd.ExtraFiles = []*os.File{f}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("activated process failed: %v: %s", err, out)
+	}
+}
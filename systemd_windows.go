@@ -0,0 +1,10 @@
+//go:build windows
+
+package ramchi
+
+import "net"
+
+// systemdListener always reports no listener, as socket activation is not supported on Windows.
+func (s *Server) systemdListener() (net.Listener, bool, error) {
+	return nil, false, nil
+}
@@ -0,0 +1,286 @@
+package ramchi
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	c "github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	CertificateOK       = "ok"
+	CertificateWarning  = "warning"
+	CertificateCritical = "critical"
+	CertificateExpired  = "expired"
+)
+
+// certificateCriticalDays is the number of days before expiry from which the
+// checker logs errors rather than warnings.
+const certificateCriticalDays = 7
+
+// certificateCheckInterval is how often the checker reloads and inspects the certificate.
+const certificateCheckInterval = time.Hour
+
+// ticketKeysKept is the number of session ticket keys kept for decryption, so that
+// a ticket is resumable for up to this many rotation intervals.
+const ticketKeysKept = 3
+
+// CertificateStatus describes the expiry of the served certificate.
+type CertificateStatus struct {
+	Status        string    `json:"status"`
+	Subject       string    `json:"subject"`
+	NotAfter      time.Time `json:"notAfter"`
+	DaysRemaining float64   `json:"daysRemaining"`
+}
+
+// certificateChecker serves the configured certificate, reloading it when its files
+// change, and logs escalating warnings as it approaches expiry.
+type certificateChecker struct {
+	certFile string
+	keyFile  string
+	// warningDays is how many days before its expiry the certificate is reported expiring.
+	warningDays int
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	modified    time.Time
+	// stapleRefresh and stapleExpiry are when the OCSP staple of cert is due for
+	// refresh, and when it ceases to be valid.
+	stapleRefresh time.Time
+	stapleExpiry  time.Time
+	// days, when set, records the days remaining at each check.
+	days *metrics.Gauge
+	log  zerolog.Logger
+}
+
+func newCertificateChecker(certFile string, keyFile string, warningDays int) (*certificateChecker, error) {
+	cc := &certificateChecker{certFile: certFile, keyFile: keyFile, warningDays: warningDays}
+	if err := cc.load(); err != nil {
+		return nil, fmt.Errorf("newCertificateChecker: %w", err)
+	}
+	return cc, nil
+}
+
+// load reads the key pair when either file has been modified since it was last read.
+func (cc *certificateChecker) load() error {
+	var modified time.Time
+	for _, name := range []string{cc.certFile, cc.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("load: failed reading file info: %w", err)
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	cc.mu.RLock()
+	current := cc.cert != nil && modified.Equal(cc.modified)
+	cc.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cc.certFile, cc.keyFile)
+	if err != nil {
+		return fmt.Errorf("load: failed loading key pair: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("load: failed parsing certificate: %w", err)
+		}
+	}
+
+	cc.mu.Lock()
+	cc.cert, cc.modified = &cert, modified
+	cc.mu.Unlock()
+	return nil
+}
+
+func (cc *certificateChecker) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return cc.cert, nil
+}
+
+func (cc *certificateChecker) status() CertificateStatus {
+	cc.mu.RLock()
+	leaf := cc.cert.Leaf
+	cc.mu.RUnlock()
+
+	remaining := time.Until(leaf.NotAfter).Hours() / 24
+	status := CertificateOK
+	switch {
+	case remaining <= 0:
+		status = CertificateExpired
+	case remaining <= certificateCriticalDays:
+		status = CertificateCritical
+	case remaining <= float64(cc.warningDays):
+		status = CertificateWarning
+	}
+	return CertificateStatus{Status: status, Subject: leaf.Subject.String(), NotAfter: leaf.NotAfter, DaysRemaining: remaining}
+}
+
+// check reloads the certificate and logs its status, at a level rising as expiry nears.
+func (cc *certificateChecker) check() {
+	if err := cc.load(); err != nil {
+		cc.log.Error().Str("Function", "check").Str("File", cc.certFile).Err(err).Msg("Failed reloading certificate")
+	}
+
+	st := cc.status()
+	if cc.days != nil {
+		cc.days.Set(st.DaysRemaining)
+	}
+	switch st.Status {
+	case CertificateWarning:
+		cc.log.Warn().Str("Subject", st.Subject).Time("NotAfter", st.NotAfter).Int("DaysRemaining", int(st.DaysRemaining)).Msg("Certificate expires soon")
+	case CertificateCritical:
+		cc.log.Error().Str("Subject", st.Subject).Time("NotAfter", st.NotAfter).Int("DaysRemaining", int(st.DaysRemaining)).Msg("Certificate expires imminently")
+	case CertificateExpired:
+		cc.log.Error().Str("Subject", st.Subject).Time("NotAfter", st.NotAfter).Msg("Certificate has expired")
+	}
+}
+
+// run checks the certificate periodically until done is closed.
+func (cc *certificateChecker) run(done <-chan struct{}) {
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+	for {
+		cc.check()
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// CertificateStatus returns the expiry status of the served certificate, and false
+// when the server is not serving TLS.
+func (s *Server) CertificateStatus() (CertificateStatus, bool) {
+	if s.certs == nil {
+		return CertificateStatus{}, false
+	}
+	return s.certs.status(), true
+}
+
+// CertificateHealth is a handler reporting the CertificateStatus, responding 503
+// Service Unavailable once the certificate has expired.
+func (s *Server) CertificateHealth(w http.ResponseWriter, r *http.Request) {
+	st, ok := s.CertificateStatus()
+	if !ok {
+		helpers.JSONError(w, r, http.StatusNotFound, "tls is not enabled")
+		return
+	}
+	code := http.StatusOK
+	if st.Status == CertificateExpired {
+		code = http.StatusServiceUnavailable
+	}
+	helpers.JSON(w, r, code, st)
+}
+
+// tlsConfig returns the TLS configuration serving the certificates of getCertificate.
+func (s *Server) tlsConfig(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: getCertificate, NextProtos: []string{"h2", "http/1.1"}}
+	if s.cfg.TLSMinVersion == c.TLSVersion13 {
+		cfg.MinVersion = tls.VersionTLS13
+	}
+	if len(s.cfg.TLSCipherSuites) > 0 {
+		suites, err := cipherSuites(s.cfg.TLSCipherSuites)
+		if err != nil {
+			return nil, fmt.Errorf("tlsConfig: %w", err)
+		}
+		cfg.CipherSuites = suites
+	}
+	cfg.ClientAuth = clientAuthTypes[s.cfg.TLSClientAuth]
+	if s.cfg.TLSClientCAFile != "" {
+		pool, err := loadCertPool(s.cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tlsConfig: %w", err)
+		}
+		cfg.ClientCAs = pool
+	}
+
+	if !s.cfg.TLSSessionTickets {
+		cfg.SessionTicketsDisabled = true
+	} else if s.cfg.TLSTicketRotation > 0 {
+		go rotateTicketKeys(cfg, time.Duration(s.cfg.TLSTicketRotation)*time.Minute, s.idle, s.log)
+	}
+	return cfg, nil
+}
+
+// clientAuthTypes maps the configured client certificate policies to crypto/tls.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	c.TLSClientAuthNone:          tls.NoClientCert,
+	c.TLSClientAuthRequest:       tls.RequestClientCert,
+	c.TLSClientAuthRequire:       tls.RequireAnyClientCert,
+	c.TLSClientAuthVerify:        tls.VerifyClientCertIfGiven,
+	c.TLSClientAuthRequireVerify: tls.RequireAndVerifyClientCert,
+}
+
+// cipherSuites returns the IDs of the named cipher suites, refusing those
+// crypto/tls considers insecure.
+func cipherSuites(names []string) ([]uint16, error) {
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("cipherSuites: %q is not a secure cipher suite", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// loadCertPool returns the pool of the PEM certificates in the file.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("loadCertPool: failed reading certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("loadCertPool: no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// rotateTicketKeys replaces the session ticket key each interval until done is closed.
+func rotateTicketKeys(cfg *tls.Config, interval time.Duration, done <-chan struct{}, logger zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var keys [][32]byte
+	for {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			logger.Error().Str("Function", "rotateTicketKeys").Err(err).Msg("Failed generating session ticket key")
+		} else {
+			keys = append([][32]byte{key}, keys...)
+			if len(keys) > ticketKeysKept {
+				keys = keys[:ticketKeysKept]
+			}
+			cfg.SetSessionTicketKeys(keys)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package ramchi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/config"
+	"github.com/Etwodev/ramchi/router"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestTLSConfig(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "client ca"}, NotAfter: time.Now().Add(time.Hour), IsCA: true, BasicConstraintsValid: true}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := New(WithConfig(&config.Config{
+		TLSMinVersion:   config.TLSVersion13,
+		TLSCipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		TLSClientAuth:   config.TLSClientAuthRequireVerify,
+		TLSClientCAFile: ca,
+	}))
+	cfg, err := ts.tlsConfig(nil)
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	if cfg.MinVersion != tls.VersionTLS13 || len(cfg.CipherSuites) != 1 || cfg.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("got version %x and suites %v", cfg.MinVersion, cfg.CipherSuites)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Fatalf("got client auth %v", cfg.ClientAuth)
+	}
+	if !cfg.SessionTicketsDisabled {
+		t.Fatal("session tickets issued while tlsSessionTickets is off")
+	}
+
+	ts = New(WithConfig(&config.Config{TLSClientAuth: config.TLSClientAuthVerify, TLSClientCAFile: filepath.Join(t.TempDir(), "missing.pem")}))
+	if _, err := ts.tlsConfig(nil); err == nil {
+		t.Fatal("tlsConfig succeeded without the client CA file")
+	}
+}
+
+// writeKeyPair writes a self-signed certificate for 127.0.0.1 expiring at notAfter,
+// and its key, to the directory.
+func writeKeyPair(t *testing.T, dir string, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "127.0.0.1"}, NotBefore: notAfter.Add(-30 * 24 * time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSHTTP2(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t, t.TempDir(), time.Now().Add(time.Hour))
+	ts := New(WithConfig(&config.Config{
+		Address:     "127.0.0.1",
+		Port:        "7002",
+		EnableTLS:   true,
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+	}), WithSignals())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, ForceAttemptHTTP2: true}}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("https://127.0.0.1:7002/"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("got %s, want HTTP/2 negotiated over TLS", resp.Proto)
+	}
+}
+
+func TestCertificateHealth(t *testing.T) {
+	ts := New(WithSignals())
+	rec := httptest.NewRecorder()
+	ts.CertificateHealth(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("without TLS: got %d, want 404", rec.Code)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, time.Now().Add(3*24*time.Hour))
+	certs, err := newCertificateChecker(certFile, keyFile, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.certs = certs
+	health := func() (int, CertificateStatus) {
+		rec := httptest.NewRecorder()
+		ts.CertificateHealth(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var st CertificateStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, st
+	}
+	if code, st := health(); code != http.StatusOK || st.Status != CertificateCritical || st.DaysRemaining < 2.9 || st.DaysRemaining > 3 {
+		t.Fatalf("got %d %+v, want a critical certificate three days from expiry", code, st)
+	}
+
+	// The rotated files are reloaded by the next check.
+	writeKeyPair(t, dir, time.Now().Add(-time.Hour))
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	certs.check()
+	if code, st := health(); code != http.StatusServiceUnavailable || st.Status != CertificateExpired {
+		t.Fatalf("got %d %+v, want the expired certificate reloaded", code, st)
+	}
+}
+
+func TestOCSPStapling(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ca"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour), IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	status := ocsp.Good
+	var mu sync.Mutex
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		tmpl := ocsp.Response{Status: status, SerialNumber: req.SerialNumber, ThisUpdate: time.Now().Add(-time.Minute), NextUpdate: time.Now().Add(2 * time.Hour), RevokedAt: time.Now().Add(-time.Minute)}
+		mu.Unlock()
+		resp, err := ocsp.CreateResponse(ca, ca, tmpl, caKey)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "127.0.0.1"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour), OCSPServer: []string{responder.URL}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	if err := os.WriteFile(certFile, chain, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := newCertificateChecker(certFile, keyFile, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait := certs.refreshStaple()
+	served, _ := certs.getCertificate(nil)
+	if served.OCSPStaple == nil {
+		t.Fatal("no OCSP response stapled")
+	}
+	if wait < 50*time.Minute || wait > time.Hour {
+		t.Fatalf("refreshing in %v, want halfway through the response's validity", wait)
+	}
+	if again := certs.refreshStaple(); again > wait {
+		t.Fatalf("refreshing in %v after %v, want the fresh staple kept", again, wait)
+	}
+
+	// A revoked certificate is no longer stapled once the staple is refreshed.
+	mu.Lock()
+	status = ocsp.Revoked
+	mu.Unlock()
+	certs.mu.Lock()
+	certs.stapleRefresh = time.Time{}
+	certs.mu.Unlock()
+	certs.refreshStaple()
+	if served, _ := certs.getCertificate(nil); served.OCSPStaple != nil {
+		t.Fatal("stapled the response of a revoked certificate")
+	}
+}
+
+func TestTicketRotation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "127.0.0.1"}, NotAfter: time.Now().Add(time.Hour), IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	done := make(chan struct{})
+	defer close(done)
+	go rotateTicketKeys(cfg, 200*time.Millisecond, done, zerolog.Nop())
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("x"))
+			conn.Close()
+		}
+	}()
+
+	client := &tls.Config{InsecureSkipVerify: true, ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+	resumed := func() bool {
+		t.Helper()
+		conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		io.ReadAll(conn)
+		return conn.ConnectionState().DidResume
+	}
+
+	resumed()
+	if !resumed() {
+		t.Fatal("session not resumed with the current ticket key")
+	}
+	time.Sleep(time.Duration(ticketKeysKept+2) * 200 * time.Millisecond)
+	if resumed() {
+		t.Fatal("session resumed with a ticket key rotated out")
+	}
+}
+
+func TestAutocert(t *testing.T) {
+	ts := New(WithConfig(&config.Config{
+		Address:              "127.0.0.1",
+		Port:                 "7002",
+		EnableAutocert:       true,
+		AutocertDomains:      []string{"example.com"},
+		AutocertCacheDir:     t.TempDir(),
+		AutocertHTTPAddress:  "127.0.0.1:7003",
+		AutocertDirectoryURL: "http://127.0.0.1:1/directory",
+	}), WithSignals())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://127.0.0.1:7003/account?tab=1"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "https://127.0.0.1:443/account?tab=1" {
+		t.Fatalf("got %d to %q, want a redirect to https", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if ts.instance.TLSConfig == nil || ts.instance.TLSConfig.GetCertificate == nil {
+		t.Fatal("main listener does not serve TLS")
+	}
+	if protos := strings.Join(ts.instance.TLSConfig.NextProtos, ","); protos != "h2,http/1.1,acme-tls/1" {
+		t.Fatalf("got ALPN protocols %s, want HTTP/2 offered beside the ACME challenges", protos)
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "127.0.0.1"}, NotAfter: time.Now().Add(time.Hour), IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := New(WithConfig(&config.Config{
+		Address:           "127.0.0.1",
+		Port:              "7002",
+		Experimental:      true,
+		EnableTLS:         true,
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+		TLSSessionTickets: true,
+		EnableHTTP3:       true,
+	}), WithSignals())
+	ts.LoadRouter([]router.Router{
+		router.NewRouter([]router.Route{
+			router.NewGetRoute("/proto", true, false, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}),
+		}, true),
+	})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- ts.StartE()
+	}()
+	defer func() {
+		ts.Stop(context.Background())
+		<-errs
+	}()
+
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	get := func(client *http.Client) (*http.Response, string) {
+		t.Helper()
+		var resp *http.Response
+		var err error
+		for i := 0; i < 50; i++ {
+			if resp, err = client.Get("https://127.0.0.1:7002/proto"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, _ := get(&http.Client{Transport: &http.Transport{TLSClientConfig: insecure}})
+	if alt := resp.Header.Get("Alt-Svc"); !strings.Contains(alt, `h3=":7002"`) {
+		t.Fatalf("got Alt-Svc %q, want HTTP/3 advertised", alt)
+	}
+	quic := &http3.RoundTripper{TLSClientConfig: insecure}
+	defer quic.Close()
+	if _, proto := get(&http.Client{Transport: quic}); proto != "HTTP/3.0" {
+		t.Fatalf("got %q, want HTTP/3.0", proto)
+	}
+	if text := ts.Registry().Text(); !strings.Contains(text, "ramchi_tls_certificate_days_remaining 0.04") {
+		t.Fatalf("got metrics %s, want the hour left on the certificate", text)
+	}
+	if ts.http3.QuicConfig.Allow0RTT {
+		t.Fatal("0-RTT accepted without tlsEarlyData")
+	}
+}
+
+func TestRejectEarlyData(t *testing.T) {
+	h := rejectEarlyData(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	pending, complete := make(chan struct{}), make(chan struct{})
+	close(complete)
+	for _, tc := range []struct {
+		method    string
+		handshake chan struct{}
+		want      int
+	}{
+		{http.MethodGet, pending, http.StatusOK},
+		{http.MethodPost, pending, http.StatusTooEarly},
+		{http.MethodPost, complete, http.StatusOK},
+		{http.MethodPost, nil, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		if tc.handshake != nil {
+			req = req.WithContext(context.WithValue(req.Context(), handshakeKey{}, (<-chan struct{})(tc.handshake)))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.method, rec.Code, tc.want)
+		}
+	}
+}
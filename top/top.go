@@ -0,0 +1,206 @@
+// Package top counts requests per client, to identify the heaviest clients
+// without analysing logs.
+package top
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/metrics"
+	"github.com/Etwodev/ramchi/middleware"
+	"github.com/Etwodev/ramchi/router"
+)
+
+// Path is the conventional path of the report route.
+const Path = "/_top"
+
+// Client is the request count of a client. Counts are approximate once more clients
+// are seen than the counter tracks, overestimating by at most Error.
+type Client struct {
+	Key      string `json:"key"`
+	Requests int64  `json:"requests"`
+	Error    int64  `json:"error"`
+}
+
+// Counter tracks the request counts of the most active clients over the current
+// and previous window, in memory bounded by its capacity.
+type Counter struct {
+	// Key returns the client of a request.
+	Key      func(r *http.Request) string
+	capacity int
+	window   time.Duration
+
+	mu       sync.Mutex
+	current  map[string]*Client
+	previous map[string]*Client
+	rotated  time.Time
+	heaviest int64
+	// tracked and top, when registered, record the clients of the current window
+	// and the requests of the most active one.
+	tracked *metrics.Gauge
+	top     *metrics.Gauge
+}
+
+// New initializes a counter tracking up to capacity clients per window, keyed by
+// the X-Api-Key header or, when absent, the client IP.
+func New(capacity int, window time.Duration) *Counter {
+	return &Counter{
+		Key:      ByAPIKeyOrIP,
+		capacity: capacity,
+		window:   window,
+		current:  make(map[string]*Client),
+		previous: make(map[string]*Client),
+		rotated:  time.Now(),
+	}
+}
+
+// ByIP keys requests by client IP.
+func ByIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// ByAPIKeyOrIP keys requests by the X-Api-Key header, falling back to the client IP.
+// Keys are hashed, so that the report does not disclose credentials.
+func ByAPIKeyOrIP(r *http.Request) string {
+	if key := r.Header.Get("X-Api-Key"); key != "" {
+		return "key:" + helpers.Hash(key)
+	}
+	return ByIP(r)
+}
+
+// Add counts a request from the client. When the window is full, the least active
+// client is replaced, the new client inheriting its count as error (the
+// space-saving algorithm), so that heavy clients are never lost.
+func (c *Counter) Add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if elapsed := now.Sub(c.rotated); elapsed >= c.window {
+		c.previous, c.current, c.rotated = c.current, make(map[string]*Client), now
+		if elapsed >= 2*c.window {
+			// The previous window saw no requests.
+			c.previous = make(map[string]*Client)
+		}
+		c.heaviest = 0
+	}
+	defer c.record()
+
+	if cl, ok := c.current[key]; ok {
+		cl.Requests++
+		c.heaviest = max(c.heaviest, cl.Requests)
+		return
+	}
+	if len(c.current) < c.capacity {
+		c.current[key] = &Client{Key: key, Requests: 1}
+		c.heaviest = max(c.heaviest, 1)
+		return
+	}
+
+	var min *Client
+	for _, cl := range c.current {
+		if min == nil || cl.Requests < min.Requests {
+			min = cl
+		}
+	}
+	delete(c.current, min.Key)
+	c.current[key] = &Client{Key: key, Requests: min.Requests + 1, Error: min.Requests}
+	c.heaviest = max(c.heaviest, min.Requests+1)
+}
+
+// record updates the registered gauges, with c.mu held.
+func (c *Counter) record() {
+	if c.tracked == nil {
+		return
+	}
+	c.tracked.Set(float64(len(c.current)))
+	c.top.Set(float64(c.heaviest))
+}
+
+// Top returns the n most active clients over the current and previous window.
+func (c *Counter) Top(n int) []Client {
+	c.mu.Lock()
+	merged := make(map[string]Client, len(c.current)+len(c.previous))
+	for _, m := range []map[string]*Client{c.previous, c.current} {
+		for key, cl := range m {
+			sum := merged[key]
+			sum.Key = key
+			sum.Requests += cl.Requests
+			sum.Error += cl.Error
+			merged[key] = sum
+		}
+	}
+	c.mu.Unlock()
+
+	clients := make([]Client, 0, len(merged))
+	for _, cl := range merged {
+		clients = append(clients, cl)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Requests == clients[j].Requests {
+			return clients[i].Key < clients[j].Key
+		}
+		return clients[i].Requests > clients[j].Requests
+	})
+	if n >= 0 && len(clients) > n {
+		clients = clients[:n]
+	}
+	return clients
+}
+
+// Handler counts each request once it has been served, skipping those of routes
+// marked noisy or internal as sampled by helpers.Observed.
+func (c *Counter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if !helpers.Observed(r) {
+			return
+		}
+		if key := c.Key(r); key != "" {
+			c.Add(key)
+		}
+	})
+}
+
+// Middleware returns the counter as a server middleware.
+func (c *Counter) Middleware(opts ...middleware.MiddlewareWrapper) middleware.Middleware {
+	return middleware.NewMiddleware(c.Handler, "top", true, false, opts...)
+}
+
+// Route returns a route reporting the most active clients, 10 by default or as many as
+// its n query parameter requests. The report identifies clients, so the route should
+// be protected or served on an internal listener.
+func (c *Counter) Route(path string, opts ...router.RouteWrapper) router.Route {
+	return router.NewGetRoute(path, true, false, func(w http.ResponseWriter, r *http.Request) {
+		n := 10
+		if v := r.URL.Query().Get("n"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 {
+				helpers.JSONError(w, r, http.StatusBadRequest, "n must be a positive integer")
+				return
+			}
+			n = parsed
+		}
+		helpers.JSON(w, r, http.StatusOK, c.Top(n))
+	}, opts...)
+}
+
+// Register records in reg, such as the Registry of the server, the clients tracked
+// in the current window and the requests of the most active one. Clients are not
+// labelled, as the report route identifies them.
+func (c *Counter) Register(reg *metrics.Registry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracked = reg.Gauge("ramchi_top_clients", "Clients tracked in the current window.")
+	c.top = reg.Gauge("ramchi_top_client_requests", "Requests of the most active client in the current window.")
+	c.record()
+}
@@ -0,0 +1,46 @@
+package top
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/metrics"
+)
+
+func TestCounter(t *testing.T) {
+	c := New(2, time.Hour)
+	for i := 0; i < 5; i++ {
+		c.Add("203.0.113.7")
+	}
+	c.Add("203.0.113.8")
+	c.Add("203.0.113.8")
+	c.Add("203.0.113.9")
+
+	top := c.Top(2)
+	if len(top) != 2 || top[0].Key != "203.0.113.7" || top[0].Requests != 5 {
+		t.Fatalf("expected heaviest client first, got %+v", top)
+	}
+	if top[1].Key != "203.0.113.9" || top[1].Requests != 3 || top[1].Error != 2 {
+		t.Fatalf("expected newest client to replace the least active, got %+v", top[1])
+	}
+}
+
+func TestCounterRegister(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := New(10, time.Hour)
+	c.Register(reg)
+	for _, key := range []string{"203.0.113.7", "203.0.113.7", "203.0.113.7", "203.0.113.8"} {
+		c.Add(key)
+	}
+
+	text := reg.Text()
+	for _, want := range []string{"ramchi_top_clients 2\n", "ramchi_top_client_requests 3\n"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "203.0.113") {
+		t.Fatal("metrics identify clients")
+	}
+}
@@ -0,0 +1,155 @@
+package ramchi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/Etwodev/ramchi/helpers"
+)
+
+// maxTypedBody bounds the request bodies decoded by Adapt.
+const maxTypedBody = 1 << 20
+
+// Empty is used as the request type of Adapt for requests without input, or as
+// the response type for responses without a body, answered with 204 No Content.
+type Empty struct{}
+
+// StatusError is an error answered with its status and message by Adapt.
+type StatusError struct {
+	Status  int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// Errorf returns a StatusError with the status and formatted message.
+func Errorf(status int, format string, args ...interface{}) error {
+	return &StatusError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validator is implemented by request types validating themselves once bound.
+type Validator interface {
+	Validate() error
+}
+
+// Adapt adapts a typed function to a handler, binding the request struct from the
+// request and writing the response as JSON.
+func Adapt[TReq any, TResp any](fn func(ctx context.Context, req TReq) (TResp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TReq
+		if err := bind(w, r, &req); err != nil {
+			var se *StatusError
+			if errors.As(err, &se) {
+				helpers.Problem(w, r, se.Status, se.Message)
+				return
+			}
+			helpers.Problem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := helpers.ValidatePasswords(&req); err != nil {
+			helpers.Problem(w, r, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if v, ok := any(&req).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				helpers.Problem(w, r, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			var se *StatusError
+			if errors.As(err, &se) {
+				helpers.Problem(w, r, se.Status, se.Message)
+				return
+			}
+			HandleRequest(w, r, "Adapt", err, "Handler failed", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := any(resp).(Empty); ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		helpers.JSON(w, r, http.StatusOK, resp)
+	}
+}
+
+// bind decodes the request into v, a pointer to a struct.
+func bind(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if r.Body != nil && r.ContentLength != 0 && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if ct := r.Header.Get("Content-Type"); ct != "" {
+			mt, _, err := mime.ParseMediaType(ct)
+			if err == nil && (mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data") {
+				r.Body = http.MaxBytesReader(w, r.Body, maxTypedBody)
+				if err := helpers.BindForm(r, v); err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						return &StatusError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+					}
+					return err
+				}
+				return bindParams(r, v)
+			}
+			if err != nil || mt != "application/json" {
+				return &StatusError{Status: http.StatusUnsupportedMediaType, Message: "request body must be JSON or a form"}
+			}
+		}
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTypedBody)).Decode(v)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &StatusError{Status: http.StatusRequestEntityTooLarge, Message: "request body too large"}
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("invalid request body: %v", err)
+		}
+	}
+
+	return bindParams(r, v)
+}
+
+// bindParams binds the fields of v tagged with path and query parameters.
+func bindParams(r *http.Request, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	query := r.URL.Query()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if name, ok := f.Tag.Lookup("path"); ok {
+			if value := helpers.URLParam(r, name); value != "" {
+				if err := helpers.ParseValue(rv.Field(i), value); err != nil {
+					return fmt.Errorf("path parameter %s: %v", name, err)
+				}
+			}
+		}
+		if name, ok := f.Tag.Lookup("query"); ok {
+			values, present := query[name]
+			if !present {
+				continue
+			}
+			field := rv.Field(i)
+			if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String {
+				field.Set(reflect.ValueOf(values))
+				continue
+			}
+			if err := helpers.ParseValue(field, values[0]); err != nil {
+				return fmt.Errorf("query parameter %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// upgradeListenerEnv names the descriptor of the listener handed to a new process.
+	upgradeListenerEnv = "RAMCHI_LISTENER_FD"
+	// upgradeReadyEnv names the descriptor the new process closes once it is serving.
+	upgradeReadyEnv = "RAMCHI_READY_FD"
+	// upgradeTimeout is how long the old process waits for the new one to become ready.
+	upgradeTimeout = 30 * time.Second
+)
+
+// upgradeSignal triggers an upgrade, so it may not begin a shutdown.
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// inheritedListener returns the listener handed over by the process which started
+// this one during an upgrade.
+func inheritedListener() (net.Listener, bool, error) {
+	v, ok := os.LookupEnv(upgradeListenerEnv)
+	if !ok {
+		return nil, false, nil
+	}
+	os.Unsetenv(upgradeListenerEnv)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, false, fmt.Errorf("inheritedListener: failed parsing descriptor: %w", err)
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("inheritedListener: failed creating listener: %w", err)
+	}
+	return ln, true, nil
+}
+
+// notifyReady tells the process which started this one during an upgrade that
+// it may stop serving.
+func (s *Server) notifyReady() {
+	v, ok := os.LookupEnv(upgradeReadyEnv)
+	if !ok {
+		return
+	}
+	os.Unsetenv(upgradeReadyEnv)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		s.log.Warn().Str("Function", "notifyReady").Err(err).Msg("Failed parsing descriptor")
+		return
+	}
+	if err := os.NewFile(uintptr(fd), "ready").Close(); err != nil {
+		s.log.Warn().Str("Function", "notifyReady").Err(err).Msg("Failed notifying parent")
+	}
+}
+
+// watchUpgrade starts a new process from the current executable on each SIGUSR2,
+// handing it the listener. Once the new process is serving, this one shuts down
+// gracefully, so that no connection is refused during the upgrade.
+func (s *Server) watchUpgrade(ln net.Listener) {
+	sigusr2 := make(chan os.Signal, 1)
+	signal.Notify(sigusr2, upgradeSignal)
+	defer signal.Stop(sigusr2)
+
+	for {
+		select {
+		case <-sigusr2:
+		case <-s.idle:
+			return
+		}
+		s.log.Info().Str("Function", "watchUpgrade").Msg("Upgrade requested")
+		pid, err := upgrade(ln)
+		if err != nil {
+			s.log.Error().Str("Function", "watchUpgrade").Err(err).Msg("Upgrade failed, continuing to serve")
+			continue
+		}
+		s.log.Info().Str("Function", "watchUpgrade").Int("PID", pid).Msg("Upgrade complete, shutting down")
+		s.shutdown()
+		return
+	}
+}
+
+// upgrade starts the new process and waits for it to become ready, returning its PID.
+func upgrade(ln net.Listener) (int, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return 0, errors.New("upgrade: listener does not expose its descriptor")
+	}
+	lf, err := filer.File()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: failed duplicating listener: %w", err)
+	}
+	defer lf.Close()
+
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: failed creating pipe: %w", err)
+	}
+	defer ready.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		notify.Close()
+		return 0, fmt.Errorf("upgrade: failed locating executable: %w", err)
+	}
+
+	// The listener and pipe are the first extra files, so descriptors 3 and 4 in the new process.
+	env := append(os.Environ(), upgradeListenerEnv+"=3", upgradeReadyEnv+"=4")
+	process, err := os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, lf, notify},
+	})
+	notify.Close()
+	if err != nil {
+		return 0, fmt.Errorf("upgrade: failed starting process: %w", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		process.Wait()
+		close(exited)
+	}()
+
+	// The read returns EOF once the new process closes its end, or exits.
+	done := make(chan struct{})
+	go func() {
+		ready.Read(make([]byte, 1))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(upgradeTimeout):
+		process.Kill()
+		return 0, errors.New("upgrade: timed out waiting for process")
+	}
+
+	// An exit also closes the pipe, so give the process a moment to be seen exiting.
+	select {
+	case <-exited:
+		return 0, errors.New("upgrade: process exited before becoming ready")
+	case <-time.After(100 * time.Millisecond):
+	}
+	return process.Pid, nil
+}
@@ -0,0 +1,113 @@
+//go:build !windows
+
+package ramchi
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Etwodev/ramchi/config"
+)
+
+// dupListener returns a duplicate of the listener's descriptor, as a new process
+// receives it.
+func dupListener(t *testing.T, ln net.Listener) int {
+	t.Helper()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func TestInheritedListener(t *testing.T) {
+	if _, ok, err := inheritedListener(); ok || err != nil {
+		t.Fatalf("got %v and %v without a handed over listener", ok, err)
+	}
+
+	parent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parent.Close()
+	t.Setenv(upgradeListenerEnv, strconv.Itoa(dupListener(t, parent)))
+
+	ln, ok, err := inheritedListener()
+	if err != nil || !ok {
+		t.Fatalf("got %v and %v, want the handed over listener", ok, err)
+	}
+	defer ln.Close()
+	if _, set := os.LookupEnv(upgradeListenerEnv); set {
+		t.Fatal("descriptor left in the environment of later processes")
+	}
+	if ln.Addr().String() != parent.Addr().String() {
+		t.Fatalf("got %s, want %s", ln.Addr(), parent.Addr())
+	}
+
+	// Connections to the old process's address are accepted by the new one.
+	parent.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.Write([]byte("new"))
+			conn.Close()
+		}
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if b, _ := io.ReadAll(conn); string(b) != "new" {
+		t.Fatalf("got %q from the inherited listener", b)
+	}
+}
+
+func TestNotifyReady(t *testing.T) {
+	ready, notify, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ready.Close()
+	fd, err := syscall.Dup(int(notify.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notify.Close()
+	t.Setenv(upgradeReadyEnv, strconv.Itoa(fd))
+
+	(&Server{}).notifyReady()
+	ready.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := ready.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("got %v, want the parent's pipe closed", err)
+	}
+}
+
+func TestUpgradeListeners(t *testing.T) {
+	// Only the main listener is handed over, so a server binding others refuses to
+	// start rather than fail to bind them in the new process.
+	ts := New(WithConfig(&config.Config{
+		Address:       "127.0.0.1",
+		Port:          "7002",
+		EnableUpgrade: true,
+		AdminAddress:  "127.0.0.1:7003",
+		Listeners:     map[string]string{"internal": "127.0.0.1:7004"},
+	}), WithSignals())
+	err := ts.StartE()
+	for _, want := range []string{"listeners: cannot be set with enableUpgrade", "adminAddress: cannot be set with enableUpgrade"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("got %v, want %q", err, want)
+		}
+	}
+}
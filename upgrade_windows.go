@@ -0,0 +1,23 @@
+//go:build windows
+
+package ramchi
+
+import (
+	"net"
+	"os"
+)
+
+// upgradeSignal is nil, as upgrades are not supported on Windows.
+var upgradeSignal os.Signal
+
+// inheritedListener always reports no listener, as upgrades are not supported on Windows.
+func inheritedListener() (net.Listener, bool, error) {
+	return nil, false, nil
+}
+
+func (s *Server) notifyReady() {}
+
+// watchUpgrade logs that upgrades are not supported on Windows.
+func (s *Server) watchUpgrade(ln net.Listener) {
+	s.log.Warn().Str("Function", "watchUpgrade").Msg("Upgrades are not supported on Windows")
+}
@@ -0,0 +1,121 @@
+// Package watchdog guards a process against exhausting its memory or goroutines,
+// collecting garbage, reporting pressure and dumping goroutines before it is killed.
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "watchdog").Logger()
+
+// relief is the fraction of a threshold under which pressure is relieved, so that
+// a process hovering at a threshold does not flap.
+const relief = 0.9
+
+// Watchdog checks the heap and goroutines of the process at each interval of Run.
+// A heap over its threshold is first collected; the process is under pressure
+// while the heap remains over its threshold or the goroutines over theirs, until
+// both fall under 90% of them.
+type Watchdog struct {
+	maxHeap       uint64
+	maxGoroutines int
+	interval      time.Duration
+	onPressure    func(pressure bool)
+	pressure      bool
+}
+
+// Option configures a Watchdog.
+type Option func(w *Watchdog)
+
+// WithPressure calls fn when the process comes under pressure and when it is
+// relieved, such as to fail readiness checks so that load is shed.
+func WithPressure(fn func(pressure bool)) Option {
+	return func(w *Watchdog) {
+		w.onPressure = fn
+	}
+}
+
+// New returns a Watchdog with the thresholds of heap bytes and goroutines, zero
+// being unlimited.
+func New(maxHeap uint64, maxGoroutines int, interval time.Duration, opts ...Option) *Watchdog {
+	w := &Watchdog{maxHeap: maxHeap, maxGoroutines: maxGoroutines, interval: interval}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// SetMemoryLimit sets the soft memory limit of the runtime, as GOMEMLIMIT does, so
+// that garbage is collected more eagerly as the limit nears. A limit of zero keeps
+// the current one. It returns the previous limit.
+func SetMemoryLimit(limit int64) int64 {
+	if limit <= 0 {
+		return debug.SetMemoryLimit(-1)
+	}
+	return debug.SetMemoryLimit(limit)
+}
+
+// Run checks the process until the context is done, so that it may be loaded as a
+// worker of a server.
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check checks the process once, returning whether it is under pressure.
+func (w *Watchdog) Check() bool {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	heap := m.HeapAlloc
+	if w.maxHeap > 0 && heap >= w.maxHeap {
+		runtime.GC()
+		debug.FreeOSMemory()
+		runtime.ReadMemStats(&m)
+		log.Warn().Str("Function", "Check").Uint64("Heap", heap).Uint64("Collected", heap-min(heap, m.HeapAlloc)).Uint64("Threshold", w.maxHeap).Msg("Heap over threshold, collected garbage")
+		heap = m.HeapAlloc
+	}
+	goroutines := runtime.NumGoroutine()
+
+	heapOver := w.maxHeap > 0 && heap >= w.maxHeap
+	goroutinesOver := w.maxGoroutines > 0 && goroutines >= w.maxGoroutines
+	heapClear := w.maxHeap == 0 || float64(heap) < relief*float64(w.maxHeap)
+	goroutinesClear := w.maxGoroutines == 0 || float64(goroutines) < relief*float64(w.maxGoroutines)
+
+	switch {
+	case !w.pressure && (heapOver || goroutinesOver):
+		w.pressure = true
+		event := log.Error().Str("Function", "Check").Uint64("Heap", heap).Int("Goroutines", goroutines)
+		if goroutinesOver {
+			var dump bytes.Buffer
+			_ = pprof.Lookup("goroutine").WriteTo(&dump, 1)
+			event = event.Str("Dump", dump.String())
+		}
+		event.Msg("Process under pressure")
+		if w.onPressure != nil {
+			w.onPressure(true)
+		}
+	case w.pressure && heapClear && goroutinesClear:
+		w.pressure = false
+		log.Info().Str("Function", "Check").Uint64("Heap", heap).Int("Goroutines", goroutines).Msg("Process pressure relieved")
+		if w.onPressure != nil {
+			w.onPressure(false)
+		}
+	}
+	return w.pressure
+}
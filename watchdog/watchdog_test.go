@@ -0,0 +1,34 @@
+package watchdog
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCheckGoroutines(t *testing.T) {
+	var pressures []bool
+	w := New(0, runtime.NumGoroutine()+10, 0, WithPressure(func(pressure bool) {
+		pressures = append(pressures, pressure)
+	}))
+	if w.Check() {
+		t.Fatal("under pressure below the threshold")
+	}
+
+	stop := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() { <-stop }()
+	}
+	if !w.Check() || !w.Check() {
+		t.Fatal("not under pressure over the threshold")
+	}
+	close(stop)
+	for i := 0; i < 100 && w.Check(); i++ {
+		runtime.Gosched()
+	}
+	if w.Check() {
+		t.Fatal("pressure not relieved")
+	}
+	if len(pressures) != 2 || !pressures[0] || pressures[1] {
+		t.Fatalf("got pressure changes %v, want [true false]", pressures)
+	}
+}
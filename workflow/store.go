@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned when an instance does not exist.
+var ErrNotFound = errors.New("instance not found")
+
+// Store persists instances, so that they resume after a restart.
+type Store interface {
+	// Save creates or replaces the instance.
+	Save(ctx context.Context, inst *Instance) error
+	Load(ctx context.Context, id string) (*Instance, error)
+	// Pending returns the instances which are running or compensating.
+	Pending(ctx context.Context) ([]*Instance, error)
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	instances map[string]*Instance
+}
+
+// NewMemoryStore initializes a Store local to the process, whose instances do not
+// survive a restart.
+func NewMemoryStore() Store {
+	return &memoryStore{instances: make(map[string]*Instance)}
+}
+
+func (m *memoryStore) Save(ctx context.Context, inst *Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[inst.ID] = clone(inst)
+	return nil
+}
+
+func (m *memoryStore) Load(ctx context.Context, id string) (*Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inst, ok := m.instances[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(inst), nil
+}
+
+func (m *memoryStore) Pending(ctx context.Context) ([]*Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pending []*Instance
+	for _, inst := range m.instances {
+		if inst.Status == StatusRunning || inst.Status == StatusCompensating {
+			pending = append(pending, clone(inst))
+		}
+	}
+	return pending, nil
+}
+
+func clone(inst *Instance) *Instance {
+	c := *inst
+	c.Data = make(map[string]json.RawMessage, len(inst.Data))
+	for k, v := range inst.Data {
+		c.Data[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,260 @@
+// Package workflow runs multi-step operations as sagas: each step has a
+// compensation undoing it, run in reverse should a later step fail. Progress is
+// persisted after each step, so that instances resume after a restart.
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Etwodev/ramchi/helpers"
+	"github.com/Etwodev/ramchi/scheduler"
+
+	"github.com/rs/zerolog"
+)
+
+var log = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"}).With().Timestamp().Str("Group", "workflow").Logger()
+
+// ErrUnknownWorkflow is returned when starting a workflow which is not registered.
+var ErrUnknownWorkflow = errors.New("unknown workflow")
+
+// Status is the state of an instance.
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusCompleted    Status = "completed"
+	StatusCompensating Status = "compensating"
+	// StatusCompensated instances failed, and their completed steps were undone.
+	StatusCompensated Status = "compensated"
+	// StatusFailed instances failed to compensate, and need intervention.
+	StatusFailed Status = "failed"
+)
+
+// Step is a unit of a workflow. As an instance may be interrupted after a step
+// completes but before its progress is saved, steps and compensations must be
+// idempotent.
+type Step struct {
+	Name string
+	Do   func(ctx context.Context, inst *Instance) error
+	// Compensate undoes Do, and may be nil for steps needing no undoing.
+	Compensate func(ctx context.Context, inst *Instance) error
+}
+
+// Workflow is a named sequence of steps.
+type Workflow struct {
+	Name  string
+	Steps []Step
+}
+
+// Instance is a run of a workflow.
+type Instance struct {
+	ID       string `json:"id"`
+	Workflow string `json:"workflow"`
+	Status   Status `json:"status"`
+	// Step is the number of steps completed and not compensated.
+	Step    int                        `json:"step"`
+	Data    map[string]json.RawMessage `json:"data"`
+	Error   string                     `json:"error,omitempty"`
+	Created time.Time                  `json:"created"`
+	Updated time.Time                  `json:"updated"`
+}
+
+// Set stores the value as JSON in the data of the instance, passed between steps.
+func (i *Instance) Set(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Set: failed marshalling %s: %w", key, err)
+	}
+	i.Data[key] = b
+	return nil
+}
+
+// Get decodes the value stored with Set, leaving v unchanged if it is absent.
+func (i *Instance) Get(key string, v interface{}) error {
+	b, ok := i.Data[key]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("Get: failed unmarshalling %s: %w", key, err)
+	}
+	return nil
+}
+
+// Engine starts instances of registered workflows, and advances them as a worker.
+type Engine struct {
+	Store Store
+	// Retry retries steps and compensations failing with errors marked with
+	// helpers.Retryable. Other errors fail the step at once.
+	Retry helpers.RetryPolicy
+	// Interval is how often the worker resumes pending instances as a scheduled job.
+	Interval time.Duration
+
+	workflows map[string]Workflow
+	mu        sync.Mutex
+	running   map[string]bool
+}
+
+// New initializes an engine retrying steps with helpers.DefaultRetryPolicy, and
+// resuming pending instances every second.
+func New(store Store) *Engine {
+	retry := helpers.DefaultRetryPolicy
+	retry.Name = "workflow"
+	return &Engine{
+		Store:     store,
+		Retry:     retry,
+		Interval:  time.Second,
+		workflows: make(map[string]Workflow),
+		running:   make(map[string]bool),
+	}
+}
+
+// Register registers the workflow, replacing one of the same name.
+func (e *Engine) Register(w Workflow) {
+	e.workflows[w.Name] = w
+}
+
+// Start persists a running instance of the workflow with the data, returning its
+// ID. It is run by the worker, so that handlers may answer at once.
+func (e *Engine) Start(ctx context.Context, workflow string, data map[string]interface{}) (string, error) {
+	if _, ok := e.workflows[workflow]; !ok {
+		return "", fmt.Errorf("Start: %s: %w", workflow, ErrUnknownWorkflow)
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Start: failed generating ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	inst := &Instance{ID: hex.EncodeToString(b), Workflow: workflow, Status: StatusRunning, Data: make(map[string]json.RawMessage), Created: now, Updated: now}
+	for k, v := range data {
+		if err := inst.Set(k, v); err != nil {
+			return "", fmt.Errorf("Start: %w", err)
+		}
+	}
+	if err := e.Store.Save(ctx, inst); err != nil {
+		return "", fmt.Errorf("Start: failed saving instance: %w", err)
+	}
+	return inst.ID, nil
+}
+
+// Get returns the instance, such as for reporting its status.
+func (e *Engine) Get(ctx context.Context, id string) (*Instance, error) {
+	inst, err := e.Store.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Get: %w", err)
+	}
+	return inst, nil
+}
+
+// Job returns the worker as a singleton job, so that one replica advances instances.
+func (e *Engine) Job() scheduler.Job {
+	return scheduler.Job{Name: "workflow", Every: e.Interval, Singleton: true, Run: e.Resume}
+}
+
+// Resume advances the pending instances, oldest first, until each completes,
+// compensates, or the context is done.
+func (e *Engine) Resume(ctx context.Context) error {
+	pending, err := e.Store.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("Resume: failed listing instances: %w", err)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+
+	for _, inst := range pending {
+		if ctx.Err() != nil {
+			return fmt.Errorf("Resume: %w", ctx.Err())
+		}
+		if err := e.advance(ctx, inst); err != nil {
+			log.Error().Str("Function", "Resume").Str("ID", inst.ID).Str("Workflow", inst.Workflow).Err(err).Msg("Failed advancing instance")
+		}
+	}
+	return nil
+}
+
+func (e *Engine) advance(ctx context.Context, inst *Instance) error {
+	e.mu.Lock()
+	if e.running[inst.ID] {
+		e.mu.Unlock()
+		return nil
+	}
+	e.running[inst.ID] = true
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.running, inst.ID)
+		e.mu.Unlock()
+	}()
+
+	w, ok := e.workflows[inst.Workflow]
+	if !ok {
+		return fmt.Errorf("advance: %s: %w", inst.Workflow, ErrUnknownWorkflow)
+	}
+
+	for inst.Status == StatusRunning {
+		if inst.Step >= len(w.Steps) {
+			inst.Status = StatusCompleted
+			break
+		}
+		step := w.Steps[inst.Step]
+		err := helpers.Retry(ctx, e.Retry, func(ctx context.Context) error { return step.Do(ctx, inst) })
+		if ctx.Err() != nil {
+			// Interrupted, rather than failed: the step is run again when resumed.
+			return fmt.Errorf("advance: %w", ctx.Err())
+		}
+		if err != nil {
+			log.Warn().Str("Function", "advance").Str("ID", inst.ID).Str("Step", step.Name).Err(err).Msg("Step failed, compensating")
+			inst.Status, inst.Error = StatusCompensating, fmt.Sprintf("%s: %v", step.Name, err)
+		} else {
+			inst.Step++
+		}
+		if err := e.save(ctx, inst); err != nil {
+			return fmt.Errorf("advance: %w", err)
+		}
+	}
+
+	for inst.Status == StatusCompensating {
+		if inst.Step == 0 {
+			inst.Status = StatusCompensated
+			break
+		}
+		step := w.Steps[inst.Step-1]
+		if step.Compensate != nil {
+			err := helpers.Retry(ctx, e.Retry, func(ctx context.Context) error { return step.Compensate(ctx, inst) })
+			if ctx.Err() != nil {
+				return fmt.Errorf("advance: %w", ctx.Err())
+			}
+			if err != nil {
+				log.Error().Str("Function", "advance").Str("ID", inst.ID).Str("Step", step.Name).Err(err).Msg("Compensation failed")
+				inst.Status, inst.Error = StatusFailed, fmt.Sprintf("%s; compensating %s: %v", inst.Error, step.Name, err)
+				break
+			}
+		}
+		inst.Step--
+		if err := e.save(ctx, inst); err != nil {
+			return fmt.Errorf("advance: %w", err)
+		}
+	}
+
+	if err := e.save(ctx, inst); err != nil {
+		return fmt.Errorf("advance: %w", err)
+	}
+	return nil
+}
+
+func (e *Engine) save(ctx context.Context, inst *Instance) error {
+	inst.Updated = time.Now().UTC()
+	if err := e.Store.Save(ctx, inst); err != nil {
+		return fmt.Errorf("save: failed saving instance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompensation(t *testing.T) {
+	var trail []string
+	step := func(name string, fail bool) Step {
+		return Step{
+			Name: name,
+			Do: func(ctx context.Context, inst *Instance) error {
+				if fail {
+					return errors.New("declined")
+				}
+				trail = append(trail, name)
+				return inst.Set(name, true)
+			},
+			Compensate: func(ctx context.Context, inst *Instance) error {
+				trail = append(trail, "undo "+name)
+				return nil
+			},
+		}
+	}
+
+	e := New(NewMemoryStore())
+	e.Register(Workflow{Name: "order", Steps: []Step{step("reserve", false), step("charge", false), step("ship", true)}})
+	e.Register(Workflow{Name: "refund", Steps: []Step{step("refund", false)}})
+
+	ctx := context.Background()
+	order, err := e.Start(ctx, "order", map[string]interface{}{"id": 7})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	refund, _ := e.Start(ctx, "refund", nil)
+	if _, err := e.Start(ctx, "unknown", nil); !errors.Is(err, ErrUnknownWorkflow) {
+		t.Fatalf("Start unknown: got %v", err)
+	}
+	if err := e.Resume(ctx); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+
+	inst, _ := e.Get(ctx, order)
+	if inst.Status != StatusCompensated || inst.Step != 0 {
+		t.Fatalf("order: got %s at step %d", inst.Status, inst.Step)
+	}
+	var id int
+	if inst.Get("id", &id); id != 7 {
+		t.Fatalf("order data: got id %d", id)
+	}
+	if inst, _ := e.Get(ctx, refund); inst.Status != StatusCompleted {
+		t.Fatalf("refund: got %s", inst.Status)
+	}
+	want := []string{"reserve", "charge", "undo charge", "undo reserve", "refund"}
+	if !reflect.DeepEqual(trail, want) {
+		t.Fatalf("trail: got %v, want %v", trail, want)
+	}
+}